@@ -31,6 +31,12 @@ func main() {
 	log.Printf("Loaded configuration: %d sites to monitor", len(cfg.Sites.List))
 	log.Printf("  Inter-test delay: %v", cfg.General.InterTestDelay)
 	log.Printf("  Global timeout: %v", cfg.General.GlobalTimeout)
+	if cfg.Dedup.Enabled {
+		log.Printf("  Failure dedup: enabled (heartbeat every %v)", cfg.Dedup.HeartbeatInterval)
+	}
+	if cfg.NetworkDown.Enabled {
+		log.Printf("  Network-down shortcut: enabled (after %d DNS failures)", cfg.NetworkDown.DNSFailureThreshold)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -47,12 +53,20 @@ func main() {
 	// Initialize output modules
 	dispatcher := metrics.NewDispatcher()
 
+	// Result-level outputs can collapse repeated failures; stats outputs need every result
+	dedup := func(o metrics.Output) metrics.Output {
+		if !cfg.Dedup.Enabled {
+			return o
+		}
+		return outputs.NewDedup(o, cfg.Dedup.HeartbeatInterval)
+	}
+
 	// Always enable JSON logger
 	logger, err := outputs.NewLogger(&cfg.Logging)
 	if err != nil {
 		log.Fatalf("Failed to create logger: %v", err)
 	}
-	dispatcher.RegisterOutput(logger)
+	dispatcher.RegisterOutput(dedup(logger))
 	log.Println("✓ JSON logger enabled")
 
 	// Initialize optional outputs
@@ -62,7 +76,7 @@ func main() {
 		log.Fatalf("Failed to create Elasticsearch output: %v", err)
 	}
 	if esOutput != nil {
-		dispatcher.RegisterOutput(esOutput)
+		dispatcher.RegisterOutput(dedup(esOutput))
 		log.Println("✓ Elasticsearch output enabled")
 	} else {
 		log.Println("Elasticsearch output not enabled (config.Enabled=false)")
@@ -77,7 +91,7 @@ func main() {
 		log.Println("✓ Prometheus exporter enabled")
 	}
 
-	snmpOutput, err := outputs.NewSNMPOutput(&cfg.SNMP)
+	snmpOutput, err := outputs.NewSNMPOutput(&cfg.SNMP, version, cfg.Sites.List)
 	if err != nil {
 		log.Fatalf("Failed to create SNMP output: %v", err)
 	}
@@ -86,6 +100,30 @@ func main() {
 		log.Println("✓ SNMP agent enabled")
 	}
 
+	syslogOutput, err := outputs.NewSyslogOutput(&cfg.Syslog)
+	if err != nil {
+		log.Fatalf("Failed to create syslog output: %v", err)
+	}
+	if syslogOutput != nil {
+		dispatcher.RegisterOutput(dedup(syslogOutput))
+		log.Println("✓ Syslog output enabled")
+	}
+
+	// SQLite keeps the full history, so it sees every result
+	sqliteOutput, err := outputs.NewSQLiteOutput(&cfg.SQLite)
+	if err != nil {
+		log.Fatalf("Failed to create SQLite output: %v", err)
+	}
+	if sqliteOutput != nil {
+		dispatcher.RegisterOutput(sqliteOutput)
+		log.Println("✓ SQLite output enabled")
+	}
+
+	// The weighted "is the internet up" verdict follows every result
+	availability := metrics.NewAvailability(cfg.Sites.List, cfg.General.AvailabilityThreshold)
+	dispatcher.RegisterOutput(availability)
+	log.Printf("✓ Availability verdict enabled (threshold %.2f)", cfg.General.AvailabilityThreshold)
+
 	// Initialize health check endpoint
 	healthCfg := &health.Config{
 		Enabled:       cfg.Advanced.HealthCheckEnabled,
@@ -98,9 +136,20 @@ func main() {
 		log.Fatalf("Failed to create health check server: %v", err)
 	}
 	if healthServer != nil {
+		healthServer.SetAvailability(availability)
 		log.Println("✓ Health check endpoint enabled")
 	}
 
+	// Decide what happens when every result sink is failing
+	if err := dispatcher.SetOutputFailurePolicy(metrics.OutputFailureConfig{
+		Policy:       cfg.Advanced.OutputFailurePolicy,
+		After:        cfg.Advanced.OutputFailureAfter,
+		FallbackFile: cfg.Advanced.OutputFallbackFile,
+		SetHealthy:   healthServer.SetHealthy,
+	}); err != nil {
+		log.Fatalf("Invalid output failure policy: %v", err)
+	}
+
 	// Create test loop
 	testLoop, err := testloop.NewTestLoop(cfg, browserCtrl, dispatcher)
 	if err != nil {
@@ -114,20 +163,29 @@ func main() {
 		loopDone <- testLoop.Run(ctx)
 	}()
 
-	// Set up signal handling for graceful shutdown
+	// Set up signal handling for graceful shutdown and site list reloads
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
 	// Wait for shutdown signal or loop error
 	log.Println("Internet Connection Monitor started. Press Ctrl+C to stop.")
 	log.Println()
 
-	select {
-	case <-sigChan:
-		log.Println("\nReceived shutdown signal...")
-	case err := <-loopDone:
-		if err != nil {
-			log.Printf("Test loop exited with error: %v", err)
+wait:
+	for {
+		select {
+		case <-reloadChan:
+			reloadSites(testLoop)
+		case <-sigChan:
+			log.Println("\nReceived shutdown signal...")
+			break wait
+		case err := <-loopDone:
+			if err != nil {
+				log.Printf("Test loop exited with error: %v", err)
+			}
+			break wait
 		}
 	}
 
@@ -177,6 +235,22 @@ func main() {
 		}
 	}
 
+	if syslogOutput != nil {
+		if err := syslogOutput.Close(); err != nil {
+			log.Printf("Error closing syslog output: %v", err)
+		} else {
+			log.Println("✓ Syslog output closed")
+		}
+	}
+
+	if sqliteOutput != nil {
+		if err := sqliteOutput.Close(); err != nil {
+			log.Printf("Error closing SQLite output: %v", err)
+		} else {
+			log.Println("✓ SQLite output closed")
+		}
+	}
+
 	// Close health check server
 	if healthServer != nil {
 		if err := healthServer.Close(); err != nil {
@@ -220,6 +294,18 @@ func loadConfig() (*config.Config, error) {
 	return cfg, nil
 }
 
+// reloadSites re-reads the configuration and applies its site list to the
+// running test loop; on error the current site list is kept
+func reloadSites(testLoop *testloop.TestLoop) {
+	log.Println("Received SIGHUP, reloading site list...")
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Reload failed, keeping current sites: %v", err)
+		return
+	}
+	testLoop.Reload(cfg)
+}
+
 func printBanner() {
 	fmt.Println("╔════════════════════════════════════════════════════════════════╗")
 	fmt.Println("║  Internet Connection Monitor                                   ║")
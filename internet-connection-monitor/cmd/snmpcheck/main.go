@@ -5,10 +5,17 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/outputs"
 )
 
 func main() {
@@ -20,10 +27,19 @@ func main() {
 	baseOID := flag.String("base", ".1.3.6.1.4.1.99999", "Base OID to query")
 	retries := flag.Int("retries", 3, "Number of SNMP retries")
 	timeout := flag.Duration("timeout", 3*time.Second, "Timeout for SNMP requests")
+	watch := flag.Bool("watch", false, "Poll repeatedly, printing each summary and its changes, until interrupted")
+	interval := flag.Duration("interval", 5*time.Second, "Time between polls with -watch")
+	export := flag.String("export", "", "Print a poller template or MIB module for the agent's OIDs instead of checking it (zabbix, telegraf or mib)")
 	flag.Parse()
 
+	if *export != "" {
+		if err := exportTemplate(*export, *baseOID, *target, *port, *community); err != nil {
+			log.Fatalf("failed to export %s template: %v", *export, err)
+		}
+		return
+	}
+
 	normalizedBase := normalizeOID(*baseOID)
-	cacheOID := normalizedBase + ".1.0"
 
 	client := &gosnmp.GoSNMP{
 		Target:    *target,
@@ -43,42 +59,144 @@ func main() {
 		_ = client.Conn.Close()
 	}()
 
+	if *watch {
+		watchAgent(client, normalizedBase, *interval)
+		return
+	}
+
+	summary, err := poll(client, normalizedBase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("SNMP agent healthy: %s\n", summary)
+}
+
+// pollSummary is what one check of the agent found
+type pollSummary struct {
+	cacheSize   uint64
+	totalVars   int
+	siteEntries int
+}
+
+func (p pollSummary) String() string {
+	return fmt.Sprintf("cache_size=%d, variables=%d, site_entries=%d", p.cacheSize, p.totalVars, p.siteEntries)
+}
+
+// delta formats the changes from prev, e.g. "cache_size=+3, variables=+0, site_entries=-1"
+func (p pollSummary) delta(prev pollSummary) string {
+	return fmt.Sprintf("cache_size=%+d, variables=%+d, site_entries=%+d",
+		int64(p.cacheSize)-int64(prev.cacheSize), p.totalVars-prev.totalVars, p.siteEntries-prev.siteEntries)
+}
+
+// poll reads the cache size and walks the agent's tree, failing if the agent
+// is unreachable or exports no site entries
+func poll(client *gosnmp.GoSNMP, normalizedBase string) (pollSummary, error) {
+	var summary pollSummary
+	cacheOID := normalizedBase + ".1.0"
+
 	response, err := client.Get([]string{cacheOID})
 	if err != nil {
-		log.Fatalf("failed to fetch cache OID %s: %v", cacheOID, err)
+		return summary, fmt.Errorf("failed to fetch cache OID %s: %w", cacheOID, err)
 	}
 	if len(response.Variables) == 0 {
-		log.Fatalf("no variables returned for cache OID %s", cacheOID)
+		return summary, fmt.Errorf("no variables returned for cache OID %s", cacheOID)
 	}
 
-	cacheSize, err := numericValue(response.Variables[0])
+	summary.cacheSize, err = numericValue(response.Variables[0])
 	if err != nil {
-		log.Fatalf("unable to parse cache size from %s: %v", cacheOID, err)
+		return summary, fmt.Errorf("unable to parse cache size from %s: %w", cacheOID, err)
 	}
 
-	var totalVars int
-	var siteEntries int
 	sitePrefix := normalizedBase + ".5."
 
 	err = client.Walk(normalizedBase, func(pdu gosnmp.SnmpPDU) error {
-		totalVars++
+		summary.totalVars++
 		if strings.HasPrefix(pdu.Name, sitePrefix) && strings.HasSuffix(pdu.Name, ".1") {
-			siteEntries++
+			summary.siteEntries++
 		}
 		return nil
 	})
 	if err != nil {
-		log.Fatalf("failed to walk SNMP tree at %s: %v", normalizedBase, err)
+		return summary, fmt.Errorf("failed to walk SNMP tree at %s: %w", normalizedBase, err)
+	}
+
+	if summary.totalVars == 0 {
+		return summary, fmt.Errorf("SNMP walk for %s returned no results", normalizedBase)
+	}
+	if summary.siteEntries == 0 {
+		return summary, fmt.Errorf("SNMP walk did not include any site entries under %s. Received %d variables", sitePrefix, summary.totalVars)
 	}
+	return summary, nil
+}
 
-	if totalVars == 0 {
-		log.Fatalf("SNMP walk for %s returned no results", normalizedBase)
+// watchAgent polls every interval, printing each poll's summary and its
+// changes since the previous successful poll, until interrupted. Failed polls
+// are reported and polling continues, since the agent being unreachable is
+// often what is being watched for.
+func watchAgent(client *gosnmp.GoSNMP, normalizedBase string, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
 	}
-	if siteEntries == 0 {
-		log.Fatalf("SNMP walk did not include any site entries under %s. Received %d variables", sitePrefix, totalVars)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Watching SNMP agent %s:%d every %v (Ctrl+C to stop)\n", client.Target, client.Port, interval)
+
+	var (
+		polls, failures int
+		first, last     pollSummary
+		seen            bool
+		started         = time.Now()
+	)
+	for {
+		polls++
+		now := time.Now().Format("15:04:05")
+		summary, err := poll(client, normalizedBase)
+		switch {
+		case err != nil:
+			failures++
+			fmt.Printf("%s UNHEALTHY: %v\n", now, err)
+		case !seen:
+			fmt.Printf("%s %s\n", now, summary)
+			first, seen = summary, true
+		default:
+			fmt.Printf("%s %s (%s)\n", now, summary, summary.delta(last))
+		}
+		if err == nil {
+			last = summary
+		}
+
+		select {
+		case <-sigChan:
+			fmt.Printf("\n%d polls over %v, %d failed\n", polls, time.Since(started).Round(time.Second), failures)
+			if seen {
+				fmt.Printf("Last: %s\nChange since first poll: %s\n", last, last.delta(first))
+			}
+			return
+		case <-ticker.C:
+		}
 	}
+}
 
-	fmt.Printf("SNMP agent healthy: cache_size=%d, variables=%d, site_entries=%d\n", cacheSize, totalVars, siteEntries)
+func exportTemplate(format, baseOID, target string, port int, community string) error {
+	switch format {
+	case "zabbix":
+		tmpl, err := outputs.ExportZabbixTemplate(baseOID)
+		if err != nil {
+			return err
+		}
+		fmt.Print(tmpl)
+	case "telegraf":
+		agent := fmt.Sprintf("udp://%s", net.JoinHostPort(target, strconv.Itoa(port)))
+		fmt.Print(outputs.ExportTelegrafConfig(baseOID, agent, community))
+	case "mib":
+		fmt.Print(outputs.ExportSMIv2Module(baseOID))
+	default:
+		return fmt.Errorf("unknown format %q (want zabbix, telegraf or mib)", format)
+	}
+	return nil
 }
 
 func normalizeOID(oid string) string {
@@ -0,0 +1,109 @@
+package browser
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/chromedp/chromedp"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// allocatorOptions returns the Chrome allocator options for testing a site:
+// the controller-wide options followed by any site-specific flags,
+// --proxy-server when proxyServer is set and --host-resolver-rules when
+// hostRules is.
+// Later flags override earlier ones with the same name.
+func (c *ControllerImpl) allocatorOptions(site models.SiteDefinition, proxyServer, hostRules string) []chromedp.ExecAllocatorOption {
+	flags := siteFlags(site)
+	if proxyServer != "" {
+		flags["proxy-server"] = proxyServer
+	}
+	if hostRules != "" {
+		flags["host-resolver-rules"] = hostRules
+	}
+	if len(flags) == 0 {
+		return c.allocatorOpts
+	}
+
+	opts := make([]chromedp.ExecAllocatorOption, 0, len(c.allocatorOpts)+len(flags))
+	opts = append(opts, c.allocatorOpts...)
+	for name, value := range flags {
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+	return opts
+}
+
+// protocolFlags returns the flags that decide which protocols Chrome may use
+// and whether it may resume TLS sessions.
+//
+// By default every connection is measured cold: HTTP/2 is disabled, so each
+// request to a host opens its own TCP and TLS connection instead of sharing
+// one; QUIC is disabled, as HTTP/3 has no separate TCP phase and can skip the
+// handshake with 0-RTT; and TLS session resumption is disabled, so every
+// handshake is a full one. This keeps DNS, TCP and TLS timings comparable from
+// test to test. The price is that pages load the way no real browser loads
+// them. EnableHTTP2 and EnableHTTP3 let Chrome negotiate those protocols, and
+// turning ForceFreshConnections off lets it resume TLS sessions: total load
+// times then reflect real-world performance, but connection phases get shorter
+// or disappear depending on what was reused, so they no longer compare with
+// cold results. Chrome only switches to HTTP/3 after an Alt-Svc header
+// announces it, so in a fresh browser the main document still comes over TCP.
+func protocolFlags(cfg *config.BrowserConfig) []chromedp.ExecAllocatorOption {
+	var opts []chromedp.ExecAllocatorOption
+	if !cfg.EnableHTTP2 {
+		opts = append(opts, chromedp.Flag("disable-http2", "true")) // Force HTTP/1.1 (no connection multiplexing)
+	}
+	if !cfg.EnableHTTP3 {
+		opts = append(opts, chromedp.Flag("disable-quic", "true")) // Disable HTTP/3
+	}
+	if cfg.ForceFreshConnections {
+		opts = append(opts, chromedp.Flag("disable-features", "NetworkService,TLSSessionResumption")) // Disable TLS session cache
+	} else {
+		opts = append(opts, chromedp.Flag("disable-features", "NetworkService"))
+	}
+	return opts
+}
+
+// siteFlags returns the Chrome command-line flags a site needs on top of the defaults.
+// A false value removes a default flag.
+func siteFlags(site models.SiteDefinition) map[string]interface{} {
+	flags := make(map[string]interface{})
+
+	// HTTP/3-only probe: undo the default disable-quic and force QUIC for the site's origin
+	if site.ForceHTTP3 {
+		flags["disable-quic"] = false
+		flags["enable-quic"] = true
+		if origin := quicOrigin(site.URL); origin != "" {
+			flags["origin-to-force-quic-on"] = origin
+		}
+	}
+
+	// Start Chrome in the site's locale; TestSite also overrides it per page
+	if locale, err := siteLocale(site.AcceptLanguage); err == nil && locale != "" {
+		flags["lang"] = locale
+	}
+
+	return flags
+}
+
+// dohFeature returns the --enable-features value that turns on Chrome's secure
+// DNS with a single DoH template (e.g. "https://dns.google/dns-query{?dns}").
+// Fallback is disabled so a DoH provider outage fails the DNS phase instead of
+// silently falling back to the system resolver.
+func dohFeature(template string) string {
+	return "DnsOverHttps:Fallback/false/Templates/" + url.QueryEscape(template)
+}
+
+// quicOrigin returns the host:port form Chrome expects for --origin-to-force-quic-on
+func quicOrigin(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSiteFlags_Default(t *testing.T) {
+	flags := siteFlags(models.SiteDefinition{URL: "https://example.com"})
+	if len(flags) != 0 {
+		t.Errorf("Expected no site flags by default, got %v", flags)
+	}
+}
+
+func TestSiteFlags_ForceHTTP3(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		origin string
+	}{
+		{name: "default port", url: "https://example.com/path", origin: "example.com:443"},
+		{name: "explicit port", url: "https://example.com:8443", origin: "example.com:8443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := siteFlags(models.SiteDefinition{URL: tt.url, ForceHTTP3: true})
+
+			if flags["disable-quic"] != false {
+				t.Errorf("Expected disable-quic to be removed, got %v", flags["disable-quic"])
+			}
+			if flags["enable-quic"] != true {
+				t.Errorf("Expected enable-quic, got %v", flags["enable-quic"])
+			}
+			if flags["origin-to-force-quic-on"] != tt.origin {
+				t.Errorf("Expected origin-to-force-quic-on %q, got %v", tt.origin, flags["origin-to-force-quic-on"])
+			}
+		})
+	}
+}
+
+func TestDoHFeature(t *testing.T) {
+	got := dohFeature("https://dns.google/dns-query{?dns}")
+	want := "DnsOverHttps:Fallback/false/Templates/https%3A%2F%2Fdns.google%2Fdns-query%7B%3Fdns%7D"
+	if got != want {
+		t.Errorf("dohFeature() = %q, want %q", got, want)
+	}
+}
+
+func TestProtocolFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func(cfg *config.BrowserConfig)
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "default",
+			set:     func(*config.BrowserConfig) {},
+			present: []string{"--disable-http2=true", "--disable-quic=true", "--disable-features=NetworkService,TLSSessionResumption"},
+		},
+		{
+			name:    "HTTP/2",
+			set:     func(cfg *config.BrowserConfig) { cfg.EnableHTTP2 = true },
+			present: []string{"--disable-quic=true"},
+			absent:  []string{"--disable-http2=true"},
+		},
+		{
+			name:    "HTTP/3",
+			set:     func(cfg *config.BrowserConfig) { cfg.EnableHTTP3 = true },
+			present: []string{"--disable-http2=true"},
+			absent:  []string{"--disable-quic=true"},
+		},
+		{
+			name:    "session resumption",
+			set:     func(cfg *config.BrowserConfig) { cfg.ForceFreshConnections = false },
+			present: []string{"--disable-features=NetworkService"},
+			absent:  []string{"--disable-features=NetworkService,TLSSessionResumption"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig().Browser
+			tt.set(&cfg)
+			c, err := NewControllerImpl(&cfg)
+			if err != nil {
+				t.Fatalf("NewControllerImpl failed: %v", err)
+			}
+
+			args := chromeArgs(t, c.allocatorOpts)
+			for _, flag := range tt.present {
+				if !slices.Contains(args, flag) {
+					t.Errorf("Expected %s, got %v", flag, args)
+				}
+			}
+			for _, flag := range tt.absent {
+				if slices.Contains(args, flag) {
+					t.Errorf("Expected no %s, got %v", flag, args)
+				}
+			}
+		})
+	}
+}
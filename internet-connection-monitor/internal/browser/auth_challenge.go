@@ -0,0 +1,186 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// credentials answer an HTTP authentication challenge. They are never put
+// in results or logs.
+type credentials struct {
+	username string
+	password string
+	origin   string // Only this challenger is answered; "" answers any
+}
+
+// siteCredentials returns the basic auth credentials for site, nil if it has
+// none. They are only offered to the site's own origin, so a third-party
+// resource or cross-origin redirect that asks for credentials doesn't get them.
+func siteCredentials(site models.SiteDefinition) (*credentials, error) {
+	if site.BasicAuthUser == "" && site.BasicAuthPass == "" {
+		return nil, nil
+	}
+	// Both would set the Authorization header
+	if site.OAuth2 != nil {
+		return nil, fmt.Errorf("basic auth can't be combined with oauth2")
+	}
+	origin, ok := normalizeOrigin(site.URL)
+	if !ok {
+		return nil, fmt.Errorf("cannot determine the origin of %q", site.URL)
+	}
+	return &credentials{username: site.BasicAuthUser, password: site.BasicAuthPass, origin: origin}, nil
+}
+
+// normalizeOrigin returns rawURL's origin as scheme://host:port, with the
+// scheme's default port filled in
+func normalizeOrigin(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port), true
+}
+
+// bearerToken is an Authorization header value for one origin's requests
+type bearerToken struct {
+	authorization string
+	origin        string // As normalizeOrigin returns it
+}
+
+// requestAuth authenticates one test's requests. The Fetch domain has a
+// single set of patterns per target, so everything that intercepts requests
+// goes through here: the bearer token is added to requests for its origin
+// only, the proxy's challenges are answered with proxy and the site's with
+// site. Any may be nil; challenges without credentials are left to Chrome's
+// default handling, which fails them headless. Credentials a challenger
+// refused aren't offered again for the same request, so bad credentials
+// surface as the 401 or 407.
+type requestAuth struct {
+	proxy  *credentials
+	site   *credentials
+	bearer *bearerToken
+
+	mu       sync.Mutex
+	answered map[fetch.RequestID]map[fetch.AuthChallengeSource]bool
+}
+
+// authenticateRequests returns an action that pauses every request so it can
+// be authenticated by a's credentials and bearer token. It enables the Fetch
+// domain once for both, since a second fetch.Enable would replace the first.
+func authenticateRequests(ctx context.Context, a *requestAuth) chromedp.Action {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		action := a.respond(ev)
+		if action == nil {
+			return
+		}
+		go func() {
+			c := chromedp.FromContext(ctx)
+			// The page may already be gone; nothing to do then
+			_ = action.Do(cdp.WithExecutor(ctx, c.Target))
+		}()
+	})
+	enable := fetch.Enable().WithPatterns([]*fetch.RequestPattern{{URLPattern: "*"}})
+	if a.proxy != nil || a.site != nil {
+		enable = enable.WithHandleAuthRequests(true)
+	}
+	return enable
+}
+
+// respond returns the command that answers a Fetch event, nil for other events
+func (a *requestAuth) respond(ev interface{}) chromedp.Action {
+	switch ev := ev.(type) {
+	case *fetch.EventAuthRequired:
+		return fetch.ContinueWithAuth(ev.RequestID, a.challengeResponse(ev))
+	case *fetch.EventRequestPaused:
+		continueRequest := fetch.ContinueRequest(ev.RequestID)
+		if headers := a.authorizedHeaders(ev.Request); headers != nil {
+			continueRequest = continueRequest.WithHeaders(headers)
+		}
+		return continueRequest
+	}
+	return nil
+}
+
+// authorizedHeaders returns req's headers with the bearer token added, or nil
+// to send req as is: without a token, or to any origin but the token's (CDNs,
+// analytics, a cross-origin redirect)
+func (a *requestAuth) authorizedHeaders(req *network.Request) []*fetch.HeaderEntry {
+	if a.bearer == nil || req == nil {
+		return nil
+	}
+	if origin, ok := normalizeOrigin(req.URL); !ok || origin != a.bearer.origin {
+		return nil
+	}
+	headers := []*fetch.HeaderEntry{{Name: "Authorization", Value: a.bearer.authorization}}
+	for name, value := range req.Headers {
+		if !strings.EqualFold(name, "Authorization") {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+		}
+	}
+	return headers
+}
+
+// challengeResponse picks the credentials for a challenge, if any apply
+func (a *requestAuth) challengeResponse(ev *fetch.EventAuthRequired) *fetch.AuthChallengeResponse {
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	if ev.AuthChallenge == nil {
+		return response
+	}
+
+	var creds *credentials
+	switch ev.AuthChallenge.Source {
+	case fetch.AuthChallengeSourceProxy:
+		creds = a.proxy
+	case fetch.AuthChallengeSourceServer:
+		creds = a.site
+	}
+	if creds == nil {
+		return response
+	}
+	if creds.origin != "" {
+		if origin, ok := normalizeOrigin(ev.AuthChallenge.Origin); !ok || origin != creds.origin {
+			return response
+		}
+	}
+
+	a.mu.Lock()
+	if a.answered == nil {
+		a.answered = make(map[fetch.RequestID]map[fetch.AuthChallengeSource]bool)
+	}
+	if a.answered[ev.RequestID] == nil {
+		a.answered[ev.RequestID] = make(map[fetch.AuthChallengeSource]bool)
+	}
+	retry := a.answered[ev.RequestID][ev.AuthChallenge.Source]
+	a.answered[ev.RequestID][ev.AuthChallenge.Source] = true
+	a.mu.Unlock()
+
+	if retry {
+		response.Response = fetch.AuthChallengeResponseResponseCancelAuth
+		return response
+	}
+	response.Response = fetch.AuthChallengeResponseResponseProvideCredentials
+	response.Username = creds.username
+	response.Password = creds.password
+	return response
+}
@@ -0,0 +1,165 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSiteCredentials(t *testing.T) {
+	site := models.SiteDefinition{URL: "https://Dashboard.example.com/login"}
+	if creds, err := siteCredentials(site); creds != nil || err != nil {
+		t.Errorf("Expected no credentials by default, got %+v, %v", creds, err)
+	}
+
+	site.BasicAuthUser, site.BasicAuthPass = "monitor", "secret"
+	creds, err := siteCredentials(site)
+	if err != nil {
+		t.Fatalf("siteCredentials failed: %v", err)
+	}
+	want := credentials{username: "monitor", password: "secret", origin: "https://dashboard.example.com:443"}
+	if *creds != want {
+		t.Errorf("Expected %+v, got %+v", want, *creds)
+	}
+
+	site.OAuth2 = &models.OAuth2Credentials{TokenURL: "https://auth.example.com/token"}
+	if _, err := siteCredentials(site); err == nil {
+		t.Error("Expected an error for basic auth with oauth2")
+	}
+}
+
+func TestRequestAuthSupplyCredentials(t *testing.T) {
+	a := &requestAuth{
+		proxy: &credentials{username: "proxyuser", password: "proxypass"},
+		site:  &credentials{username: "monitor", password: "secret", origin: "https://example.com:443"},
+	}
+	challenge := func(id fetch.RequestID, source fetch.AuthChallengeSource, origin string) *fetch.AuthChallengeResponse {
+		t.Helper()
+		action := a.respond(&fetch.EventAuthRequired{
+			RequestID:     id,
+			AuthChallenge: &fetch.AuthChallenge{Source: source, Origin: origin, Scheme: "basic", Realm: "dashboard"},
+		})
+		params, ok := action.(*fetch.ContinueWithAuthParams)
+		if !ok {
+			t.Fatalf("Expected Fetch.continueWithAuth, got %T", action)
+		}
+		if params.RequestID != id {
+			t.Fatalf("Expected an answer for request %s, got %s", id, params.RequestID)
+		}
+		return params.AuthChallengeResponse
+	}
+
+	// The site's challenge gets the site's credentials
+	got := challenge("1", fetch.AuthChallengeSourceServer, "https://example.com")
+	if got.Response != fetch.AuthChallengeResponseResponseProvideCredentials || got.Username != "monitor" || got.Password != "secret" {
+		t.Errorf("Expected the site's credentials, got %+v", got)
+	}
+
+	// A second challenge for the same request means they were refused
+	got = challenge("1", fetch.AuthChallengeSourceServer, "https://example.com")
+	if got.Response != fetch.AuthChallengeResponseResponseCancelAuth {
+		t.Errorf("Expected refused credentials to be cancelled, got %+v", got)
+	}
+
+	// The proxy's challenge gets the proxy's, even on the same request
+	got = challenge("1", fetch.AuthChallengeSourceProxy, "http://proxy.example.com:3128")
+	if got.Response != fetch.AuthChallengeResponseResponseProvideCredentials || got.Username != "proxyuser" || got.Password != "proxypass" {
+		t.Errorf("Expected the proxy's credentials, got %+v", got)
+	}
+
+	// Other origins get nothing
+	got = challenge("2", fetch.AuthChallengeSourceServer, "https://tracker.example.net")
+	if got.Response != fetch.AuthChallengeResponseResponseDefault || got.Username != "" || got.Password != "" {
+		t.Errorf("Expected another origin to get no credentials, got %+v", got)
+	}
+
+	// Paused requests are let through untouched
+	if _, ok := a.respond(&fetch.EventRequestPaused{RequestID: "3"}).(*fetch.ContinueRequestParams); !ok {
+		t.Error("Expected paused requests to be continued")
+	}
+	if a.respond(&fetch.EventRequestPaused{}) == nil || a.respond("other event") != nil {
+		t.Error("Expected only Fetch events to be answered")
+	}
+}
+
+func TestRequestAuthWithoutCredentials(t *testing.T) {
+	a := &requestAuth{proxy: &credentials{username: "proxyuser", password: "proxypass"}}
+	action := a.respond(&fetch.EventAuthRequired{
+		RequestID:     "1",
+		AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer, Origin: "https://example.com"},
+	})
+	got := action.(*fetch.ContinueWithAuthParams).AuthChallengeResponse
+	if got.Response != fetch.AuthChallengeResponseResponseDefault || got.Username != "" {
+		t.Errorf("Expected the proxy's credentials to stay with the proxy, got %+v", got)
+	}
+}
+
+func TestRequestAuthScopesBearerToken(t *testing.T) {
+	// OAuth2 behind an authenticated proxy: one handler does both
+	bearer, err := authorizeOrigin("https://api.example.com/status", "Bearer tok")
+	if err != nil {
+		t.Fatalf("authorizeOrigin failed: %v", err)
+	}
+	a := &requestAuth{proxy: &credentials{username: "proxyuser", password: "proxypass"}, bearer: bearer}
+	paused := func(id fetch.RequestID, url string) *fetch.ContinueRequestParams {
+		t.Helper()
+		params, ok := a.respond(&fetch.EventRequestPaused{
+			RequestID: id,
+			Request:   &network.Request{URL: url, Headers: network.Headers{"Accept": "text/html", "authorization": "Basic stale"}},
+		}).(*fetch.ContinueRequestParams)
+		if !ok {
+			t.Fatalf("Expected %s to be continued", url)
+		}
+		return params
+	}
+
+	params := paused("1", "https://api.example.com:443/status?x=1")
+	var authorization []string
+	for _, h := range params.Headers {
+		if strings.EqualFold(h.Name, "Authorization") {
+			authorization = append(authorization, h.Value)
+		}
+	}
+	if len(authorization) != 1 || authorization[0] != "Bearer tok" || len(params.Headers) != 2 {
+		t.Errorf("Expected the token in place of the page's Authorization, got %+v", params.Headers)
+	}
+
+	// Other origins, including the site's on another scheme or port, are
+	// continued as they are
+	for _, url := range []string{
+		"https://cdn.example.net/app.js",
+		"https://analytics.example.org/collect",
+		"https://login.example.com/?redirect=api.example.com",
+		"http://api.example.com/status",
+		"https://api.example.com:8443/status",
+	} {
+		if params := paused("2", url); params.Headers != nil {
+			t.Errorf("Expected no token for %s, got %+v", url, params.Headers)
+		}
+	}
+
+	// The proxy's challenge is still answered, and the site's token is no
+	// answer to a server challenge
+	proxyResponse := a.respond(&fetch.EventAuthRequired{
+		RequestID:     "3",
+		AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceProxy, Origin: "http://proxy.example.com:3128"},
+	}).(*fetch.ContinueWithAuthParams).AuthChallengeResponse
+	if proxyResponse.Response != fetch.AuthChallengeResponseResponseProvideCredentials || proxyResponse.Username != "proxyuser" {
+		t.Errorf("Expected the proxy's credentials, got %+v", proxyResponse)
+	}
+	serverResponse := a.respond(&fetch.EventAuthRequired{
+		RequestID:     "4",
+		AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer, Origin: "https://api.example.com"},
+	}).(*fetch.ContinueWithAuthParams).AuthChallengeResponse
+	if serverResponse.Response != fetch.AuthChallengeResponseResponseDefault {
+		t.Errorf("Expected the default response to a server challenge, got %+v", serverResponse)
+	}
+
+	if _, err := authorizeOrigin("not a url", "Bearer tok"); err == nil {
+		t.Error("Expected an error for a URL without an origin")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package browser
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// canBindToDevice reports whether sites may set Interface on this platform
+const canBindToDevice = true
+
+// bindToDeviceControl returns a dialer Control function that binds each
+// socket to iface with SO_BINDTODEVICE, which requires CAP_NET_RAW
+func bindToDeviceControl(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("bind to interface %s: %w", iface, sockErr)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package browser
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// canBindToDevice reports whether sites may set Interface on this platform
+const canBindToDevice = false
+
+// bindToDeviceControl fails every dial: only Linux has SO_BINDTODEVICE
+func bindToDeviceControl(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return fmt.Errorf("binding to interface %s is only supported on Linux", iface)
+	}
+}
@@ -0,0 +1,206 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// proxyDialTimeout bounds each upstream connection the bind proxy makes
+const proxyDialTimeout = 15 * time.Second
+
+// hopHeaders are connection-level headers a proxy must not forward
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// bindProxy is a loopback HTTP proxy that makes every upstream connection
+// through a sourceBinding. Chrome has no way to choose its local address or
+// interface, so sites that pin one are tested with --proxy-server pointing
+// here. HTTPS is tunnelled with CONNECT, so TLS is still Chrome's own.
+// Each test starts its own proxy and closes it afterwards.
+type bindProxy struct {
+	binding   *sourceBinding
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+
+	mu      sync.Mutex
+	tunnels map[net.Conn]struct{} // hijacked connections, closed with the proxy
+	local   net.Addr              // local address of the first upstream connection
+	dialErr error                 // first failed upstream connection
+}
+
+// startBindProxy listens on a random loopback port and serves until closed
+func startBindProxy(binding *sourceBinding) (*bindProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &bindProxy{
+		binding:  binding,
+		listener: listener,
+		tunnels:  make(map[net.Conn]struct{}),
+	}
+	p.transport = &http.Transport{
+		DialContext:       func(ctx context.Context, _, address string) (net.Conn, error) { return p.dial(ctx, address) },
+		DisableKeepAlives: true,
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: proxyDialTimeout}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Bind proxy stopped: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// URL is the proxy's address in the form --proxy-server expects
+func (p *bindProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// dial connects upstream over the binding, recording the local address used
+// or the first failure
+func (p *bindProxy) dial(ctx context.Context, address string) (net.Conn, error) {
+	conn, err := p.binding.dial(ctx, address)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		if p.dialErr == nil {
+			p.dialErr = err
+		}
+		return nil, err
+	}
+	if p.local == nil {
+		p.local = conn.LocalAddr()
+	}
+	return conn, nil
+}
+
+// localIP returns the local address of the first upstream connection (nil if none)
+func (p *bindProxy) localIP() net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if addr, ok := p.local.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+// firstDialError returns the first upstream connection failure, if any
+func (p *bindProxy) firstDialError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dialErr
+}
+
+func (p *bindProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	// Plain HTTP: forward the request as an origin-form request
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// tunnel answers a CONNECT by splicing the client to a bound upstream connection
+func (p *bindProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.dial(r.Context(), r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunnelling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if !p.track(client, upstream) {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	defer p.untrack(client, upstream)
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, buffered)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	// Either side closing ends the tunnel; untrack closes both
+	<-done
+}
+
+// track registers a tunnel's connections; it returns false once the proxy is closed
+func (p *bindProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tunnels == nil {
+		return false
+	}
+	for _, c := range conns {
+		p.tunnels[c] = struct{}{}
+	}
+	return true
+}
+
+func (p *bindProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+		delete(p.tunnels, c)
+	}
+}
+
+// Close stops the proxy and tears down any open tunnels
+func (p *bindProxy) Close() error {
+	err := p.server.Close()
+	p.mu.Lock()
+	for c := range p.tunnels {
+		c.Close()
+	}
+	p.tunnels = nil
+	p.mu.Unlock()
+	p.transport.CloseIdleConnections()
+	return err
+}
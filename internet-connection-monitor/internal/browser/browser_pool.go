@@ -0,0 +1,128 @@
+package browser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// browserAliveTimeout bounds the health check run on a pooled browser
+const browserAliveTimeout = 2 * time.Second
+
+// pooledBrowser is a long-lived Chrome that realistic-mode tests borrow.
+// Each test opens its own tab, but every tab shares the browser's DNS cache
+// and connection pool, so connection freshness is deliberately given up.
+type pooledBrowser struct {
+	ctx    context.Context // chromedp browser context; tabs are derived from it
+	cancel context.CancelFunc
+	uses   int
+}
+
+// browserPool keeps up to size idle browsers for reuse. A browser is retired
+// after maxReuse tests (0 = never) or as soon as it is found dead.
+type browserPool struct {
+	mu       sync.Mutex
+	idle     []*pooledBrowser
+	size     int
+	maxReuse int
+	closed   bool
+
+	launch func() (*pooledBrowser, error)
+	alive  func(b *pooledBrowser) bool
+}
+
+func newBrowserPool(size, maxReuse int, launch func() (*pooledBrowser, error)) *browserPool {
+	return &browserPool{
+		size:     size,
+		maxReuse: maxReuse,
+		launch:   launch,
+		alive:    browserAlive,
+	}
+}
+
+// get returns an idle browser, discarding any that died while idle, or
+// launches a new one
+func (p *browserPool) get() (*pooledBrowser, error) {
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			return p.launch()
+		}
+		b := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if p.alive(b) {
+			return b, nil
+		}
+		b.cancel() // crashed while idle
+	}
+}
+
+// put hands a browser back after a test. Crashed, worn-out and surplus
+// browsers are shut down instead of kept.
+func (p *browserPool) put(b *pooledBrowser) {
+	b.uses++
+	if (p.maxReuse > 0 && b.uses >= p.maxReuse) || !p.alive(b) {
+		b.cancel()
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.size {
+		p.mu.Unlock()
+		b.cancel()
+		return
+	}
+	p.idle = append(p.idle, b)
+	p.mu.Unlock()
+}
+
+// close shuts down every idle browser. Browsers still in use are shut down
+// when they are handed back.
+func (p *browserPool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, b := range idle {
+		b.cancel()
+	}
+}
+
+// launchPooledBrowser starts a Chrome with the controller's default flags
+func (c *ControllerImpl) launchPooledBrowser() (*pooledBrowser, error) {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), c.allocatorOpts...)
+	ctx, cancelBrowser := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelBrowser()
+		cancelAlloc()
+	}
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	return &pooledBrowser{ctx: ctx, cancel: cancel}, nil
+}
+
+// browserAlive reports whether a pooled browser still answers the DevTools protocol
+func browserAlive(b *pooledBrowser) bool {
+	if b.ctx.Err() != nil {
+		return false
+	}
+	c := chromedp.FromContext(b.ctx)
+	if c == nil || c.Browser == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, browserAliveTimeout)
+	defer cancel()
+	_, _, _, _, _, err := browser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser))
+	return err == nil
+}
@@ -0,0 +1,146 @@
+package browser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// fakePool returns a pool whose browsers are plain contexts; a browser is
+// alive until its context is cancelled
+func fakePool(size, maxReuse int) (*browserPool, *int) {
+	launched := 0
+	p := newBrowserPool(size, maxReuse, func() (*pooledBrowser, error) {
+		launched++
+		ctx, cancel := context.WithCancel(context.Background())
+		return &pooledBrowser{ctx: ctx, cancel: cancel}, nil
+	})
+	p.alive = func(b *pooledBrowser) bool { return b.ctx.Err() == nil }
+	return p, &launched
+}
+
+func TestBrowserPoolReusesBrowsers(t *testing.T) {
+	p, launched := fakePool(1, 0)
+
+	first, _ := p.get()
+	p.put(first)
+	second, _ := p.get()
+
+	if second != first || *launched != 1 {
+		t.Errorf("Expected the idle browser to be reused, launched %d", *launched)
+	}
+	if first.uses != 1 {
+		t.Errorf("Expected 1 recorded use, got %d", first.uses)
+	}
+}
+
+func TestBrowserPoolChecksOutEachBrowserOnce(t *testing.T) {
+	p, launched := fakePool(2, 0)
+
+	// A browser in use isn't handed to a concurrent test
+	a, _ := p.get()
+	b, _ := p.get()
+	if a == b || *launched != 2 {
+		t.Fatalf("Expected two browsers for two concurrent tests, launched %d", *launched)
+	}
+
+	// Both come back, and are checked out again without launching more
+	p.put(a)
+	p.put(b)
+	if len(p.idle) != 2 {
+		t.Fatalf("Expected 2 idle browsers, got %d", len(p.idle))
+	}
+	c, _ := p.get()
+	d, _ := p.get()
+	if c == d || *launched != 2 || len(p.idle) != 0 {
+		t.Errorf("Expected both idle browsers to be reused, launched %d, %d idle", *launched, len(p.idle))
+	}
+}
+
+func TestBrowserPoolRetiresAfterMaxReuse(t *testing.T) {
+	p, launched := fakePool(1, 2)
+
+	b, _ := p.get()
+	p.put(b)
+	b, _ = p.get()
+	p.put(b)
+
+	if b.ctx.Err() == nil {
+		t.Error("Expected the browser to be shut down after its last allowed use")
+	}
+	if next, _ := p.get(); next == b || *launched != 2 {
+		t.Errorf("Expected a replacement browser, launched %d", *launched)
+	}
+}
+
+func TestBrowserPoolRecyclesCrashedBrowsers(t *testing.T) {
+	p, launched := fakePool(2, 0)
+
+	// Crashed during a test: not returned to the pool
+	b, _ := p.get()
+	b.cancel()
+	p.put(b)
+	if len(p.idle) != 0 {
+		t.Fatalf("Expected the crashed browser to be dropped, %d idle", len(p.idle))
+	}
+
+	// Crashed while idle: skipped by get
+	b, _ = p.get()
+	p.put(b)
+	b.cancel()
+	if next, _ := p.get(); next == b || *launched != 3 {
+		t.Errorf("Expected a fresh browser after an idle crash, launched %d", *launched)
+	}
+}
+
+func TestBrowserPoolKeepsAtMostSizeIdle(t *testing.T) {
+	p, _ := fakePool(1, 0)
+
+	a, _ := p.get()
+	b, _ := p.get()
+	p.put(a)
+	p.put(b)
+
+	if len(p.idle) != 1 {
+		t.Errorf("Expected 1 idle browser, got %d", len(p.idle))
+	}
+	if b.ctx.Err() == nil {
+		t.Error("Expected the surplus browser to be shut down")
+	}
+
+	p.close()
+	if a.ctx.Err() == nil {
+		t.Error("Expected close to shut down idle browsers")
+	}
+	c, _ := p.get()
+	p.put(c)
+	if c.ctx.Err() == nil {
+		t.Error("Expected browsers returned after close to be shut down")
+	}
+}
+
+func TestUsesPool(t *testing.T) {
+	c := &ControllerImpl{pool: &browserPool{}}
+	realistic := models.SiteDefinition{URL: "https://example.com", Mode: testModeRealistic}
+
+	if !c.usesPool(realistic) {
+		t.Error("Expected realistic mode to use the pool")
+	}
+	if c.usesPool(models.SiteDefinition{URL: "https://example.com"}) {
+		t.Error("Full mode must always get a fresh browser")
+	}
+	withFlags := realistic
+	withFlags.ForceHTTP3 = true
+	if c.usesPool(withFlags) {
+		t.Error("Sites needing their own Chrome flags must not use the pool")
+	}
+	withProxy := realistic
+	withProxy.ProxyURL = "http://proxy.example.com:3128"
+	if c.usesPool(withProxy) {
+		t.Error("Sites with their own proxy must not use the pool")
+	}
+	if (&ControllerImpl{}).usesPool(realistic) {
+		t.Error("Nothing uses the pool when it is disabled")
+	}
+}
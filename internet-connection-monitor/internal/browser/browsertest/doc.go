@@ -0,0 +1,10 @@
+// Package browsertest drives the real browser controller against local
+// httptest servers with controlled per-phase delays, checking that the timing
+// pipeline (extractTimings and mergeNetworkTiming) reports what actually
+// happened on the wire.
+//
+// The tests need a Chrome or Chromium binary and are guarded by the browser
+// build tag:
+//
+//	go test -tags browser ./internal/browser/browsertest/
+package browsertest
@@ -0,0 +1,139 @@
+//go:build browser
+
+package browsertest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/browser"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+// phaseDelays configures where a fixture site spends its time
+type phaseDelays struct {
+	// TLS serves the site over HTTPS with httptest's self-signed certificate
+	TLS bool
+
+	// Handshake stalls each new connection before the server reads from it.
+	// Over TLS this lands in the handshake; over plain HTTP it would be
+	// indistinguishable from a slow first byte.
+	Handshake time.Duration
+
+	// Redirects is how many 302 hops precede the final page, each answered
+	// after Redirect
+	Redirects int
+	Redirect  time.Duration
+
+	// TTFB delays the final page's response headers
+	TTFB time.Duration
+
+	// Status is the final page's HTTP status (200 when zero)
+	Status int
+}
+
+const fixturePage = `<!DOCTYPE html><html><head><title>fixture</title></head><body>ok</body></html>`
+
+// newSiteFixture starts a server whose root URL walks the configured redirect
+// chain ("/?hop=1", "/?hop=2", ...) before serving the final page
+func newSiteFixture(t *testing.T, d phaseDelays) *httptest.Server {
+	t.Helper()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r) // favicon and friends
+			return
+		}
+
+		hop, _ := strconv.Atoi(r.URL.Query().Get("hop"))
+		if hop < d.Redirects {
+			time.Sleep(d.Redirect)
+			http.Redirect(w, r, fmt.Sprintf("/?hop=%d", hop+1), http.StatusFound)
+			return
+		}
+
+		time.Sleep(d.TTFB)
+		status := d.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		io.WriteString(w, fixturePage)
+	})
+
+	srv := httptest.NewUnstartedServer(handler)
+	if d.Handshake > 0 {
+		srv.Listener = &slowListener{Listener: srv.Listener, delay: d.Handshake}
+	}
+	if d.TLS {
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// slowListener delays the first read on every accepted connection
+type slowListener struct {
+	net.Listener
+	delay time.Duration
+}
+
+func (l *slowListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &slowConn{Conn: conn, delay: l.delay}, nil
+}
+
+type slowConn struct {
+	net.Conn
+	delay time.Duration
+	once  sync.Once
+}
+
+func (c *slowConn) Read(b []byte) (int, error) {
+	c.once.Do(func() { time.Sleep(c.delay) })
+	return c.Conn.Read(b)
+}
+
+// newController returns a controller that trusts the fixture's certificate,
+// skipping the test when no Chrome binary is installed. configure adjusts the
+// default browser config first.
+func newController(t *testing.T, configure ...func(*config.BrowserConfig)) *browser.ControllerImpl {
+	t.Helper()
+
+	found := false
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "headless-shell"} {
+		if _, err := exec.LookPath(name); err == nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Skip("no Chrome or Chromium binary on PATH")
+	}
+
+	cfg := config.DefaultConfig().Browser
+	for _, f := range configure {
+		f(&cfg)
+	}
+	c, err := browser.NewControllerImpl(&cfg)
+	if err != nil {
+		t.Fatalf("NewControllerImpl: %v", err)
+	}
+	c.IgnoreCertificateErrors()
+	t.Cleanup(func() { c.Close() })
+	return c
+}
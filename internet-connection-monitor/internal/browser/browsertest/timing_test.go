@@ -0,0 +1,136 @@
+//go:build browser
+
+package browsertest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// slack absorbs Chrome's sub-millisecond rounding when comparing a reported
+// phase against the delay the fixture injected
+const slack = 5 * time.Millisecond
+
+func testFixture(t *testing.T, srv string, site models.SiteDefinition) *models.TestResult {
+	t.Helper()
+
+	site.URL = srv
+	if site.Name == "" {
+		site.Name = "fixture"
+	}
+	site.TimeoutSeconds = 20
+
+	c := newController(t)
+	result, err := c.TestSite(context.Background(), site)
+	if err != nil {
+		t.Fatalf("TestSite: %v", err)
+	}
+	return result
+}
+
+func requirePhase(t *testing.T, phase string, got *int64) int64 {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("%s is nil, want a measurement", phase)
+	}
+	if *got < 0 {
+		t.Fatalf("%s = %dms, want >= 0", phase, *got)
+	}
+	return *got
+}
+
+func requireAtLeast(t *testing.T, phase string, got *int64, want time.Duration) {
+	t.Helper()
+	if ms := requirePhase(t, phase, got); time.Duration(ms)*time.Millisecond < want-slack {
+		t.Errorf("%s = %dms, want at least %v", phase, ms, want)
+	}
+}
+
+func requireSuccess(t *testing.T, result *models.TestResult) {
+	t.Helper()
+	if !result.Status.Success {
+		t.Fatalf("test failed: %s (%+v)", result.Status.Message, result.Error)
+	}
+}
+
+func TestPlainHTTPTimings(t *testing.T) {
+	ttfb := 300 * time.Millisecond
+	srv := newSiteFixture(t, phaseDelays{TTFB: ttfb})
+
+	result := testFixture(t, srv.URL, models.SiteDefinition{})
+	requireSuccess(t, result)
+
+	if result.Status.HTTPStatus != http.StatusOK {
+		t.Errorf("HTTPStatus = %d, want 200", result.Status.HTTPStatus)
+	}
+	requirePhase(t, "DNSLookupMs", result.Timings.DNSLookupMs)
+	requirePhase(t, "TCPConnectionMs", result.Timings.TCPConnectionMs)
+	requireAtLeast(t, "TimeToFirstByteMs", result.Timings.TimeToFirstByteMs, ttfb)
+	requireAtLeast(t, "FullPageLoadMs", result.Timings.FullPageLoadMs, ttfb)
+	if result.Timings.TLSHandshakeMs != nil {
+		t.Errorf("TLSHandshakeMs = %d for a plain HTTP site, want nil", *result.Timings.TLSHandshakeMs)
+	}
+}
+
+func TestTLSHandshakeTiming(t *testing.T) {
+	handshake := 250 * time.Millisecond
+	srv := newSiteFixture(t, phaseDelays{TLS: true, Handshake: handshake})
+
+	result := testFixture(t, srv.URL, models.SiteDefinition{})
+	requireSuccess(t, result)
+
+	requirePhase(t, "DNSLookupMs", result.Timings.DNSLookupMs)
+	requirePhase(t, "TCPConnectionMs", result.Timings.TCPConnectionMs)
+	requireAtLeast(t, "TLSHandshakeMs", result.Timings.TLSHandshakeMs, handshake)
+	requirePhase(t, "TimeToFirstByteMs", result.Timings.TimeToFirstByteMs)
+}
+
+func TestRedirectTiming(t *testing.T) {
+	hop := 200 * time.Millisecond
+	srv := newSiteFixture(t, phaseDelays{Redirects: 2, Redirect: hop})
+
+	result := testFixture(t, srv.URL, models.SiteDefinition{})
+	requireSuccess(t, result)
+
+	if !strings.HasSuffix(result.FinalURL, "/?hop=2") {
+		t.Errorf("FinalURL = %q, want the last hop of the chain", result.FinalURL)
+	}
+	requireAtLeast(t, "TimeToFinalURLMs", result.Timings.TimeToFinalURLMs, 2*hop)
+	requirePhase(t, "TimeToFirstByteMs", result.Timings.TimeToFirstByteMs)
+}
+
+func TestUnexpectedStatus(t *testing.T) {
+	srv := newSiteFixture(t, phaseDelays{Status: http.StatusServiceUnavailable})
+
+	result := testFixture(t, srv.URL, models.SiteDefinition{})
+	if result.Status.Success {
+		t.Fatal("test succeeded for a 503 page")
+	}
+	if result.Status.HTTPStatus != http.StatusServiceUnavailable {
+		t.Errorf("HTTPStatus = %d, want 503", result.Status.HTTPStatus)
+	}
+	if result.Error == nil || result.Error.ErrorType != "ERR_UNEXPECTED_STATUS" {
+		t.Errorf("Error = %+v, want ERR_UNEXPECTED_STATUS", result.Error)
+	}
+}
+
+func TestTTFBModeTimings(t *testing.T) {
+	ttfb := 300 * time.Millisecond
+	srv := newSiteFixture(t, phaseDelays{TLS: true, TTFB: ttfb})
+
+	result := testFixture(t, srv.URL, models.SiteDefinition{Mode: "ttfb"})
+	requireSuccess(t, result)
+
+	requirePhase(t, "DNSLookupMs", result.Timings.DNSLookupMs)
+	requirePhase(t, "TCPConnectionMs", result.Timings.TCPConnectionMs)
+	requirePhase(t, "TLSHandshakeMs", result.Timings.TLSHandshakeMs)
+	requireAtLeast(t, "TimeToFirstByteMs", result.Timings.TimeToFirstByteMs, ttfb)
+	if result.Timings.FullPageLoadMs != nil {
+		t.Errorf("FullPageLoadMs = %d in ttfb mode, want nil", *result.Timings.FullPageLoadMs)
+	}
+}
@@ -0,0 +1,85 @@
+//go:build browser
+
+package browsertest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// TestMeasureWarmKeepsHARCold loads a site twice with measure_warm and checks
+// that the warm load is reported only as WarmTimings: the HAR holds the cold
+// load alone.
+func TestMeasureWarmKeepsHARCold(t *testing.T) {
+	var loads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		loads.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(fixturePage))
+	}))
+	t.Cleanup(srv.Close)
+
+	harDir := t.TempDir()
+	c := newController(t, func(cfg *config.BrowserConfig) {
+		cfg.CaptureHAR = true
+		cfg.HARDir = harDir
+	})
+	result, err := c.TestSite(context.Background(), models.SiteDefinition{
+		URL:            srv.URL,
+		Name:           "warm",
+		TimeoutSeconds: 20,
+		MeasureWarm:    true,
+	})
+	if err != nil {
+		t.Fatalf("TestSite: %v", err)
+	}
+	requireSuccess(t, result)
+
+	if result.ColdTimings == nil || result.WarmTimings == nil {
+		t.Fatalf("expected cold and warm timings, got %+v / %+v", result.ColdTimings, result.WarmTimings)
+	}
+	if result.WarmTimings.TotalDurationMs <= 0 {
+		t.Errorf("expected a warm duration, got %dms", result.WarmTimings.TotalDurationMs)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("expected the page to be loaded twice, got %d", n)
+	}
+
+	data, err := os.ReadFile(result.HARPath)
+	if err != nil {
+		t.Fatalf("expected a HAR file: %v", err)
+	}
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					URL string `json:"url"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("HAR is not JSON: %v", err)
+	}
+	documents := 0
+	for _, e := range har.Log.Entries {
+		if e.Request.URL == srv.URL+"/" {
+			documents++
+		}
+	}
+	if documents != 1 {
+		t.Errorf("expected only the cold load of the page in the HAR, found %d", documents)
+	}
+}
@@ -0,0 +1,67 @@
+package browser
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Cache-busting modes for SiteDefinition.CacheBust
+const (
+	cacheBustQuery     = "query"
+	cacheBustSubdomain = "subdomain"
+)
+
+// cacheBustParam is the query parameter added in "query" mode
+const cacheBustParam = "_icm_cb"
+
+// cacheBustURL returns the URL to load for a test. With mode "query" a random
+// query parameter defeats caches keyed on the full URL; with mode "subdomain" a
+// random leading label forces a fresh DNS lookup (the domain must have a
+// wildcard record). An empty mode returns the URL unchanged.
+func cacheBustURL(siteURL, mode string) (string, error) {
+	if mode == "" {
+		return siteURL, nil
+	}
+
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid site URL %q", siteURL)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case cacheBustQuery:
+		q := u.Query()
+		q.Set(cacheBustParam, token)
+		u.RawQuery = q.Encode()
+	case cacheBustSubdomain:
+		if net.ParseIP(u.Hostname()) != nil {
+			return "", fmt.Errorf("cannot add a subdomain to IP address %s", u.Hostname())
+		}
+		host := "cb-" + token + "." + u.Hostname()
+		if port := u.Port(); port != "" {
+			host = net.JoinHostPort(host, port)
+		}
+		u.Host = host
+	default:
+		return "", fmt.Errorf("unknown cache_bust mode %q (want %q or %q)", mode, cacheBustQuery, cacheBustSubdomain)
+	}
+
+	return u.String(), nil
+}
+
+// randomToken returns 16 random hex characters, valid in a DNS label
+func randomToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate cache-bust token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
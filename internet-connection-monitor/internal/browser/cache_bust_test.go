@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCacheBustURL_None(t *testing.T) {
+	got, err := cacheBustURL("https://example.com/path", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "https://example.com/path" {
+		t.Errorf("expected URL unchanged, got %q", got)
+	}
+}
+
+func TestCacheBustURL_Query(t *testing.T) {
+	first, err := cacheBustURL("https://example.com/path?keep=1", cacheBustQuery)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := cacheBustURL("https://example.com/path?keep=1", cacheBustQuery)
+	if first == second {
+		t.Error("expected a different URL for each test")
+	}
+
+	u, err := url.Parse(first)
+	if err != nil {
+		t.Fatalf("result is not a valid URL: %v", err)
+	}
+	if u.Host != "example.com" || u.Path != "/path" {
+		t.Errorf("expected host and path unchanged, got %q", first)
+	}
+	if u.Query().Get("keep") != "1" {
+		t.Errorf("expected existing query to be kept, got %q", first)
+	}
+	if u.Query().Get(cacheBustParam) == "" {
+		t.Errorf("expected %s parameter, got %q", cacheBustParam, first)
+	}
+}
+
+func TestCacheBustURL_Subdomain(t *testing.T) {
+	got, err := cacheBustURL("https://example.com:8443/path", cacheBustSubdomain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatalf("result is not a valid URL: %v", err)
+	}
+	if !strings.HasPrefix(u.Hostname(), "cb-") || !strings.HasSuffix(u.Hostname(), ".example.com") {
+		t.Errorf("expected random label before example.com, got %q", u.Hostname())
+	}
+	if u.Port() != "8443" || u.Path != "/path" {
+		t.Errorf("expected port and path unchanged, got %q", got)
+	}
+}
+
+func TestCacheBustURL_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		mode string
+	}{
+		{"unknown mode", "https://example.com", "fragment"},
+		{"subdomain on IP", "https://192.0.2.1/", cacheBustSubdomain},
+		{"invalid URL", "://", cacheBustQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := cacheBustURL(tt.url, tt.mode); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+const (
+	// captivePortalCheckTimeout bounds a single control request
+	captivePortalCheckTimeout = 5 * time.Second
+
+	// defaultCaptivePortalInterval is used when no check interval is configured
+	defaultCaptivePortalInterval = time.Minute
+
+	// maxCaptivePortalBody caps how much of the control response is compared
+	maxCaptivePortalBody = 4096
+)
+
+// captivePortalDetector decides whether the network is behind a captive
+// portal by fetching a control URL whose exact response is known (e.g. an
+// empty 204 from a generate_204 endpoint). A portal intercepts the request
+// and answers with a redirect or its login page instead.
+//
+// Like egressIPTracker, checks never block a test: a stale verdict triggers
+// one background check and the cached verdict is used meanwhile. A control
+// request that fails outright proves nothing either way, so the previous
+// verdict is kept.
+type captivePortalDetector struct {
+	url            string
+	expectedStatus int
+	expectedBody   string
+	interval       time.Duration
+	client         *http.Client
+
+	mu          sync.Mutex
+	detected    bool
+	lastAttempt time.Time
+	checking    bool
+
+	now func() time.Time
+}
+
+func newCaptivePortalDetector(url string, expectedStatus int, expectedBody string, interval time.Duration) *captivePortalDetector {
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusNoContent
+	}
+	if interval <= 0 {
+		interval = defaultCaptivePortalInterval
+	}
+	return &captivePortalDetector{
+		url:            url,
+		expectedStatus: expectedStatus,
+		expectedBody:   expectedBody,
+		interval:       interval,
+		client: &http.Client{
+			Timeout: captivePortalCheckTimeout,
+			// A portal's redirect is the evidence; don't follow it
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// current returns the cached verdict, checking again in the background when
+// it is older than the interval
+func (d *captivePortalDetector) current() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.checking && d.now().Sub(d.lastAttempt) >= d.interval {
+		d.checking = true
+		d.lastAttempt = d.now()
+		go d.refresh()
+	}
+	return d.detected
+}
+
+// refresh performs one check and stores the verdict
+func (d *captivePortalDetector) refresh() {
+	detected, err := d.check()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.checking = false
+
+	if err != nil {
+		log.Printf("Captive portal check via %s failed, keeping previous verdict (detected=%v): %v", d.url, d.detected, err)
+		return
+	}
+	if detected != d.detected {
+		if detected {
+			log.Printf("Captive portal detected: %s did not return the expected response", d.url)
+		} else {
+			log.Printf("Captive portal no longer detected")
+		}
+	}
+	d.detected = detected
+}
+
+// check fetches the control URL and reports whether the response was tampered with
+func (d *captivePortalDetector) check() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), captivePortalCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCaptivePortalBody+1))
+	if err != nil {
+		return false, fmt.Errorf("reading control response: %w", err)
+	}
+	return resp.StatusCode != d.expectedStatus || string(body) != d.expectedBody, nil
+}
+
+// applyCaptivePortal flags a result taken behind a captive portal and, if
+// configured, stops it from counting as a success: the page that "loaded"
+// was most likely the portal's
+func (c *ControllerImpl) applyCaptivePortal(result *models.TestResult) {
+	if !c.captivePortal.current() {
+		return
+	}
+	result.CaptivePortalDetected = true
+
+	if c.config.SuppressSuccessOnCaptivePortal && result.Status.Success {
+		result.Status.Success = false
+		result.Status.Degraded = false
+		result.Status.Message = "Captive portal detected; page load not trusted"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_CAPTIVE_PORTAL",
+			ErrorMessage: "control URL " + c.captivePortal.url + " did not return the expected response",
+			FailurePhase: "http",
+		}
+	}
+}
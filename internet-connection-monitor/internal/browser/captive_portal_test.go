@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestCaptivePortalDetector(t *testing.T) {
+	// mode selects how the "network" answers the control request
+	var mode atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch mode.Load().(string) {
+		case "open":
+			w.WriteHeader(http.StatusNoContent)
+		case "redirect":
+			http.Redirect(w, r, "http://portal.example/login", http.StatusFound)
+		case "login page":
+			fmt.Fprint(w, "<html>Please accept the terms</html>")
+		case "empty 200":
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	d := newCaptivePortalDetector(server.URL, 0, "", 0)
+
+	tests := []struct {
+		mode string
+		want bool
+	}{
+		{"open", false},
+		{"redirect", true},
+		{"open", false},
+		{"login page", true},
+		{"empty 200", true},
+	}
+	for _, tt := range tests {
+		mode.Store(tt.mode)
+		d.refresh()
+		if got := d.detected; got != tt.want {
+			t.Errorf("%s: detected = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	// A failed control request keeps the previous verdict
+	server.Close()
+	d.refresh()
+	if !d.detected {
+		t.Error("Expected an unreachable control URL to keep the previous verdict")
+	}
+}
+
+func TestCaptivePortalDetectorCustomResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "success\n")
+	}))
+	defer server.Close()
+
+	d := newCaptivePortalDetector(server.URL, http.StatusOK, "success\n", 0)
+	if detected, err := d.check(); err != nil || detected {
+		t.Errorf("Expected the configured response to pass, got detected=%v err=%v", detected, err)
+	}
+}
+
+func TestApplyCaptivePortal(t *testing.T) {
+	newController := func(suppress bool) *ControllerImpl {
+		d := newCaptivePortalDetector("http://control.invalid", 0, "", 0)
+		d.detected = true
+		d.checking = true // no background check
+		return &ControllerImpl{
+			config:        &config.BrowserConfig{SuppressSuccessOnCaptivePortal: suppress},
+			captivePortal: d,
+		}
+	}
+	success := func() *models.TestResult {
+		return &models.TestResult{Status: models.StatusInfo{Success: true, Message: "Page loaded successfully"}}
+	}
+
+	result := success()
+	newController(false).applyCaptivePortal(result)
+	if !result.CaptivePortalDetected || !result.Status.Success {
+		t.Errorf("Expected a flagged success without suppression, got %+v", result.Status)
+	}
+
+	result = success()
+	newController(true).applyCaptivePortal(result)
+	if !result.CaptivePortalDetected || result.Status.Success || result.Error == nil || result.Error.ErrorType != "ERR_CAPTIVE_PORTAL" {
+		t.Errorf("Expected the success to be suppressed, got %+v / %+v", result.Status, result.Error)
+	}
+
+	c := newController(true)
+	c.captivePortal.detected = false
+	result = success()
+	c.applyCaptivePortal(result)
+	if result.CaptivePortalDetected || !result.Status.Success {
+		t.Errorf("Expected no change without a portal, got %+v", result)
+	}
+}
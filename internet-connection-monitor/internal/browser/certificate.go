@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"slices"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// certificateInfo describes the certificate in a document's security
+// details as of now, nil for a response without any. With warnDays above 0,
+// a certificate with fewer days than that left is flagged as expiring soon.
+func certificateInfo(details *network.SecurityDetails, now time.Time, warnDays int) *models.CertificateInfo {
+	if details == nil {
+		return nil
+	}
+	info := &models.CertificateInfo{
+		Subject:         details.SubjectName,
+		Issuer:          details.Issuer,
+		SubjectAltNames: slices.Clone(details.SanList),
+	}
+	if details.ValidFrom != nil {
+		info.ValidFrom = details.ValidFrom.Time().UTC()
+	}
+	if details.ValidTo != nil {
+		info.ValidTo = details.ValidTo.Time().UTC()
+		// Whole days, rounded down: a certificate expiring in 36 hours has 1 day left
+		left := info.ValidTo.Sub(now)
+		info.DaysUntilExpiry = int(left / (24 * time.Hour))
+		if left < 0 && left%(24*time.Hour) != 0 {
+			info.DaysUntilExpiry--
+		}
+		info.ExpiresSoon = warnDays > 0 && info.DaysUntilExpiry < warnDays
+	}
+	return info
+}
@@ -0,0 +1,114 @@
+package browser
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// responseWithCertificate is a document response as Chrome sends it, with
+// a certificate valid from 2024-01-01 to 2024-03-31 (UTC)
+const responseWithCertificate = `{
+	"requestId": "1000.1",
+	"loaderId": "L1",
+	"timestamp": 1000.5,
+	"type": "Document",
+	"response": {
+		"url": "https://example.com/",
+		"status": 200,
+		"statusText": "OK",
+		"headers": {"content-type": "text/html"},
+		"mimeType": "text/html",
+		"charset": "utf-8",
+		"connectionReused": false,
+		"connectionId": 12,
+		"encodedDataLength": 512,
+		"protocol": "h2",
+		"securityState": "secure",
+		"securityDetails": {
+			"protocol": "TLS 1.3",
+			"keyExchange": "",
+			"keyExchangeGroup": "X25519",
+			"cipher": "AES_128_GCM",
+			"certificateId": 0,
+			"subjectName": "example.com",
+			"sanList": ["example.com", "www.example.com"],
+			"issuer": "Example Issuing CA",
+			"validFrom": 1704067200,
+			"validTo": 1711843200,
+			"signedCertificateTimestampList": [],
+			"certificateTransparencyCompliance": "compliant",
+			"encryptedClientHello": false
+		}
+	},
+	"hasExtraInfo": true
+}`
+
+func TestNetworkEventCapture_SecurityDetails(t *testing.T) {
+	var ev network.EventResponseReceived
+	if err := json.Unmarshal([]byte(responseWithCertificate), &ev); err != nil {
+		t.Fatalf("Failed to parse the response event: %v", err)
+	}
+	capture := newNetworkEventCapture()
+	capture.handleEvent(&ev)
+
+	details := capture.GetSecurityDetails()
+	if details == nil {
+		t.Fatal("Expected the document's security details to be captured")
+	}
+
+	now := time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC)
+	info := certificateInfo(details, now, 30)
+	if info.Subject != "example.com" || info.Issuer != "Example Issuing CA" {
+		t.Errorf("Expected subject example.com issued by Example Issuing CA, got %q by %q", info.Subject, info.Issuer)
+	}
+	if !slices.Equal(info.SubjectAltNames, []string{"example.com", "www.example.com"}) {
+		t.Errorf("Expected both SANs, got %v", info.SubjectAltNames)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !info.ValidFrom.Equal(want) {
+		t.Errorf("Expected valid from %v, got %v", want, info.ValidFrom)
+	}
+	if want := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC); !info.ValidTo.Equal(want) {
+		t.Errorf("Expected valid to %v, got %v", want, info.ValidTo)
+	}
+	if info.DaysUntilExpiry != 13 || !info.ExpiresSoon {
+		t.Errorf("Expected 13 days left and a warning, got %d, %v", info.DaysUntilExpiry, info.ExpiresSoon)
+	}
+}
+
+func TestCertificateInfo(t *testing.T) {
+	validTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	details := &network.SecurityDetails{SubjectName: "example.com"}
+	if err := json.Unmarshal([]byte(`1711843200`), &details.ValidTo); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		warnDays int
+		days     int
+		soon     bool
+	}{
+		{name: "warning disabled", now: validTo.Add(-36 * time.Hour), warnDays: 0, days: 1, soon: false},
+		{name: "outside the warning", now: validTo.Add(-31 * 24 * time.Hour), warnDays: 30, days: 31, soon: false},
+		{name: "on the boundary", now: validTo.Add(-30 * 24 * time.Hour), warnDays: 30, days: 30, soon: false},
+		{name: "inside the warning", now: validTo.Add(-30*24*time.Hour + time.Minute), warnDays: 30, days: 29, soon: true},
+		{name: "expired", now: validTo.Add(12 * time.Hour), warnDays: 30, days: -1, soon: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := certificateInfo(details, tt.now, tt.warnDays)
+			if info.DaysUntilExpiry != tt.days || info.ExpiresSoon != tt.soon {
+				t.Errorf("Expected %d days, expires soon %v; got %d, %v", tt.days, tt.soon, info.DaysUntilExpiry, info.ExpiresSoon)
+			}
+		})
+	}
+
+	if certificateInfo(nil, time.Now(), 30) != nil {
+		t.Error("Expected no certificate for a plain HTTP response")
+	}
+}
@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"context"
+	"sync"
+)
+
+// chromeSlots caps the Chrome instances alive at once across every controller
+// in the process. Each Chrome costs hundreds of MB, so however much test
+// parallelism callers ask for, launches beyond the cap wait for a running
+// test to finish instead of running a small container out of memory.
+var chromeSlots = &chromeLimiter{}
+
+// chromeLimiter is a counting semaphore whose size can change at runtime.
+// Waiters are served in arrival order.
+type chromeLimiter struct {
+	mu      sync.Mutex
+	limit   int // 0 = unlimited
+	inUse   int
+	waiters []chan struct{}
+}
+
+// setLimit changes the cap (<= 0 removes it), admitting queued tests if it grew
+func (l *chromeLimiter) setLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	for len(l.waiters) > 0 && l.hasRoomLocked() {
+		l.inUse++
+		l.admitNextLocked()
+	}
+}
+
+// acquire blocks until a Chrome instance may be started or ctx is done
+func (l *chromeLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if len(l.waiters) == 0 && l.hasRoomLocked() {
+		l.inUse++
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, w := range l.waiters {
+			if w == ready {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// Admitted just as ctx ended: hand the slot on
+		l.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire
+func (l *chromeLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+func (l *chromeLimiter) releaseLocked() {
+	if len(l.waiters) > 0 && l.limit > 0 && l.inUse <= l.limit {
+		l.admitNextLocked() // the slot passes straight to the next waiter
+		return
+	}
+	l.inUse--
+}
+
+func (l *chromeLimiter) hasRoomLocked() bool {
+	return l.limit <= 0 || l.inUse < l.limit
+}
+
+func (l *chromeLimiter) admitNextLocked() {
+	close(l.waiters[0])
+	l.waiters = l.waiters[1:]
+}
@@ -0,0 +1,99 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// acquireAsync starts an acquire and returns a channel that receives its result
+func acquireAsync(ctx context.Context, l *chromeLimiter) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- l.acquire(ctx) }()
+	return done
+}
+
+func expectBlocked(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected acquire to queue, it returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func expectAcquired(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected acquire to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected acquire to succeed, it is still queued")
+	}
+}
+
+func TestChromeLimiterQueuesBeyondLimit(t *testing.T) {
+	l := &chromeLimiter{}
+	l.setLimit(2)
+	ctx := context.Background()
+
+	expectAcquired(t, acquireAsync(ctx, l))
+	expectAcquired(t, acquireAsync(ctx, l))
+	third := acquireAsync(ctx, l)
+	expectBlocked(t, third)
+
+	l.release()
+	expectAcquired(t, third)
+	if l.inUse != 2 {
+		t.Errorf("Expected 2 instances in use, got %d", l.inUse)
+	}
+}
+
+func TestChromeLimiterUnlimitedByDefault(t *testing.T) {
+	l := &chromeLimiter{}
+	for i := 0; i < 10; i++ {
+		expectAcquired(t, acquireAsync(context.Background(), l))
+	}
+}
+
+func TestChromeLimiterCancelWhileQueued(t *testing.T) {
+	l := &chromeLimiter{}
+	l.setLimit(1)
+	expectAcquired(t, acquireAsync(context.Background(), l))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := acquireAsync(ctx, l)
+	expectBlocked(t, queued)
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// The abandoned waiter must not hold the slot
+	l.release()
+	expectAcquired(t, acquireAsync(context.Background(), l))
+}
+
+func TestChromeLimiterSetLimit(t *testing.T) {
+	l := &chromeLimiter{}
+	l.setLimit(1)
+	expectAcquired(t, acquireAsync(context.Background(), l))
+	queued := acquireAsync(context.Background(), l)
+	expectBlocked(t, queued)
+
+	// Raising the cap admits queued tests
+	l.setLimit(2)
+	expectAcquired(t, queued)
+
+	// Lowering it lets running tests finish but admits no one until under the cap
+	l.setLimit(1)
+	next := acquireAsync(context.Background(), l)
+	expectBlocked(t, next)
+	l.release()
+	expectBlocked(t, next)
+	l.release()
+	expectAcquired(t, next)
+}
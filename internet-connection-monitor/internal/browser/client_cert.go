@@ -0,0 +1,133 @@
+package browser
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Client certificates (mTLS)
+//
+// Chrome has no command-line flag for presenting a client certificate from a file.
+// Instead it selects certificates from its NSS database ($HOME/.pki/nssdb) and,
+// when running headless, only presents one automatically if the
+// AutoSelectCertificateForUrls enterprise policy matches the origin.
+//
+// The operator is responsible for importing each certificate into the NSS
+// database of the user running the monitor, e.g.:
+//
+//	pk12util -d sql:$HOME/.pki/nssdb -i client.p12
+//
+// The controller then validates the configured key pair, and writes a managed
+// policy file into BrowserConfig.ChromePolicyDir that auto-selects the
+// certificate (matched by issuer CN) for the site's origin. Chrome reads
+// policies at startup, which suits the fresh-browser-per-test design.
+
+// clientCertPolicyFile is the managed policy file the controller owns
+const clientCertPolicyFile = "internet-monitor-client-certs.json"
+
+// clientCertPolicy accumulates AutoSelectCertificateForUrls entries across sites
+type clientCertPolicy struct {
+	dir     string
+	mu      sync.Mutex
+	entries map[string]string // origin -> policy entry
+}
+
+func newClientCertPolicy(dir string) *clientCertPolicy {
+	return &clientCertPolicy{
+		dir:     dir,
+		entries: make(map[string]string),
+	}
+}
+
+// ensure validates the site's client certificate and makes sure the policy file
+// auto-selects it for the site's origin
+func (p *clientCertPolicy) ensure(site models.SiteDefinition) error {
+	origin, entry, err := clientCertPolicyEntry(site)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.entries[origin] == entry {
+		return nil
+	}
+	p.entries[origin] = entry
+
+	return p.write()
+}
+
+// write rewrites the policy file with all known entries. Caller must hold p.mu.
+func (p *clientCertPolicy) write() error {
+	origins := make([]string, 0, len(p.entries))
+	for origin := range p.entries {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+
+	entries := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		entries = append(entries, p.entries[origin])
+	}
+
+	data, err := json.MarshalIndent(map[string][]string{
+		"AutoSelectCertificateForUrls": entries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return fmt.Errorf("create Chrome policy dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(p.dir, clientCertPolicyFile), data, 0o644); err != nil {
+		return fmt.Errorf("write Chrome policy: %w", err)
+	}
+	return nil
+}
+
+// clientCertPolicyEntry loads the site's key pair and returns the origin it
+// applies to and the AutoSelectCertificateForUrls entry selecting it
+func clientCertPolicyEntry(site models.SiteDefinition) (string, string, error) {
+	cc := site.ClientCert
+	if cc == nil {
+		return "", "", fmt.Errorf("site %s has no client certificate", site.GetName())
+	}
+
+	pair, err := tls.LoadX509KeyPair(cc.CertFile, cc.KeyFile)
+	if err != nil {
+		return "", "", fmt.Errorf("load client certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return "", "", fmt.Errorf("parse client certificate: %w", err)
+	}
+
+	u, err := url.Parse(site.URL)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid site URL %q", site.URL)
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	entry, err := json.Marshal(map[string]interface{}{
+		"pattern": origin,
+		"filter": map[string]interface{}{
+			"ISSUER": map[string]string{"CN": leaf.Issuer.CommonName},
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return origin, string(entry), nil
+}
@@ -0,0 +1,119 @@
+package browser
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// writeTestClientCert writes a self-signed certificate and key to dir
+func writeTestClientCert(t *testing.T, dir, issuerCN string) *models.ClientCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: issuerCN},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	cc := &models.ClientCertificate{
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+	}
+	if err := os.WriteFile(cc.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(cc.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return cc
+}
+
+func TestClientCertPolicy_WritesAutoSelectEntry(t *testing.T) {
+	dir := t.TempDir()
+	policyDir := filepath.Join(dir, "policies")
+
+	site := models.SiteDefinition{
+		URL:        "https://internal.example.com/status",
+		Name:       "internal",
+		ClientCert: writeTestClientCert(t, dir, "Internal CA"),
+	}
+
+	policy := newClientCertPolicy(policyDir)
+	if err := policy.ensure(site); err != nil {
+		t.Fatalf("ensure() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(policyDir, clientCertPolicyFile))
+	if err != nil {
+		t.Fatalf("Policy file not written: %v", err)
+	}
+
+	var doc map[string][]string
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Policy file is not valid JSON: %v", err)
+	}
+	entries := doc["AutoSelectCertificateForUrls"]
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 policy entry, got %d", len(entries))
+	}
+
+	var entry struct {
+		Pattern string `json:"pattern"`
+		Filter  struct {
+			Issuer struct {
+				CN string `json:"CN"`
+			} `json:"ISSUER"`
+		} `json:"filter"`
+	}
+	if err := json.Unmarshal([]byte(entries[0]), &entry); err != nil {
+		t.Fatalf("Policy entry is not valid JSON: %v", err)
+	}
+	if entry.Pattern != "https://internal.example.com" {
+		t.Errorf("Expected pattern for site origin, got %q", entry.Pattern)
+	}
+	if entry.Filter.Issuer.CN != "Internal CA" {
+		t.Errorf("Expected issuer CN 'Internal CA', got %q", entry.Filter.Issuer.CN)
+	}
+}
+
+func TestClientCertPolicy_InvalidKeyPair(t *testing.T) {
+	site := models.SiteDefinition{
+		URL:  "https://internal.example.com",
+		Name: "internal",
+		ClientCert: &models.ClientCertificate{
+			CertFile: "/nonexistent/client.crt",
+			KeyFile:  "/nonexistent/client.key",
+		},
+	}
+
+	policy := newClientCertPolicy(t.TempDir())
+	if err := policy.ensure(site); err == nil {
+		t.Error("Expected error for missing certificate files")
+	}
+}
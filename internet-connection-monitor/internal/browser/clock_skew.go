@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Clock skew detection
+//
+// A monitor host with a bad clock stamps every result with the wrong time,
+// which corrupts time-series data downstream. The tested site's Date header is
+// a free second opinion: it costs no extra request, and across many sites a
+// consistent offset points at the local clock rather than any one server.
+//
+// Date only has one-second resolution, so the server's clock is taken to be
+// halfway through the second it names; skews under a second are noise.
+
+// clockSkew estimates how far the local clock is ahead of the server's
+// (negative when behind) from a document response's headers and the local time
+// it was received. ok is false when the response has no usable Date header.
+func clockSkew(headers network.Headers, received time.Time) (skew time.Duration, ok bool) {
+	date, err := http.ParseTime(harHeaderValue(headers, "date"))
+	if err != nil {
+		return 0, false
+	}
+	serverNow := date.Add(500 * time.Millisecond)
+
+	// A cached response's Date is when the origin generated it; Age is how
+	// long it has been cached since
+	if age, err := strconv.Atoi(harHeaderValue(headers, "age")); err == nil && age > 0 {
+		serverNow = serverNow.Add(time.Duration(age) * time.Second)
+	}
+
+	return received.Sub(serverNow), true
+}
@@ -0,0 +1,81 @@
+package browser
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestClockSkew(t *testing.T) {
+	serverTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	date := serverTime.Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		headers  network.Headers
+		received time.Time
+		want     time.Duration
+		wantOK   bool
+	}{
+		{
+			name:     "in sync",
+			headers:  network.Headers{"date": date},
+			received: serverTime.Add(500 * time.Millisecond),
+			want:     0,
+			wantOK:   true,
+		},
+		{
+			name:     "local clock ahead, header case preserved",
+			headers:  network.Headers{"Date": date},
+			received: serverTime.Add(90*time.Second + 500*time.Millisecond),
+			want:     90 * time.Second,
+			wantOK:   true,
+		},
+		{
+			name:     "local clock behind",
+			headers:  network.Headers{"date": date},
+			received: serverTime.Add(-30 * time.Second),
+			want:     -30*time.Second - 500*time.Millisecond,
+			wantOK:   true,
+		},
+		{
+			name:     "cached response accounts for Age",
+			headers:  network.Headers{"date": date, "age": "120"},
+			received: serverTime.Add(120*time.Second + 500*time.Millisecond),
+			want:     0,
+			wantOK:   true,
+		},
+		{
+			name:     "missing Date header",
+			headers:  network.Headers{"content-type": "text/html"},
+			received: serverTime,
+			wantOK:   false,
+		},
+		{
+			name:     "malformed Date header",
+			headers:  network.Headers{"date": "yesterday"},
+			received: serverTime,
+			wantOK:   false,
+		},
+		{
+			name:     "no response",
+			headers:  nil,
+			received: time.Time{},
+			wantOK:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := clockSkew(tt.headers, tt.received)
+			if ok != tt.wantOK {
+				t.Fatalf("clockSkew ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Errorf("clockSkew = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
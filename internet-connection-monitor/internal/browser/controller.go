@@ -9,6 +9,9 @@ import (
 
 // Controller is the interface for browser automation
 type Controller interface {
+	// TestSite tests a site and returns the result, with a nil error for
+	// connectivity failures as well as successes. A non-nil error means no
+	// test took place, e.g. a *StartupError; see errors.go.
 	TestSite(ctx context.Context, site models.SiteDefinition) (*models.TestResult, error)
 	Close() error
 }
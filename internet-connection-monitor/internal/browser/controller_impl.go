@@ -3,10 +3,14 @@ package browser
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
@@ -14,14 +18,48 @@ import (
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
 )
 
-// ErrChromeStartupFailure indicates Chrome failed to start (not an Internet connectivity issue)
-var ErrChromeStartupFailure = errors.New("chrome failed to start")
+// navigationTimingJS reads the Navigation Timing Level 2 entry for the current page
+const navigationTimingJS = `
+(function() {
+	const entry = performance.getEntriesByType('navigation')[0];
+	if (!entry) return null;
+	return {
+		domainLookupStart: entry.domainLookupStart,
+		domainLookupEnd: entry.domainLookupEnd,
+		connectStart: entry.connectStart,
+		connectEnd: entry.connectEnd,
+		secureConnectionStart: entry.secureConnectionStart,
+		requestStart: entry.requestStart,
+		responseStart: entry.responseStart,
+		responseEnd: entry.responseEnd,
+		domContentLoadedEventEnd: entry.domContentLoadedEventEnd,
+		loadEventEnd: entry.loadEventEnd,
+		duration: entry.duration,
+		transferSize: entry.transferSize,
+		encodedBodySize: entry.encodedBodySize,
+		decodedBodySize: entry.decodedBodySize
+	};
+})()
+`
 
 // ControllerImpl is the concrete implementation of the browser controller
 type ControllerImpl struct {
 	config        *config.BrowserConfig
 	allocatorOpts []chromedp.ExecAllocatorOption
 	hostname      string
+	clientCerts   *clientCertPolicy
+	oauth2        *oauth2TokenSource
+	egressIP      *egressIPTracker       // nil unless egress IP lookup is enabled
+	pool          *browserPool           // nil unless UsePool is set
+	captivePortal *captivePortalDetector // nil unless CaptivePortalURL is set
+	redactor      *redactor              // nil unless RedactPatterns is set
+	securityGrade *securityHeaderGrader  // nil unless GradeSecurityHeaders is set
+	classifier    ErrorClassifier        // nil uses DefaultErrorClassifier
+	screenshot    screenshotFunc         // nil uses fullPageScreenshot
+	lookupIP      lookupIPFunc           // nil uses net.DefaultResolver.LookupIP
+	proxy         *proxySettings         // nil unless ProxyURL is set
+
+	rendererCrashes atomic.Uint64 // renderer crashes seen, retries included
 }
 
 // NewControllerImpl creates a new browser controller with chromedp
@@ -49,11 +87,9 @@ func NewControllerImpl(cfg *config.BrowserConfig) (*ControllerImpl, error) {
 		chromedp.Flag("disable-offline-load-stale-cache", "true"),
 		chromedp.Flag("disk-cache-size", "0"),
 		chromedp.Flag("media-cache-size", "0"),
-		// Force fresh DNS, TCP, and TLS on every test
-		chromedp.Flag("disable-http2", "true"),  // Force HTTP/1.1 (no connection multiplexing)
-		chromedp.Flag("disable-quic", "true"),   // Disable HTTP/3
-		chromedp.Flag("disable-features", "NetworkService,TLSSessionResumption"), // Disable TLS session cache
 	}
+	// Force fresh DNS, TCP, and TLS on every test, unless configured otherwise
+	opts = append(opts, protocolFlags(cfg)...)
 
 	if cfg.Headless {
 		opts = append(opts, chromedp.Headless)
@@ -63,105 +99,422 @@ func NewControllerImpl(cfg *config.BrowserConfig) (*ControllerImpl, error) {
 		opts = append(opts, chromedp.Flag("blink-settings", "imagesEnabled=false"))
 	}
 
-	return &ControllerImpl{
+	// Resolve through a DoH provider instead of the system resolver
+	if cfg.DoHTemplate != "" {
+		opts = append(opts, chromedp.Flag("enable-features", dohFeature(cfg.DoHTemplate)))
+	}
+
+	// Load pages through a proxy; sites may override it
+	var proxy *proxySettings
+	if cfg.ProxyURL != "" {
+		if proxy, err = parseProxyURL(cfg.ProxyURL); err != nil {
+			return nil, err
+		}
+		opts = append(opts, chromedp.ProxyServer(proxy.server))
+	}
+
+	redactor, err := newRedactor(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+	var grader *securityHeaderGrader
+	if cfg.GradeSecurityHeaders {
+		if grader, err = newSecurityHeaderGrader(cfg.SecurityHeaderRules); err != nil {
+			return nil, err
+		}
+	}
+
+	chromeSlots.setLimit(cfg.MaxChromeInstances)
+
+	c := &ControllerImpl{
 		config:        cfg,
 		allocatorOpts: opts,
 		hostname:      hostname,
-	}, nil
+		clientCerts:   newClientCertPolicy(cfg.ChromePolicyDir),
+		oauth2:        newOAuth2TokenSource(),
+		redactor:      redactor,
+		securityGrade: grader,
+		proxy:         proxy,
+	}
+	if cfg.EgressIPEndpoint != "" {
+		c.egressIP = newEgressIPTracker(cfg.EgressIPEndpoint, cfg.EgressIPInterval)
+	}
+	if cfg.CaptivePortalURL != "" {
+		c.captivePortal = newCaptivePortalDetector(cfg.CaptivePortalURL, cfg.CaptivePortalExpectedStatus, cfg.CaptivePortalExpectedBody, cfg.CaptivePortalInterval)
+	}
+	if cfg.UsePool {
+		c.pool = newBrowserPool(cfg.PoolSize, cfg.PoolMaxReuse, c.launchPooledBrowser)
+	}
+	return c, nil
 }
 
 // TestSite navigates to a site and collects metrics
 func (c *ControllerImpl) TestSite(ctx context.Context, site models.SiteDefinition) (*models.TestResult, error) {
-	// Create a fresh allocator context for this test
-	// This ensures DNS, TCP, and TLS connections are all refreshed (not cached/reused)
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), c.allocatorOpts...)
-	defer cancelAlloc()
+	// HardDeadline bounds everything from here on, queueing included
+	deadline := newHardDeadline(c.config.HardDeadline)
+	ctx, cancelDeadline := deadline.bound(ctx)
+	defer cancelDeadline()
+
+	test := func() (*models.TestResult, error) {
+		// A Chrome that failed to start under load often starts a moment later
+		return retryStartup(ctx, site.GetName(), c.config.StartupRetries, c.config.StartupRetryBackoff, func() (*models.TestResult, error) {
+			if len(site.URLs) > 0 {
+				return c.testEndpoints(ctx, site, deadline)
+			}
+			return c.testURL(ctx, site, deadline)
+		})
+	}
+
+	// A renderer crash is our browser failing, not the site: try once more
+	result, err := test()
+	if errors.Is(err, ErrRendererCrash) {
+		c.rendererCrashes.Add(1)
+		if deadline.expired() {
+			return nil, err
+		}
+		log.Printf("Renderer crashed testing %s, retrying: %v", site.GetName(), err)
+		result, err = test()
+		if errors.Is(err, ErrRendererCrash) {
+			c.rendererCrashes.Add(1)
+		}
+	}
+	if err == nil && c.captivePortal != nil {
+		c.applyCaptivePortal(result)
+	}
+	// Tests ran against the full URL; outputs only ever see the redacted one
+	if err == nil {
+		c.redactor.redactResult(result)
+	}
+	return result, err
+}
+
+// testURL loads site.URL in a browser and collects metrics
+func (c *ControllerImpl) testURL(ctx context.Context, site models.SiteDefinition, deadline hardDeadline) (*models.TestResult, error) {
+	// target is the site as actually loaded; cache busting may give it a unique URL
+	target := site
+	testURL, cacheBustErr := cacheBustURL(site.URL, site.CacheBust)
+	if cacheBustErr == nil {
+		target.URL = testURL
+	}
+	binding, bindErr := parseSourceBinding(site)
+	proxy, proxyErr := c.siteProxy(site)
+	siteAuth, siteAuthErr := siteCredentials(site)
+	hostRules, ipVersionErr := c.ipVersionRule(ctx, site)
+
+	// Queue for a Chrome slot rather than exceed MaxChromeInstances
+	if err := chromeSlots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer chromeSlots.release()
+
+	var result *models.TestResult
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+	pooled := c.usesPool(site)
+	if pooled {
+		// Realistic mode: open a tab in a pooled browser, connections and all
+		b, err := c.pool.get()
+		if err != nil {
+			return nil, &StartupError{Site: site.GetName(), Err: err}
+		}
+		defer c.pool.put(b)
+		taskCtx, cancel = chromedp.NewContext(b.ctx)
+	} else {
+		// A pinned uplink is reached through a proxy that binds the connections
+		var proxyServer string
+		if proxy != nil {
+			proxyServer = proxy.server
+		}
+		if binding != nil {
+			bp, err := startBindProxy(binding)
+			if err != nil {
+				return nil, &StartupError{Site: site.GetName(), Err: err}
+			}
+			defer bp.Close()
+			defer func() { recordSourceBinding(result, binding, bp) }()
+			proxyServer = bp.URL()
+		}
+
+		// Create a fresh allocator context for this test
+		// This ensures DNS, TCP, and TLS connections are all refreshed (not cached/reused)
+		allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), c.allocatorOptions(target, proxyServer, hostRules)...)
+		defer cancelAlloc()
 
-	// Create a new browser context using the fresh allocator
-	taskCtx, cancel := chromedp.NewContext(allocCtx)
+		// Create a new browser context using the fresh allocator
+		taskCtx, cancel = chromedp.NewContext(allocCtx)
+	}
 	defer cancel()
 
-	// Apply site-specific timeout
+	// Apply site-specific timeout, within the hard deadline
 	timeout := site.GetTimeout()
 	taskCtx, cancelTimeout := context.WithTimeout(taskCtx, timeout)
 	defer cancelTimeout()
+	taskCtx, cancelTaskDeadline := deadline.bound(taskCtx)
+	defer cancelTaskDeadline()
 
 	// Create result
-	result := &models.TestResult{
-		Timestamp: time.Now(),
-		TestID:    uuid.New().String(),
-		Site: models.SiteInfo{
-			URL:      site.URL,
-			Name:     site.GetName(),
-			Category: site.Category,
-		},
-		Status: models.StatusInfo{
-			Success: false,
-		},
-		Metadata: models.TestMetadata{
-			Hostname:  c.hostname,
-			Version:   "1.3.0",
-			UserAgent: c.config.UserAgent,
-		},
+	result = c.newResult(site)
+
+	// Whatever was collected before the hard deadline is still reported
+	defer func() {
+		if deadline.expired() {
+			result.Truncated = true
+			log.Printf("Test of %s hit the %v hard deadline; reporting partial results", site.GetName(), c.config.HardDeadline)
+		}
+	}()
+
+	if c.egressIP != nil {
+		result.Metadata.EgressPublicIP, result.Metadata.EgressPublicIPChanged = c.egressIP.current()
 	}
 
-	// Set up network listener before navigation
-	networkCapture := SetupNetworkListener(taskCtx)
+	if cacheBustErr != nil {
+		result.Status.Message = "Invalid cache-bust configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_CACHE_BUST",
+			ErrorMessage: cacheBustErr.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
+	if target.URL != site.URL {
+		result.Metadata.TestedURL = target.URL
+	}
 
-	startTime := time.Now()
+	mode, err := parseTestMode(site.Mode)
+	if err != nil {
+		result.Status.Message = "Invalid test mode configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_TEST_MODE",
+			ErrorMessage: err.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
+	if mode != testModeFull {
+		result.Metadata.Mode = mode
+	}
 
-	// Navigate and collect metrics
-	var navigationEntry map[string]interface{}
+	locale, err := siteLocale(site.AcceptLanguage)
+	if err != nil {
+		result.Status.Message = "Invalid locale configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_LOCALE",
+			ErrorMessage: err.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
+	result.Metadata.Locale = locale
 
-	err := chromedp.Run(taskCtx,
-		// Enable network events to capture Chrome error codes
-		network.Enable(),
+	if bindErr != nil {
+		result.Status.Message = "Invalid interface or source IP configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_SOURCE_BINDING",
+			ErrorMessage: bindErr.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
 
-		// Navigate to the URL
-		chromedp.Navigate(site.URL),
+	if proxyErr != nil {
+		result.Status.Message = "Invalid proxy configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_PROXY",
+			ErrorMessage: proxyErr.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
 
-		// Wait for network idle if configured
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			if site.WaitForNetworkIdle {
-				return chromedp.WaitReady("body", chromedp.ByQuery).Do(ctx)
+	if siteAuthErr != nil {
+		result.Status.Message = "Invalid basic auth configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_BASIC_AUTH",
+			ErrorMessage: siteAuthErr.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
+
+	if ipVersionErr != nil {
+		if ipVersionErr.FailurePhase == "dns" {
+			result.Status.Message = "No IPv" + site.IPVersion + " address"
+		} else {
+			result.Status.Message = "Invalid IP version configuration"
+		}
+		result.Error = ipVersionErr
+		return result, nil
+	}
+	if site.IPVersion != "" {
+		result.Metadata.IPVersion = site.IPVersion
+	}
+
+	// Make sure Chrome will present the client certificate before it launches
+	if site.ClientCert != nil {
+		result.Metadata.MTLS = true
+		if err := c.clientCerts.ensure(site); err != nil {
+			result.Status.Message = "Client certificate unavailable"
+			result.Error = &models.ErrorInfo{
+				ErrorType:    "ERR_CLIENT_CERT_INVALID",
+				ErrorMessage: err.Error(),
+				FailurePhase: "tls",
 			}
-			return nil
-		}),
+			return result, nil
+		}
+	}
 
-		// Get performance navigation timing (Level 2 API)
-		chromedp.Evaluate(`
-			(function() {
-				const entry = performance.getEntriesByType('navigation')[0];
-				if (!entry) return null;
-				return {
-					domainLookupStart: entry.domainLookupStart,
-					domainLookupEnd: entry.domainLookupEnd,
-					connectStart: entry.connectStart,
-					connectEnd: entry.connectEnd,
-					secureConnectionStart: entry.secureConnectionStart,
-					requestStart: entry.requestStart,
-					responseStart: entry.responseStart,
-					responseEnd: entry.responseEnd,
-					domContentLoadedEventEnd: entry.domContentLoadedEventEnd,
-					loadEventEnd: entry.loadEventEnd,
-					duration: entry.duration,
-					transferSize: entry.transferSize,
-					encodedBodySize: entry.encodedBodySize,
-					decodedBodySize: entry.decodedBodySize
-				};
-			})()
-		`, &navigationEntry),
-	)
+	// Get the site's bearer token before the page load; failing to is our
+	// auth problem, not the site's outage
+	var bearer *bearerToken
+	if site.OAuth2 != nil {
+		authorization, err := c.oauth2.authorization(taskCtx, site.OAuth2)
+		if err == nil {
+			bearer, err = authorizeOrigin(target.URL, authorization)
+		}
+		if err != nil {
+			result.Status.Message = "OAuth2 token unavailable"
+			result.Error = &models.ErrorInfo{
+				ErrorType:    "ERR_OAUTH2_TOKEN",
+				ErrorMessage: err.Error(),
+				FailurePhase: "auth",
+			}
+			return result, nil
+		}
+	}
 
-	totalDuration := time.Since(startTime).Milliseconds()
+	// Set up network listener before navigation
+	networkCapture := SetupNetworkListener(taskCtx)
 
-	// Extract timing metrics from performance data (works for both success and failure)
-	result.Timings = extractTimings(navigationEntry, totalDuration)
+	// A crashed renderer never finishes the navigation; stop waiting for it
+	taskCtx, cancelOnCrash := context.WithCancelCause(taskCtx)
+	defer cancelOnCrash(nil)
+	go func() {
+		select {
+		case <-networkCapture.Crashed():
+			cancelOnCrash(ErrRendererCrash)
+		case <-taskCtx.Done():
+		}
+	}()
+
+	// Keep every request so the test, or at least a failure, can be debugged
+	// from a HAR file
+	if c.config.CaptureHAR || c.config.CaptureHAROnError {
+		networkCapture.RecordHAR()
+		defer func() {
+			if c.config.CaptureHAR || result.Error != nil {
+				c.saveHAR(result, networkCapture)
+			}
+		}()
+	}
+	if c.config.ScreenshotOnFailure {
+		defer func() { c.saveScreenshot(taskCtx, deadline, result, networkCapture) }()
+	}
+
+	startTime := time.Now()
+
+	// Enable network events to capture Chrome error codes, and drop ignored third parties
+	setup := chromedp.Tasks{network.Enable()}
+	if pooled {
+		// The tabs of a pooled browser share its in-memory cache; only
+		// connections are meant to be reused
+		setup = append(setup, network.SetCacheDisabled(true))
+	}
+	if headers := requestHeaders(site); headers != nil {
+		setup = append(setup, network.SetExtraHTTPHeaders(headers))
+	}
+	if locale != "" {
+		setup = append(setup, emulation.SetLocaleOverride().WithLocale(locale))
+	}
+	var proxyAuth *credentials
+	if proxy != nil {
+		proxyAuth = proxy.credentials
+	}
+	if proxyAuth != nil || siteAuth != nil || bearer != nil {
+		setup = append(setup, authenticateRequests(taskCtx, &requestAuth{proxy: proxyAuth, site: siteAuth, bearer: bearer}))
+	}
+	if patterns := blockedURLPatterns(site.IgnoreResourceDomains); len(patterns) > 0 {
+		setup = append(setup, network.SetBlockedURLs(patterns))
+	}
+
+	// Navigate and collect metrics
+	var navigationEntry map[string]interface{}
+	var vitalsEntry map[string]interface{}
+	var title string
+
+	if mode == testModeTTFB {
+		// Stop at the document's first byte; the page itself is never loaded
+		err = chromedp.Run(taskCtx,
+			setup,
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				return navigateFirstByte(ctx, target.URL, networkCapture)
+			}),
+		)
+	} else {
+		err = chromedp.Run(taskCtx,
+			setup,
+			observeWebVitals(),
+
+			// Navigate to the URL
+			chromedp.Navigate(target.URL),
+
+			// Wait for network idle if configured
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				if site.WaitForNetworkIdle {
+					return chromedp.WaitReady("body", chromedp.ByQuery).Do(ctx)
+				}
+				return nil
+			}),
+
+			// Get performance navigation timing (Level 2 API)
+			chromedp.Evaluate(navigationTimingJS, &navigationEntry),
+			chromedp.Evaluate(webVitalsJS, &vitalsEntry),
+
+			chromedp.Title(&title),
+		)
+	}
 
-	// Merge network timing if available (fills gaps in Performance API data)
-	if networkCapture.GetTiming() != nil {
-		mergeNetworkTiming(&result.Timings, networkCapture.GetTiming())
+	totalDuration := time.Since(startTime).Milliseconds()
+
+	if mode == testModeTTFB {
+		// Only the document's network timing exists when loading was stopped
+		result.Timings = ttfbTimings(networkCapture.GetTiming(), totalDuration)
+	} else {
+		// Extract timing metrics from performance data (works for both success and failure)
+		result.Timings = extractTimings(navigationEntry, totalDuration)
+		result.CoreWebVitals = coreWebVitals(vitalsEntry)
+
+		// Merge network timing if available (fills gaps in Performance API data)
+		if networkCapture.GetTiming() != nil {
+			mergeNetworkTiming(&result.Timings, networkCapture.GetTiming())
+		}
+	}
+	result.Status.Protocol = networkCapture.GetProtocol()
+	result.Status.PageTitle = title
+	result.FinalURL = networkCapture.GetFinalURL()
+	result.RedirectChain = networkCapture.GetRedirectChain()
+	result.RemoteIP, result.RemotePort = networkCapture.GetRemoteAddress()
+	result.Certificate = certificateInfo(networkCapture.GetSecurityDetails(), time.Now(), c.config.CertExpiryWarnDays)
+	result.TLS = tlsInfo(networkCapture.GetSecurityDetails())
+	if d, ok := networkCapture.GetTimeToFinalURL(); ok {
+		result.Timings.TimeToFinalURLMs = int64Ptr(d.Milliseconds())
 	}
+	if c.config.CheckClockSkew {
+		c.recordClockSkew(result, networkCapture)
+	}
+	if headers, _ := networkCapture.GetDocumentHeaders(); headers != nil {
+		result.ServerTimings = serverTimings(headers)
+		if c.config.CaptureResponseHeaders {
+			result.ResponseHeaders = responseHeaders(headers, c.config.ResponseHeaderAllowlist)
+		}
+		if c.securityGrade != nil {
+			c.securityGrade.grade(result, headers)
+		}
+	}
+	result.FailedSubresourceCount, result.FailedSubresources = networkCapture.GetFailedSubresources()
+	result.BlockedRequestCount = networkCapture.GetBlockedRequestCount()
+	result.TotalRequests, result.TotalBytes = networkCapture.GetTotals()
+	result.JSErrorCount, result.JSErrors = networkCapture.GetJSErrors()
+	result.ConsoleErrorCount, result.ConsoleErrors = networkCapture.GetConsoleErrors()
 
 	// Handle errors
 	if err != nil {
@@ -169,40 +522,235 @@ func (c *ControllerImpl) TestSite(ctx context.Context, site models.SiteDefinitio
 		// These should not be reported as connectivity problems
 		if isChromeStartupFailure(err) {
 			// Return the special error - test loop will not report this
-			return nil, ErrChromeStartupFailure
+			return nil, &StartupError{Site: site.GetName(), Err: err}
+		}
+		if networkCapture.HasCrashed() {
+			return nil, &RendererCrashError{Site: site.GetName(), Err: err}
 		}
 
 		// Enhanced error classification with Chrome error codes and phase detection
-		errorType := parseErrorType(err, networkCapture.GetErrorText())
-		failurePhase := inferFailurePhase(&result.Timings, site.URL)
-
 		result.Status.Success = false
 		result.Status.Message = "Failed to load page"
+		result.Error = c.classify(taskCtx, Failure{
+			Site:        target,
+			Err:         err,
+			ChromeError: networkCapture.GetErrorText(),
+			HTTPStatus:  networkCapture.GetStatus(),
+			Timings:     &result.Timings,
+		}, networkCapture)
+		return result, nil // Return result even on error (for logging)
+	}
+
+	// HTTP/3-only probe: loading over a fallback protocol counts as a failure
+	if site.ForceHTTP3 && !isHTTP3Protocol(result.Status.Protocol) {
+		result.Status.Message = "Page was not served over HTTP/3"
 		result.Error = &models.ErrorInfo{
-			ErrorType:    errorType,
-			ErrorMessage: err.Error(),
-			FailurePhase: failurePhase,
+			ErrorType:    "ERR_QUIC_NOT_NEGOTIATED",
+			ErrorMessage: "negotiated protocol: " + result.Status.Protocol,
+			FailurePhase: "quic",
+		}
+		return result, nil
+	}
+
+	// A page that loaded may still be a captive portal's instead of the site's
+	var body string
+	if len(c.config.CaptivePortalProbeStrings) > 0 && mode != testModeTTFB {
+		body = documentBody(taskCtx, networkCapture)
+	}
+	if errInfo := c.captivePortalError(target.URL, result.FinalURL, networkCapture.GetStatus(), body); errInfo != nil {
+		result.Status.HTTPStatus = networkCapture.GetStatus()
+		result.Status.Message = "Captive portal intercepted the page"
+		result.Error = errInfo
+		result.CaptivePortalDetected = true
+		return result, nil
+	}
+
+	// The page loaded; judge the document's HTTP status against the site's expectations
+	if status := networkCapture.GetStatus(); status != 0 {
+		result.Status.HTTPStatus = status
+		expected, err := statusInRanges(status, site.ExpectedStatusRanges)
+		if err != nil {
+			result.Status.Message = "Invalid expected status configuration"
+			result.Error = &models.ErrorInfo{
+				ErrorType:    "ERR_INVALID_STATUS_RANGE",
+				ErrorMessage: err.Error(),
+				FailurePhase: "unknown",
+			}
+			return result, nil
+		}
+		if !expected {
+			result.Status.Message = fmt.Sprintf("Unexpected HTTP status %d", status)
+			result.Error = c.classify(taskCtx, Failure{
+				Site:       target,
+				HTTPStatus: status,
+				Timings:    &result.Timings,
+			}, networkCapture)
+			return result, nil
+		}
+	} else {
+		result.Status.HTTPStatus = 200 // Navigation succeeded but no response event was seen
+	}
+
+	// The right status can still be the wrong page
+	if mode != testModeTTFB {
+		if errInfo := pageTitleError(site, title); errInfo != nil {
+			result.Status.Message = "Unexpected page title"
+			result.Error = errInfo
+			return result, nil
+		}
+		if site.HealthCheckJS != "" {
+			if errInfo := runHealthCheck(taskCtx, site.HealthCheckJS); errInfo != nil {
+				result.Status.Message = "Health check failed"
+				result.Error = errInfo
+				return result, nil
+			}
 		}
-		return result, nil // Return result even on error (for logging)
 	}
 
 	// Success case
 	result.Status.Success = true
-	result.Status.HTTPStatus = 200 // Navigation succeeded
 	result.Status.Message = "Page loaded successfully"
 
+	// The HTML loaded, but too many broken subresources usually means a blank shell
+	if site.MaxFailedSubresources > 0 && result.FailedSubresourceCount > site.MaxFailedSubresources {
+		result.Status.Degraded = true
+		result.Status.Message = "Page loaded with failed subresources"
+	}
+	if site.DegradeOnJSErrors && result.JSErrorCount > 0 && !result.Status.Degraded {
+		result.Status.Degraded = true
+		result.Status.Message = "Page loaded with JavaScript errors"
+	}
+	if result.Certificate != nil && result.Certificate.ExpiresSoon && !result.Status.Degraded {
+		result.Status.Degraded = true
+		result.Status.Message = fmt.Sprintf("Page loaded; certificate expires in %d days", result.Certificate.DaysUntilExpiry)
+	}
+
+	// Optionally repeat the navigation warm, reusing this browser's connections.
+	// The result, HAR included, describes the cold load only.
+	if site.MeasureWarm && mode == testModeFull {
+		cold := result.Timings
+		result.ColdTimings = &cold
+		networkCapture.Detach()
+
+		warm, err := measureWarm(taskCtx, target)
+		if err != nil {
+			log.Printf("Warm test for %s failed: %v", site.GetName(), err)
+		} else {
+			result.WarmTimings = warm
+		}
+	}
+
 	return result, nil
 }
 
+// newResult returns a not-yet-successful result for a test of site starting now
+func (c *ControllerImpl) newResult(site models.SiteDefinition) *models.TestResult {
+	return &models.TestResult{
+		Timestamp: time.Now(),
+		TestID:    uuid.New().String(),
+		Site: models.SiteInfo{
+			URL:      site.URL,
+			Name:     site.GetName(),
+			Category: site.Category,
+		},
+		Status: models.StatusInfo{
+			Success: false,
+		},
+		Metadata: models.TestMetadata{
+			Hostname:  c.hostname,
+			Version:   "1.3.0",
+			UserAgent: c.config.UserAgent,
+			DoH:       c.config.DoHTemplate != "",
+		},
+	}
+}
+
+// recordClockSkew compares the local clock with the document's Date header
+// and warns when they disagree by more than the configured threshold
+func (c *ControllerImpl) recordClockSkew(result *models.TestResult, capture *NetworkEventCapture) {
+	skew, ok := clockSkew(capture.GetDocumentHeaders())
+	if !ok {
+		return
+	}
+	skewMs := skew.Milliseconds()
+	result.Metadata.ClockSkewMs = &skewMs
+
+	threshold := c.config.ClockSkewThreshold
+	if threshold > 0 && (skew > threshold || skew < -threshold) {
+		result.Metadata.ClockSkewWarning = true
+		log.Printf("Clock skew warning: local clock differs from %s by %v (threshold %v)", result.Site.Name, skew, threshold)
+	}
+}
+
+// saveHAR writes the captured network activity for a test and records its path
+func (c *ControllerImpl) saveHAR(result *models.TestResult, capture *NetworkEventCapture) {
+	har := capture.BuildHAR(result.Metadata.Version)
+	if har == nil {
+		return
+	}
+	c.redactor.redactHAR(har)
+	path, err := writeHAR(c.config.HARDir, result, har)
+	if err != nil {
+		log.Printf("Failed to write HAR for %s: %v", result.Site.Name, err)
+		return
+	}
+	result.HARPath = path
+}
+
+// measureWarm navigates to the site a second time in the same browser context.
+// DNS, TCP, and TLS state from the cold navigation is reused, so the difference
+// between cold and warm timings shows the benefit of connection reuse and CDN warmth.
+func measureWarm(ctx context.Context, site models.SiteDefinition) (*models.TimingMetrics, error) {
+	var navigationEntry map[string]interface{}
+
+	startTime := time.Now()
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(site.URL),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if site.WaitForNetworkIdle {
+				return chromedp.WaitReady("body", chromedp.ByQuery).Do(ctx)
+			}
+			return nil
+		}),
+		chromedp.Evaluate(navigationTimingJS, &navigationEntry),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	timings := extractTimings(navigationEntry, time.Since(startTime).Milliseconds())
+	return &timings, nil
+}
+
 // Close shuts down the browser controller
 // Note: Each test now creates and cleans up its own browser instance,
 // so there's no persistent browser to shut down
 func (c *ControllerImpl) Close() error {
-	// No persistent browser allocator to clean up
-	// Each TestSite() call creates and disposes of its own browser instance
+	// Each fresh-mode TestSite() call creates and disposes of its own browser
+	// instance; only pooled browsers outlive a test
+	if c.pool != nil {
+		c.pool.close()
+	}
 	return nil
 }
 
+// RendererCrashes returns how many times a page's renderer has crashed
+// during a test, including crashes on retry
+func (c *ControllerImpl) RendererCrashes() uint64 {
+	return c.rendererCrashes.Load()
+}
+
+// usesPool reports whether a site is tested in a pooled browser. Only
+// realistic mode qualifies, and only for sites that need nothing the shared
+// browser was not launched with (extra flags, a client certificate or a pinned
+// interface or source IP).
+func (c *ControllerImpl) usesPool(site models.SiteDefinition) bool {
+	return c.pool != nil && site.Mode == testModeRealistic &&
+		len(siteFlags(site)) == 0 && site.ClientCert == nil &&
+		site.Interface == "" && site.SourceIP == "" && site.ProxyURL == "" &&
+		site.IPVersion == ""
+}
+
 // int64Ptr is a helper function to create a pointer to an int64 value
 func int64Ptr(val int64) *int64 {
 	return &val
@@ -210,8 +758,8 @@ func int64Ptr(val int64) *int64 {
 
 // extractTimings converts performance navigation timing data to our metrics structure
 //
-// The browser is configured to force fresh DNS, TCP, and TLS on every test by disabling
-// HTTP/2, QUIC, and TLS session resumption. This ensures accurate timing measurements
+// By default the browser is configured to force fresh DNS, TCP, and TLS on every test by
+// disabling HTTP/2, QUIC, and TLS session resumption (see protocolFlags). This ensures accurate timing measurements
 // for every connection phase, allowing us to detect network issues in DNS resolution,
 // TCP handshakes, and TLS negotiation.
 func extractTimings(perfData map[string]interface{}, totalMs int64) models.TimingMetrics {
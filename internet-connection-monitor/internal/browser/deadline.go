@@ -0,0 +1,34 @@
+package browser
+
+import (
+	"context"
+	"time"
+)
+
+// hardDeadline is the point in time by which a whole TestSite call must
+// finish, however its phases (queueing for Chrome, navigation, warm
+// measurement...) are configured. The zero value imposes no deadline.
+type hardDeadline struct {
+	at time.Time
+}
+
+func newHardDeadline(budget time.Duration) hardDeadline {
+	if budget <= 0 {
+		return hardDeadline{}
+	}
+	return hardDeadline{at: time.Now().Add(budget)}
+}
+
+// bound returns ctx limited by the deadline
+func (d hardDeadline) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.at.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, d.at)
+}
+
+// expired reports whether the deadline has passed, i.e. whatever was still
+// running when it did was cut short
+func (d hardDeadline) expired() bool {
+	return !d.at.IsZero() && !time.Now().Before(d.at)
+}
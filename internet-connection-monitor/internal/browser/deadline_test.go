@@ -0,0 +1,60 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHardDeadlineDisabled(t *testing.T) {
+	d := newHardDeadline(0)
+	ctx, cancel := d.bound(context.Background())
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline when the budget is 0")
+	}
+	if d.expired() {
+		t.Error("A disabled deadline must never expire")
+	}
+}
+
+func TestHardDeadlineBoundsLongerTimeouts(t *testing.T) {
+	d := newHardDeadline(30 * time.Millisecond)
+
+	// A generous per-phase timeout is cut short by the hard deadline
+	phaseCtx, cancelPhase := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelPhase()
+	ctx, cancel := d.bound(phaseCtx)
+	defer cancel()
+
+	if d.expired() {
+		t.Fatal("Deadline expired immediately")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the hard deadline to cancel the context")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", ctx.Err())
+	}
+	if !d.expired() {
+		t.Error("Expected the deadline to report expiry")
+	}
+}
+
+func TestHardDeadlineKeepsShorterTimeouts(t *testing.T) {
+	d := newHardDeadline(time.Minute)
+
+	phaseCtx, cancelPhase := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelPhase()
+	ctx, cancel := d.bound(phaseCtx)
+	defer cancel()
+
+	<-ctx.Done()
+	if d.expired() {
+		t.Error("A phase timing out on its own must not count as truncation")
+	}
+}
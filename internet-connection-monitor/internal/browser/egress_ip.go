@@ -0,0 +1,122 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// egressLookupTimeout bounds a single public IP lookup
+	egressLookupTimeout = 5 * time.Second
+
+	// defaultEgressIPInterval is used when no refresh interval is configured
+	defaultEgressIPInterval = 5 * time.Minute
+)
+
+// egressIPTracker caches the monitor's public (egress) IP as reported by a
+// "what is my IP" endpoint that returns the address as plain text
+// (e.g. https://api.ipify.org).
+//
+// Lookups never block a test: a stale value triggers one background refresh
+// and the cached address is used meanwhile. The endpoint is reached over the
+// same connection being monitored, so a failed lookup keeps the last known
+// address and waits a full interval before trying again.
+type egressIPTracker struct {
+	endpoint string
+	interval time.Duration
+	client   *http.Client
+
+	mu          sync.Mutex
+	ip          string    // last successfully resolved address
+	lastAttempt time.Time // when the last lookup started
+	refreshing  bool
+	reported    string // address attached to the previous result
+
+	now func() time.Time
+}
+
+func newEgressIPTracker(endpoint string, interval time.Duration) *egressIPTracker {
+	if interval <= 0 {
+		interval = defaultEgressIPInterval
+	}
+	return &egressIPTracker{
+		endpoint: endpoint,
+		interval: interval,
+		client:   &http.Client{Timeout: egressLookupTimeout},
+		now:      time.Now,
+	}
+}
+
+// current returns the cached egress IP ("" if never resolved) and whether it
+// differs from the address returned by the previous call, refreshing the
+// cache in the background when it is older than the interval
+func (t *egressIPTracker) current() (ip string, changed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.refreshing && t.now().Sub(t.lastAttempt) >= t.interval {
+		t.refreshing = true
+		t.lastAttempt = t.now()
+		go t.refresh()
+	}
+
+	changed = t.ip != "" && t.reported != "" && t.ip != t.reported
+	if t.ip != "" {
+		t.reported = t.ip
+	}
+	return t.ip, changed
+}
+
+// refresh performs one lookup and stores the result
+func (t *egressIPTracker) refresh() {
+	ip, err := t.lookup()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refreshing = false
+
+	if err != nil {
+		log.Printf("Egress IP lookup via %s failed, keeping %q: %v", t.endpoint, t.ip, err)
+		return
+	}
+	if t.ip != "" && ip != t.ip {
+		log.Printf("Egress public IP changed: %s -> %s", t.ip, ip)
+	}
+	t.ip = ip
+}
+
+func (t *egressIPTracker) lookup() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), egressLookupTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return "", fmt.Errorf("response is not an IP address: %q", strings.TrimSpace(string(body)))
+	}
+	return ip.String(), nil
+}
@@ -0,0 +1,96 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForEgressRefresh waits for a background lookup started by current() to finish
+func waitForEgressRefresh(t *testing.T, tracker *egressIPTracker) {
+	t.Helper()
+	deadline := time.Now().Add(2 * egressLookupTimeout)
+	for time.Now().Before(deadline) {
+		tracker.mu.Lock()
+		refreshing := tracker.refreshing
+		tracker.mu.Unlock()
+		if !refreshing {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("egress IP refresh did not finish")
+}
+
+func TestEgressIPTracker(t *testing.T) {
+	var response atomic.Value
+	response.Store("203.0.113.7\n")
+	var lookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		body := response.Load().(string)
+		if body == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	clock := time.Now()
+	tracker := newEgressIPTracker(server.URL, time.Minute)
+	tracker.now = func() time.Time { return clock }
+
+	// The first call never blocks on the lookup
+	if ip, changed := tracker.current(); ip != "" || changed {
+		t.Errorf("Expected no IP before the first lookup completes, got %q changed=%v", ip, changed)
+	}
+	waitForEgressRefresh(t, tracker)
+
+	if ip, changed := tracker.current(); ip != "203.0.113.7" || changed {
+		t.Errorf("Expected 203.0.113.7 unchanged, got %q changed=%v", ip, changed)
+	}
+
+	// Within the interval the cached value is used
+	response.Store("198.51.100.1")
+	clock = clock.Add(30 * time.Second)
+	tracker.current()
+	waitForEgressRefresh(t, tracker)
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("Expected 1 lookup within the interval, got %d", got)
+	}
+
+	// After the interval the new address is picked up and flagged once
+	clock = clock.Add(time.Minute)
+	tracker.current()
+	waitForEgressRefresh(t, tracker)
+	if ip, changed := tracker.current(); ip != "198.51.100.1" || !changed {
+		t.Errorf("Expected change to 198.51.100.1, got %q changed=%v", ip, changed)
+	}
+	if _, changed := tracker.current(); changed {
+		t.Error("Expected the change to be flagged only once")
+	}
+
+	// An unreachable endpoint keeps the last known address
+	response.Store("")
+	clock = clock.Add(time.Minute)
+	tracker.current()
+	waitForEgressRefresh(t, tracker)
+	if ip, changed := tracker.current(); ip != "198.51.100.1" || changed {
+		t.Errorf("Expected last known IP to be kept on failure, got %q changed=%v", ip, changed)
+	}
+}
+
+func TestEgressIPTrackerRejectsNonIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>captive portal</html>")
+	}))
+	defer server.Close()
+
+	if _, err := newEgressIPTracker(server.URL, 0).lookup(); err == nil {
+		t.Error("Expected an error for a non-IP response")
+	}
+}
@@ -0,0 +1,127 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// URL policies for sites with several endpoints (SiteDefinition.URLPolicy)
+const (
+	urlPolicyAny   = "any"
+	urlPolicyAll   = "all"
+	urlPolicyFirst = "first"
+)
+
+// parseURLPolicy validates a site's URL policy, defaulting to any
+func parseURLPolicy(policy string) (string, error) {
+	switch policy {
+	case "", urlPolicyAny:
+		return urlPolicyAny, nil
+	case urlPolicyAll, urlPolicyFirst:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown URL policy %q (expected %q, %q or %q)", policy, urlPolicyAny, urlPolicyAll, urlPolicyFirst)
+	}
+}
+
+// testEndpoints tests each of a site's URLs as its own page load and
+// combines them into one result for the site
+func (c *ControllerImpl) testEndpoints(ctx context.Context, site models.SiteDefinition, deadline hardDeadline) (*models.TestResult, error) {
+	policy, err := parseURLPolicy(site.URLPolicy)
+	if err != nil {
+		endpoint := site
+		endpoint.URL = site.URLs[0]
+		result := c.newResult(endpoint)
+		result.Site.URL = site.GetIdentity()
+		result.Status.Message = "Invalid URL policy configuration"
+		result.Error = &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_URL_POLICY",
+			ErrorMessage: err.Error(),
+			FailurePhase: "unknown",
+		}
+		return result, nil
+	}
+
+	var results []*models.TestResult
+	truncated := false
+	for i, url := range site.URLs {
+		if i > 0 && deadline.expired() {
+			truncated = true // no time left for the remaining endpoints
+			break
+		}
+
+		endpoint := site
+		endpoint.URL = url
+		endpoint.URLs = nil
+		result, err := c.testURL(ctx, endpoint, deadline)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+
+		if policy == urlPolicyFirst && result.Status.Success {
+			break
+		}
+	}
+
+	result := combineEndpointResults(results, policy)
+	if identity := site.GetIdentity(); result.Site.URL != identity {
+		if result.Metadata.TestedURL == "" {
+			result.Metadata.TestedURL = result.Site.URL
+		}
+		result.Site.URL = identity
+	}
+	result.Truncated = result.Truncated || truncated
+	return result, nil
+}
+
+// combineEndpointResults picks the result that represents the site - the
+// first success, or the first failure when the site is down under the
+// policy - and attaches every endpoint's outcome to it
+func combineEndpointResults(results []*models.TestResult, policy string) *models.TestResult {
+	endpoints := make([]models.EndpointResult, len(results))
+	var firstSuccess, firstFailure *models.TestResult
+	succeeded := 0
+	for i, r := range results {
+		endpoints[i] = models.EndpointResult{
+			URL:             r.Site.URL,
+			Success:         r.Status.Success,
+			HTTPStatus:      r.Status.HTTPStatus,
+			TotalDurationMs: r.Timings.TotalDurationMs,
+		}
+		if r.Error != nil {
+			endpoints[i].ErrorType = r.Error.ErrorType
+		}
+
+		if r.Status.Success {
+			succeeded++
+			if firstSuccess == nil {
+				firstSuccess = r
+			}
+		} else if firstFailure == nil {
+			firstFailure = r
+		}
+	}
+
+	up := succeeded > 0
+	if policy == urlPolicyAll {
+		up = succeeded == len(results)
+	}
+
+	var result *models.TestResult
+	if up {
+		result = firstSuccess
+		if succeeded < len(results) {
+			result.Status.Message = fmt.Sprintf("%d of %d endpoints up", succeeded, len(results))
+		}
+	} else {
+		result = firstFailure
+		if succeeded > 0 {
+			result.Status.Message = fmt.Sprintf("Only %d of %d endpoints up", succeeded, len(results))
+		}
+	}
+	result.Endpoints = endpoints
+	return result
+}
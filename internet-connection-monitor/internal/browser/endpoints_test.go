@@ -0,0 +1,83 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestParseURLPolicy(t *testing.T) {
+	for policy, want := range map[string]string{"": "any", "any": "any", "all": "all", "first": "first"} {
+		if got, err := parseURLPolicy(policy); err != nil || got != want {
+			t.Errorf("parseURLPolicy(%q) = %q, %v; want %q", policy, got, err, want)
+		}
+	}
+	if _, err := parseURLPolicy("ANY"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+func endpointResult(url string, success bool) *models.TestResult {
+	r := &models.TestResult{Site: models.SiteInfo{URL: url}}
+	r.Status.Success = success
+	r.Timings.TotalDurationMs = 100
+	if success {
+		r.Status.HTTPStatus = 200
+	} else {
+		r.Error = &models.ErrorInfo{ErrorType: "ERR_CONNECTION_REFUSED"}
+	}
+	return r
+}
+
+func TestCombineEndpointResults(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		outcomes    []bool
+		wantSuccess bool
+		wantURL     string // endpoint the combined result describes
+	}{
+		{name: "any with primary down", policy: "any", outcomes: []bool{false, true}, wantSuccess: true, wantURL: "https://1.example"},
+		{name: "any all down", policy: "any", outcomes: []bool{false, false}, wantSuccess: false, wantURL: "https://0.example"},
+		{name: "all with one down", policy: "all", outcomes: []bool{true, false}, wantSuccess: false, wantURL: "https://1.example"},
+		{name: "all up", policy: "all", outcomes: []bool{true, true}, wantSuccess: true, wantURL: "https://0.example"},
+		{name: "first stopped at success", policy: "first", outcomes: []bool{false, true}, wantSuccess: true, wantURL: "https://1.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []*models.TestResult
+			for i, ok := range tt.outcomes {
+				results = append(results, endpointResult("https://"+string(rune('0'+i))+".example", ok))
+			}
+
+			got := combineEndpointResults(results, tt.policy)
+			if got.Status.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v", got.Status.Success, tt.wantSuccess)
+			}
+			if got.Site.URL != tt.wantURL {
+				t.Errorf("combined result describes %s, want %s", got.Site.URL, tt.wantURL)
+			}
+			if len(got.Endpoints) != len(tt.outcomes) {
+				t.Fatalf("got %d endpoint results, want %d", len(got.Endpoints), len(tt.outcomes))
+			}
+			for i, ok := range tt.outcomes {
+				e := got.Endpoints[i]
+				if e.Success != ok || (ok && e.HTTPStatus != 200) || (!ok && e.ErrorType != "ERR_CONNECTION_REFUSED") {
+					t.Errorf("endpoint %d = %+v, want success %v", i, e, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestSiteIdentity(t *testing.T) {
+	withURL := models.SiteDefinition{URL: "https://svc.example", URLs: []string{"https://a.example"}}
+	if got := withURL.GetIdentity(); got != "https://svc.example" {
+		t.Errorf("Expected URL to identify the site, got %s", got)
+	}
+	urlsOnly := models.SiteDefinition{URLs: []string{"https://a.example", "https://b.example"}}
+	if got := urlsOnly.GetIdentity(); got != "https://a.example" {
+		t.Errorf("Expected the first endpoint to identify a site without URL, got %s", got)
+	}
+}
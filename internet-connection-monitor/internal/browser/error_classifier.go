@@ -1,12 +1,161 @@
 package browser
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
 )
 
+// ErrorClassifier turns a failed test into the ErrorInfo that is reported.
+// Install one with ControllerImpl.SetErrorClassifier to map environment-specific
+// failures (e.g. a proxy's own error pages) onto a custom taxonomy; it can defer
+// to DefaultErrorClassifier for everything it doesn't recognise.
+type ErrorClassifier interface {
+	Classify(f Failure) *models.ErrorInfo
+}
+
+// Failure describes a test that failed, either because navigation failed (Err
+// is set) or because the page loaded with an unexpected HTTP status
+type Failure struct {
+	// Site is the site as loaded (after cache busting)
+	Site models.SiteDefinition
+
+	// Err is the navigation error, nil for an unexpected status
+	Err error
+
+	// ChromeError is Chrome's raw error text (e.g. "net::ERR_NAME_NOT_RESOLVED"), if any
+	ChromeError string
+
+	// HTTPStatus is the main document's status (0 if it never responded)
+	HTTPStatus int
+
+	// Timings are the phases that completed before the failure
+	Timings *models.TimingMetrics
+
+	// Body is the start of the main document's response body. It is only
+	// fetched for custom classifiers and is empty when no response arrived or
+	// the test's deadline had already passed.
+	Body string
+}
+
+// DefaultErrorClassifier is the built-in classification: Chrome's error code,
+// or "timeout"/"unknown", with the failure phase inferred from the timings
+type DefaultErrorClassifier struct{}
+
+// Classify implements ErrorClassifier
+func (DefaultErrorClassifier) Classify(f Failure) *models.ErrorInfo {
+	if f.Err == nil {
+		return &models.ErrorInfo{
+			ErrorType:    "ERR_UNEXPECTED_STATUS",
+			ErrorMessage: fmt.Sprintf("HTTP status %d is not in the expected ranges", f.HTTPStatus),
+			FailurePhase: "http",
+		}
+	}
+
+	errorType := parseErrorType(f.Err, f.ChromeError)
+	failurePhase := inferFailurePhase(f.Timings, f.Site.URL)
+	if f.Site.ForceHTTP3 && isQUICError(errorType) {
+		failurePhase = "quic"
+	}
+	return &models.ErrorInfo{
+		ErrorType:    errorType,
+		ErrorMessage: f.Err.Error(),
+		FailurePhase: failurePhase,
+	}
+}
+
+// maxClassifierBodyBytes caps the response body handed to a custom classifier
+const maxClassifierBodyBytes = 64 << 10
+
+// SetErrorClassifier replaces the error classification for subsequent tests.
+// nil restores DefaultErrorClassifier.
+func (c *ControllerImpl) SetErrorClassifier(classifier ErrorClassifier) {
+	c.classifier = classifier
+}
+
+// classify runs the configured classifier, fetching the document body first
+// when a custom classifier is installed
+func (c *ControllerImpl) classify(ctx context.Context, f Failure, capture *NetworkEventCapture) *models.ErrorInfo {
+	if c.classifier == nil {
+		return DefaultErrorClassifier{}.Classify(f)
+	}
+	f.Body = documentBody(ctx, capture)
+	return c.classifier.Classify(f)
+}
+
+// documentBody returns up to maxClassifierBodyBytes of the main document's
+// response body, or "" if it is unavailable
+func documentBody(ctx context.Context, capture *NetworkEventCapture) string {
+	id := capture.GetDocumentRequestID()
+	if id == "" || !capture.HasResponse() || ctx.Err() != nil {
+		return ""
+	}
+	var body []byte
+	if err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		body, err = network.GetResponseBody(id).Do(ctx)
+		return err
+	})); err != nil {
+		return ""
+	}
+	if len(body) > maxClassifierBodyBytes {
+		body = body[:maxClassifierBodyBytes]
+	}
+	return string(body)
+}
+
+// captivePortalError looks for a captive portal's answer in place of the
+// site's page: an HTTP 511 Network Authentication Required, a final URL on a
+// host that isn't the site's (if CaptivePortalCheckHost is set), or one of
+// CaptivePortalProbeStrings in the document body. It returns nil when the page
+// looks like the site's own.
+func (c *ControllerImpl) captivePortalError(requestedURL, finalURL string, status int, body string) *models.ErrorInfo {
+	portal := func(msg string) *models.ErrorInfo {
+		return &models.ErrorInfo{
+			ErrorType:    "ERR_CAPTIVE_PORTAL",
+			ErrorMessage: msg,
+			FailurePhase: "captive_portal",
+		}
+	}
+
+	if status == http.StatusNetworkAuthenticationRequired {
+		return portal("HTTP status 511 Network Authentication Required")
+	}
+	if c.config.CaptivePortalCheckHost {
+		requested, errRequested := url.Parse(requestedURL)
+		final, errFinal := url.Parse(finalURL)
+		// Only http(s) documents say anything about where we were sent
+		if errRequested == nil && errFinal == nil && (final.Scheme == "http" || final.Scheme == "https") &&
+			!sameSiteHost(requested.Hostname(), final.Hostname()) {
+			return portal(fmt.Sprintf("redirected from %s to %s", requested.Hostname(), final.Hostname()))
+		}
+	}
+	lowerBody := strings.ToLower(body)
+	for _, probe := range c.config.CaptivePortalProbeStrings {
+		if probe != "" && strings.Contains(lowerBody, strings.ToLower(probe)) {
+			return portal(fmt.Sprintf("page contains captive portal probe %q", probe))
+		}
+	}
+	return nil
+}
+
+// sameSiteHost reports whether a redirect from host a to host b stays with
+// the site: the same host, ignoring a www. prefix, or one a subdomain of the
+// other (example.com to login.example.com)
+func sameSiteHost(a, b string) bool {
+	normalize := func(host string) string {
+		return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(host), "."), "www.")
+	}
+	a, b = normalize(a), normalize(b)
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
 // inferFailurePhase determines which network layer failed based on timing data
 // Logic: If we have timing for phase X but not X+1, failure was in X+1
 func inferFailurePhase(timings *models.TimingMetrics, siteURL string) string {
@@ -41,6 +190,17 @@ func inferFailurePhase(timings *models.TimingMetrics, siteURL string) string {
 	return "http"
 }
 
+// isQUICError reports whether a Chrome error code came from the QUIC transport
+// (e.g. "ERR_QUIC_PROTOCOL_ERROR", "ERR_QUIC_HANDSHAKE_FAILED")
+func isQUICError(errorType string) bool {
+	return strings.HasPrefix(errorType, "ERR_QUIC_")
+}
+
+// isHTTP3Protocol reports whether a negotiated protocol is HTTP/3 (including drafts like "h3-29")
+func isHTTP3Protocol(protocol string) bool {
+	return protocol == "h3" || strings.HasPrefix(protocol, "h3-")
+}
+
 // parseErrorType extracts the Chrome error code from error text
 // Returns the error code (e.g., "ERR_NAME_NOT_RESOLVED") or a fallback
 func parseErrorType(err error, chromeError string) string {
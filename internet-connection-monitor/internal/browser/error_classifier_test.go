@@ -1,9 +1,12 @@
 package browser
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
 )
 
@@ -186,3 +189,206 @@ func TestParseErrorType(t *testing.T) {
 		})
 	}
 }
+
+func TestIsQUICError(t *testing.T) {
+	tests := []struct {
+		errorType string
+		expected  bool
+	}{
+		{"ERR_QUIC_PROTOCOL_ERROR", true},
+		{"ERR_QUIC_HANDSHAKE_FAILED", true},
+		{"ERR_CONNECTION_REFUSED", false},
+		{"timeout", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.errorType, func(t *testing.T) {
+			if got := isQUICError(tt.errorType); got != tt.expected {
+				t.Errorf("isQUICError(%q) = %v, want %v", tt.errorType, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsHTTP3Protocol(t *testing.T) {
+	tests := []struct {
+		protocol string
+		expected bool
+	}{
+		{"h3", true},
+		{"h3-29", true},
+		{"h2", false},
+		{"http/1.1", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			if got := isHTTP3Protocol(tt.protocol); got != tt.expected {
+				t.Errorf("isHTTP3Protocol(%q) = %v, want %v", tt.protocol, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDefaultErrorClassifier(t *testing.T) {
+	connected := &models.TimingMetrics{
+		DNSLookupMs:     int64PtrTest(5),
+		TCPConnectionMs: int64PtrTest(10),
+	}
+
+	tests := []struct {
+		name    string
+		failure Failure
+		want    models.ErrorInfo
+	}{
+		{
+			name: "Chrome error with inferred phase",
+			failure: Failure{
+				Site:        models.SiteDefinition{URL: "https://example.com"},
+				Err:         errors.New("page load error net::ERR_CONNECTION_RESET"),
+				ChromeError: "net::ERR_CONNECTION_RESET",
+				Timings:     connected,
+			},
+			want: models.ErrorInfo{
+				ErrorType:    "ERR_CONNECTION_RESET",
+				ErrorMessage: "page load error net::ERR_CONNECTION_RESET",
+				FailurePhase: "tls",
+			},
+		},
+		{
+			name: "QUIC error on an HTTP/3 probe",
+			failure: Failure{
+				Site:        models.SiteDefinition{URL: "https://example.com", ForceHTTP3: true},
+				Err:         errors.New("page load error net::ERR_QUIC_PROTOCOL_ERROR"),
+				ChromeError: "net::ERR_QUIC_PROTOCOL_ERROR",
+				Timings:     connected,
+			},
+			want: models.ErrorInfo{
+				ErrorType:    "ERR_QUIC_PROTOCOL_ERROR",
+				ErrorMessage: "page load error net::ERR_QUIC_PROTOCOL_ERROR",
+				FailurePhase: "quic",
+			},
+		},
+		{
+			name: "unexpected status",
+			failure: Failure{
+				Site:       models.SiteDefinition{URL: "https://example.com"},
+				HTTPStatus: 502,
+				Timings:    connected,
+			},
+			want: models.ErrorInfo{
+				ErrorType:    "ERR_UNEXPECTED_STATUS",
+				ErrorMessage: "HTTP status 502 is not in the expected ranges",
+				FailurePhase: "http",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultErrorClassifier{}.Classify(tt.failure)
+			if got == nil || *got != tt.want {
+				t.Errorf("Classify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// proxyClassifier recognises a proxy's error page and defers everything else
+type proxyClassifier struct {
+	seen []Failure
+}
+
+func (p *proxyClassifier) Classify(f Failure) *models.ErrorInfo {
+	p.seen = append(p.seen, f)
+	if f.HTTPStatus == 502 {
+		return &models.ErrorInfo{ErrorType: "PROXY_UPSTREAM_DOWN", ErrorMessage: "proxy could not reach upstream", FailurePhase: "proxy"}
+	}
+	return DefaultErrorClassifier{}.Classify(f)
+}
+
+func TestSetErrorClassifier(t *testing.T) {
+	c := &ControllerImpl{}
+	capture := newNetworkEventCapture()
+	failure := Failure{
+		Site:       models.SiteDefinition{URL: "https://example.com"},
+		HTTPStatus: 502,
+		Timings:    &models.TimingMetrics{},
+	}
+
+	custom := &proxyClassifier{}
+	c.SetErrorClassifier(custom)
+	if got := c.classify(context.Background(), failure, capture); got.ErrorType != "PROXY_UPSTREAM_DOWN" {
+		t.Errorf("Expected the custom classification, got %+v", got)
+	}
+	if len(custom.seen) != 1 || custom.seen[0].Body != "" {
+		t.Errorf("Expected one call with no body (no document response), got %+v", custom.seen)
+	}
+
+	// Deferring to the default keeps the built-in taxonomy
+	failure.HTTPStatus = 404
+	if got := c.classify(context.Background(), failure, capture); got.ErrorType != "ERR_UNEXPECTED_STATUS" {
+		t.Errorf("Expected the default classification, got %+v", got)
+	}
+
+	c.SetErrorClassifier(nil)
+	failure.HTTPStatus = 502
+	if got := c.classify(context.Background(), failure, capture); !strings.HasPrefix(got.ErrorType, "ERR_") {
+		t.Errorf("Expected the default classifier to be restored, got %+v", got)
+	}
+	if len(custom.seen) != 2 {
+		t.Errorf("Expected the custom classifier to be uninstalled, got %d calls", len(custom.seen))
+	}
+}
+
+func TestCaptivePortalError(t *testing.T) {
+	c := &ControllerImpl{config: &config.BrowserConfig{
+		CaptivePortalCheckHost:    true,
+		CaptivePortalProbeStrings: []string{"Accept the Terms of Use"},
+	}}
+	tests := []struct {
+		name     string
+		finalURL string
+		status   int
+		body     string
+		wantMsg  string // "" when no portal is expected
+	}{
+		{name: "normal load", finalURL: "https://example.com/", status: 200, body: "<h1>Example Domain</h1>"},
+		{name: "redirect to www", finalURL: "https://www.example.com/", status: 200},
+		{name: "redirect to own subdomain", finalURL: "https://login.example.com/sso", status: 200},
+		{name: "no document", finalURL: "", status: 0},
+		{name: "error page", finalURL: "chrome-error://chromewebdata/", status: 0},
+
+		{name: "redirect to login", finalURL: "http://login.hotelwifi.net/portal?orig=example.com", status: 200, wantMsg: "redirected from example.com to login.hotelwifi.net"},
+		{name: "redirect to gateway address", finalURL: "http://192.168.1.1/login", status: 200, wantMsg: "redirected from example.com to 192.168.1.1"},
+		{name: "network authentication required", finalURL: "https://example.com/", status: 511, wantMsg: "HTTP status 511"},
+		{name: "probe string in page", finalURL: "https://example.com/", status: 200, body: "<p>Please accept the terms of use to continue</p>", wantMsg: `page contains captive portal probe "Accept the Terms of Use"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errInfo := c.captivePortalError("https://example.com/?cb=123", tt.finalURL, tt.status, tt.body)
+			if tt.wantMsg == "" {
+				if errInfo != nil {
+					t.Fatalf("Expected no captive portal, got %+v", errInfo)
+				}
+				return
+			}
+			if errInfo == nil {
+				t.Fatalf("Expected a captive portal: %s", tt.wantMsg)
+			}
+			if errInfo.ErrorType != "ERR_CAPTIVE_PORTAL" || errInfo.FailurePhase != "captive_portal" {
+				t.Errorf("Expected ERR_CAPTIVE_PORTAL in phase captive_portal, got %s in %s", errInfo.ErrorType, errInfo.FailurePhase)
+			}
+			if !strings.HasPrefix(errInfo.ErrorMessage, tt.wantMsg) {
+				t.Errorf("Expected message %q, got %q", tt.wantMsg, errInfo.ErrorMessage)
+			}
+		})
+	}
+
+	// Host checking is opt-in: some sites do redirect elsewhere
+	c.config.CaptivePortalCheckHost = false
+	if errInfo := c.captivePortalError("https://example.com/", "https://login.hotelwifi.net/", 200, ""); errInfo != nil {
+		t.Errorf("Expected no host check when disabled, got %+v", errInfo)
+	}
+}
@@ -0,0 +1,74 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Controller.TestSite
+//
+// TestSite reports the outcome of testing a site as a TestResult, including
+// connectivity failures (DNS, TCP, TLS, HTTP errors, timeouts) and invalid
+// site configuration, which are described by TestResult.Error and returned
+// with a nil error. A non-nil error means no test took place and there is no
+// result to report:
+//
+//   - *StartupError: Chrome could not be started (resource exhaustion, missing
+//     binary), not even on the retries TestSite makes. This says nothing about
+//     the Internet connection; retry later or restart the process.
+//     errors.Is(err, ErrChromeStartupFailure) also matches.
+//   - *RendererCrashError: the page's renderer crashed mid-test, and again on
+//     the one retry TestSite makes. Browser instability, not a site outage.
+//     errors.Is(err, ErrRendererCrash) also matches.
+//   - anything else: an unexpected internal failure.
+
+// ErrChromeStartupFailure indicates Chrome failed to start (not an Internet connectivity issue).
+// TestSite returns it wrapped in a *StartupError.
+var ErrChromeStartupFailure = errors.New("chrome failed to start")
+
+// StartupError reports that the browser could not be started for a test
+type StartupError struct {
+	// Site is the name of the site that was about to be tested
+	Site string
+
+	// Err is the underlying error from the browser launcher
+	Err error
+
+	// Attempts is how many times Chrome was started for the test, retries
+	// included; 0 when unknown
+	Attempts int
+}
+
+// ErrRendererCrash indicates the page's renderer (tab) crashed during a test
+// (not an Internet connectivity issue). TestSite returns it wrapped in a *RendererCrashError.
+var ErrRendererCrash = errors.New("ERR_RENDERER_CRASH")
+
+// RendererCrashError reports that the renderer crashed while testing a site
+type RendererCrashError struct {
+	// Site is the name of the site being tested
+	Site string
+
+	// Err is the error the navigation ended with
+	Err error
+}
+
+func (e *RendererCrashError) Error() string {
+	return fmt.Sprintf("renderer crashed (%v) testing %s: %v", ErrRendererCrash, e.Site, e.Err)
+}
+
+// Unwrap exposes both ErrRendererCrash and the underlying cause
+func (e *RendererCrashError) Unwrap() []error {
+	return []error{ErrRendererCrash, e.Err}
+}
+
+func (e *StartupError) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("%v for %s after %d attempts: %v", ErrChromeStartupFailure, e.Site, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%v for %s: %v", ErrChromeStartupFailure, e.Site, e.Err)
+}
+
+// Unwrap exposes both ErrChromeStartupFailure and the underlying cause
+func (e *StartupError) Unwrap() []error {
+	return []error{ErrChromeStartupFailure, e.Err}
+}
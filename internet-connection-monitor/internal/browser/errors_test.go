@@ -0,0 +1,54 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStartupError(t *testing.T) {
+	cause := errors.New("exec: \"google-chrome\": executable file not found in $PATH")
+	var err error = &StartupError{Site: "example", Err: cause}
+
+	// Callers can match the sentinel, the type, or the underlying cause
+	if !errors.Is(err, ErrChromeStartupFailure) {
+		t.Error("Expected StartupError to match ErrChromeStartupFailure")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected StartupError to match its cause")
+	}
+
+	wrapped := fmt.Errorf("test loop: %w", err)
+	var startupErr *StartupError
+	if !errors.As(wrapped, &startupErr) {
+		t.Fatal("Expected errors.As to find the StartupError")
+	}
+	if startupErr.Site != "example" {
+		t.Errorf("Expected site 'example', got %q", startupErr.Site)
+	}
+
+	for _, want := range []string{"chrome failed to start", "example", "executable file not found"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in error message %q", want, err.Error())
+		}
+	}
+}
+
+func TestRendererCrashError(t *testing.T) {
+	cause := errors.New("context canceled")
+	var err error = fmt.Errorf("test loop: %w", &RendererCrashError{Site: "example", Err: cause})
+
+	if !errors.Is(err, ErrRendererCrash) {
+		t.Error("Expected RendererCrashError to match ErrRendererCrash")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected RendererCrashError to match its cause")
+	}
+	if errors.Is(err, ErrChromeStartupFailure) {
+		t.Error("A renderer crash is not a startup failure")
+	}
+	if !strings.Contains(err.Error(), "ERR_RENDERER_CRASH") || !strings.Contains(err.Error(), "example") {
+		t.Errorf("Expected the error type and site in %q", err.Error())
+	}
+}
@@ -0,0 +1,315 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// HAR (HTTP Archive 1.2) capture
+//
+// When enabled, NetworkEventCapture keeps every request it sees, not just the
+// document, and the controller writes them out as a .har file that can be
+// opened in Chrome DevTools or any HAR viewer. Bodies are not captured.
+
+// harRecorder accumulates HAR entries from CDP network events.
+// It is owned by a NetworkEventCapture and guarded by its mutex.
+type harRecorder struct {
+	entries []*harRequest
+	byID    map[network.RequestID]*harRequest
+}
+
+// harRequest is one request/response exchange as observed over CDP
+type harRequest struct {
+	started     time.Time // wall clock, for startedDateTime
+	startMono   time.Time // monotonic, for durations
+	endMono     time.Time
+	request     *network.Request
+	response    *network.Response
+	bodySize    float64 // Encoded (transferred) body bytes
+	contentSize float64 // Decoded body bytes
+	errorText   string
+}
+
+func newHARRecorder() *harRecorder {
+	return &harRecorder{byID: make(map[network.RequestID]*harRequest)}
+}
+
+func (h *harRecorder) handleEvent(ev interface{}) {
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// Redirects reuse the request ID; close out the previous hop first
+		if prev, ok := h.byID[e.RequestID]; ok && e.RedirectResponse != nil {
+			prev.response = e.RedirectResponse
+			if e.Timestamp != nil {
+				prev.endMono = e.Timestamp.Time()
+			}
+		}
+		req := &harRequest{request: e.Request}
+		if e.WallTime != nil {
+			req.started = e.WallTime.Time()
+		}
+		if e.Timestamp != nil {
+			req.startMono = e.Timestamp.Time()
+		}
+		h.entries = append(h.entries, req)
+		h.byID[e.RequestID] = req
+	case *network.EventResponseReceived:
+		if req, ok := h.byID[e.RequestID]; ok {
+			req.response = e.Response
+		}
+	case *network.EventDataReceived:
+		if req, ok := h.byID[e.RequestID]; ok {
+			req.contentSize += float64(e.DataLength)
+		}
+	case *network.EventLoadingFinished:
+		if req, ok := h.byID[e.RequestID]; ok {
+			req.bodySize = e.EncodedDataLength
+			if e.Timestamp != nil {
+				req.endMono = e.Timestamp.Time()
+			}
+		}
+	case *network.EventLoadingFailed:
+		if req, ok := h.byID[e.RequestID]; ok {
+			req.errorText = e.ErrorText
+			if e.Timestamp != nil {
+				req.endMono = e.Timestamp.Time()
+			}
+		}
+	}
+}
+
+// HAR 1.2 document structure (only the fields we can fill from CDP events)
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string     `json:"startedDateTime"`
+	Time            float64    `json:"time"`
+	Request         harReq     `json:"request"`
+	Response        harResp    `json:"response"`
+	Cache           struct{}   `json:"cache"`
+	Timings         harTimings `json:"timings"`
+	ServerIPAddress string     `json:"serverIPAddress,omitempty"`
+	Comment         string     `json:"comment,omitempty"`
+}
+
+type harReq struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResp struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// build converts the recorded requests into a HAR document
+func (h *harRecorder) build(version string) *harFile {
+	file := &harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "internet-connection-monitor", Version: version},
+		Entries: make([]harEntry, 0, len(h.entries)),
+	}}
+	for _, req := range h.entries {
+		if req.request == nil {
+			continue
+		}
+		file.Log.Entries = append(file.Log.Entries, req.harEntry())
+	}
+	return file
+}
+
+func (r *harRequest) harEntry() harEntry {
+	entry := harEntry{
+		StartedDateTime: r.started.UTC().Format(time.RFC3339Nano),
+		Time:            -1,
+		Request: harReq{
+			Method:      r.request.Method,
+			URL:         r.request.URL,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(r.request.Headers),
+			QueryString: harQueryString(r.request.URL),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResp{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: 0, Receive: 0},
+		Comment: r.errorText,
+	}
+	if !r.startMono.IsZero() && !r.endMono.IsZero() {
+		entry.Time = float64(r.endMono.Sub(r.startMono).Microseconds()) / 1000
+	}
+
+	if resp := r.response; resp != nil {
+		entry.Request.HTTPVersion = resp.Protocol
+		entry.Response = harResp{
+			Status:      int(resp.Status),
+			StatusText:  resp.StatusText,
+			HTTPVersion: resp.Protocol,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(resp.Headers),
+			Content:     harContent{Size: int(r.contentSize), MimeType: resp.MimeType},
+			RedirectURL: harHeaderValue(resp.Headers, "location"),
+			HeadersSize: -1,
+			BodySize:    int(r.bodySize),
+		}
+		entry.ServerIPAddress = resp.RemoteIPAddress
+		if resp.Timing != nil {
+			entry.Timings = harTimingsFrom(resp.Timing, entry.Time)
+		}
+	}
+	return entry
+}
+
+// harTimingsFrom converts CDP resource timing (ms offsets from requestTime,
+// -1 when not applicable) into HAR phase durations
+func harTimingsFrom(t *network.ResourceTiming, total float64) harTimings {
+	span := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return end - start
+	}
+
+	timings := harTimings{
+		Blocked: -1,
+		DNS:     span(t.DNSStart, t.DNSEnd),
+		Connect: span(t.ConnectStart, t.ConnectEnd),
+		SSL:     span(t.SslStart, t.SslEnd),
+		Send:    span(t.SendStart, t.SendEnd),
+		Wait:    span(t.SendEnd, t.ReceiveHeadersEnd),
+	}
+	if timings.Send < 0 {
+		timings.Send = 0
+	}
+	if timings.Wait < 0 {
+		timings.Wait = 0
+	}
+	if total >= 0 && t.ReceiveHeadersEnd >= 0 && total > t.ReceiveHeadersEnd {
+		timings.Receive = total - t.ReceiveHeadersEnd
+	}
+	return timings
+}
+
+func harHeaders(headers network.Headers) []harNameValue {
+	out := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		v := fmt.Sprint(value)
+		// Credentials (e.g. an OAuth2 bearer token) never go to disk
+		if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "Proxy-Authorization") {
+			v = redactedText
+		}
+		out = append(out, harNameValue{Name: name, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func harHeaderValue(headers network.Headers, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func harQueryString(rawURL string) []harNameValue {
+	out := []harNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return out
+	}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeHAR writes the HAR document for a test into dir and returns its path
+func writeHAR(dir string, result *models.TestResult, har *harFile) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create HAR dir: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s_%s.har",
+		result.Timestamp.UTC().Format("20060102T150405Z"),
+		unsafeFileChars.ReplaceAllString(result.Site.Name, "_"),
+		result.TestID)
+	path := filepath.Join(dir, name)
+
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("write HAR: %w", err)
+	}
+	return path, nil
+}
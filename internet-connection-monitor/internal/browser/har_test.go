@@ -0,0 +1,325 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestNetworkEventCapture_HARRecordsAllRequests(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	// Events before RecordHAR are not kept
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "early",
+		Request:   &network.Request{URL: "https://example.com/early", Method: "GET"},
+	})
+	capture.RecordHAR()
+
+	start := time.Unix(1700000000, 0)
+	mono := func(offset time.Duration) *cdp.MonotonicTime {
+		t := cdp.MonotonicTime(start.Add(offset))
+		return &t
+	}
+	wall := cdp.TimeSinceEpoch(start)
+
+	// Document redirected once, then served
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "doc",
+		Request:   &network.Request{URL: "http://example.com/", Method: "GET"},
+		Timestamp: mono(0),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeDocument,
+	})
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "doc",
+		Request:   &network.Request{URL: "https://example.com/?a=1", Method: "GET"},
+		RedirectResponse: &network.Response{
+			Status:  301,
+			Headers: network.Headers{"Location": "https://example.com/?a=1"},
+		},
+		Timestamp: mono(10 * time.Millisecond),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeDocument,
+	})
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			Status:   200,
+			Protocol: "h2",
+			MimeType: "text/html",
+			Timing:   &network.ResourceTiming{DNSStart: 0, DNSEnd: 5, ConnectStart: 5, ConnectEnd: 20, SslStart: 10, SslEnd: 20, SendStart: 20, SendEnd: 21, ReceiveHeadersEnd: 40},
+		},
+	})
+	capture.handleEvent(&network.EventLoadingFinished{
+		RequestID:         "doc",
+		Timestamp:         mono(60 * time.Millisecond),
+		EncodedDataLength: 1234,
+	})
+
+	// A failed script
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "js",
+		Request:   &network.Request{URL: "https://cdn.example.com/app.js", Method: "GET"},
+		Timestamp: mono(70 * time.Millisecond),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeScript,
+	})
+	capture.handleEvent(&network.EventLoadingFailed{
+		RequestID: "js",
+		Type:      network.ResourceTypeScript,
+		ErrorText: "net::ERR_CONNECTION_RESET",
+		Timestamp: mono(80 * time.Millisecond),
+	})
+
+	har := capture.BuildHAR("test")
+	if har == nil {
+		t.Fatal("Expected a HAR document")
+	}
+	entries := har.Log.Entries
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries (redirect, document, script), got %d", len(entries))
+	}
+
+	if entries[0].Response.Status != 301 || entries[0].Response.RedirectURL != "https://example.com/?a=1" {
+		t.Errorf("Expected redirect hop first, got %+v", entries[0].Response)
+	}
+
+	doc := entries[1]
+	if doc.Response.Status != 200 || doc.Response.BodySize != 1234 {
+		t.Errorf("Unexpected document response %+v", doc.Response)
+	}
+	if doc.Time != 50 {
+		t.Errorf("Expected document time 50ms, got %v", doc.Time)
+	}
+	if doc.Timings.DNS != 5 || doc.Timings.SSL != 10 || doc.Timings.Wait != 19 || doc.Timings.Receive != 10 {
+		t.Errorf("Unexpected document timings %+v", doc.Timings)
+	}
+	if len(doc.Request.QueryString) != 1 || doc.Request.QueryString[0].Name != "a" {
+		t.Errorf("Expected query string to be parsed, got %+v", doc.Request.QueryString)
+	}
+
+	if entries[2].Comment != "net::ERR_CONNECTION_RESET" {
+		t.Errorf("Expected failed request to carry its error, got %q", entries[2].Comment)
+	}
+}
+
+func TestNetworkEventCapture_HARDisabled(t *testing.T) {
+	capture := newNetworkEventCapture()
+	if capture.BuildHAR("test") != nil {
+		t.Error("Expected no HAR when recording was not enabled")
+	}
+}
+
+func TestWriteHAR(t *testing.T) {
+	dir := t.TempDir()
+	result := &models.TestResult{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		TestID:    "abc",
+		Site:      models.SiteInfo{Name: "my site/1"},
+	}
+
+	path, err := writeHAR(dir, result, newHARRecorder().build("test"))
+	if err != nil {
+		t.Fatalf("writeHAR failed: %v", err)
+	}
+	if want := dir + "/20240102T030405Z_my_site_1_abc.har"; path != want {
+		t.Errorf("Expected path %s, got %s", want, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("HAR not written: %v", err)
+	}
+	var doc map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("HAR is not valid JSON: %v", err)
+	}
+	if doc["log"]["version"] != "1.2" {
+		t.Errorf("Expected HAR version 1.2, got %v", doc["log"]["version"])
+	}
+}
+
+// harRequiredFields lists the fields HAR 1.2 requires of each object, and the
+// JSON type of each
+var harRequiredFields = map[string]map[string]string{
+	"log":      {"version": "string", "creator": "object", "entries": "array"},
+	"creator":  {"name": "string", "version": "string"},
+	"entry":    {"startedDateTime": "string", "time": "number", "request": "object", "response": "object", "cache": "object", "timings": "object"},
+	"request":  {"method": "string", "url": "string", "httpVersion": "string", "cookies": "array", "headers": "array", "queryString": "array", "headersSize": "number", "bodySize": "number"},
+	"response": {"status": "number", "statusText": "string", "httpVersion": "string", "cookies": "array", "headers": "array", "content": "object", "redirectURL": "string", "headersSize": "number", "bodySize": "number"},
+	"content":  {"size": "number", "mimeType": "string"},
+	"timings":  {"send": "number", "wait": "number", "receive": "number"},
+}
+
+// requireHARFields checks obj has every field HAR 1.2 requires of a kind
+func requireHARFields(t *testing.T, kind, path string, obj map[string]interface{}) {
+	t.Helper()
+	for field, typ := range harRequiredFields[kind] {
+		value, ok := obj[field]
+		if !ok {
+			t.Errorf("%s: missing required field %q", path, field)
+			continue
+		}
+		var got string
+		switch value.(type) {
+		case string:
+			got = "string"
+		case float64:
+			got = "number"
+		case map[string]interface{}:
+			got = "object"
+		case []interface{}:
+			got = "array"
+		}
+		if got != typ {
+			t.Errorf("%s.%s: expected a %s, got %T", path, field, typ, value)
+		}
+	}
+}
+
+func TestHARMatchesSchema(t *testing.T) {
+	capture := newNetworkEventCapture()
+	capture.RecordHAR()
+
+	start := time.Unix(1700000000, 0)
+	mono := func(offset time.Duration) *cdp.MonotonicTime {
+		t := cdp.MonotonicTime(start.Add(offset))
+		return &t
+	}
+	wall := cdp.TimeSinceEpoch(start)
+
+	// A served document, an image, and a request that never got a response
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "doc",
+		Request:   &network.Request{URL: "https://example.com/", Method: "GET", Headers: network.Headers{"Accept": "text/html"}},
+		Timestamp: mono(0),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeDocument,
+	})
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			Status:     200,
+			StatusText: "OK",
+			Protocol:   "h2",
+			MimeType:   "text/html",
+			Headers:    network.Headers{"Content-Type": "text/html"},
+			Timing:     &network.ResourceTiming{DNSStart: 0, DNSEnd: 5, ConnectStart: 5, ConnectEnd: 20, SslStart: 10, SslEnd: 20, SendStart: 20, SendEnd: 21, ReceiveHeadersEnd: 40},
+		},
+	})
+	capture.handleEvent(&network.EventDataReceived{RequestID: "doc", DataLength: 3000, EncodedDataLength: 900})
+	capture.handleEvent(&network.EventDataReceived{RequestID: "doc", DataLength: 1000, EncodedDataLength: 300})
+	capture.handleEvent(&network.EventLoadingFinished{RequestID: "doc", Timestamp: mono(60 * time.Millisecond), EncodedDataLength: 1200})
+
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "img",
+		Request:   &network.Request{URL: "https://cdn.example.com/logo.png?v=2", Method: "GET"},
+		Timestamp: mono(65 * time.Millisecond),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeImage,
+	})
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "img",
+		Type:      network.ResourceTypeImage,
+		Response:  &network.Response{Status: 404, StatusText: "Not Found", Protocol: "http/1.1", MimeType: "text/plain"},
+	})
+	capture.handleEvent(&network.EventLoadingFinished{RequestID: "img", Timestamp: mono(90 * time.Millisecond), EncodedDataLength: 120})
+
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "api",
+		Request:   &network.Request{URL: "https://api.example.com/data", Method: "POST"},
+		Timestamp: mono(95 * time.Millisecond),
+		WallTime:  &wall,
+		Type:      network.ResourceTypeFetch,
+	})
+	capture.handleEvent(&network.EventLoadingFailed{RequestID: "api", ErrorText: "net::ERR_NAME_NOT_RESOLVED", Timestamp: mono(99 * time.Millisecond)})
+
+	result := &models.TestResult{Timestamp: start, TestID: "abc", Site: models.SiteInfo{Name: "example"}}
+	path, err := writeHAR(t.TempDir(), result, capture.BuildHAR("test"))
+	if err != nil {
+		t.Fatalf("writeHAR failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("HAR not written: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("HAR is not valid JSON: %v", err)
+	}
+
+	log, ok := doc["log"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a log object, got %v", doc["log"])
+	}
+	requireHARFields(t, "log", "log", log)
+	if log["version"] != "1.2" {
+		t.Errorf("Expected HAR version 1.2, got %v", log["version"])
+	}
+	if creator, ok := log["creator"].(map[string]interface{}); ok {
+		requireHARFields(t, "creator", "log.creator", creator)
+	}
+
+	entries, _ := log["entries"].([]interface{})
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		path := fmt.Sprintf("log.entries[%d]", i)
+		entry := e.(map[string]interface{})
+		requireHARFields(t, "entry", path, entry)
+		if started, _ := entry["startedDateTime"].(string); started != "" {
+			if _, err := time.Parse(time.RFC3339Nano, started); err != nil {
+				t.Errorf("%s.startedDateTime is not ISO 8601: %v", path, err)
+			}
+		}
+		if d, _ := entry["time"].(float64); d < 0 {
+			t.Errorf("%s.time: expected an elapsed time, got %v", path, d)
+		}
+		if req, ok := entry["request"].(map[string]interface{}); ok {
+			requireHARFields(t, "request", path+".request", req)
+		}
+		if resp, ok := entry["response"].(map[string]interface{}); ok {
+			requireHARFields(t, "response", path+".response", resp)
+			if content, ok := resp["content"].(map[string]interface{}); ok {
+				requireHARFields(t, "content", path+".response.content", content)
+			}
+		}
+		if timings, ok := entry["timings"].(map[string]interface{}); ok {
+			requireHARFields(t, "timings", path+".timings", timings)
+			for _, phase := range []string{"send", "wait", "receive"} {
+				if v, _ := timings[phase].(float64); v < 0 {
+					t.Errorf("%s.timings.%s: required phases can't be -1, got %v", path, phase, v)
+				}
+			}
+		}
+	}
+
+	// Status codes, timings and sizes per entry
+	doc0 := entries[0].(map[string]interface{})
+	resp := doc0["response"].(map[string]interface{})
+	if resp["status"] != 200.0 || resp["bodySize"] != 1200.0 {
+		t.Errorf("Expected the document's 200 and 1200 transferred bytes, got %v and %v", resp["status"], resp["bodySize"])
+	}
+	if size := resp["content"].(map[string]interface{})["size"]; size != 4000.0 {
+		t.Errorf("Expected 4000 decoded bytes, got %v", size)
+	}
+	if doc0["time"] != 60.0 || doc0["timings"].(map[string]interface{})["dns"] != 5.0 {
+		t.Errorf("Expected the document's 60ms and 5ms DNS, got %v and %v", doc0["time"], doc0["timings"])
+	}
+	if status := entries[1].(map[string]interface{})["response"].(map[string]interface{})["status"]; status != 404.0 {
+		t.Errorf("Expected the image's 404, got %v", status)
+	}
+	if comment := entries[2].(map[string]interface{})["comment"]; comment != "net::ERR_NAME_NOT_RESOLVED" {
+		t.Errorf("Expected the failed request's error, got %v", comment)
+	}
+}
@@ -0,0 +1,105 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// runHealthCheck evaluates a site's HealthCheckJS expression in the loaded
+// page and returns the failure if the result is falsy or the script throws,
+// nil if it passes. A promise is awaited, so async checks (e.g. a fetch of a
+// status API) work. The script gets whatever is left of the site timeout in
+// ctx, and V8 terminates it once that runs out, so a runaway loop can't
+// hold the test past its timeout.
+func runHealthCheck(ctx context.Context, script string) *models.ErrorInfo {
+	params := runtime.Evaluate(script).WithAwaitPromise(true)
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining > 0 {
+			params = params.WithTimeout(runtime.TimeDelta(remaining.Milliseconds()))
+		}
+	}
+
+	var res *runtime.RemoteObject
+	var exc *runtime.ExceptionDetails
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		res, exc, err = params.Do(ctx)
+		return err
+	}))
+	return healthCheckError(res, exc, err)
+}
+
+// healthCheckError judges a health check's outcome: the value it returned,
+// the exception it threw, or the error that kept it from running
+func healthCheckError(res *runtime.RemoteObject, exc *runtime.ExceptionDetails, err error) *models.ErrorInfo {
+	var msg string
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		msg = "health check timed out"
+	case err != nil:
+		msg = fmt.Sprintf("health check could not run: %v", err)
+	case exc != nil:
+		msg = "health check threw: " + exceptionMessage(exc)
+	case !truthy(res):
+		msg = "health check returned " + describeRemoteObject(res)
+	default:
+		return nil
+	}
+	return &models.ErrorInfo{
+		ErrorType:    "HEALTHCHECK_FAILED",
+		ErrorMessage: msg,
+		FailurePhase: "http",
+	}
+}
+
+// truthy applies JavaScript's truthiness rules to an evaluation result
+func truthy(obj *runtime.RemoteObject) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type {
+	case runtime.TypeUndefined:
+		return false
+	case runtime.TypeBoolean:
+		return string(obj.Value) == "true"
+	case runtime.TypeNumber:
+		if obj.UnserializableValue != "" {
+			// NaN, -0, Infinity or -Infinity
+			return obj.UnserializableValue != "NaN" && obj.UnserializableValue != "-0"
+		}
+		n, err := strconv.ParseFloat(string(obj.Value), 64)
+		return err == nil && n != 0
+	case runtime.TypeString:
+		return string(obj.Value) != `""`
+	case runtime.TypeBigint:
+		return obj.UnserializableValue != "0n"
+	case runtime.TypeObject:
+		return obj.Subtype != runtime.SubtypeNull
+	}
+	return true // functions and symbols
+}
+
+// describeRemoteObject renders an evaluation result for an error message
+func describeRemoteObject(obj *runtime.RemoteObject) string {
+	switch {
+	case obj == nil:
+		return "nothing"
+	case obj.Subtype == runtime.SubtypeNull:
+		return "null"
+	case obj.UnserializableValue != "":
+		return string(obj.UnserializableValue)
+	case len(obj.Value) > 0:
+		return truncateJSError(string(obj.Value))
+	case obj.Description != "":
+		return truncateJSError(obj.Description)
+	}
+	return string(obj.Type)
+}
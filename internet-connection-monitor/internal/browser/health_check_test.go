@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func TestHealthCheckError(t *testing.T) {
+	value := func(typ runtime.Type, v string) *runtime.RemoteObject {
+		return &runtime.RemoteObject{Type: typ, Value: []byte(v)}
+	}
+	tests := []struct {
+		name    string
+		res     *runtime.RemoteObject
+		exc     *runtime.ExceptionDetails
+		err     error
+		wantMsg string // "" for a pass
+	}{
+		{name: "true", res: value(runtime.TypeBoolean, "true")},
+		{name: "element found", res: &runtime.RemoteObject{Type: runtime.TypeObject, Subtype: runtime.SubtypeNode, Description: "input#search"}},
+		{name: "non-zero number", res: value(runtime.TypeNumber, "3")},
+		{name: "non-empty string", res: value(runtime.TypeString, `"ok"`)},
+		{name: "infinity", res: &runtime.RemoteObject{Type: runtime.TypeNumber, UnserializableValue: "Infinity"}},
+
+		{name: "false", res: value(runtime.TypeBoolean, "false"), wantMsg: "health check returned false"},
+		{name: "null", res: &runtime.RemoteObject{Type: runtime.TypeObject, Subtype: runtime.SubtypeNull, Value: []byte("null")}, wantMsg: "health check returned null"},
+		{name: "undefined", res: &runtime.RemoteObject{Type: runtime.TypeUndefined}, wantMsg: "health check returned undefined"},
+		{name: "zero", res: value(runtime.TypeNumber, "0"), wantMsg: "health check returned 0"},
+		{name: "NaN", res: &runtime.RemoteObject{Type: runtime.TypeNumber, UnserializableValue: "NaN"}, wantMsg: "health check returned NaN"},
+		{name: "empty string", res: value(runtime.TypeString, `""`), wantMsg: `health check returned ""`},
+		{name: "zero bigint", res: &runtime.RemoteObject{Type: runtime.TypeBigint, UnserializableValue: "0n"}, wantMsg: "health check returned 0n"},
+
+		{
+			name: "throws",
+			res:  &runtime.RemoteObject{Type: runtime.TypeObject, Subtype: runtime.SubtypeError},
+			exc: &runtime.ExceptionDetails{
+				Text:      "Uncaught",
+				Exception: &runtime.RemoteObject{Type: runtime.TypeObject, Subtype: runtime.SubtypeError, Description: "Error: widget missing\n    at <anonymous>:1:7"},
+			},
+			wantMsg: "health check threw: Error: widget missing",
+		},
+		{name: "timed out", err: context.DeadlineExceeded, wantMsg: "health check timed out"},
+		{name: "could not run", err: errors.New("target closed"), wantMsg: "health check could not run: target closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errInfo := healthCheckError(tt.res, tt.exc, tt.err)
+			if tt.wantMsg == "" {
+				if errInfo != nil {
+					t.Fatalf("Expected the check to pass, got %+v", errInfo)
+				}
+				return
+			}
+			if errInfo == nil {
+				t.Fatalf("Expected the check to fail with %q", tt.wantMsg)
+			}
+			if errInfo.ErrorType != "HEALTHCHECK_FAILED" || errInfo.FailurePhase != "http" {
+				t.Errorf("Expected HEALTHCHECK_FAILED in phase http, got %s in %s", errInfo.ErrorType, errInfo.FailurePhase)
+			}
+			if !strings.HasPrefix(errInfo.ErrorMessage, tt.wantMsg) {
+				t.Errorf("Expected message %q, got %q", tt.wantMsg, errInfo.ErrorMessage)
+			}
+		})
+	}
+}
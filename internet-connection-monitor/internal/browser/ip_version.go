@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// lookupIPFunc resolves host to its addresses for network "ip", "ip4" or "ip6"
+type lookupIPFunc func(ctx context.Context, network, host string) ([]net.IP, error)
+
+// ipVersionRule pins a site to the address family its IPVersion asks for. It
+// resolves the site's host and returns the --host-resolver-rules value that
+// maps the host to its first address in that family, or "" when the site
+// doesn't pin one or its URL already holds an address of that family.
+// Chrome can't be told to prefer a family, so only the site's own host is
+// pinned; subresources from other hosts still use either.
+// A failure is returned as the error to report: ERR_INVALID_IP_VERSION for a
+// bad setting, or a DNS-phase ERR_NAME_NOT_RESOLVED or
+// ERR_ADDRESS_FAMILY_UNAVAILABLE when the host has no address at all or none
+// in the family.
+func (c *ControllerImpl) ipVersionRule(ctx context.Context, site models.SiteDefinition) (string, *models.ErrorInfo) {
+	if site.IPVersion == "" {
+		return "", nil
+	}
+	invalid := func(err error) (string, *models.ErrorInfo) {
+		return "", &models.ErrorInfo{
+			ErrorType:    "ERR_INVALID_IP_VERSION",
+			ErrorMessage: err.Error(),
+			FailurePhase: "unknown",
+		}
+	}
+	if site.IPVersion != "4" && site.IPVersion != "6" {
+		return invalid(fmt.Errorf("ip_version must be \"4\" or \"6\", got %q", site.IPVersion))
+	}
+	// A proxy or binding proxy resolves the host itself, out of Chrome's reach
+	if site.Interface != "" || site.SourceIP != "" || site.ProxyURL != "" || c.proxy != nil {
+		return invalid(fmt.Errorf("ip_version can't be combined with a proxy, interface or source_ip"))
+	}
+	u, err := url.Parse(site.URL)
+	if err != nil || u.Hostname() == "" {
+		return invalid(fmt.Errorf("cannot determine the host of %q", site.URL))
+	}
+	host := u.Hostname()
+
+	unavailable := func() (string, *models.ErrorInfo) {
+		return "", &models.ErrorInfo{
+			ErrorType:    "ERR_ADDRESS_FAMILY_UNAVAILABLE",
+			ErrorMessage: fmt.Sprintf("%s has no IPv%s address", host, site.IPVersion),
+			FailurePhase: "dns",
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ipFamily(ip) != site.IPVersion {
+			return unavailable()
+		}
+		return "", nil
+	}
+
+	// Look up both families, so a host with no addresses at all is told
+	// apart from one without the family
+	lookup := c.lookupIP
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupIP
+	}
+	ctx, cancel := context.WithTimeout(ctx, site.GetTimeout())
+	defer cancel()
+	ips, err := lookup(ctx, "ip", host)
+	if err != nil {
+		return "", &models.ErrorInfo{
+			ErrorType:    "ERR_NAME_NOT_RESOLVED",
+			ErrorMessage: err.Error(),
+			FailurePhase: "dns",
+		}
+	}
+	for _, ip := range ips {
+		if ipFamily(ip) == site.IPVersion {
+			return hostResolverRule(host, ip), nil
+		}
+	}
+	return unavailable()
+}
+
+// ipFamily returns "4" or "6" for ip's address family
+func ipFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return "4"
+	}
+	return "6"
+}
+
+// hostResolverRule returns the --host-resolver-rules value mapping host to
+// ip; IPv6 addresses are bracketed, as Chrome reads the target as host[:port]
+func hostResolverRule(host string, ip net.IP) string {
+	target := ip.String()
+	if strings.Contains(target, ":") {
+		target = "[" + target + "]"
+	}
+	return "MAP " + host + " " + target
+}
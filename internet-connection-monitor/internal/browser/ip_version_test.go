@@ -0,0 +1,109 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestIPVersionRule(t *testing.T) {
+	addrs := map[string][]net.IP{
+		"example.com":    {net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+		"v4only.example": {net.ParseIP("192.0.2.2")},
+	}
+	c := &ControllerImpl{
+		lookupIP: func(_ context.Context, network, host string) ([]net.IP, error) {
+			if network != "ip" {
+				t.Errorf("Expected both families looked up, got %q", network)
+			}
+			if ips, ok := addrs[host]; ok {
+				return ips, nil
+			}
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		},
+	}
+
+	tests := []struct {
+		name      string
+		url       string
+		version   string
+		rule      string
+		errorType string
+	}{
+		{name: "unpinned", url: "https://example.com", version: ""},
+		{name: "IPv4", url: "https://example.com/path", version: "4", rule: "MAP example.com 192.0.2.1"},
+		{name: "IPv6", url: "https://example.com:8443", version: "6", rule: "MAP example.com [2001:db8::1]"},
+		{name: "no IPv6 address", url: "https://v4only.example", version: "6", errorType: "ERR_ADDRESS_FAMILY_UNAVAILABLE"},
+		{name: "no address at all", url: "https://missing.example", version: "4", errorType: "ERR_NAME_NOT_RESOLVED"},
+		{name: "IPv6 literal", url: "https://[2001:db8::2]/", version: "6"},
+		{name: "IPv4 literal pinned to IPv6", url: "https://192.0.2.3/", version: "6", errorType: "ERR_ADDRESS_FAMILY_UNAVAILABLE"},
+		{name: "bad version", url: "https://example.com", version: "ipv6", errorType: "ERR_INVALID_IP_VERSION"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, errInfo := c.ipVersionRule(context.Background(), models.SiteDefinition{URL: tt.url, IPVersion: tt.version})
+			if tt.errorType != "" {
+				if errInfo == nil || errInfo.ErrorType != tt.errorType {
+					t.Fatalf("Expected %s, got %+v", tt.errorType, errInfo)
+				}
+				return
+			}
+			if errInfo != nil {
+				t.Fatalf("Expected no error, got %+v", errInfo)
+			}
+			if rule != tt.rule {
+				t.Errorf("Expected rule %q, got %q", tt.rule, rule)
+			}
+		})
+	}
+}
+
+func TestIPVersionRuleRejectsProxies(t *testing.T) {
+	lookup := func(context.Context, string, string) ([]net.IP, error) {
+		return nil, errors.New("unexpected lookup")
+	}
+	sites := []models.SiteDefinition{
+		{URL: "https://example.com", IPVersion: "6", SourceIP: "2001:db8::10"},
+		{URL: "https://example.com", IPVersion: "6", ProxyURL: "http://proxy.example.com:3128"},
+	}
+	for _, site := range sites {
+		_, errInfo := (&ControllerImpl{lookupIP: lookup}).ipVersionRule(context.Background(), site)
+		if errInfo == nil || errInfo.ErrorType != "ERR_INVALID_IP_VERSION" {
+			t.Errorf("Expected ERR_INVALID_IP_VERSION for %+v, got %+v", site, errInfo)
+		}
+	}
+
+	c := &ControllerImpl{lookupIP: lookup, proxy: &proxySettings{server: "http://proxy.example.com:3128"}}
+	_, errInfo := c.ipVersionRule(context.Background(), models.SiteDefinition{URL: "https://example.com", IPVersion: "4"})
+	if errInfo == nil || errInfo.ErrorType != "ERR_INVALID_IP_VERSION" {
+		t.Errorf("Expected ERR_INVALID_IP_VERSION behind the browser's proxy, got %+v", errInfo)
+	}
+}
+
+func TestAllocatorOptions_HostResolverRules(t *testing.T) {
+	cfg := config.DefaultConfig().Browser
+	c, err := NewControllerImpl(&cfg)
+	if err != nil {
+		t.Fatalf("NewControllerImpl failed: %v", err)
+	}
+	site := models.SiteDefinition{URL: "https://example.com", IPVersion: "6"}
+
+	for _, rule := range []string{"MAP example.com 192.0.2.1", "MAP example.com [2001:db8::1]"} {
+		args := chromeArgs(t, c.allocatorOptions(site, "", rule))
+		if !slices.Contains(args, "--host-resolver-rules="+rule) {
+			t.Errorf("Expected the resolver rule %q, got %v", rule, args)
+		}
+	}
+	for _, arg := range chromeArgs(t, c.allocatorOptions(models.SiteDefinition{URL: "https://example.com"}, "", "")) {
+		if strings.HasPrefix(arg, "--host-resolver-rules=") {
+			t.Errorf("Expected no resolver rules for an unpinned site, got %s", arg)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package browser
+
+import (
+	"encoding/json"
+	"strings"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+const (
+	// maxJSErrors caps how many JS error messages are kept per test
+	maxJSErrors = 10
+
+	// maxConsoleErrors caps how many browser console errors are kept per test
+	maxConsoleErrors = 10
+
+	// maxJSErrorLength truncates each kept message; stack-laden errors and
+	// console.error dumps of whole objects can run to kilobytes
+	maxJSErrorLength = 300
+)
+
+// exceptionMessage describes an uncaught exception the way DevTools does:
+// the exception's description (e.g. "TypeError: x is undefined\n    at ...")
+// when available, otherwise the bare "Uncaught" text, plus where it was thrown
+func exceptionMessage(d *runtime.ExceptionDetails) string {
+	if d == nil {
+		return ""
+	}
+	msg := d.Text
+	if d.Exception != nil && d.Exception.Description != "" {
+		// The first line is the error itself; the rest is the stack
+		msg = strings.SplitN(d.Exception.Description, "\n", 2)[0]
+	}
+	if d.URL != "" {
+		msg += " (" + d.URL + ")"
+	}
+	return truncateJSError(msg)
+}
+
+// consoleMessage joins console.error arguments with spaces, as the console
+// prints them
+func consoleMessage(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		var s string
+		switch {
+		case arg.Type == runtime.TypeString && json.Unmarshal(arg.Value, &s) == nil:
+		case arg.Description != "":
+			s = arg.Description
+		case len(arg.Value) > 0:
+			s = string(arg.Value)
+		case arg.UnserializableValue != "":
+			s = string(arg.UnserializableValue)
+		default:
+			s = string(arg.Type)
+		}
+		parts = append(parts, s)
+	}
+	return truncateJSError("console.error: " + strings.Join(parts, " "))
+}
+
+// logEntryMessage describes an error Chrome itself logged to the console (a
+// failed resource load, a CSP or mixed content block...), prefixed with its
+// source like DevTools groups them
+func logEntryMessage(e *cdplog.Entry) string {
+	msg := string(e.Source) + ": " + e.Text
+	if e.URL != "" && !strings.Contains(e.Text, e.URL) {
+		msg += " (" + e.URL + ")"
+	}
+	return truncateJSError(msg)
+}
+
+func truncateJSError(msg string) string {
+	if len(msg) <= maxJSErrorLength {
+		return msg
+	}
+	// Back up to a rune boundary so the result stays valid UTF-8
+	cut := maxJSErrorLength
+	for cut > 0 && msg[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return msg[:cut] + "…"
+}
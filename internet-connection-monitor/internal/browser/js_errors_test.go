@@ -0,0 +1,74 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func TestExceptionMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		details *runtime.ExceptionDetails
+		want    string
+	}{
+		{
+			name:    "nil details",
+			details: nil,
+			want:    "",
+		},
+		{
+			name:    "thrown primitive has no description",
+			details: &runtime.ExceptionDetails{Text: "Uncaught boom"},
+			want:    "Uncaught boom",
+		},
+		{
+			name: "stack is dropped",
+			details: &runtime.ExceptionDetails{
+				Text:      "Uncaught",
+				Exception: &runtime.RemoteObject{Description: "ReferenceError: foo is not defined\n    at <anonymous>:1:1"},
+			},
+			want: "ReferenceError: foo is not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exceptionMessage(tt.details); got != tt.want {
+				t.Errorf("exceptionMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsoleMessage(t *testing.T) {
+	args := []*runtime.RemoteObject{
+		{Type: runtime.TypeString, Value: []byte(`"request failed:"`)},
+		{Type: runtime.TypeNumber, Value: []byte(`503`), Description: "503"},
+		{Type: runtime.TypeObject, ClassName: "Error", Description: "Error: timeout"},
+		{Type: runtime.TypeNumber, UnserializableValue: "NaN"},
+	}
+	want := "console.error: request failed: 503 Error: timeout NaN"
+	if got := consoleMessage(args); got != want {
+		t.Errorf("consoleMessage = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateJSError(t *testing.T) {
+	short := "TypeError: x"
+	if got := truncateJSError(short); got != short {
+		t.Errorf("short message changed to %q", got)
+	}
+
+	// A multi-byte rune straddling the cut must not be split
+	long := strings.Repeat("a", maxJSErrorLength-1) + "é" + strings.Repeat("b", 50)
+	got := truncateJSError(long)
+	if !utf8.ValidString(got) {
+		t.Errorf("truncated message is not valid UTF-8: %q", got)
+	}
+	if !strings.HasSuffix(got, "…") || len(got) > maxJSErrorLength+len("…") {
+		t.Errorf("unexpected truncation %q (%d bytes)", got, len(got))
+	}
+}
@@ -0,0 +1,67 @@
+package browser
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// siteLocale returns the locale a site is emulated in: the first language of
+// its AcceptLanguage (e.g. "de-DE" for "de-DE,de;q=0.9,en;q=0.5"), or "" when
+// the site has none. The tag is checked for BCP 47 shape only; Chrome decides
+// whether it knows the language.
+func siteLocale(acceptLanguage string) (string, error) {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return "", nil
+	}
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	tag = strings.TrimSpace(tag)
+
+	subtags := strings.Split(tag, "-")
+	for i, sub := range subtags {
+		if !isLanguageSubtag(sub, i == 0) {
+			return "", fmt.Errorf("invalid language tag %q in accept_language %q", tag, acceptLanguage)
+		}
+	}
+	return tag, nil
+}
+
+// isLanguageSubtag reports whether s can be a subtag of a language tag; the
+// primary subtag must be letters only
+func isLanguageSubtag(s string, primary bool) bool {
+	if len(s) == 0 || len(s) > 8 {
+		return false
+	}
+	for _, r := range s {
+		letter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		digit := r >= '0' && r <= '9'
+		if !letter && (primary || !digit) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestHeaders returns the headers added to every request the site's page
+// makes: its custom headers, with Accept-Language set from AcceptLanguage when
+// given (it takes precedence over a custom Accept-Language). Nil means none.
+func requestHeaders(site models.SiteDefinition) network.Headers {
+	if len(site.CustomHeaders) == 0 && site.AcceptLanguage == "" {
+		return nil
+	}
+	headers := make(network.Headers, len(site.CustomHeaders)+1)
+	for name, value := range site.CustomHeaders {
+		if site.AcceptLanguage != "" && http.CanonicalHeaderKey(name) == "Accept-Language" {
+			continue
+		}
+		headers[name] = value
+	}
+	if site.AcceptLanguage != "" {
+		headers["Accept-Language"] = site.AcceptLanguage
+	}
+	return headers
+}
@@ -0,0 +1,65 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSiteLocale(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+		wantErr        bool
+	}{
+		{acceptLanguage: "", want: ""},
+		{acceptLanguage: "fr", want: "fr"},
+		{acceptLanguage: "de-DE,de;q=0.9,en;q=0.5", want: "de-DE"},
+		{acceptLanguage: " ja-JP ;q=1", want: "ja-JP"},
+		{acceptLanguage: "es-419", want: "es-419"},
+		{acceptLanguage: "zh-Hant-TW", want: "zh-Hant-TW"},
+		{acceptLanguage: "*", wantErr: true},
+		{acceptLanguage: "en_US", wantErr: true},
+		{acceptLanguage: "1a-US", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := siteLocale(tt.acceptLanguage)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("siteLocale(%q) error = %v, wantErr %v", tt.acceptLanguage, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("siteLocale(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	if h := requestHeaders(models.SiteDefinition{}); h != nil {
+		t.Errorf("Expected no headers by default, got %v", h)
+	}
+
+	h := requestHeaders(models.SiteDefinition{
+		CustomHeaders: map[string]string{
+			"X-Probe":         "monitor",
+			"accept-language": "en",
+		},
+		AcceptLanguage: "de-DE,de;q=0.9",
+	})
+	if len(h) != 2 || h["X-Probe"] != "monitor" || h["Accept-Language"] != "de-DE,de;q=0.9" {
+		t.Errorf("Expected custom headers with AcceptLanguage taking precedence, got %v", h)
+	}
+
+	h = requestHeaders(models.SiteDefinition{CustomHeaders: map[string]string{"Accept-Language": "en"}})
+	if h["Accept-Language"] != "en" {
+		t.Errorf("Expected a custom Accept-Language to be kept without AcceptLanguage, got %v", h)
+	}
+}
+
+func TestSiteFlags_Locale(t *testing.T) {
+	flags := siteFlags(models.SiteDefinition{URL: "https://example.com", AcceptLanguage: "pt-BR,pt;q=0.8"})
+	if flags["lang"] != "pt-BR" {
+		t.Errorf("Expected lang flag pt-BR, got %v", flags["lang"])
+	}
+}
@@ -2,53 +2,419 @@ package browser
 
 import (
 	"context"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/chromedp/cdproto/inspector"
+	cdplog "github.com/chromedp/cdproto/log"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
 )
 
+// maxFailedSubresourceURLs caps how many failed subresource URLs are kept per test
+const maxFailedSubresourceURLs = 10
+
 // NetworkEventCapture stores network events for the main document request
 type NetworkEventCapture struct {
-	errorText   string                  // Raw Chrome error (e.g., "net::ERR_NAME_NOT_RESOLVED")
-	timing      *network.ResourceTiming // Partial timing data if available
-	hasResponse bool                    // Did we get a response event?
+	mu          sync.Mutex
+	errorText   string                   // Raw Chrome error (e.g., "net::ERR_NAME_NOT_RESOLVED")
+	timing      *network.ResourceTiming  // Partial timing data if available
+	hasResponse bool                     // Did we get a response event?
+	protocol    string                   // Negotiated protocol (e.g. "http/1.1", "h2", "h3")
+	status      int                      // HTTP status of the first (main frame) document response
+	responded   chan struct{}            // Closed once the main document has responded or failed
+	crashed     chan struct{}            // Closed if the page's renderer crashes
+	headers     network.Headers          // Response headers of the main document
+	receivedAt  time.Time                // Local time the main document's response arrived
+	remoteIP    string                   // Peer address Chrome connected to for the main document
+	remotePort  int                      // Peer port for the main document
+	security    *network.SecurityDetails // TLS details of the main document; nil over plain HTTP
+
+	// Main document request and its redirect hops (which reuse the request ID)
+	documentID     network.RequestID // Request ID of the first document request
+	documentStart  time.Time         // When the first hop was sent (browser monotonic clock)
+	lastRedirectAt time.Time         // When the last redirect was followed (zero if none)
+	finalURL       string            // URL of the last hop
+	redirects      []models.RedirectHop
+
+	requestURLs            map[network.RequestID]string // URL of every request seen, for failure reporting
+	failedSubresourceCount int                          // Failed non-document requests
+	failedSubresources     []string                     // First few failed non-document URLs
+	blockedRequestCount    int                          // Requests blocked by IgnoreResourceDomains
+	totalRequests          int                          // Requests sent over the network, redirect hops included
+	totalBytes             int64                        // Encoded bytes received for finished requests
+
+	// Uncaught exceptions and console.error calls in the page
+	jsErrorCount int
+	jsErrors     []string // First few messages, each truncated
+
+	// Errors Chrome logged to the console itself, rather than the page's scripts
+	consoleErrorCount int
+	consoleErrors     []string // First few messages, each truncated
+
+	har *harRecorder // Every request and response, when HAR capture is enabled
+
+	detached bool // Set by Detach; only renderer crashes are still tracked
 }
 
 // SetupNetworkListener configures event listeners to capture network data
 // Call this before navigation begins
 func SetupNetworkListener(ctx context.Context) *NetworkEventCapture {
-	capture := &NetworkEventCapture{}
-
-	chromedp.ListenTarget(ctx, func(ev interface{}) {
-		switch e := ev.(type) {
-		case *network.EventLoadingFailed:
-			// Only capture main document request (not images, CSS, etc.)
-			if e.Type == network.ResourceTypeDocument {
-				capture.errorText = e.ErrorText
+	capture := newNetworkEventCapture()
+	chromedp.ListenTarget(ctx, capture.handleEvent)
+	return capture
+}
+
+func newNetworkEventCapture() *NetworkEventCapture {
+	return &NetworkEventCapture{
+		requestURLs: make(map[network.RequestID]string),
+		responded:   make(chan struct{}),
+		crashed:     make(chan struct{}),
+	}
+}
+
+// handleEvent records a single CDP event. Events arrive on chromedp's goroutine.
+func (n *NetworkEventCapture) handleEvent(ev interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := ev.(*inspector.EventTargetCrashed); ok {
+		select {
+		case <-n.crashed:
+		default:
+			close(n.crashed)
+		}
+		return
+	}
+	if n.detached {
+		return
+	}
+
+	if n.har != nil {
+		n.har.handleEvent(ev)
+	}
+
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if e.Request != nil {
+			n.requestURLs[e.RequestID] = e.Request.URL
+			// data: URLs are decoded in the renderer, not fetched
+			if !strings.HasPrefix(e.Request.URL, "data:") {
+				n.totalRequests++
+			}
+		}
+		if e.Type == network.ResourceTypeDocument && e.Request != nil {
+			n.trackDocumentHop(e)
+		}
+	case *network.EventLoadingFailed:
+		// Requests we blocked on purpose are neither errors nor failed subresources
+		if e.BlockedReason == network.BlockedReasonInspector && e.Type != network.ResourceTypeDocument {
+			n.blockedRequestCount++
+			// It was counted when sent, but never left the browser
+			if _, ok := n.requestURLs[e.RequestID]; ok {
+				n.totalRequests--
 			}
-		case *network.EventResponseReceived:
-			// Capture timing data from response
-			if e.Type == network.ResourceTypeDocument {
-				capture.timing = e.Response.Timing
-				capture.hasResponse = true
+			return
+		}
+		// Only the main document request determines the error
+		if e.Type == network.ResourceTypeDocument {
+			n.errorText = e.ErrorText
+			n.markResponded()
+			return
+		}
+		// Subresources (scripts, API calls, fonts...) can fail while the HTML loads
+		if e.Canceled {
+			return
+		}
+		n.failedSubresourceCount++
+		if len(n.failedSubresources) < maxFailedSubresourceURLs {
+			if u, ok := n.requestURLs[e.RequestID]; ok {
+				n.failedSubresources = append(n.failedSubresources, u)
+			}
+		}
+	case *network.EventLoadingFinished:
+		n.totalBytes += int64(e.EncodedDataLength)
+	case *network.EventResponseReceived:
+		// Capture timing data from response
+		if e.Type == network.ResourceTypeDocument {
+			n.timing = e.Response.Timing
+			n.hasResponse = true
+			n.protocol = e.Response.Protocol
+			if n.status == 0 {
+				n.status = int(e.Response.Status)
+				n.headers = e.Response.Headers
+				n.receivedAt = time.Now()
+				n.remoteIP = e.Response.RemoteIPAddress
+				n.remotePort = int(e.Response.RemotePort)
+				n.security = e.Response.SecurityDetails
 			}
+			n.markResponded()
 		}
-	})
+	case *runtime.EventExceptionThrown:
+		n.recordJSError(exceptionMessage(e.ExceptionDetails))
+	case *runtime.EventConsoleAPICalled:
+		if e.Type == runtime.APITypeError {
+			n.recordJSError(consoleMessage(e.Args))
+		}
+	case *cdplog.EventEntryAdded:
+		if e.Entry != nil && e.Entry.Level == cdplog.LevelError {
+			n.consoleErrorCount++
+			if len(n.consoleErrors) < maxConsoleErrors {
+				n.consoleErrors = append(n.consoleErrors, logEntryMessage(e.Entry))
+			}
+		}
+	}
+}
 
-	return capture
+// recordJSError counts a JS error, keeping its message while under the cap.
+// The caller must hold n.mu.
+func (n *NetworkEventCapture) recordJSError(msg string) {
+	n.jsErrorCount++
+	if len(n.jsErrors) < maxJSErrors {
+		n.jsErrors = append(n.jsErrors, msg)
+	}
+}
+
+// trackDocumentHop follows the main document through its redirects.
+// The caller must hold n.mu.
+func (n *NetworkEventCapture) trackDocumentHop(e *network.EventRequestWillBeSent) {
+	if n.documentID == "" {
+		n.documentID = e.RequestID
+		if e.Timestamp != nil {
+			n.documentStart = e.Timestamp.Time()
+		}
+	}
+	if e.RequestID != n.documentID {
+		return // iframe or a later navigation
+	}
+	if e.RedirectResponse != nil {
+		// The redirect answered the previous hop's request
+		n.redirects = append(n.redirects, models.RedirectHop{
+			URL:        n.finalURL,
+			HTTPStatus: int(e.RedirectResponse.Status),
+		})
+		if e.Timestamp != nil {
+			n.lastRedirectAt = e.Timestamp.Time()
+		}
+	}
+	n.finalURL = e.Request.URL
+}
+
+// markResponded closes the responded channel the first time it is called.
+// The caller must hold n.mu.
+func (n *NetworkEventCapture) markResponded() {
+	select {
+	case <-n.responded:
+	default:
+		close(n.responded)
+	}
+}
+
+// Crashed returns a channel that is closed if the page's renderer crashes.
+// Chrome does not fail the navigation itself; it just never finishes.
+func (n *NetworkEventCapture) Crashed() <-chan struct{} {
+	return n.crashed
+}
+
+// HasCrashed reports whether the page's renderer crashed
+func (n *NetworkEventCapture) HasCrashed() bool {
+	select {
+	case <-n.crashed:
+		return true
+	default:
+		return false
+	}
+}
+
+// DocumentResponded returns a channel that is closed once the main document's
+// response headers have arrived (or its request has failed)
+func (n *NetworkEventCapture) DocumentResponded() <-chan struct{} {
+	return n.responded
+}
+
+// Detach stops recording the page's events, so a later navigation in the
+// same tab (the warm measurement) doesn't end up in this test's HAR and
+// request data. A renderer crash is still reported through Crashed.
+func (n *NetworkEventCapture) Detach() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.detached = true
+}
+
+// RecordHAR starts keeping every request and response for BuildHAR.
+// Call this before navigation begins.
+func (n *NetworkEventCapture) RecordHAR() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.har == nil {
+		n.har = newHARRecorder()
+	}
+}
+
+// BuildHAR returns everything recorded since RecordHAR as a HAR document
+// (nil if HAR recording was not enabled)
+func (n *NetworkEventCapture) BuildHAR(version string) *harFile {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.har == nil {
+		return nil
+	}
+	return n.har.build(version)
 }
 
 // GetErrorText returns the captured Chrome error text
 func (n *NetworkEventCapture) GetErrorText() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	return n.errorText
 }
 
 // GetTiming returns the captured network timing data
 func (n *NetworkEventCapture) GetTiming() *network.ResourceTiming {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	return n.timing
 }
 
+// GetProtocol returns the negotiated protocol of the document response
+func (n *NetworkEventCapture) GetProtocol() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.protocol
+}
+
+// GetStatus returns the HTTP status of the main document (0 if no response was seen)
+func (n *NetworkEventCapture) GetStatus() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.status
+}
+
+// GetDocumentHeaders returns the main document's response headers and the
+// local time they arrived (nil and zero if no response was seen)
+func (n *NetworkEventCapture) GetDocumentHeaders() (network.Headers, time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.headers, n.receivedAt
+}
+
+// GetFinalURL returns the main document's URL after following redirects
+// ("" if no document request was seen)
+func (n *NetworkEventCapture) GetFinalURL() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.finalURL
+}
+
+// GetRedirectChain returns the redirects the main document followed, in
+// order (nil without redirects)
+func (n *NetworkEventCapture) GetRedirectChain() []models.RedirectHop {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.redirects) == 0 {
+		return nil
+	}
+
+	hops := make([]models.RedirectHop, len(n.redirects))
+	copy(hops, n.redirects)
+	return hops
+}
+
+// GetTimeToFinalURL returns how long after the first document request the
+// last redirect was followed (0 without redirects). ok is false if no
+// document request was seen.
+func (n *NetworkEventCapture) GetTimeToFinalURL() (d time.Duration, ok bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.documentID == "" || n.documentStart.IsZero() {
+		return 0, false
+	}
+	if n.lastRedirectAt.IsZero() {
+		return 0, true
+	}
+	return n.lastRedirectAt.Sub(n.documentStart), true
+}
+
+// GetBlockedRequestCount returns how many requests were blocked as ignored resources
+func (n *NetworkEventCapture) GetBlockedRequestCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.blockedRequestCount
+}
+
+// GetTotals returns the number of requests the page sent over the network
+// and the encoded bytes (headers and compressed bodies) received for those
+// that finished
+func (n *NetworkEventCapture) GetTotals() (int, int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.totalRequests, n.totalBytes
+}
+
 // HasResponse returns true if a response event was captured
 func (n *NetworkEventCapture) HasResponse() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	return n.hasResponse
 }
+
+// GetDocumentRequestID returns the main document's request ID ("" before navigation)
+func (n *NetworkEventCapture) GetDocumentRequestID() network.RequestID {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.documentID
+}
+
+// GetRemoteAddress returns the IP and port Chrome connected to for the main
+// document ("" and 0 if it never responded). Behind a proxy this is the proxy.
+func (n *NetworkEventCapture) GetRemoteAddress() (string, int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.remoteIP, n.remotePort
+}
+
+// GetSecurityDetails returns the main document's TLS connection and
+// certificate details, nil over plain HTTP or if it never responded
+func (n *NetworkEventCapture) GetSecurityDetails() *network.SecurityDetails {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.security
+}
+
+// GetJSErrors returns the number of uncaught exceptions and console errors
+// and up to maxJSErrors of their messages
+func (n *NetworkEventCapture) GetJSErrors() (int, []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	msgs := make([]string, len(n.jsErrors))
+	copy(msgs, n.jsErrors)
+	return n.jsErrorCount, msgs
+}
+
+// GetConsoleErrors returns the number of errors Chrome logged to the console
+// (failed loads, blocked content...) and up to maxConsoleErrors of their
+// messages
+func (n *NetworkEventCapture) GetConsoleErrors() (int, []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	msgs := make([]string, len(n.consoleErrors))
+	copy(msgs, n.consoleErrors)
+	return n.consoleErrorCount, msgs
+}
+
+// GetFailedSubresources returns the number of failed non-document requests
+// and up to maxFailedSubresourceURLs of their URLs
+func (n *NetworkEventCapture) GetFailedSubresources() (int, []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	urls := make([]string, len(n.failedSubresources))
+	copy(urls, n.failedSubresources)
+	return n.failedSubresourceCount, urls
+}
@@ -0,0 +1,494 @@
+package browser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/inspector"
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestNetworkEventCapture_DocumentFailure(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&network.EventLoadingFailed{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		ErrorText: "net::ERR_NAME_NOT_RESOLVED",
+	})
+
+	if got := capture.GetErrorText(); got != "net::ERR_NAME_NOT_RESOLVED" {
+		t.Errorf("Expected document error text, got %q", got)
+	}
+	if count, _ := capture.GetFailedSubresources(); count != 0 {
+		t.Errorf("Document failure should not count as a subresource failure, got %d", count)
+	}
+}
+
+func TestNetworkEventCapture_DocumentStatus(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{Status: 401, Protocol: "h2"},
+	})
+	// An iframe document must not overwrite the main document's status
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "frame",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{Status: 200, Protocol: "h2"},
+	})
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "img",
+		Type:      network.ResourceTypeImage,
+		Response:  &network.Response{Status: 404},
+	})
+
+	if got := capture.GetStatus(); got != 401 {
+		t.Errorf("Expected main document status 401, got %d", got)
+	}
+}
+
+func TestNetworkEventCapture_FailedSubresources(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	for i := 0; i < maxFailedSubresourceURLs+5; i++ {
+		id := network.RequestID(fmt.Sprintf("req-%d", i))
+		capture.handleEvent(&network.EventRequestWillBeSent{
+			RequestID: id,
+			Request:   &network.Request{URL: fmt.Sprintf("https://cdn.example.com/app-%d.js", i)},
+			Type:      network.ResourceTypeScript,
+		})
+		capture.handleEvent(&network.EventLoadingFailed{
+			RequestID: id,
+			Type:      network.ResourceTypeScript,
+			ErrorText: "net::ERR_CONNECTION_RESET",
+		})
+	}
+
+	// Cancelled requests are not failures
+	capture.handleEvent(&network.EventLoadingFailed{
+		RequestID: "cancelled",
+		Type:      network.ResourceTypeImage,
+		Canceled:  true,
+	})
+
+	count, urls := capture.GetFailedSubresources()
+	if count != maxFailedSubresourceURLs+5 {
+		t.Errorf("Expected %d failed subresources, got %d", maxFailedSubresourceURLs+5, count)
+	}
+	if len(urls) != maxFailedSubresourceURLs {
+		t.Fatalf("Expected URL list capped at %d, got %d", maxFailedSubresourceURLs, len(urls))
+	}
+	if urls[0] != "https://cdn.example.com/app-0.js" {
+		t.Errorf("Expected first failed URL recorded, got %q", urls[0])
+	}
+	if capture.GetErrorText() != "" {
+		t.Errorf("Subresource failures should not set the document error, got %q", capture.GetErrorText())
+	}
+}
+
+func TestNetworkEventCapture_DocumentResponse(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			Protocol: "h2",
+			Timing:   &network.ResourceTiming{DNSStart: 0, DNSEnd: 12},
+		},
+	})
+
+	if !capture.HasResponse() {
+		t.Error("Expected document response to be recorded")
+	}
+	if capture.GetProtocol() != "h2" {
+		t.Errorf("Expected protocol h2, got %q", capture.GetProtocol())
+	}
+	if capture.GetTiming() == nil {
+		t.Error("Expected document timing to be recorded")
+	}
+}
+
+func TestNetworkEventCapture_DocumentResponded(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "img",
+		Type:      network.ResourceTypeImage,
+		Response:  &network.Response{Status: 200},
+	})
+	select {
+	case <-capture.DocumentResponded():
+		t.Fatal("A subresource response must not signal the document")
+	default:
+	}
+
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{Status: 200},
+	})
+	// A later document failure (e.g. the load being stopped) must not close twice
+	capture.handleEvent(&network.EventLoadingFailed{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		ErrorText: "net::ERR_ABORTED",
+		Canceled:  true,
+	})
+	select {
+	case <-capture.DocumentResponded():
+	default:
+		t.Fatal("Expected the document response to be signalled")
+	}
+}
+
+func TestNetworkEventCapture_Redirects(t *testing.T) {
+	capture := newNetworkEventCapture()
+	start := time.Now()
+	at := func(ms int) *cdp.MonotonicTime {
+		ts := cdp.MonotonicTime(start.Add(time.Duration(ms) * time.Millisecond))
+		return &ts
+	}
+
+	if _, ok := capture.GetTimeToFinalURL(); ok {
+		t.Error("Expected no redirect timing before any document request")
+	}
+
+	// http -> https -> login, then the final page loads an iframe
+	hops := []struct {
+		url string
+		ms  int
+	}{
+		{"http://example.com/", 0},
+		{"https://example.com/", 40},
+		{"https://login.example.com/", 180},
+	}
+	for i, hop := range hops {
+		ev := &network.EventRequestWillBeSent{
+			RequestID: "doc",
+			Request:   &network.Request{URL: hop.url},
+			Type:      network.ResourceTypeDocument,
+			Timestamp: at(hop.ms),
+		}
+		if i > 0 {
+			ev.RedirectResponse = &network.Response{Status: 302}
+		}
+		capture.handleEvent(ev)
+	}
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "frame",
+		Request:   &network.Request{URL: "https://ads.example.net/frame"},
+		Type:      network.ResourceTypeDocument,
+		Timestamp: at(400),
+	})
+
+	if got := capture.GetFinalURL(); got != "https://login.example.com/" {
+		t.Errorf("Expected final URL after redirects, got %q", got)
+	}
+	if got, ok := capture.GetTimeToFinalURL(); !ok || got != 180*time.Millisecond {
+		t.Errorf("Expected 180ms to the final URL, got %v (ok=%v)", got, ok)
+	}
+}
+
+func TestNetworkEventCapture_RedirectChain(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	request := func(id string, resourceType network.ResourceType, url string, redirect *network.Response) {
+		capture.handleEvent(&network.EventRequestWillBeSent{
+			RequestID:        network.RequestID(id),
+			Request:          &network.Request{URL: url},
+			Type:             resourceType,
+			RedirectResponse: redirect,
+		})
+	}
+
+	// http -> https (301), then intercepted by a captive portal (302); a
+	// subresource redirect is not part of the document's chain
+	request("doc", network.ResourceTypeDocument, "http://example.com/", nil)
+	request("doc", network.ResourceTypeDocument, "https://example.com/", &network.Response{URL: "http://example.com/", Status: 301})
+	request("img", network.ResourceTypeImage, "https://cdn.example.com/b.png", &network.Response{URL: "https://cdn.example.com/a.png", Status: 302})
+	request("doc", network.ResourceTypeDocument, "http://portal.hotspot.example/login", &network.Response{URL: "https://example.com/", Status: 302})
+
+	want := []models.RedirectHop{
+		{URL: "http://example.com/", HTTPStatus: 301},
+		{URL: "https://example.com/", HTTPStatus: 302},
+	}
+	got := capture.GetRedirectChain()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected redirect chain %+v, got %+v", want, got)
+	}
+	if final := capture.GetFinalURL(); final != "http://portal.hotspot.example/login" {
+		t.Errorf("Expected the portal as final URL, got %q", final)
+	}
+
+	// The chain returned is a copy
+	got[0].URL = "changed"
+	if capture.GetRedirectChain()[0].URL != "http://example.com/" {
+		t.Error("Expected GetRedirectChain to return a copy")
+	}
+}
+
+func TestNetworkEventCapture_NoRedirect(t *testing.T) {
+	capture := newNetworkEventCapture()
+	ts := cdp.MonotonicTime(time.Now())
+
+	capture.handleEvent(&network.EventRequestWillBeSent{
+		RequestID: "doc",
+		Request:   &network.Request{URL: "https://example.com/"},
+		Type:      network.ResourceTypeDocument,
+		Timestamp: &ts,
+	})
+
+	if got := capture.GetFinalURL(); got != "https://example.com/" {
+		t.Errorf("Expected the requested URL as final URL, got %q", got)
+	}
+	if got, ok := capture.GetTimeToFinalURL(); !ok || got != 0 {
+		t.Errorf("Expected 0 without redirects, got %v (ok=%v)", got, ok)
+	}
+	if chain := capture.GetRedirectChain(); chain != nil {
+		t.Errorf("Expected no redirect chain, got %+v", chain)
+	}
+}
+
+func TestNetworkEventCapture_BlockedRequests(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	for i := 0; i < 3; i++ {
+		capture.handleEvent(&network.EventLoadingFailed{
+			RequestID:     network.RequestID(fmt.Sprintf("beacon-%d", i)),
+			Type:          network.ResourceTypePing,
+			ErrorText:     "net::ERR_BLOCKED_BY_CLIENT",
+			BlockedReason: network.BlockedReasonInspector,
+		})
+	}
+
+	if got := capture.GetBlockedRequestCount(); got != 3 {
+		t.Errorf("Expected 3 blocked requests, got %d", got)
+	}
+	if count, _ := capture.GetFailedSubresources(); count != 0 {
+		t.Errorf("Blocked requests must not count as failed subresources, got %d", count)
+	}
+	if got := capture.GetErrorText(); got != "" {
+		t.Errorf("Blocked requests must not set the document error, got %q", got)
+	}
+}
+
+func TestNetworkEventCapture_JSErrors(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&runtime.EventExceptionThrown{
+		ExceptionDetails: &runtime.ExceptionDetails{
+			Text:      "Uncaught",
+			URL:       "https://example.com/app.js",
+			Exception: &runtime.RemoteObject{Description: "TypeError: x is undefined\n    at app.js:1:1"},
+		},
+	})
+	// Only console.error counts
+	capture.handleEvent(&runtime.EventConsoleAPICalled{
+		Type: runtime.APITypeLog,
+		Args: []*runtime.RemoteObject{{Type: runtime.TypeString, Value: []byte(`"hello"`)}},
+	})
+	for i := 0; i < maxJSErrors+2; i++ {
+		capture.handleEvent(&runtime.EventConsoleAPICalled{
+			Type: runtime.APITypeError,
+			Args: []*runtime.RemoteObject{{Type: runtime.TypeString, Value: []byte(`"failed to load"`)}},
+		})
+	}
+
+	count, msgs := capture.GetJSErrors()
+	if count != maxJSErrors+3 {
+		t.Errorf("Expected %d JS errors, got %d", maxJSErrors+3, count)
+	}
+	if len(msgs) != maxJSErrors {
+		t.Fatalf("Expected messages capped at %d, got %d", maxJSErrors, len(msgs))
+	}
+	if msgs[0] != "TypeError: x is undefined (https://example.com/app.js)" {
+		t.Errorf("Unexpected exception message %q", msgs[0])
+	}
+	if msgs[1] != "console.error: failed to load" {
+		t.Errorf("Unexpected console message %q", msgs[1])
+	}
+}
+
+func TestNetworkEventCapture_ConsoleErrors(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&cdplog.EventEntryAdded{Entry: &cdplog.Entry{
+		Source: cdplog.SourceNetwork,
+		Level:  cdplog.LevelError,
+		Text:   "Failed to load resource: net::ERR_NAME_NOT_RESOLVED",
+		URL:    "https://cdn.example.net/app.js",
+	}})
+	capture.handleEvent(&cdplog.EventEntryAdded{Entry: &cdplog.Entry{
+		Source: cdplog.SourceSecurity,
+		Level:  cdplog.LevelError,
+		Text:   "Mixed Content: The page at 'https://example.com/' requested 'http://example.com/a.js'",
+	}})
+	// Only errors count
+	capture.handleEvent(&cdplog.EventEntryAdded{Entry: &cdplog.Entry{
+		Source: cdplog.SourceDeprecation,
+		Level:  cdplog.LevelWarning,
+		Text:   "Deprecated API",
+	}})
+	for i := 0; i < maxConsoleErrors+2; i++ {
+		capture.handleEvent(&cdplog.EventEntryAdded{Entry: &cdplog.Entry{
+			Source: cdplog.SourceNetwork,
+			Level:  cdplog.LevelError,
+			Text:   "Failed to load resource: the server responded with a status of 404 ()",
+		}})
+	}
+
+	count, msgs := capture.GetConsoleErrors()
+	if count != maxConsoleErrors+4 {
+		t.Errorf("Expected %d console errors, got %d", maxConsoleErrors+4, count)
+	}
+	if len(msgs) != maxConsoleErrors {
+		t.Fatalf("Expected messages capped at %d, got %d", maxConsoleErrors, len(msgs))
+	}
+	if msgs[0] != "network: Failed to load resource: net::ERR_NAME_NOT_RESOLVED (https://cdn.example.net/app.js)" {
+		t.Errorf("Unexpected network error message %q", msgs[0])
+	}
+	if msgs[1] != "security: Mixed Content: The page at 'https://example.com/' requested 'http://example.com/a.js'" {
+		t.Errorf("Unexpected security error message %q", msgs[1])
+	}
+
+	// They're kept apart from the page's own errors
+	if n, _ := capture.GetJSErrors(); n != 0 {
+		t.Errorf("Expected no JS errors, got %d", n)
+	}
+}
+
+func TestNetworkEventCapture_RemoteAddress(t *testing.T) {
+	capture := newNetworkEventCapture()
+
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{Status: 200, RemoteIPAddress: "2606:4700::1111", RemotePort: 443},
+	})
+	// Subresources and iframes can come from elsewhere
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "frame",
+		Type:      network.ResourceTypeDocument,
+		Response:  &network.Response{Status: 200, RemoteIPAddress: "192.0.2.7", RemotePort: 8443},
+	})
+
+	if ip, port := capture.GetRemoteAddress(); ip != "2606:4700::1111" || port != 443 {
+		t.Errorf("Expected main document peer [2606:4700::1111]:443, got %q:%d", ip, port)
+	}
+
+	// A connection that failed before any response leaves the address empty
+	failed := newNetworkEventCapture()
+	failed.handleEvent(&network.EventLoadingFailed{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		ErrorText: "net::ERR_CONNECTION_REFUSED",
+	})
+	if ip, port := failed.GetRemoteAddress(); ip != "" || port != 0 {
+		t.Errorf("Expected no peer after a failed connection, got %q:%d", ip, port)
+	}
+}
+
+func TestNetworkEventCapture_Crashed(t *testing.T) {
+	capture := newNetworkEventCapture()
+	if capture.HasCrashed() {
+		t.Fatal("Expected no crash before any event")
+	}
+
+	capture.handleEvent(&inspector.EventTargetCrashed{})
+	capture.handleEvent(&inspector.EventTargetCrashed{}) // must not close twice
+
+	if !capture.HasCrashed() {
+		t.Error("Expected the crash to be recorded")
+	}
+	select {
+	case <-capture.Crashed():
+	default:
+		t.Error("Expected the Crashed channel to be closed")
+	}
+}
+
+func TestNetworkEventCapture_Detach(t *testing.T) {
+	capture := newNetworkEventCapture()
+	capture.RecordHAR()
+	send := func(id, url string) {
+		capture.handleEvent(&network.EventRequestWillBeSent{
+			RequestID: network.RequestID(id),
+			Request:   &network.Request{URL: url, Method: "GET"},
+			Type:      network.ResourceTypeDocument,
+		})
+	}
+
+	send("cold", "https://example.com/")
+	capture.Detach()
+	// The warm navigation's requests belong to neither the totals nor the HAR
+	send("warm", "https://example.com/")
+
+	if requests, _ := capture.GetTotals(); requests != 1 {
+		t.Errorf("Expected only the cold request counted, got %d", requests)
+	}
+	if har := capture.BuildHAR("test"); len(har.Log.Entries) != 1 {
+		t.Errorf("Expected only the cold request in the HAR, got %d entries", len(har.Log.Entries))
+	}
+
+	capture.handleEvent(&inspector.EventTargetCrashed{})
+	if !capture.HasCrashed() {
+		t.Error("Expected a crash to be noticed after Detach")
+	}
+}
+
+func TestNetworkEventCapture_Totals(t *testing.T) {
+	capture := newNetworkEventCapture()
+	send := func(id, url string, redirect bool) {
+		ev := &network.EventRequestWillBeSent{
+			RequestID: network.RequestID(id),
+			Request:   &network.Request{URL: url, Method: "GET"},
+			Type:      network.ResourceTypeScript,
+		}
+		if redirect {
+			ev.RedirectResponse = &network.Response{Status: 302}
+		}
+		capture.handleEvent(ev)
+	}
+
+	// A document that redirected once, three subresources, an inline image,
+	// a blocked beacon and a request that failed
+	send("doc", "http://example.com/", false)
+	send("doc", "https://example.com/", true)
+	send("css", "https://example.com/site.css", false)
+	send("js", "https://cdn.example.com/app.js", false)
+	send("font", "https://fonts.example.com/a.woff2", false)
+	send("inline", "data:image/png;base64,iVBORw0KGgo=", false)
+	send("beacon", "https://analytics.example.com/collect", false)
+	capture.handleEvent(&network.EventLoadingFailed{
+		RequestID:     "beacon",
+		Type:          network.ResourceTypePing,
+		BlockedReason: network.BlockedReasonInspector,
+	})
+	capture.handleEvent(&network.EventLoadingFailed{
+		RequestID: "font",
+		Type:      network.ResourceTypeFont,
+		ErrorText: "net::ERR_CONNECTION_RESET",
+	})
+	for id, size := range map[string]float64{"doc": 15000, "css": 4000, "js": 120000, "inline": 0} {
+		capture.handleEvent(&network.EventLoadingFinished{RequestID: network.RequestID(id), EncodedDataLength: size})
+	}
+
+	requests, bytes := capture.GetTotals()
+	if requests != 5 {
+		t.Errorf("Expected 5 requests (2 document hops, css, js, font), got %d", requests)
+	}
+	if bytes != 139000 {
+		t.Errorf("Expected 139000 bytes, got %d", bytes)
+	}
+}
@@ -0,0 +1,165 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+const (
+	// oauth2FetchTimeout bounds a single token request
+	oauth2FetchTimeout = 10 * time.Second
+
+	// oauth2RefreshMargin is how long before expiry a token is replaced, so a
+	// test never starts with a token that runs out during the page load
+	oauth2RefreshMargin = time.Minute
+
+	// defaultOAuth2TokenLifetime is assumed when the token response has no expires_in
+	defaultOAuth2TokenLifetime = 5 * time.Minute
+)
+
+// oauth2TokenSource fetches bearer tokens with the OAuth2 client credentials
+// grant (RFC 6749 section 4.4) and caches them per client and scope set
+// until shortly before they expire.
+type oauth2TokenSource struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]*oauth2CachedToken
+
+	now func() time.Time
+}
+
+// oauth2CachedToken is one client's token; its lock serializes refreshes so
+// concurrent tests of the same site share a single token request
+type oauth2CachedToken struct {
+	mu        sync.Mutex
+	value     string // Authorization header value, e.g. "Bearer abc"
+	refreshAt time.Time
+}
+
+// oauth2TokenResponse is the token endpoint's successful response
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func newOAuth2TokenSource() *oauth2TokenSource {
+	return &oauth2TokenSource{
+		client: &http.Client{Timeout: oauth2FetchTimeout},
+		tokens: make(map[string]*oauth2CachedToken),
+		now:    time.Now,
+	}
+}
+
+// authorization returns the Authorization header value for creds, fetching a
+// new token when there is no cached one or it is about to expire
+func (s *oauth2TokenSource) authorization(ctx context.Context, creds *models.OAuth2Credentials) (string, error) {
+	entry := s.entry(creds)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	now := s.now()
+	if entry.value != "" && now.Before(entry.refreshAt) {
+		return entry.value, nil
+	}
+
+	resp, err := s.fetch(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	lifetime := defaultOAuth2TokenLifetime
+	if resp.ExpiresIn > 0 {
+		lifetime = time.Duration(resp.ExpiresIn) * time.Second
+	}
+	// Short-lived tokens are refreshed halfway through instead
+	margin := oauth2RefreshMargin
+	if margin > lifetime/2 {
+		margin = lifetime / 2
+	}
+	entry.value = "Bearer " + resp.AccessToken
+	entry.refreshAt = now.Add(lifetime - margin)
+	return entry.value, nil
+}
+
+// entry returns the cache slot for creds, creating it if needed. A changed
+// secret gets a new slot, so a rotated secret takes effect on the next test.
+func (s *oauth2TokenSource) entry(creds *models.OAuth2Credentials) *oauth2CachedToken {
+	scopes := append([]string(nil), creds.Scopes...)
+	sort.Strings(scopes)
+	key := strings.Join([]string{creds.TokenURL, creds.ClientID, creds.ClientSecret, strings.Join(scopes, " ")}, "\x00")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.tokens[key]
+	if !ok {
+		entry = &oauth2CachedToken{}
+		s.tokens[key] = entry
+	}
+	return entry
+}
+
+// fetch requests a new token, authenticating the client with HTTP Basic auth
+func (s *oauth2TokenSource) fetch(ctx context.Context, creds *models.OAuth2Credentials) (*oauth2TokenResponse, error) {
+	if creds.TokenURL == "" || creds.ClientID == "" {
+		return nil, fmt.Errorf("oauth2 requires token_url and client_id")
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(creds.Scopes) > 0 {
+		form.Set("scope", strings.Join(creds.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(creds.ClientID), url.QueryEscape(creds.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token oauth2TokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported token_type %q", token.TokenType)
+	}
+	return &token, nil
+}
+
+// authorizeOrigin scopes the Authorization header value to siteURL's origin.
+// requestAuth adds it to that origin's requests only; requests to other
+// origins (CDNs, analytics, a cross-origin redirect) never see the token.
+func authorizeOrigin(siteURL, authorization string) (*bearerToken, error) {
+	origin, ok := normalizeOrigin(siteURL)
+	if !ok {
+		return nil, fmt.Errorf("cannot determine the origin of %q", siteURL)
+	}
+	return &bearerToken{authorization: authorization, origin: origin}, nil
+}
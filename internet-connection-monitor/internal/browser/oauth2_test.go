@@ -0,0 +1,135 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestOAuth2TokenSource(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "monitor" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read status" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	src := newOAuth2TokenSource()
+	src.now = func() time.Time { return now }
+	creds := &models.OAuth2Credentials{
+		TokenURL:     server.URL,
+		ClientID:     "monitor",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"read", "status"},
+	}
+
+	auth, err := src.authorization(context.Background(), creds)
+	if err != nil || auth != "Bearer token-1" {
+		t.Fatalf("got %q, %v; want Bearer token-1", auth, err)
+	}
+
+	// Cached until a minute before expiry
+	now = now.Add(58 * time.Minute)
+	if auth, _ := src.authorization(context.Background(), creds); auth != "Bearer token-1" {
+		t.Errorf("expected the cached token, got %q", auth)
+	}
+	now = now.Add(2 * time.Minute)
+	if auth, _ := src.authorization(context.Background(), creds); auth != "Bearer token-2" {
+		t.Errorf("expected a refreshed token near expiry, got %q", auth)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 token requests, got %d", got)
+	}
+
+	// Bad credentials fail without caching anything
+	bad := *creds
+	bad.ClientSecret = "wrong"
+	if _, err := src.authorization(context.Background(), &bad); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an HTTP 401 error, got %v", err)
+	}
+}
+
+func TestOAuth2TokenSourceShortLived(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"access_token":"short","expires_in":60}`)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	src := newOAuth2TokenSource()
+	src.now = func() time.Time { return now }
+	creds := &models.OAuth2Credentials{TokenURL: server.URL, ClientID: "monitor"}
+
+	src.authorization(context.Background(), creds)
+	now = now.Add(29 * time.Second)
+	src.authorization(context.Background(), creds)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected the token reused within half its lifetime, got %d requests", got)
+	}
+	now = now.Add(2 * time.Second)
+	src.authorization(context.Background(), creds)
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected a refresh past half the lifetime, got %d requests", got)
+	}
+}
+
+func TestOAuth2TokenSourceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", `<html>login</html>`},
+		{"no token", `{"token_type":"Bearer"}`},
+		{"wrong type", `{"access_token":"x","token_type":"mac"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			creds := &models.OAuth2Credentials{TokenURL: server.URL, ClientID: "monitor"}
+			if _, err := newOAuth2TokenSource().authorization(context.Background(), creds); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if _, err := newOAuth2TokenSource().authorization(context.Background(), &models.OAuth2Credentials{}); err == nil {
+		t.Error("expected an error without a token URL")
+	}
+}
+
+func TestHARHeadersHideCredentials(t *testing.T) {
+	headers := harHeaders(network.Headers{"authorization": "Bearer secret", "Accept": "text/html"})
+	for _, h := range headers {
+		if strings.Contains(h.Value, "secret") {
+			t.Errorf("header %s leaked its credentials: %q", h.Name, h.Value)
+		}
+	}
+	if len(headers) != 2 {
+		t.Errorf("expected both headers kept, got %v", headers)
+	}
+}
@@ -0,0 +1,23 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// pageTitleError returns the failure for a loaded page whose title doesn't
+// contain the site's ExpectedTitleContains, or nil if it does or the site
+// expects nothing. An ISP's error or splash page often answers 200, so the
+// status alone can't tell it from the real site.
+func pageTitleError(site models.SiteDefinition, title string) *models.ErrorInfo {
+	if site.ExpectedTitleContains == "" || strings.Contains(title, site.ExpectedTitleContains) {
+		return nil
+	}
+	return &models.ErrorInfo{
+		ErrorType:    "ERR_CONTENT_MISMATCH",
+		ErrorMessage: fmt.Sprintf("page title %q does not contain %q", title, site.ExpectedTitleContains),
+		FailurePhase: "http",
+	}
+}
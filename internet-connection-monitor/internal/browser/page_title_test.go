@@ -0,0 +1,41 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestPageTitleError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+		title    string
+		fail     bool
+	}{
+		{"no expectation", "", "Anything", false},
+		{"no expectation, empty title", "", "", false},
+		{"exact match", "Google", "Google", false},
+		{"contained", "GitHub", "GitHub: Let's build from here", false},
+		{"splash page", "Google", "Welcome to ExampleISP - Service Notice", true},
+		{"case differs", "google", "Google", true},
+		{"no title", "Google", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			site := models.SiteDefinition{Name: "site", ExpectedTitleContains: tc.expected}
+			errInfo := pageTitleError(site, tc.title)
+			if !tc.fail {
+				if errInfo != nil {
+					t.Errorf("Expected title %q to match %q, got %+v", tc.title, tc.expected, errInfo)
+				}
+				return
+			}
+			if errInfo == nil {
+				t.Fatalf("Expected title %q not to match %q", tc.title, tc.expected)
+			}
+			if errInfo.ErrorType != "ERR_CONTENT_MISMATCH" || errInfo.FailurePhase != "http" {
+				t.Errorf("Expected ERR_CONTENT_MISMATCH in the http phase, got %+v", errInfo)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package browser
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// redactedText replaces redacted values
+const redactedText = "[REDACTED]"
+
+// redactor removes secrets (tokens, PII) matched by BrowserConfig.RedactPatterns
+// from results and HAR files before they leave the controller. Tests always
+// run against the full values.
+//
+// A pattern without capture groups has its whole match replaced; one with
+// groups has only the groups replaced, so `[?&]token=([^&]+)` keeps the
+// parameter name: "?token=[REDACTED]".
+type redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// newRedactor compiles the patterns; it returns nil when there are none
+func newRedactor(patterns []string) (*redactor, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	r := &redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// redact returns s with every pattern's matches replaced. A nil redactor returns s.
+func (r *redactor) redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllLiteralString(s, redactedText)
+			continue
+		}
+		s = redactGroups(re, s)
+	}
+	return s
+}
+
+// redactGroups replaces the text of each match's capture groups
+func redactGroups(re *regexp.Regexp, s string) string {
+	var out []byte
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		for g := 2; g+1 < len(m); g += 2 {
+			start, end := m[g], m[g+1]
+			if start < last || start < 0 { // unmatched or nested group
+				continue
+			}
+			out = append(out, s[last:start]...)
+			out = append(out, redactedText...)
+			last = end
+		}
+	}
+	if out == nil {
+		return s
+	}
+	return string(append(out, s[last:]...))
+}
+
+func (r *redactor) redactAll(values []string) {
+	for i := range values {
+		values[i] = r.redact(values[i])
+	}
+}
+
+// redactResult redacts every field of a result that can carry a URL or page content
+func (r *redactor) redactResult(result *models.TestResult) {
+	if r == nil {
+		return
+	}
+	result.Site.URL = r.redact(result.Site.URL)
+	result.FinalURL = r.redact(result.FinalURL)
+	result.Metadata.TestedURL = r.redact(result.Metadata.TestedURL)
+	result.Status.Message = r.redact(result.Status.Message)
+	r.redactAll(result.FailedSubresources)
+	r.redactAll(result.JSErrors)
+	r.redactAll(result.ConsoleErrors)
+	for i := range result.Endpoints {
+		result.Endpoints[i].URL = r.redact(result.Endpoints[i].URL)
+	}
+	for name, value := range result.ResponseHeaders {
+		result.ResponseHeaders[name] = r.redact(value)
+	}
+	for i := range result.RedirectChain {
+		result.RedirectChain[i].URL = r.redact(result.RedirectChain[i].URL)
+	}
+	if result.Error != nil {
+		result.Error.ErrorMessage = r.redact(result.Error.ErrorMessage)
+		result.Error.StackTrace = r.redact(result.Error.StackTrace)
+	}
+}
+
+// redactHAR redacts URLs, query strings, headers and cookies in a HAR document
+func (r *redactor) redactHAR(har *harFile) {
+	if r == nil || har == nil {
+		return
+	}
+	redactPairs := func(pairs []harNameValue) {
+		for i := range pairs {
+			pairs[i].Value = r.redact(pairs[i].Value)
+		}
+	}
+	for i := range har.Log.Entries {
+		e := &har.Log.Entries[i]
+		e.Request.URL = r.redact(e.Request.URL)
+		redactPairs(e.Request.QueryString)
+		redactPairs(e.Request.Headers)
+		redactPairs(e.Request.Cookies)
+		redactPairs(e.Response.Headers)
+		redactPairs(e.Response.Cookies)
+		e.Response.RedirectURL = r.redact(e.Response.RedirectURL)
+		e.Comment = r.redact(e.Comment)
+	}
+}
@@ -0,0 +1,124 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestRedactorRedact(t *testing.T) {
+	r, err := newRedactor([]string{
+		`[?&](?:token|sig)=([^&#]+)`,
+		`[\w.+-]+@[\w-]+\.[\w.]+`,
+	})
+	if err != nil {
+		t.Fatalf("newRedactor: %v", err)
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"https://example.com/", "https://example.com/"},
+		{"https://example.com/a?token=abc123", "https://example.com/a?token=[REDACTED]"},
+		{"https://example.com/a?x=1&sig=s3cr3t&y=2", "https://example.com/a?x=1&sig=[REDACTED]&y=2"},
+		{"https://example.com/?token=a&sig=b#frag", "https://example.com/?token=[REDACTED]&sig=[REDACTED]#frag"},
+		{"failed for user@example.com", "failed for [REDACTED]"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := r.redact(tt.in); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewRedactor(t *testing.T) {
+	if r, err := newRedactor(nil); r != nil || err != nil {
+		t.Errorf("no patterns: got %v, %v; want nil, nil", r, err)
+	}
+	if _, err := newRedactor([]string{"("}); err == nil {
+		t.Error("invalid pattern: expected error")
+	}
+
+	// A nil redactor leaves everything untouched
+	var r *redactor
+	if got := r.redact("https://example.com/?token=x"); got != "https://example.com/?token=x" {
+		t.Errorf("nil redactor changed input: %q", got)
+	}
+	r.redactResult(&models.TestResult{})
+	r.redactHAR(&harFile{})
+}
+
+func TestRedactorRedactResult(t *testing.T) {
+	r, err := newRedactor([]string{`token=([^&]+)`})
+	if err != nil {
+		t.Fatalf("newRedactor: %v", err)
+	}
+
+	const secretURL = "https://example.com/?token=hunter2"
+	const want = "https://example.com/?token=[REDACTED]"
+	result := &models.TestResult{
+		Site:               models.SiteInfo{URL: secretURL},
+		FinalURL:           secretURL,
+		Metadata:           models.TestMetadata{TestedURL: secretURL},
+		FailedSubresources: []string{secretURL},
+		JSErrors:           []string{"fetch " + secretURL},
+		ConsoleErrors:      []string{"network: Failed to load resource (" + secretURL + ")"},
+		Endpoints:          []models.EndpointResult{{URL: secretURL}},
+		RedirectChain:      []models.RedirectHop{{URL: secretURL, HTTPStatus: 302}},
+		ResponseHeaders:    map[string]string{"location": secretURL},
+		Error:              &models.ErrorInfo{ErrorMessage: "net::ERR_FAILED at " + secretURL},
+	}
+	r.redactResult(result)
+
+	for name, got := range map[string]string{
+		"Site.URL":           result.Site.URL,
+		"FinalURL":           result.FinalURL,
+		"TestedURL":          result.Metadata.TestedURL,
+		"FailedSubresources": result.FailedSubresources[0],
+		"JSErrors":           result.JSErrors[0],
+		"ConsoleErrors":      result.ConsoleErrors[0],
+		"Endpoints":          result.Endpoints[0].URL,
+		"RedirectChain":      result.RedirectChain[0].URL,
+		"ResponseHeaders":    result.ResponseHeaders["location"],
+		"ErrorMessage":       result.Error.ErrorMessage,
+	} {
+		if got == "" || strings.Contains(got, "hunter2") {
+			t.Errorf("%s not redacted: %q", name, got)
+		}
+	}
+	if result.Site.URL != want {
+		t.Errorf("Site.URL = %q, want %q", result.Site.URL, want)
+	}
+}
+
+func TestRedactorRedactHAR(t *testing.T) {
+	r, err := newRedactor([]string{`token=([^&]+)`, `Bearer (\S+)`})
+	if err != nil {
+		t.Fatalf("newRedactor: %v", err)
+	}
+
+	har := &harFile{Log: harLog{Entries: []harEntry{{
+		Request: harReq{
+			URL:         "https://api.example.com/v1?token=hunter2",
+			Headers:     []harNameValue{{Name: "Authorization", Value: "Bearer abc.def"}},
+			QueryString: []harNameValue{{Name: "token", Value: "token=hunter2"}},
+		},
+		Response: harResp{
+			RedirectURL: "https://example.com/next?token=hunter2",
+		},
+	}}}}
+	r.redactHAR(har)
+
+	e := har.Log.Entries[0]
+	if e.Request.URL != "https://api.example.com/v1?token=[REDACTED]" {
+		t.Errorf("request URL = %q", e.Request.URL)
+	}
+	if e.Request.Headers[0].Value != "Bearer [REDACTED]" {
+		t.Errorf("Authorization header = %q", e.Request.Headers[0].Value)
+	}
+	if e.Response.RedirectURL != "https://example.com/next?token=[REDACTED]" {
+		t.Errorf("redirect URL = %q", e.Response.RedirectURL)
+	}
+}
@@ -0,0 +1,42 @@
+package browser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// blockedURLPatterns turns a site's IgnoreResourceDomains into Network.setBlockedURLs
+// patterns matching each domain and all of its subdomains, over any scheme.
+// Entries may be bare domains ("doubleclick.net"), wildcards ("*.doubleclick.net")
+// or URLs ("https://www.googletagmanager.com/gtm.js"); only the host is used.
+func blockedURLPatterns(domains []string) []string {
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, d := range domains {
+		host := ignoredDomainHost(d)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		patterns = append(patterns, "*://"+host+"/*", "*://*."+host+"/*")
+	}
+	return patterns
+}
+
+// ignoredDomainHost normalizes one IgnoreResourceDomains entry to a lowercase host
+func ignoredDomainHost(entry string) string {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if strings.Contains(entry, "://") {
+		u, err := url.Parse(entry)
+		if err != nil {
+			return ""
+		}
+		entry = u.Hostname()
+	}
+	entry = strings.TrimPrefix(entry, "*.")
+	entry = strings.Trim(entry, ".")
+	if i := strings.IndexAny(entry, "/:"); i >= 0 {
+		entry = entry[:i]
+	}
+	return entry
+}
@@ -0,0 +1,30 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockedURLPatterns(t *testing.T) {
+	got := blockedURLPatterns([]string{
+		"doubleclick.net",
+		" *.Google-Analytics.com ",
+		"https://www.googletagmanager.com/gtm.js?id=1",
+		"doubleclick.net", // duplicate
+		"cdn.example.com:8443/path",
+		"",
+	})
+	want := []string{
+		"*://doubleclick.net/*", "*://*.doubleclick.net/*",
+		"*://google-analytics.com/*", "*://*.google-analytics.com/*",
+		"*://www.googletagmanager.com/*", "*://*.www.googletagmanager.com/*",
+		"*://cdn.example.com/*", "*://*.cdn.example.com/*",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blockedURLPatterns() =\n%v\nwant\n%v", got, want)
+	}
+
+	if got := blockedURLPatterns(nil); got != nil {
+		t.Errorf("Expected no patterns for no domains, got %v", got)
+	}
+}
@@ -0,0 +1,32 @@
+package browser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// responseHeaders returns the document headers to record, keyed by lowercase
+// name. With an allowlist only the headers it names (in any case) are kept.
+// Set-Cookie carries session state and is never kept.
+func responseHeaders(headers network.Headers, allowlist []string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		if name == "set-cookie" {
+			continue
+		}
+		if len(allowlist) > 0 && !slices.ContainsFunc(allowlist, func(allowed string) bool {
+			return strings.EqualFold(allowed, name)
+		}) {
+			continue
+		}
+		out[name] = fmt.Sprint(value)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
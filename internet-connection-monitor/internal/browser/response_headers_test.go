@@ -0,0 +1,51 @@
+package browser
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	capture := newNetworkEventCapture()
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "doc",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			URL:    "https://example.com/",
+			Status: 200,
+			Headers: network.Headers{
+				"Server":       "cloudflare",
+				"CF-Ray":       "8a1b2c3d4e5f6789-AMS",
+				"Via":          "1.1 varnish",
+				"x-cache":      "HIT",
+				"Content-Type": "text/html",
+				"Set-Cookie":   "session=secret\nprefs=1",
+			},
+		},
+	})
+	headers, _ := capture.GetDocumentHeaders()
+
+	all := responseHeaders(headers, nil)
+	want := map[string]string{
+		"server":       "cloudflare",
+		"cf-ray":       "8a1b2c3d4e5f6789-AMS",
+		"via":          "1.1 varnish",
+		"x-cache":      "HIT",
+		"content-type": "text/html",
+	}
+	if !maps.Equal(all, want) {
+		t.Errorf("Expected every header but Set-Cookie, got %v", all)
+	}
+
+	allowed := responseHeaders(headers, []string{"Server", "cf-ray", "X-Cache", "Age", "Set-Cookie"})
+	want = map[string]string{"server": "cloudflare", "cf-ray": "8a1b2c3d4e5f6789-AMS", "x-cache": "HIT"}
+	if !maps.Equal(allowed, want) {
+		t.Errorf("Expected only the allowlisted headers, got %v", allowed)
+	}
+
+	if got := responseHeaders(headers, []string{"age"}); got != nil {
+		t.Errorf("Expected nil when no header is kept, got %v", got)
+	}
+}
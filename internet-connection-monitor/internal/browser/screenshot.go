@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// screenshotTimeout bounds taking a failure screenshot. The test has already
+// failed, often by timing out, so the screenshot gets its own budget.
+const screenshotTimeout = 5 * time.Second
+
+// screenshotFunc captures the page in ctx (a chromedp context) as a PNG
+type screenshotFunc func(ctx context.Context) ([]byte, error)
+
+// fullPageScreenshot captures the whole page, not just the viewport. Quality
+// 100 makes Chrome encode a lossless PNG.
+func fullPageScreenshot(ctx context.Context) ([]byte, error) {
+	var png []byte
+	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&png, 100)); err != nil {
+		return nil, err
+	}
+	return png, nil
+}
+
+// saveScreenshot writes a screenshot of a failed test's page and records its
+// path. It is best effort: the test's own error is what gets reported, so a
+// screenshot that can't be taken is only logged. A crashed renderer has no
+// page to capture, and isn't asked to.
+func (c *ControllerImpl) saveScreenshot(ctx context.Context, deadline hardDeadline, result *models.TestResult, capture *NetworkEventCapture) {
+	if result.Error == nil || capture.HasCrashed() {
+		return
+	}
+
+	// ctx is usually already cancelled by the failure; keep its browser
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), screenshotTimeout)
+	defer cancel()
+	ctx, cancelDeadline := deadline.bound(ctx)
+	defer cancelDeadline()
+
+	screenshot := c.screenshot
+	if screenshot == nil {
+		screenshot = fullPageScreenshot
+	}
+	png, err := screenshot(ctx)
+	if err != nil {
+		log.Printf("Failed to take screenshot of %s: %v", result.Site.Name, err)
+		return
+	}
+	path, err := writeScreenshot(c.config.ScreenshotDir, result, png)
+	if err != nil {
+		log.Printf("Failed to write screenshot of %s: %v", result.Site.Name, err)
+		return
+	}
+	result.Metadata.ScreenshotPath = path
+}
+
+// writeScreenshot saves png to dir, named like the result's HAR file
+func writeScreenshot(dir string, result *models.TestResult, png []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create screenshot dir: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s_%s.png",
+		result.Timestamp.UTC().Format("20060102T150405Z"),
+		unsafeFileChars.ReplaceAllString(result.Site.Name, "_"),
+		result.TestID)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, png, 0o644); err != nil {
+		return "", fmt.Errorf("write screenshot: %w", err)
+	}
+	return path, nil
+}
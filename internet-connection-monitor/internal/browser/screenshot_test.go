@@ -0,0 +1,110 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/inspector"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSaveScreenshotOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	calls := 0
+	c := &ControllerImpl{
+		config: &config.BrowserConfig{ScreenshotDir: dir},
+		screenshot: func(ctx context.Context) ([]byte, error) {
+			calls++
+			// The failed test's context is done; the screenshot's is not
+			if err := ctx.Err(); err != nil {
+				t.Errorf("Expected a live context for the screenshot, got %v", err)
+			}
+			return png, nil
+		},
+	}
+	newResult := func(failed bool) *models.TestResult {
+		result := &models.TestResult{
+			Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			TestID:    "abc",
+			Site:      models.SiteInfo{Name: "example site"},
+		}
+		if failed {
+			result.Error = &models.ErrorInfo{ErrorType: "timeout"}
+		}
+		return result
+	}
+	taskCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Successful tests aren't captured
+	result := newResult(false)
+	c.saveScreenshot(taskCtx, hardDeadline{}, result, newNetworkEventCapture())
+	if calls != 0 || result.Metadata.ScreenshotPath != "" {
+		t.Fatalf("Expected no screenshot of a successful test, got %d calls, path %q", calls, result.Metadata.ScreenshotPath)
+	}
+
+	// Failed tests are, and the path is recorded
+	result = newResult(true)
+	c.saveScreenshot(taskCtx, hardDeadline{}, result, newNetworkEventCapture())
+	if calls != 1 {
+		t.Fatalf("Expected the screenshot to be taken once on failure, got %d", calls)
+	}
+	want := filepath.Join(dir, "20240501T120000Z_example_site_abc.png")
+	if result.Metadata.ScreenshotPath != want {
+		t.Fatalf("Expected screenshot path %q, got %q", want, result.Metadata.ScreenshotPath)
+	}
+	if data, err := os.ReadFile(want); err != nil || !bytes.Equal(data, png) {
+		t.Errorf("Expected the PNG written to %s, got %q (%v)", want, data, err)
+	}
+
+	// A crashed renderer is left alone
+	crashed := newNetworkEventCapture()
+	crashed.handleEvent(&inspector.EventTargetCrashed{})
+	result = newResult(true)
+	c.saveScreenshot(taskCtx, hardDeadline{}, result, crashed)
+	if calls != 1 || result.Metadata.ScreenshotPath != "" {
+		t.Errorf("Expected no screenshot after a renderer crash, got %d calls, path %q", calls, result.Metadata.ScreenshotPath)
+	}
+}
+
+func TestSaveScreenshotIsBestEffort(t *testing.T) {
+	c := &ControllerImpl{
+		config: &config.BrowserConfig{ScreenshotDir: t.TempDir()},
+		screenshot: func(context.Context) ([]byte, error) {
+			return nil, errors.New("target closed")
+		},
+	}
+	result := &models.TestResult{
+		Site:  models.SiteInfo{Name: "example"},
+		Error: &models.ErrorInfo{ErrorType: "ERR_CONNECTION_REFUSED"},
+	}
+	c.saveScreenshot(context.Background(), hardDeadline{}, result, newNetworkEventCapture())
+
+	if result.Metadata.ScreenshotPath != "" {
+		t.Errorf("Expected no screenshot path, got %q", result.Metadata.ScreenshotPath)
+	}
+	if result.Error.ErrorType != "ERR_CONNECTION_REFUSED" {
+		t.Errorf("Expected the original error to be kept, got %q", result.Error.ErrorType)
+	}
+}
+
+func TestWriteScreenshotDirError(t *testing.T) {
+	// A file where the directory should be
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := writeScreenshot(filepath.Join(file, "shots"), &models.TestResult{}, []byte("png"))
+	if err == nil || !strings.Contains(err.Error(), "screenshot dir") {
+		t.Errorf("Expected a screenshot dir error, got %v", err)
+	}
+}
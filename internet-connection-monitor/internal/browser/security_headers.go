@@ -0,0 +1,168 @@
+package browser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Security header grading
+//
+// The document's response headers are already captured, so grading the
+// site's security headers costs no extra request. Each rule names a header
+// and its weight: a header that passes its quality check earns the full
+// weight, one that is present but weak earns half, and a missing one earns
+// nothing. The share of the total weight earned gives the grade.
+
+// hstsMinMaxAge is the shortest HSTS max-age counted as strong (180 days)
+const hstsMinMaxAge = 180 * 24 * 60 * 60
+
+// securityHeaderRule grades one response header
+type securityHeaderRule struct {
+	header string
+	weight int
+	strong func(value string) bool // nil: presence is enough
+}
+
+// defaultSecurityHeaderRules weigh the headers that matter most; they add up to 100
+var defaultSecurityHeaderRules = []securityHeaderRule{
+	{"Strict-Transport-Security", 30, strongHSTS},
+	{"Content-Security-Policy", 25, func(v string) bool {
+		return !strings.Contains(v, "'unsafe-inline'") && !strings.Contains(v, "'unsafe-eval'")
+	}},
+	{"X-Content-Type-Options", 15, func(v string) bool { return strings.EqualFold(strings.TrimSpace(v), "nosniff") }},
+	{"X-Frame-Options", 10, func(v string) bool {
+		v = strings.TrimSpace(v)
+		return strings.EqualFold(v, "DENY") || strings.EqualFold(v, "SAMEORIGIN")
+	}},
+	{"Referrer-Policy", 10, func(v string) bool {
+		v = strings.ToLower(v)
+		return !strings.Contains(v, "unsafe-url") && !strings.Contains(v, "no-referrer-when-downgrade")
+	}},
+	{"Permissions-Policy", 10, nil},
+}
+
+// strongHSTS requires a max-age of at least hstsMinMaxAge
+func strongHSTS(value string) bool {
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			age, err := strconv.Atoi(strings.Trim(arg, `"`))
+			return err == nil && age >= hstsMinMaxAge
+		}
+	}
+	return false
+}
+
+// securityHeaderGrader grades a document's headers against a rule set
+type securityHeaderGrader struct {
+	rules []securityHeaderRule
+}
+
+// newSecurityHeaderGrader builds a grader from the default rules and
+// overrides of the form "Header=weight" or "Header=weight=regexp". An override
+// replaces the default rule for its header (a weight of 0 drops it) or adds a
+// new one; with a regexp the header is strong only when its value matches.
+func newSecurityHeaderGrader(overrides []string) (*securityHeaderGrader, error) {
+	rules := append([]securityHeaderRule(nil), defaultSecurityHeaderRules...)
+	for _, o := range overrides {
+		rule, err := parseSecurityHeaderRule(o)
+		if err != nil {
+			return nil, err
+		}
+		replaced := false
+		for i := range rules {
+			if strings.EqualFold(rules[i].header, rule.header) {
+				rules[i] = rule
+				replaced = true
+			}
+		}
+		if !replaced {
+			rules = append(rules, rule)
+		}
+	}
+
+	g := &securityHeaderGrader{}
+	for _, r := range rules {
+		if r.weight > 0 {
+			g.rules = append(g.rules, r)
+		}
+	}
+	if len(g.rules) == 0 {
+		return nil, fmt.Errorf("security header rules: every rule has weight 0")
+	}
+	return g, nil
+}
+
+func parseSecurityHeaderRule(s string) (securityHeaderRule, error) {
+	parts := strings.SplitN(s, "=", 3)
+	header := strings.TrimSpace(parts[0])
+	if len(parts) < 2 || header == "" {
+		return securityHeaderRule{}, fmt.Errorf("security header rule %q is not Header=weight[=regexp]", s)
+	}
+	weight, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || weight < 0 {
+		return securityHeaderRule{}, fmt.Errorf("security header rule %q: weight must be a non-negative integer", s)
+	}
+	rule := securityHeaderRule{header: header, weight: weight}
+	if len(parts) == 3 {
+		re, err := regexp.Compile(parts[2])
+		if err != nil {
+			return securityHeaderRule{}, fmt.Errorf("security header rule %q: %w", s, err)
+		}
+		rule.strong = re.MatchString
+	}
+	return rule, nil
+}
+
+// grade records the grade and the missing and weak headers on the result
+func (g *securityHeaderGrader) grade(result *models.TestResult, headers network.Headers) {
+	var total, earned int
+	var missing, weak []string
+	for _, r := range g.rules {
+		total += 2 * r.weight // in half points, so weak headers score exactly
+		value, present := headerValue(headers, r.header)
+		switch {
+		case !present:
+			missing = append(missing, r.header)
+		case r.strong != nil && !r.strong(value):
+			weak = append(weak, r.header)
+			earned += r.weight
+		default:
+			earned += 2 * r.weight
+		}
+	}
+	result.SecurityHeaderGrade = securityGrade(float64(earned) / float64(total) * 100)
+	result.MissingSecurityHeaders = missing
+	result.WeakSecurityHeaders = weak
+}
+
+// headerValue looks a header up case-insensitively, reporting whether it was set
+func headerValue(headers network.Headers, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+// securityGrade maps a score out of 100 to a letter grade
+func securityGrade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 75:
+		return "B"
+	case score >= 60:
+		return "C"
+	case score >= 40:
+		return "D"
+	default:
+		return "F"
+	}
+}
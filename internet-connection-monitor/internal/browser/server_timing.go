@@ -0,0 +1,80 @@
+package browser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Server-Timing parsing
+//
+// Backends that emit Server-Timing report how long they spent on the request
+// themselves (database, cache, app), which separates server processing from
+// network latency when a site is slow. The header is a comma-separated list
+// of metrics, each a name followed by ";"-separated parameters:
+//
+//	Server-Timing: db;dur=53.2, cache;desc="Cache Read";dur=0.1, miss
+//
+// Only metrics with a dur parameter (milliseconds) are recorded. Chrome joins
+// repeated header lines with newlines, so those separate metrics too.
+
+// serverTimings returns the durations reported in the document's
+// Server-Timing header, keyed by metric name; nil when there are none
+func serverTimings(headers network.Headers) map[string]float64 {
+	value := harHeaderValue(headers, "server-timing")
+	if value == "" {
+		return nil
+	}
+
+	var timings map[string]float64
+	for _, metric := range splitUnquoted(value, ",\n") {
+		params := splitUnquoted(metric, ";")
+		name := strings.TrimSpace(params[0])
+		if name == "" || strings.ContainsAny(name, " \t=\"") {
+			continue
+		}
+		// The first occurrence of a metric wins, as it does for its dur
+		if _, seen := timings[name]; seen {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, val, ok := strings.Cut(param, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "dur") {
+				continue
+			}
+			dur, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(val), `"`), 64)
+			if err != nil || dur < 0 {
+				break
+			}
+			if timings == nil {
+				timings = make(map[string]float64)
+			}
+			timings[name] = dur
+			break
+		}
+	}
+	return timings
+}
+
+// splitUnquoted splits s at any of the separator bytes that are not inside a
+// quoted string (honouring backslash escapes)
+func splitUnquoted(s, separators string) []string {
+	var parts []string
+	inQuotes, escaped := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case inQuotes && c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && strings.IndexByte(separators, c) >= 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
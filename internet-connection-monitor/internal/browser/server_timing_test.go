@@ -0,0 +1,52 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestServerTimings(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers network.Headers
+		want    map[string]float64
+	}{
+		{"absent", network.Headers{"content-type": "text/html"}, nil},
+		{"single", network.Headers{"Server-Timing": "app;dur=47.2"}, map[string]float64{"app": 47.2}},
+		{
+			"several metrics with descriptions",
+			network.Headers{"server-timing": `db;dur=53, cache;desc="Cache Read";dur=23.2, miss`},
+			map[string]float64{"db": 53, "cache": 23.2},
+		},
+		{
+			"separators inside quoted descriptions",
+			network.Headers{"server-timing": `edge;desc="a, b; \"c\"";dur=1.5,origin;dur=9`},
+			map[string]float64{"edge": 1.5, "origin": 9},
+		},
+		{
+			"repeated header lines",
+			network.Headers{"server-timing": "cdn-cache;desc=HIT\nedge; dur=4 ,origin ; DUR = \"12\""},
+			map[string]float64{"edge": 4, "origin": 12},
+		},
+		{
+			"first occurrence wins",
+			network.Headers{"server-timing": "db;dur=1;dur=2, db;dur=3"},
+			map[string]float64{"db": 1},
+		},
+		{
+			"malformed metrics are skipped",
+			network.Headers{"server-timing": `;dur=1, bad name;dur=2, neg;dur=-1, nan;dur=x, ok;dur=0`},
+			map[string]float64{"ok": 0},
+		},
+		{"no durations", network.Headers{"server-timing": "miss, region;desc=eu"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverTimings(tt.headers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serverTimings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// sourceBinding pins connections to a network interface and/or local address,
+// from a site's Interface and SourceIP
+type sourceBinding struct {
+	iface    string
+	sourceIP net.IP // nil when only the interface is pinned
+}
+
+// parseSourceBinding validates a site's Interface and SourceIP. It returns
+// nil when the site sets neither.
+func parseSourceBinding(site models.SiteDefinition) (*sourceBinding, error) {
+	if site.Interface == "" && site.SourceIP == "" {
+		return nil, nil
+	}
+	b := &sourceBinding{iface: site.Interface}
+	if site.Interface != "" {
+		if !canBindToDevice {
+			return nil, fmt.Errorf("binding to interface %q is only supported on Linux", site.Interface)
+		}
+		if _, err := net.InterfaceByName(site.Interface); err != nil {
+			return nil, fmt.Errorf("interface %q: %w", site.Interface, err)
+		}
+	}
+	if site.SourceIP != "" {
+		if b.sourceIP = net.ParseIP(site.SourceIP); b.sourceIP == nil {
+			return nil, fmt.Errorf("source IP %q is not an IP address", site.SourceIP)
+		}
+	}
+	return b, nil
+}
+
+// family returns the address family suffix ("4", "6" or "") connections must
+// use: a source address can only reach destinations of its own family
+func (b *sourceBinding) family() string {
+	switch {
+	case b.sourceIP == nil:
+		return ""
+	case b.sourceIP.To4() != nil:
+		return "4"
+	default:
+		return "6"
+	}
+}
+
+// dialer returns a dialer for network ("tcp", "udp", ...) whose sockets are
+// bound as configured. DNS lookups go through the same binding so they
+// follow the pinned uplink too.
+func (b *sourceBinding) dialer(network string) *net.Dialer {
+	d := &net.Dialer{Timeout: proxyDialTimeout}
+	if b.sourceIP != nil {
+		if strings.HasPrefix(network, "udp") {
+			d.LocalAddr = &net.UDPAddr{IP: b.sourceIP}
+		} else {
+			d.LocalAddr = &net.TCPAddr{IP: b.sourceIP}
+		}
+	}
+	if b.iface != "" {
+		d.Control = bindToDeviceControl(b.iface)
+	}
+	return d
+}
+
+// dial connects to address over the binding, resolving the host over it as well
+func (b *sourceBinding) dial(ctx context.Context, address string) (net.Conn, error) {
+	d := b.dialer("tcp")
+	d.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, server string) (net.Conn, error) {
+			return b.dialer(network).DialContext(ctx, network, server)
+		},
+	}
+	return d.DialContext(ctx, "tcp"+b.family(), address)
+}
+
+// interfaceName returns the pinned interface, or else the name of the
+// interface that owns ip ("" if none does)
+func (b *sourceBinding) interfaceName(ip net.IP) string {
+	if b.iface != "" {
+		return b.iface
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
+
+// recordSourceBinding notes the interface and local address a bound test's
+// connections used. Chrome only sees the proxy fail a tunnel, so a failure
+// caused by the upstream connection is reported as the DNS or connection
+// error Chrome would have seen itself.
+func recordSourceBinding(result *models.TestResult, binding *sourceBinding, proxy *bindProxy) {
+	if result == nil {
+		return
+	}
+	ip := proxy.localIP()
+	if ip != nil {
+		result.Metadata.SourceIP = ip.String()
+	}
+	result.Metadata.Interface = binding.interfaceName(ip)
+
+	dialErr := proxy.firstDialError()
+	if dialErr == nil || result.Error == nil || result.Error.ErrorType != "ERR_TUNNEL_CONNECTION_FAILED" {
+		return
+	}
+	var dnsErr *net.DNSError
+	if errors.As(dialErr, &dnsErr) {
+		result.Error.ErrorType = "ERR_NAME_NOT_RESOLVED"
+		result.Error.FailurePhase = "dns"
+	} else {
+		result.Error.ErrorType = "ERR_CONNECTION_FAILED"
+		result.Error.FailurePhase = "tcp"
+	}
+	result.Error.ErrorMessage = fmt.Sprintf("%s (bound connection: %v)", result.Error.ErrorMessage, dialErr)
+}
@@ -0,0 +1,129 @@
+package browser
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestParseSourceBinding(t *testing.T) {
+	if b, err := parseSourceBinding(models.SiteDefinition{}); b != nil || err != nil {
+		t.Errorf("unpinned site: got %v, %v; want nil, nil", b, err)
+	}
+
+	b, err := parseSourceBinding(models.SiteDefinition{SourceIP: "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("valid source IP: %v", err)
+	}
+	if b.family() != "4" {
+		t.Errorf("expected IPv4 family for an IPv4 source, got %q", b.family())
+	}
+	if b, _ := parseSourceBinding(models.SiteDefinition{SourceIP: "2001:db8::1"}); b.family() != "6" {
+		t.Errorf("expected IPv6 family for an IPv6 source, got %q", b.family())
+	}
+
+	for _, site := range []models.SiteDefinition{
+		{SourceIP: "not-an-ip"},
+		{Interface: "no-such-interface0"},
+	} {
+		if _, err := parseSourceBinding(site); err == nil {
+			t.Errorf("expected an error for %+v", site)
+		}
+	}
+}
+
+// proxiedClient returns a client that sends everything through the bind proxy
+func proxiedClient(t *testing.T, proxy *bindProxy, tlsConfig *tls.Config) *http.Client {
+	t.Helper()
+	proxyURL, err := url.Parse(proxy.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL), TLSClientConfig: tlsConfig}}
+}
+
+func TestBindProxy(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	binding, err := parseSourceBinding(models.SiteDefinition{SourceIP: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy, err := startBindProxy(binding)
+	if err != nil {
+		t.Fatalf("startBindProxy: %v", err)
+	}
+	defer proxy.Close()
+
+	client := proxiedClient(t, proxy, secure.Client().Transport.(*http.Transport).TLSClientConfig)
+	for name, target := range map[string]string{"http": plain.URL, "https (CONNECT)": secure.URL} {
+		resp, err := client.Get(target)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		host, _, _ := net.SplitHostPort(string(body))
+		if resp.StatusCode != http.StatusOK || host != "127.0.0.1" {
+			t.Errorf("%s: got status %d from %q, want 200 from the source IP", name, resp.StatusCode, body)
+		}
+	}
+
+	if ip := proxy.localIP(); !ip.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected the bound source IP to be recorded, got %v", ip)
+	}
+	if proxy.firstDialError() != nil {
+		t.Errorf("unexpected dial error: %v", proxy.firstDialError())
+	}
+}
+
+func TestBindProxyDialFailure(t *testing.T) {
+	// A listener closed straight away leaves a port nothing answers on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+
+	binding, _ := parseSourceBinding(models.SiteDefinition{SourceIP: "127.0.0.1"})
+	proxy, err := startBindProxy(binding)
+	if err != nil {
+		t.Fatalf("startBindProxy: %v", err)
+	}
+	defer proxy.Close()
+
+	if _, err := proxiedClient(t, proxy, nil).Get("https://" + deadAddr); err == nil {
+		t.Fatal("expected the tunnel to fail")
+	}
+	if proxy.firstDialError() == nil {
+		t.Fatal("expected the failed upstream dial to be recorded")
+	}
+
+	// Chrome reports the failed tunnel; the result gets the real cause
+	result := &models.TestResult{Error: &models.ErrorInfo{
+		ErrorType:    "ERR_TUNNEL_CONNECTION_FAILED",
+		ErrorMessage: "net::ERR_TUNNEL_CONNECTION_FAILED",
+		FailurePhase: "unknown",
+	}}
+	recordSourceBinding(result, binding, proxy)
+	if result.Error.ErrorType != "ERR_CONNECTION_FAILED" || result.Error.FailurePhase != "tcp" {
+		t.Errorf("expected ERR_CONNECTION_FAILED in the tcp phase, got %s in %s", result.Error.ErrorType, result.Error.FailurePhase)
+	}
+	if result.Metadata.SourceIP != "" {
+		t.Errorf("expected no source IP without a connection, got %q", result.Metadata.SourceIP)
+	}
+}
@@ -0,0 +1,46 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// retryStartup runs test, and runs it again up to retries times while it
+// fails with a *StartupError, waiting backoff before the first retry and
+// doubling the wait each time. Only a Chrome that failed to start is retried:
+// a site's connectivity failure is a result, not an error, and is returned
+// as is. If Chrome never starts, the last *StartupError is returned with its
+// Attempts set, marking the failure as persistent rather than transient.
+func retryStartup(ctx context.Context, site string, retries int, backoff time.Duration, test func() (*models.TestResult, error)) (*models.TestResult, error) {
+	result, err := test()
+	for attempt := 1; ; attempt++ {
+		var startupErr *StartupError
+		if !errors.As(err, &startupErr) {
+			if attempt > 1 {
+				log.Printf("Chrome started testing %s after %d failed attempts", site, attempt-1)
+			}
+			return result, err
+		}
+		if attempt > retries {
+			startupErr.Attempts = attempt
+			return nil, err
+		}
+
+		wait := backoff << (attempt - 1)
+		log.Printf("Chrome failed to start testing %s, retrying in %v (%d of %d): %v", site, wait, attempt, retries, startupErr.Err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// No time left for another try
+			timer.Stop()
+			startupErr.Attempts = attempt
+			return nil, err
+		}
+		result, err = test()
+	}
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// flakyAllocator stands in for the browser launch: it fails to start Chrome
+// failures times, then succeeds
+func flakyAllocator(failures int) (test func() (*models.TestResult, error), calls *int) {
+	calls = new(int)
+	test = func() (*models.TestResult, error) {
+		*calls++
+		if *calls <= failures {
+			return nil, &StartupError{Site: "example", Err: errors.New("failed to allocate: fork/exec: resource temporarily unavailable")}
+		}
+		return &models.TestResult{Status: models.StatusInfo{Success: true}}, nil
+	}
+	return test, calls
+}
+
+func TestRetryStartupRecoversFromTransientFailure(t *testing.T) {
+	test, calls := flakyAllocator(1)
+
+	result, err := retryStartup(context.Background(), "example", 2, time.Millisecond, test)
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if result == nil || !result.Status.Success || *calls != 2 {
+		t.Errorf("Expected a successful result after 2 attempts, got %+v after %d", result, *calls)
+	}
+}
+
+func TestRetryStartupGivesUpOnPersistentFailure(t *testing.T) {
+	test, calls := flakyAllocator(10)
+
+	_, err := retryStartup(context.Background(), "example", 2, time.Millisecond, test)
+	var startupErr *StartupError
+	if !errors.As(err, &startupErr) || !errors.Is(err, ErrChromeStartupFailure) {
+		t.Fatalf("Expected a StartupError, got %v", err)
+	}
+	if *calls != 3 || startupErr.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, made %d, reported %d", *calls, startupErr.Attempts)
+	}
+
+	// Retries disabled
+	test, calls = flakyAllocator(10)
+	if _, err := retryStartup(context.Background(), "example", 0, time.Millisecond, test); err == nil || *calls != 1 {
+		t.Errorf("Expected a single attempt without retries, made %d (%v)", *calls, err)
+	}
+}
+
+func TestRetryStartupDoesNotRetryConnectivityFailures(t *testing.T) {
+	var calls int
+	failed := &models.TestResult{Error: &models.ErrorInfo{ErrorType: "ERR_CONNECTION_REFUSED"}}
+	crash := &RendererCrashError{Site: "example", Err: errors.New("target crashed")}
+	for _, tt := range []struct {
+		result *models.TestResult
+		err    error
+	}{
+		{result: failed},
+		{err: crash},
+	} {
+		calls = 0
+		result, err := retryStartup(context.Background(), "example", 2, time.Millisecond, func() (*models.TestResult, error) {
+			calls++
+			return tt.result, tt.err
+		})
+		if calls != 1 || result != tt.result || err != tt.err {
+			t.Errorf("Expected %v / %v to be returned without a retry, got %d calls", tt.result, tt.err, calls)
+		}
+	}
+}
+
+func TestRetryStartupStopsAtDeadline(t *testing.T) {
+	test, calls := flakyAllocator(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := retryStartup(ctx, "example", 2, time.Hour, test)
+	var startupErr *StartupError
+	if !errors.As(err, &startupErr) || startupErr.Attempts != 1 || *calls != 1 {
+		t.Errorf("Expected the first StartupError once the deadline passed, got %v after %d calls", err, *calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the backoff to end at the deadline, waited %v", elapsed)
+	}
+}
@@ -0,0 +1,51 @@
+package browser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultExpectedStatusRanges applies when a site sets no ExpectedStatusRanges
+var defaultExpectedStatusRanges = []string{"200-299"}
+
+// statusInRanges reports whether an HTTP status matches any of the ranges.
+// Each range is either a single code ("401") or an inclusive span ("200-299").
+func statusInRanges(status int, ranges []string) (bool, error) {
+	if len(ranges) == 0 {
+		ranges = defaultExpectedStatusRanges
+	}
+
+	matched := false
+	for _, r := range ranges {
+		low, high, err := parseStatusRange(r)
+		if err != nil {
+			return false, err
+		}
+		if status >= low && status <= high {
+			matched = true
+		}
+	}
+	return matched, nil
+}
+
+func parseStatusRange(r string) (int, int, error) {
+	lowText, highText, isSpan := strings.Cut(strings.TrimSpace(r), "-")
+
+	low, err := strconv.Atoi(strings.TrimSpace(lowText))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid status range %q", r)
+	}
+	high := low
+	if isSpan {
+		high, err = strconv.Atoi(strings.TrimSpace(highText))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid status range %q", r)
+		}
+	}
+
+	if low < 100 || high > 599 || low > high {
+		return 0, 0, fmt.Errorf("invalid status range %q", r)
+	}
+	return low, high, nil
+}
@@ -0,0 +1,41 @@
+package browser
+
+import "testing"
+
+func TestStatusInRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		ranges []string
+		want   bool
+	}{
+		{"default accepts 200", 200, nil, true},
+		{"default accepts 204", 204, nil, true},
+		{"default rejects 404", 404, nil, false},
+		{"default rejects 301", 301, nil, false},
+		{"single code", 401, []string{"200-299", "401"}, true},
+		{"span", 302, []string{"300-399"}, true},
+		{"outside all ranges", 500, []string{"200-299", "401"}, false},
+		{"whitespace tolerated", 204, []string{" 200 - 299 "}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := statusInRanges(tt.status, tt.ranges)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("statusInRanges(%d, %v) = %v, want %v", tt.status, tt.ranges, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusInRanges_Invalid(t *testing.T) {
+	for _, r := range []string{"2xx", "299-200", "200-", "42", "200-700"} {
+		if _, err := statusInRanges(200, []string{r}); err == nil {
+			t.Errorf("expected error for range %q", r)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build browser
+
+package browser
+
+import "github.com/chromedp/chromedp"
+
+// IgnoreCertificateErrors makes Chrome accept any TLS certificate so
+// browser-tagged tests can point the controller at httptest's self-signed
+// servers. It is only built with the browser tag and must be called before
+// the first TestSite.
+func (c *ControllerImpl) IgnoreCertificateErrors() {
+	c.allocatorOpts = append(c.allocatorOpts, chromedp.Flag("ignore-certificate-errors", true))
+}
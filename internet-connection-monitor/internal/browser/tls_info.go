@@ -0,0 +1,22 @@
+package browser
+
+import (
+	"github.com/chromedp/cdproto/network"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// tlsInfo returns the negotiated protocol and cipher suite in a document's
+// security details, nil for a response without any (plain HTTP)
+func tlsInfo(details *network.SecurityDetails) *models.TLSInfo {
+	if details == nil {
+		return nil
+	}
+	return &models.TLSInfo{
+		Version:          details.Protocol,
+		Cipher:           details.Cipher,
+		MAC:              details.Mac,
+		KeyExchange:      details.KeyExchange,
+		KeyExchangeGroup: details.KeyExchangeGroup,
+	}
+}
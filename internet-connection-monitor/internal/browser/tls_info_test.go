@@ -0,0 +1,45 @@
+package browser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestTLSInfo(t *testing.T) {
+	var ev network.EventResponseReceived
+	if err := json.Unmarshal([]byte(responseWithCertificate), &ev); err != nil {
+		t.Fatalf("Failed to parse the response event: %v", err)
+	}
+	capture := newNetworkEventCapture()
+	capture.handleEvent(&ev)
+
+	got := tlsInfo(capture.GetSecurityDetails())
+	want := models.TLSInfo{Version: "TLS 1.3", Cipher: "AES_128_GCM", KeyExchangeGroup: "X25519"}
+	if got == nil || *got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestTLSInfo_PlainHTTP(t *testing.T) {
+	capture := newNetworkEventCapture()
+	capture.handleEvent(&network.EventResponseReceived{
+		RequestID: "1000.1",
+		Type:      network.ResourceTypeDocument,
+		Response: &network.Response{
+			URL:      "http://example.com/",
+			Status:   200,
+			Protocol: "http/1.1",
+		},
+	})
+
+	if got := tlsInfo(capture.GetSecurityDetails()); got != nil {
+		t.Errorf("Expected no TLS details for plain HTTP, got %+v", got)
+	}
+	if got := certificateInfo(capture.GetSecurityDetails(), capture.receivedAt, 30); got != nil {
+		t.Errorf("Expected no certificate for plain HTTP, got %+v", got)
+	}
+}
@@ -0,0 +1,106 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Test modes (SiteDefinition.Mode)
+const (
+	// testModeFull loads the page and all of its subresources (default)
+	testModeFull = "full"
+
+	// testModeTTFB stops loading as soon as the main document's response
+	// arrives, so only DNS, TCP, TLS, and TTFB are measured
+	testModeTTFB = "ttfb"
+
+	// testModeRealistic is a full page load that may reuse connections. With
+	// BrowserConfig.UsePool it runs in a pooled browser shared between tests.
+	testModeRealistic = "realistic"
+)
+
+// parseTestMode validates a site's test mode, defaulting to full
+func parseTestMode(mode string) (string, error) {
+	switch mode {
+	case "", testModeFull:
+		return testModeFull, nil
+	case testModeTTFB:
+		return testModeTTFB, nil
+	case testModeRealistic:
+		return testModeRealistic, nil
+	default:
+		return "", fmt.Errorf("unknown test mode %q (expected %q, %q or %q)", mode, testModeFull, testModeTTFB, testModeRealistic)
+	}
+}
+
+// navigateFirstByte starts navigating to url, waits until the main document's
+// response headers have arrived, then stops the page load so no body or
+// subresources are fetched. Navigation errors are reported the same way as
+// chromedp.Navigate so they classify identically.
+func navigateFirstByte(ctx context.Context, url string, capture *NetworkEventCapture) error {
+	_, _, errorText, _, err := page.Navigate(url).Do(ctx)
+	switch {
+	case err != nil:
+		return err
+	case errorText != "":
+		return fmt.Errorf("page load error %s", errorText)
+	}
+
+	// Page.navigate returns at commit, which can race the listener goroutine
+	select {
+	case <-capture.DocumentResponded():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return page.StopLoading().Do(ctx)
+}
+
+// ttfbTimings converts the main document's network timing into our metrics.
+//
+// Navigation Timing is unavailable once the load is stopped, so the early
+// phases come from the same underlying Chrome timing record, mapped the way
+// extractTimings maps the Navigation Timing entry: requestStart is sendStart,
+// secureConnectionStart is sslStart, and responseStart is receiveHeadersStart.
+func ttfbTimings(t *network.ResourceTiming, totalMs int64) models.TimingMetrics {
+	timings := models.TimingMetrics{
+		TotalDurationMs: totalMs,
+	}
+
+	if t == nil {
+		return timings
+	}
+
+	// DNS lookup duration (-1 when the lookup was skipped)
+	if t.DNSStart >= 0 && t.DNSEnd >= 0 {
+		timings.DNSLookupMs = int64Ptr(int64(t.DNSEnd - t.DNSStart))
+	}
+
+	// TCP connection duration, excluding TLS for HTTPS
+	if t.ConnectStart >= 0 && t.ConnectEnd >= 0 {
+		if t.SslStart >= 0 {
+			timings.TCPConnectionMs = int64Ptr(int64(t.SslStart - t.ConnectStart))
+		} else {
+			timings.TCPConnectionMs = int64Ptr(int64(t.ConnectEnd - t.ConnectStart))
+		}
+	}
+
+	// TLS handshake duration (only for HTTPS connections)
+	if t.SslStart >= 0 && t.ConnectEnd > t.SslStart {
+		timings.TLSHandshakeMs = int64Ptr(int64(t.ConnectEnd - t.SslStart))
+	}
+
+	// Time to first byte: from request start to response start
+	responseStart := t.ReceiveHeadersStart
+	if responseStart <= 0 {
+		responseStart = t.ReceiveHeadersEnd // older Chrome only reports the end
+	}
+	if t.SendStart >= 0 && responseStart > 0 {
+		timings.TimeToFirstByteMs = int64Ptr(int64(responseStart - t.SendStart))
+	}
+
+	return timings
+}
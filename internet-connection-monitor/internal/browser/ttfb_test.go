@@ -0,0 +1,98 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestParseTestMode(t *testing.T) {
+	for _, mode := range []string{"", "full"} {
+		if got, err := parseTestMode(mode); err != nil || got != testModeFull {
+			t.Errorf("parseTestMode(%q) = %q, %v; want full", mode, got, err)
+		}
+	}
+	if got, err := parseTestMode("ttfb"); err != nil || got != testModeTTFB {
+		t.Errorf("parseTestMode(ttfb) = %q, %v; want ttfb", got, err)
+	}
+	if got, err := parseTestMode("realistic"); err != nil || got != testModeRealistic {
+		t.Errorf("parseTestMode(realistic) = %q, %v; want realistic", got, err)
+	}
+	if _, err := parseTestMode("TTFB"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+// The early phases must match what a full page load reports for the same request
+func TestTTFBTimingsMatchNavigationTiming(t *testing.T) {
+	// HTTPS request starting 100ms after navigationStart
+	timing := &network.ResourceTiming{
+		DNSStart:            0,
+		DNSEnd:              12,
+		ConnectStart:        12,
+		SslStart:            30,
+		SslEnd:              75,
+		ConnectEnd:          75,
+		SendStart:           76,
+		SendEnd:             77,
+		ReceiveHeadersStart: 140,
+		ReceiveHeadersEnd:   141,
+	}
+	const offset = 100
+	navigationEntry := map[string]interface{}{
+		"domainLookupStart":     offset + timing.DNSStart,
+		"domainLookupEnd":       offset + timing.DNSEnd,
+		"connectStart":          offset + timing.ConnectStart,
+		"connectEnd":            offset + timing.ConnectEnd,
+		"secureConnectionStart": offset + timing.SslStart,
+		"requestStart":          offset + timing.SendStart,
+		"responseStart":         offset + timing.ReceiveHeadersStart,
+	}
+
+	full := extractTimings(navigationEntry, 500)
+	ttfb := ttfbTimings(timing, 500)
+
+	for name, pair := range map[string][2]*int64{
+		"dns":  {full.DNSLookupMs, ttfb.DNSLookupMs},
+		"tcp":  {full.TCPConnectionMs, ttfb.TCPConnectionMs},
+		"tls":  {full.TLSHandshakeMs, ttfb.TLSHandshakeMs},
+		"ttfb": {full.TimeToFirstByteMs, ttfb.TimeToFirstByteMs},
+	} {
+		if pair[0] == nil || pair[1] == nil {
+			t.Fatalf("%s: expected both modes to report a value, got full=%v ttfb=%v", name, pair[0], pair[1])
+		}
+		if *pair[0] != *pair[1] {
+			t.Errorf("%s: full mode %d ms, ttfb mode %d ms", name, *pair[0], *pair[1])
+		}
+	}
+
+	if ttfb.DOMContentLoadedMs != nil || ttfb.FullPageLoadMs != nil || ttfb.NetworkIdleMs != nil {
+		t.Error("ttfb mode must not report page load timings")
+	}
+	if ttfb.TotalDurationMs != 500 {
+		t.Errorf("Expected total duration 500, got %d", ttfb.TotalDurationMs)
+	}
+}
+
+func TestTTFBTimingsReusedConnection(t *testing.T) {
+	// Chrome reports -1 for phases that did not happen
+	timing := &network.ResourceTiming{
+		DNSStart: -1, DNSEnd: -1,
+		ConnectStart: -1, ConnectEnd: -1,
+		SslStart: -1, SslEnd: -1,
+		SendStart:         1,
+		ReceiveHeadersEnd: 21,
+	}
+
+	got := ttfbTimings(timing, 30)
+	if got.DNSLookupMs != nil || got.TCPConnectionMs != nil || got.TLSHandshakeMs != nil {
+		t.Errorf("Expected no connection timings, got %+v", got)
+	}
+	if got.TimeToFirstByteMs == nil || *got.TimeToFirstByteMs != 20 {
+		t.Errorf("Expected TTFB 20 from receiveHeadersEnd, got %v", got.TimeToFirstByteMs)
+	}
+
+	if got := ttfbTimings(nil, 30); got.TotalDurationMs != 30 || got.TimeToFirstByteMs != nil {
+		t.Errorf("Expected only the total duration without timing data, got %+v", got)
+	}
+}
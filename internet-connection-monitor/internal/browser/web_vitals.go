@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// webVitalsObserverJS runs in every new document before the page's own
+// scripts. LCP and layout shifts are only reported to a PerformanceObserver,
+// never through performance.getEntriesByType, so they have to be collected
+// as the page loads. CLS follows the current definition: shifts within 1s of
+// each other (for at most 5s) form a session, and the worst session counts.
+const webVitalsObserverJS = `
+(function() {
+	const vitals = {};
+	const observers = [];
+	const observe = (type, handle) => {
+		try {
+			const observer = new PerformanceObserver(list => list.getEntries().forEach(handle));
+			observer.observe({type: type, buffered: true});
+			observers.push({observer: observer, handle: handle});
+			return true;
+		} catch (e) {
+			return false;
+		}
+	};
+
+	observe('paint', entry => {
+		if (entry.name === 'first-contentful-paint') vitals.fcp = entry.startTime;
+	});
+	observe('largest-contentful-paint', entry => {
+		vitals.lcp = entry.renderTime || entry.loadTime || entry.startTime;
+	});
+	let session = 0, sessionStart = 0, lastShift = 0;
+	if (observe('layout-shift', entry => {
+		if (entry.hadRecentInput) return;
+		if (session > 0 && entry.startTime - lastShift < 1000 && entry.startTime - sessionStart < 5000) {
+			session += entry.value;
+		} else {
+			session = entry.value;
+			sessionStart = entry.startTime;
+		}
+		lastShift = entry.startTime;
+		vitals.cls = Math.max(vitals.cls, session);
+	})) {
+		vitals.cls = 0;
+	}
+
+	Object.defineProperty(window, '__connectionMonitorWebVitals', {
+		value: () => {
+			// Entries not yet delivered to the callbacks
+			observers.forEach(o => o.observer.takeRecords().forEach(o.handle));
+			return {lcp: vitals.lcp, fcp: vitals.fcp, cls: vitals.cls};
+		},
+	});
+})();
+`
+
+// webVitalsJS reads what webVitalsObserverJS collected, null if it never ran
+const webVitalsJS = `
+(function() {
+	const read = window.__connectionMonitorWebVitals;
+	return typeof read === 'function' ? read() : null;
+})()
+`
+
+// observeWebVitals returns an action that installs webVitalsObserverJS for
+// the documents loaded after it
+func observeWebVitals() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(webVitalsObserverJS).Do(ctx)
+		return err
+	})
+}
+
+// coreWebVitals converts the measurements webVitalsJS returned, nil when
+// there are none
+func coreWebVitals(data map[string]interface{}) *models.CoreWebVitals {
+	// Missing entries come back as absent keys (undefined isn't JSON)
+	getFloat := func(key string) (float64, bool) {
+		f, ok := data[key].(float64)
+		return f, ok && f >= 0
+	}
+
+	var vitals models.CoreWebVitals
+	found := false
+	if lcp, ok := getFloat("lcp"); ok {
+		vitals.LargestContentfulPaintMs = int64Ptr(int64(lcp))
+		found = true
+	}
+	if fcp, ok := getFloat("fcp"); ok {
+		vitals.FirstContentfulPaintMs = int64Ptr(int64(fcp))
+		found = true
+	}
+	if cls, ok := getFloat("cls"); ok {
+		vitals.CumulativeLayoutShift = &cls
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &vitals
+}
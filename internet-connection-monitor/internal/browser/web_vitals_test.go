@@ -0,0 +1,48 @@
+package browser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCoreWebVitals(t *testing.T) {
+	// As webVitalsJS returns them: undefined measurements are dropped from
+	// the JSON
+	parse := func(raw string) map[string]interface{} {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &data); err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	vitals := coreWebVitals(parse(`{"lcp": 1234.5, "fcp": 456.7, "cls": 0.125}`))
+	if vitals == nil {
+		t.Fatal("Expected vitals")
+	}
+	if vitals.LargestContentfulPaintMs == nil || *vitals.LargestContentfulPaintMs != 1234 {
+		t.Errorf("Expected LCP 1234ms, got %v", vitals.LargestContentfulPaintMs)
+	}
+	if vitals.FirstContentfulPaintMs == nil || *vitals.FirstContentfulPaintMs != 456 {
+		t.Errorf("Expected FCP 456ms, got %v", vitals.FirstContentfulPaintMs)
+	}
+	if vitals.CumulativeLayoutShift == nil || *vitals.CumulativeLayoutShift != 0.125 {
+		t.Errorf("Expected CLS 0.125, got %v", vitals.CumulativeLayoutShift)
+	}
+
+	// A page that never shifted still has a CLS, of 0; a blank page has no paints
+	vitals = coreWebVitals(parse(`{"cls": 0}`))
+	if vitals == nil || vitals.CumulativeLayoutShift == nil || *vitals.CumulativeLayoutShift != 0 {
+		t.Fatalf("Expected a CLS of 0, got %+v", vitals)
+	}
+	if vitals.LargestContentfulPaintMs != nil || vitals.FirstContentfulPaintMs != nil {
+		t.Errorf("Expected no paint metrics, got LCP %v, FCP %v", vitals.LargestContentfulPaintMs, vitals.FirstContentfulPaintMs)
+	}
+
+	// Nothing measured, or the observer never ran
+	for _, raw := range []string{`{}`, `null`, `{"lcp": "soon", "cls": -1}`} {
+		if vitals := coreWebVitals(parse(raw)); vitals != nil {
+			t.Errorf("Expected no vitals for %s, got %+v", raw, vitals)
+		}
+	}
+}
@@ -8,21 +8,28 @@ import (
 
 // Config represents the complete application configuration
 type Config struct {
-	General       GeneralConfig       `yaml:"general"`
-	Sites         SitesConfig         `yaml:"sites"`
-	Browser       BrowserConfig       `yaml:"browser"`
-	Logging       LoggingConfig       `yaml:"logging"`
-	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
-	SNMP          SNMPConfig          `yaml:"snmp"`
-	Prometheus    PrometheusConfig    `yaml:"prometheus"`
-	Advanced      AdvancedConfig      `yaml:"advanced"`
+	General        GeneralConfig        `yaml:"general"`
+	Sites          SitesConfig          `yaml:"sites"`
+	Browser        BrowserConfig        `yaml:"browser"`
+	Logging        LoggingConfig        `yaml:"logging"`
+	Elasticsearch  ElasticsearchConfig  `yaml:"elasticsearch"`
+	SNMP           SNMPConfig           `yaml:"snmp"`
+	Dedup          DedupConfig          `yaml:"dedup"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	NetworkDown    NetworkDownConfig    `yaml:"network_down"`
+	Prometheus     PrometheusConfig     `yaml:"prometheus"`
+	Syslog         SyslogConfig         `yaml:"syslog"`
+	SQLite         SQLiteConfig         `yaml:"sqlite"`
+	Advanced       AdvancedConfig       `yaml:"advanced"`
 }
 
 // GeneralConfig contains general application settings
 type GeneralConfig struct {
-	InterTestDelay  time.Duration `yaml:"inter_test_delay"`
-	GlobalTimeout   time.Duration `yaml:"global_timeout"`
-	CacheSize       int           `yaml:"cache_size"`
+	InterTestDelay time.Duration `yaml:"inter_test_delay"`
+	GlobalTimeout  time.Duration `yaml:"global_timeout"`
+	CacheSize      int           `yaml:"cache_size"`
+
+	AvailabilityThreshold float64 `yaml:"availability_threshold"`
 }
 
 // SitesConfig contains the list of sites to monitor
@@ -32,13 +39,57 @@ type SitesConfig struct {
 
 // BrowserConfig contains browser-specific settings
 type BrowserConfig struct {
-	Headless          bool   `yaml:"headless"`
-	UserAgent         string `yaml:"user_agent"`
-	WindowWidth       int    `yaml:"window_width"`
-	WindowHeight      int    `yaml:"window_height"`
-	DisableImages     bool   `yaml:"disable_images"`
-	DisableJavaScript bool   `yaml:"disable_javascript"`
-	ClearCookies      bool   `yaml:"clear_cookies"`
+	Headless            bool          `yaml:"headless"`
+	UserAgent           string        `yaml:"user_agent"`
+	WindowWidth         int           `yaml:"window_width"`
+	WindowHeight        int           `yaml:"window_height"`
+	DisableImages       bool          `yaml:"disable_images"`
+	DisableJavaScript   bool          `yaml:"disable_javascript"`
+	ClearCookies        bool          `yaml:"clear_cookies"`
+	ChromePolicyDir     string        `yaml:"chrome_policy_dir"`
+	CaptureHAROnError   bool          `yaml:"capture_har_on_error"`
+	HARDir              string        `yaml:"har_dir"`
+	ScreenshotOnFailure bool          `yaml:"screenshot_on_failure"`
+	ScreenshotDir       string        `yaml:"screenshot_dir"`
+	DoHTemplate         string        `yaml:"doh_template"`
+	CheckClockSkew      bool          `yaml:"check_clock_skew"`
+	ClockSkewThreshold  time.Duration `yaml:"clock_skew_threshold"`
+	EgressIPEndpoint    string        `yaml:"egress_ip_endpoint"`
+	EgressIPInterval    time.Duration `yaml:"egress_ip_interval"`
+	MaxChromeInstances  int           `yaml:"max_chrome_instances"`
+	HardDeadline        time.Duration `yaml:"hard_deadline"`
+	UsePool             bool          `yaml:"use_pool"`
+	PoolSize            int           `yaml:"pool_size"`
+	PoolMaxReuse        int           `yaml:"pool_max_reuse"`
+
+	CaptivePortalURL               string        `yaml:"captive_portal_url"`
+	CaptivePortalExpectedStatus    int           `yaml:"captive_portal_expected_status"`
+	CaptivePortalExpectedBody      string        `yaml:"captive_portal_expected_body"`
+	CaptivePortalInterval          time.Duration `yaml:"captive_portal_interval"`
+	SuppressSuccessOnCaptivePortal bool          `yaml:"suppress_success_on_captive_portal"`
+	CaptivePortalCheckHost         bool          `yaml:"captive_portal_check_host"`
+	CaptivePortalProbeStrings      []string      `yaml:"captive_portal_probe_strings"`
+
+	RedactPatterns []string `yaml:"redact_patterns"`
+
+	GradeSecurityHeaders bool     `yaml:"grade_security_headers"`
+	SecurityHeaderRules  []string `yaml:"security_header_rules"`
+
+	ProxyURL string `yaml:"proxy_url"`
+
+	ForceFreshConnections bool `yaml:"force_fresh_connections"`
+	EnableHTTP2           bool `yaml:"enable_http2"`
+	EnableHTTP3           bool `yaml:"enable_http3"`
+
+	CertExpiryWarnDays int `yaml:"cert_expiry_warn_days"`
+
+	CaptureHAR bool `yaml:"capture_har"`
+
+	CaptureResponseHeaders  bool     `yaml:"capture_response_headers"`
+	ResponseHeaderAllowlist []string `yaml:"response_header_allowlist"`
+
+	StartupRetries      int           `yaml:"startup_retries"`
+	StartupRetryBackoff time.Duration `yaml:"startup_retry_backoff"`
 }
 
 // LoggingConfig contains logging settings
@@ -69,23 +120,93 @@ type ElasticsearchConfig struct {
 
 // SNMPConfig contains SNMP agent settings
 type SNMPConfig struct {
-	Enabled        bool   `yaml:"enabled"`
-	Port           int    `yaml:"port"`
-	Community      string `yaml:"community"`
-	ListenAddress  string `yaml:"listen_address"`
-	EnterpriseOID  string `yaml:"enterprise_oid"`
+	Enabled           bool                `yaml:"enabled"`
+	Port              int                 `yaml:"port"`
+	Community         string              `yaml:"community"`
+	ListenAddress     string              `yaml:"listen_address"`
+	EnterpriseOID     string              `yaml:"enterprise_oid"`
+	SiteHistorySize   int                 `yaml:"site_history_size"`
+	AllowAnyCommunity bool                `yaml:"allow_any_community"`
+	DrainPath         string              `yaml:"drain_path"`
+	SiteTTL           time.Duration       `yaml:"site_ttl"`
+	WriteCommunity    string              `yaml:"write_community"`
+	PruneRemovedSites bool                `yaml:"prune_removed_sites"`
+	Views             map[string][]string `yaml:"views"`
+	SysDescr          string              `yaml:"sys_descr"`
+	SysContact        string              `yaml:"sys_contact"`
+	SysName           string              `yaml:"sys_name"`
+	SysLocation       string              `yaml:"sys_location"`
+	EWMAAlpha         float64             `yaml:"ewma_alpha"`
+	SecurityLevel     string              `yaml:"security_level"`
+	Username          string              `yaml:"username"`
+	AuthProtocol      string              `yaml:"auth_protocol"`
+	AuthPassphrase    string              `yaml:"auth_passphrase"`
+	PrivProtocol      string              `yaml:"priv_protocol"`
+	PrivPassphrase    string              `yaml:"priv_passphrase"`
+	TrapTargets       []string            `yaml:"trap_targets"`
+	TrapCommunity     string              `yaml:"trap_community"`
+	AllowedCIDRs      []string            `yaml:"allowed_cidrs"`
+	Transport         string              `yaml:"transport"`
+	DualStack         bool                `yaml:"dual_stack"`
+	PercentileSamples int                 `yaml:"percentile_samples"`
+	MaxResponseSize   int                 `yaml:"max_response_size"`
+	MaxTrackedSites   int                 `yaml:"max_tracked_sites"`
+	SuccessWindow     time.Duration       `yaml:"success_window"`
+	RateLimit         float64             `yaml:"rate_limit"`
+	RateLimitBurst    int                 `yaml:"rate_limit_burst"`
+}
+
+// DedupConfig contains failure deduplication settings for result-level outputs
+type DedupConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+}
+
+// CircuitBreakerConfig contains settings for backing off persistently failing sites
+type CircuitBreakerConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	FailureThreshold int           `yaml:"failure_threshold"`
+	InitialBackoff   time.Duration `yaml:"initial_backoff"`
+	MaxBackoff       time.Duration `yaml:"max_backoff"`
+}
+
+// NetworkDownConfig contains settings for skipping the rest of a test cycle
+// when its first tests all fail DNS
+type NetworkDownConfig struct {
+	Enabled             bool `yaml:"enabled"`
+	DNSFailureThreshold int  `yaml:"dns_failure_threshold"`
 }
 
 // PrometheusConfig contains Prometheus exporter settings
 type PrometheusConfig struct {
-	Enabled          bool    `yaml:"enabled"`
-	Port             int     `yaml:"port"`
-	Path             string  `yaml:"path"`
-	ListenAddress    string  `yaml:"listen_address"`
-	IncludeGoMetrics bool    `yaml:"include_go_metrics"`
+	Enabled          bool      `yaml:"enabled"`
+	Port             int       `yaml:"port"`
+	Path             string    `yaml:"path"`
+	ListenAddress    string    `yaml:"listen_address"`
+	IncludeGoMetrics bool      `yaml:"include_go_metrics"`
 	LatencyBuckets   []float64 `yaml:"latency_buckets"`
 }
 
+// SyslogConfig contains syslog (RFC 5424) output settings
+type SyslogConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Address       string `yaml:"address"`
+	Protocol      string `yaml:"protocol"`
+	Facility      string `yaml:"facility"`
+	AppName       string `yaml:"app_name"`
+	TLSSkipVerify bool   `yaml:"tls_skip_verify"`
+}
+
+// SQLiteConfig contains settings for storing results in a local SQLite database
+type SQLiteConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Path          string        `yaml:"path"`
+	RetentionDays int           `yaml:"retention_days"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	PruneInterval time.Duration `yaml:"prune_interval"`
+}
+
 // AdvancedConfig contains advanced/debugging settings
 type AdvancedConfig struct {
 	PProfEnabled             bool          `yaml:"pprof_enabled"`
@@ -99,6 +220,10 @@ type AdvancedConfig struct {
 	CaptureScreenshots       bool          `yaml:"capture_screenshots"`
 	ScreenshotPath           string        `yaml:"screenshot_path"`
 	DNSServers               []string      `yaml:"dns_servers"`
+
+	OutputFailurePolicy string        `yaml:"output_failure_policy"`
+	OutputFailureAfter  time.Duration `yaml:"output_failure_after"`
+	OutputFallbackFile  string        `yaml:"output_fallback_file"`
 }
 
 // Load loads configuration from file and environment variables
@@ -134,13 +259,30 @@ func DefaultConfig() *Config {
 			InterTestDelay: 2 * time.Second,
 			GlobalTimeout:  30 * time.Second,
 			CacheSize:      100,
+
+			AvailabilityThreshold: 0.5,
 		},
 		Browser: BrowserConfig{
-			Headless:     true,
-			UserAgent:    "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			WindowWidth:  1920,
-			WindowHeight: 1080,
-			ClearCookies: true,
+			Headless:           true,
+			UserAgent:          "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			WindowWidth:        1920,
+			WindowHeight:       1080,
+			ClearCookies:       true,
+			ChromePolicyDir:    "/etc/chromium/policies/managed",
+			HARDir:             "/tmp/internet-monitor-har",
+			ScreenshotDir:      "/tmp/internet-monitor-screenshots",
+			ClockSkewThreshold: 2 * time.Second,
+			EgressIPInterval:   5 * time.Minute,
+			PoolSize:           2,
+			PoolMaxReuse:       50,
+
+			ForceFreshConnections: true,
+
+			CaptivePortalExpectedStatus: 204,
+			CaptivePortalInterval:       time.Minute,
+
+			StartupRetries:      2,
+			StartupRetryBackoff: time.Second,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -155,11 +297,26 @@ func DefaultConfig() *Config {
 			RetryBackoff:  1 * time.Second,
 		},
 		SNMP: SNMPConfig{
-			Enabled:       true,
-			Port:          161,
-			Community:     "public",
-			ListenAddress: "0.0.0.0",
-			EnterpriseOID: ".1.3.6.1.4.1.99999",
+			Enabled:         true,
+			Port:            161,
+			Community:       "public",
+			ListenAddress:   "0.0.0.0",
+			EnterpriseOID:   ".1.3.6.1.4.1.99999",
+			SiteHistorySize: 10,
+		},
+		Dedup: DedupConfig{
+			Enabled:           false,
+			HeartbeatInterval: 5 * time.Minute,
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:          false,
+			FailureThreshold: 5,
+			InitialBackoff:   1 * time.Minute,
+			MaxBackoff:       30 * time.Minute,
+		},
+		NetworkDown: NetworkDownConfig{
+			Enabled:             false,
+			DNSFailureThreshold: 3,
 		},
 		Prometheus: PrometheusConfig{
 			Enabled:          true,
@@ -169,6 +326,20 @@ func DefaultConfig() *Config {
 			IncludeGoMetrics: true,
 			LatencyBuckets:   []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
 		},
+		Syslog: SyslogConfig{
+			Enabled:  false,
+			Protocol: "udp",
+			Facility: "local0",
+			AppName:  "internet-connection-monitor",
+		},
+		SQLite: SQLiteConfig{
+			Enabled:       false,
+			Path:          "data/results.db",
+			RetentionDays: 30,
+			BatchSize:     50,
+			FlushInterval: 10 * time.Second,
+			PruneInterval: 1 * time.Hour,
+		},
 		Advanced: AdvancedConfig{
 			HealthCheckEnabled:       true,
 			HealthCheckPort:          8080,
@@ -177,6 +348,10 @@ func DefaultConfig() *Config {
 			ShutdownTimeout:          30 * time.Second,
 			MaxConcurrentBrowsers:    1,
 			ScreenshotPath:           "/tmp/screenshots",
+
+			OutputFailurePolicy: "continue",
+			OutputFailureAfter:  5 * time.Minute,
+			OutputFallbackFile:  "/tmp/internet-monitor-fallback.jsonl",
 		},
 	}
 }
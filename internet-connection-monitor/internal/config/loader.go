@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -54,6 +55,208 @@ func LoadFromEnv(cfg *Config) error {
 		cfg.Browser.UserAgent = v
 	}
 
+	if v := os.Getenv("BROWSER_CAPTURE_HAR_ON_ERROR"); v != "" {
+		cfg.Browser.CaptureHAROnError = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_CAPTURE_HAR"); v != "" {
+		cfg.Browser.CaptureHAR = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_HAR_DIR"); v != "" {
+		cfg.Browser.HARDir = v
+	}
+
+	if v := os.Getenv("BROWSER_SCREENSHOT_ON_FAILURE"); v != "" {
+		cfg.Browser.ScreenshotOnFailure = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_SCREENSHOT_DIR"); v != "" {
+		cfg.Browser.ScreenshotDir = v
+	}
+
+	if v := os.Getenv("BROWSER_DOH_TEMPLATE"); v != "" {
+		cfg.Browser.DoHTemplate = v
+	}
+
+	if v := os.Getenv("BROWSER_CHECK_CLOCK_SKEW"); v != "" {
+		cfg.Browser.CheckClockSkew = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_CLOCK_SKEW_THRESHOLD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_CLOCK_SKEW_THRESHOLD: %w", err)
+		}
+		cfg.Browser.ClockSkewThreshold = d
+	}
+
+	if v := os.Getenv("BROWSER_EGRESS_IP_ENDPOINT"); v != "" {
+		cfg.Browser.EgressIPEndpoint = v
+	}
+
+	if v := os.Getenv("BROWSER_EGRESS_IP_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_EGRESS_IP_INTERVAL: %w", err)
+		}
+		cfg.Browser.EgressIPInterval = d
+	}
+
+	if v := os.Getenv("BROWSER_HARD_DEADLINE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_HARD_DEADLINE: %w", err)
+		}
+		cfg.Browser.HardDeadline = d
+	}
+
+	if v := os.Getenv("BROWSER_MAX_CHROME_INSTANCES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_MAX_CHROME_INSTANCES: %w", err)
+		}
+		cfg.Browser.MaxChromeInstances = n
+	}
+
+	if v := os.Getenv("BROWSER_CAPTIVE_PORTAL_URL"); v != "" {
+		cfg.Browser.CaptivePortalURL = v
+	}
+
+	if v := os.Getenv("BROWSER_CAPTIVE_PORTAL_EXPECTED_STATUS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_CAPTIVE_PORTAL_EXPECTED_STATUS: %w", err)
+		}
+		cfg.Browser.CaptivePortalExpectedStatus = n
+	}
+
+	if v, ok := os.LookupEnv("BROWSER_CAPTIVE_PORTAL_EXPECTED_BODY"); ok {
+		cfg.Browser.CaptivePortalExpectedBody = v
+	}
+
+	if v := os.Getenv("BROWSER_CAPTIVE_PORTAL_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_CAPTIVE_PORTAL_INTERVAL: %w", err)
+		}
+		cfg.Browser.CaptivePortalInterval = d
+	}
+
+	if v := os.Getenv("BROWSER_SUPPRESS_SUCCESS_ON_CAPTIVE_PORTAL"); v != "" {
+		cfg.Browser.SuppressSuccessOnCaptivePortal = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_CAPTIVE_PORTAL_CHECK_HOST"); v != "" {
+		cfg.Browser.CaptivePortalCheckHost = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_CAPTIVE_PORTAL_PROBE_STRINGS"); v != "" {
+		cfg.Browser.CaptivePortalProbeStrings = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				cfg.Browser.CaptivePortalProbeStrings = append(cfg.Browser.CaptivePortalProbeStrings, s)
+			}
+		}
+	}
+
+	// One pattern per line: regular expressions commonly contain commas
+	if v := os.Getenv("BROWSER_REDACT_PATTERNS"); v != "" {
+		cfg.Browser.RedactPatterns = nil
+		for _, p := range strings.Split(v, "\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				cfg.Browser.RedactPatterns = append(cfg.Browser.RedactPatterns, p)
+			}
+		}
+	}
+
+	if v := os.Getenv("BROWSER_GRADE_SECURITY_HEADERS"); v != "" {
+		cfg.Browser.GradeSecurityHeaders = v == "true" || v == "1"
+	}
+
+	// One rule per line, as rules may contain regular expressions
+	if v := os.Getenv("BROWSER_SECURITY_HEADER_RULES"); v != "" {
+		cfg.Browser.SecurityHeaderRules = nil
+		for _, r := range strings.Split(v, "\n") {
+			if r = strings.TrimSpace(r); r != "" {
+				cfg.Browser.SecurityHeaderRules = append(cfg.Browser.SecurityHeaderRules, r)
+			}
+		}
+	}
+
+	if v := os.Getenv("BROWSER_CAPTURE_RESPONSE_HEADERS"); v != "" {
+		cfg.Browser.CaptureResponseHeaders = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_RESPONSE_HEADER_ALLOWLIST"); v != "" {
+		cfg.Browser.ResponseHeaderAllowlist = nil
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				cfg.Browser.ResponseHeaderAllowlist = append(cfg.Browser.ResponseHeaderAllowlist, h)
+			}
+		}
+	}
+
+	if v := os.Getenv("BROWSER_PROXY_URL"); v != "" {
+		cfg.Browser.ProxyURL = v
+	}
+
+	if v := os.Getenv("BROWSER_FORCE_FRESH_CONNECTIONS"); v != "" {
+		cfg.Browser.ForceFreshConnections = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_ENABLE_HTTP2"); v != "" {
+		cfg.Browser.EnableHTTP2 = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_ENABLE_HTTP3"); v != "" {
+		cfg.Browser.EnableHTTP3 = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_CERT_EXPIRY_WARN_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_CERT_EXPIRY_WARN_DAYS: %w", err)
+		}
+		cfg.Browser.CertExpiryWarnDays = n
+	}
+
+	if v := os.Getenv("BROWSER_USE_POOL"); v != "" {
+		cfg.Browser.UsePool = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("BROWSER_POOL_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_POOL_SIZE: %w", err)
+		}
+		cfg.Browser.PoolSize = n
+	}
+
+	if v := os.Getenv("BROWSER_POOL_MAX_REUSE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_POOL_MAX_REUSE: %w", err)
+		}
+		cfg.Browser.PoolMaxReuse = n
+	}
+
+	if v := os.Getenv("BROWSER_STARTUP_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_STARTUP_RETRIES: %w", err)
+		}
+		cfg.Browser.StartupRetries = n
+	}
+
+	if v := os.Getenv("BROWSER_STARTUP_RETRY_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid BROWSER_STARTUP_RETRY_BACKOFF: %w", err)
+		}
+		cfg.Browser.StartupRetryBackoff = d
+	}
+
 	// Logging
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		cfg.Logging.Level = v
@@ -121,10 +324,177 @@ func LoadFromEnv(cfg *Config) error {
 		cfg.SNMP.Community = v
 	}
 
+	if v := os.Getenv("SNMP_WRITE_COMMUNITY"); v != "" {
+		cfg.SNMP.WriteCommunity = v
+	}
+
 	if v := os.Getenv("SNMP_LISTEN_ADDRESS"); v != "" {
 		cfg.SNMP.ListenAddress = v
 	}
 
+	if v := os.Getenv("SNMP_TRANSPORT"); v != "" {
+		cfg.SNMP.Transport = v
+	}
+
+	if v := os.Getenv("SNMP_DUAL_STACK"); v != "" {
+		cfg.SNMP.DualStack = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("SNMP_DRAIN_PATH"); v != "" {
+		cfg.SNMP.DrainPath = v
+	}
+
+	if v := os.Getenv("SNMP_SITE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_SITE_TTL: %w", err)
+		}
+		cfg.SNMP.SiteTTL = d
+	}
+
+	if v := os.Getenv("SNMP_MAX_TRACKED_SITES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_MAX_TRACKED_SITES: %w", err)
+		}
+		cfg.SNMP.MaxTrackedSites = n
+	}
+
+	if v := os.Getenv("SNMP_PRUNE_REMOVED_SITES"); v != "" {
+		cfg.SNMP.PruneRemovedSites = v == "true" || v == "1"
+	}
+
+	// Format: community=oid,oid;community=oid
+	if v := os.Getenv("SNMP_VIEWS"); v != "" {
+		views, err := parseSNMPViews(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_VIEWS: %w", err)
+		}
+		cfg.SNMP.Views = views
+	}
+
+	if v := os.Getenv("SNMP_SYS_DESCR"); v != "" {
+		cfg.SNMP.SysDescr = v
+	}
+
+	if v := os.Getenv("SNMP_SYS_CONTACT"); v != "" {
+		cfg.SNMP.SysContact = v
+	}
+
+	if v := os.Getenv("SNMP_SYS_NAME"); v != "" {
+		cfg.SNMP.SysName = v
+	}
+
+	if v := os.Getenv("SNMP_SYS_LOCATION"); v != "" {
+		cfg.SNMP.SysLocation = v
+	}
+
+	if v := os.Getenv("SNMP_EWMA_ALPHA"); v != "" {
+		alpha, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_EWMA_ALPHA: %w", err)
+		}
+		cfg.SNMP.EWMAAlpha = alpha
+	}
+
+	if v := os.Getenv("SNMP_RATE_LIMIT"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_RATE_LIMIT: %w", err)
+		}
+		cfg.SNMP.RateLimit = rate
+	}
+
+	if v := os.Getenv("SNMP_RATE_LIMIT_BURST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_RATE_LIMIT_BURST: %w", err)
+		}
+		cfg.SNMP.RateLimitBurst = n
+	}
+
+	if v := os.Getenv("SNMP_PERCENTILE_SAMPLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_PERCENTILE_SAMPLES: %w", err)
+		}
+		cfg.SNMP.PercentileSamples = n
+	}
+
+	if v := os.Getenv("SNMP_SUCCESS_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_SUCCESS_WINDOW: %w", err)
+		}
+		cfg.SNMP.SuccessWindow = d
+	}
+
+	if v := os.Getenv("SNMP_MAX_RESPONSE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SNMP_MAX_RESPONSE_SIZE: %w", err)
+		}
+		cfg.SNMP.MaxResponseSize = n
+	}
+
+	if v := os.Getenv("SNMP_V3_SECURITY_LEVEL"); v != "" {
+		cfg.SNMP.SecurityLevel = v
+	}
+
+	if v := os.Getenv("SNMP_V3_USERNAME"); v != "" {
+		cfg.SNMP.Username = v
+	}
+
+	if v := os.Getenv("SNMP_V3_AUTH_PROTOCOL"); v != "" {
+		cfg.SNMP.AuthProtocol = v
+	}
+
+	if v := os.Getenv("SNMP_V3_AUTH_PASSPHRASE"); v != "" {
+		cfg.SNMP.AuthPassphrase = v
+	}
+
+	if v := os.Getenv("SNMP_V3_PRIV_PROTOCOL"); v != "" {
+		cfg.SNMP.PrivProtocol = v
+	}
+
+	if v := os.Getenv("SNMP_V3_PRIV_PASSPHRASE"); v != "" {
+		cfg.SNMP.PrivPassphrase = v
+	}
+
+	if v := os.Getenv("SNMP_TRAP_TARGETS"); v != "" {
+		cfg.SNMP.TrapTargets = nil
+		for _, target := range strings.Split(v, ",") {
+			if target = strings.TrimSpace(target); target != "" {
+				cfg.SNMP.TrapTargets = append(cfg.SNMP.TrapTargets, target)
+			}
+		}
+	}
+
+	if v := os.Getenv("SNMP_TRAP_COMMUNITY"); v != "" {
+		cfg.SNMP.TrapCommunity = v
+	}
+
+	if v := os.Getenv("SNMP_ALLOWED_CIDRS"); v != "" {
+		cfg.SNMP.AllowedCIDRs = nil
+		for _, cidr := range strings.Split(v, ",") {
+			if cidr = strings.TrimSpace(cidr); cidr != "" {
+				cfg.SNMP.AllowedCIDRs = append(cfg.SNMP.AllowedCIDRs, cidr)
+			}
+		}
+	}
+
+	// Dedup
+	if v := os.Getenv("DEDUP_ENABLED"); v != "" {
+		cfg.Dedup.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("DEDUP_HEARTBEAT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid DEDUP_HEARTBEAT_INTERVAL: %w", err)
+		}
+		cfg.Dedup.HeartbeatInterval = d
+	}
+
 	// Prometheus
 	if v := os.Getenv("PROM_ENABLED"); v != "" {
 		cfg.Prometheus.Enabled = v == "true" || v == "1"
@@ -146,6 +516,114 @@ func LoadFromEnv(cfg *Config) error {
 		cfg.Prometheus.ListenAddress = v
 	}
 
+	// Circuit breaker
+	if v := os.Getenv("CIRCUIT_BREAKER_ENABLED"); v != "" {
+		cfg.CircuitBreaker.Enabled = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("CIRCUIT_BREAKER_FAILURE_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid CIRCUIT_BREAKER_FAILURE_THRESHOLD: %w", err)
+		}
+		cfg.CircuitBreaker.FailureThreshold = n
+	}
+
+	if v := os.Getenv("CIRCUIT_BREAKER_INITIAL_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CIRCUIT_BREAKER_INITIAL_BACKOFF: %w", err)
+		}
+		cfg.CircuitBreaker.InitialBackoff = d
+	}
+
+	if v := os.Getenv("CIRCUIT_BREAKER_MAX_BACKOFF"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CIRCUIT_BREAKER_MAX_BACKOFF: %w", err)
+		}
+		cfg.CircuitBreaker.MaxBackoff = d
+	}
+
+	// Network-down shortcut
+	if v := os.Getenv("NETWORK_DOWN_SHORTCUT_ENABLED"); v != "" {
+		cfg.NetworkDown.Enabled = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("NETWORK_DOWN_DNS_FAILURE_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid NETWORK_DOWN_DNS_FAILURE_THRESHOLD: %w", err)
+		}
+		cfg.NetworkDown.DNSFailureThreshold = n
+	}
+
+	// Syslog
+	if v := os.Getenv("SYSLOG_ENABLED"); v != "" {
+		cfg.Syslog.Enabled = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("SYSLOG_ADDRESS"); v != "" {
+		cfg.Syslog.Address = v
+	}
+
+	if v := os.Getenv("SYSLOG_PROTOCOL"); v != "" {
+		cfg.Syslog.Protocol = v
+	}
+
+	if v := os.Getenv("SYSLOG_FACILITY"); v != "" {
+		cfg.Syslog.Facility = v
+	}
+
+	if v := os.Getenv("SYSLOG_APP_NAME"); v != "" {
+		cfg.Syslog.AppName = v
+	}
+
+	if v := os.Getenv("SYSLOG_TLS_SKIP_VERIFY"); v != "" {
+		cfg.Syslog.TLSSkipVerify = v == "true" || v == "1"
+	}
+
+	// SQLite
+	if v := os.Getenv("SQLITE_ENABLED"); v != "" {
+		cfg.SQLite.Enabled = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("SQLITE_PATH"); v != "" {
+		cfg.SQLite.Path = v
+	}
+
+	if v := os.Getenv("SQLITE_RETENTION_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SQLITE_RETENTION_DAYS: %w", err)
+		}
+		cfg.SQLite.RetentionDays = n
+	}
+
+	if v := os.Getenv("SQLITE_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid SQLITE_BATCH_SIZE: %w", err)
+		}
+		cfg.SQLite.BatchSize = n
+	}
+
+	if v := os.Getenv("SQLITE_FLUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid SQLITE_FLUSH_INTERVAL: %w", err)
+		}
+		cfg.SQLite.FlushInterval = d
+	}
+
+	if v := os.Getenv("SQLITE_PRUNE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid SQLITE_PRUNE_INTERVAL: %w", err)
+		}
+		cfg.SQLite.PruneInterval = d
+	}
+
 	// Advanced
 	if v := os.Getenv("HEALTH_CHECK_ENABLED"); v != "" {
 		cfg.Advanced.HealthCheckEnabled = v == "true" || v == "1"
@@ -163,6 +641,22 @@ func LoadFromEnv(cfg *Config) error {
 		cfg.Advanced.HealthCheckListenAddress = v
 	}
 
+	if v := os.Getenv("OUTPUT_FAILURE_POLICY"); v != "" {
+		cfg.Advanced.OutputFailurePolicy = v
+	}
+
+	if v := os.Getenv("OUTPUT_FAILURE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid OUTPUT_FAILURE_AFTER: %w", err)
+		}
+		cfg.Advanced.OutputFailureAfter = d
+	}
+
+	if v := os.Getenv("OUTPUT_FALLBACK_FILE"); v != "" {
+		cfg.Advanced.OutputFallbackFile = v
+	}
+
 	return nil
 }
 
@@ -209,3 +703,30 @@ func ParseSimpleSiteList(sitesStr string) ([]models.SiteDefinition, error) {
 
 	return sites, nil
 }
+
+// parseSNMPViews parses SNMP views in the form "community=oid,oid;community=oid"
+func parseSNMPViews(s string) (map[string][]string, error) {
+	views := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		community, oids, ok := strings.Cut(entry, "=")
+		community = strings.TrimSpace(community)
+		if !ok || community == "" {
+			return nil, fmt.Errorf("view %q is not community=oid[,oid...]", entry)
+		}
+		var prefixes []string
+		for _, oid := range strings.Split(oids, ",") {
+			if oid = strings.TrimSpace(oid); oid != "" {
+				prefixes = append(prefixes, oid)
+			}
+		}
+		if len(prefixes) == 0 {
+			return nil, fmt.Errorf("view for community %q has no OIDs", community)
+		}
+		views[community] = prefixes
+	}
+	return views, nil
+}
@@ -547,3 +547,26 @@ func TestLoadFromEnv_Sites(t *testing.T) {
 		t.Errorf("Expected first site name 'google', got '%s'", cfg.Sites.List[0].Name)
 	}
 }
+
+// TestParseSNMPViews tests parsing the SNMP_VIEWS format
+func TestParseSNMPViews(t *testing.T) {
+	views, err := parseSNMPViews(" nms-public=.1.3.6.1.4.1.99999.1, .1.3.6.1.4.1.99999.3 ;ops=.1.3.6.1.4.1.99999;")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(views) != 2 {
+		t.Fatalf("Expected 2 views, got %d", len(views))
+	}
+	if got := views["nms-public"]; len(got) != 2 || got[1] != ".1.3.6.1.4.1.99999.3" {
+		t.Errorf("Unexpected nms-public view: %v", got)
+	}
+	if got := views["ops"]; len(got) != 1 || got[0] != ".1.3.6.1.4.1.99999" {
+		t.Errorf("Unexpected ops view: %v", got)
+	}
+
+	for _, bad := range []string{"nms-public", "=.1.3.6", "nms-public= , "} {
+		if _, err := parseSNMPViews(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
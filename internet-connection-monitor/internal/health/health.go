@@ -20,6 +20,14 @@ type HealthServer struct {
 	successCount   int64
 	failureCount   int64
 	isHealthy      bool
+	availability   AvailabilitySource
+}
+
+// AvailabilitySource reports the weighted "is the internet up" verdict
+// across all sites (see metrics.Availability)
+type AvailabilitySource interface {
+	Availability() float64
+	IsUp() bool
 }
 
 // Config contains health check server configuration
@@ -39,6 +47,11 @@ type HealthResponse struct {
 	SuccessCount int64     `json:"success_count"`
 	FailureCount int64     `json:"failure_count"`
 	Uptime       string    `json:"uptime"`
+	// Availability and InternetUp are the aggregated verdict across sites,
+	// omitted when no source is set. The internet being down doesn't make
+	// the monitor itself unhealthy.
+	Availability *float64 `json:"availability,omitempty"`
+	InternetUp   *bool    `json:"internet_up,omitempty"`
 }
 
 var startTime = time.Now()
@@ -106,6 +119,11 @@ func (h *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 		FailureCount: h.failureCount,
 		Uptime:       time.Since(startTime).String(),
 	}
+	if h.availability != nil {
+		availability, up := h.availability.Availability(), h.availability.IsUp()
+		response.Availability = &availability
+		response.InternetUp = &up
+	}
 
 	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
@@ -148,6 +166,18 @@ func (h *HealthServer) SetHealthy(healthy bool) {
 	h.isHealthy = healthy
 }
 
+// SetAvailability reports source's verdict in every health response
+func (h *HealthServer) SetAvailability(source AvailabilitySource) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.availability = source
+}
+
 // GetStats returns current health statistics
 func (h *HealthServer) GetStats() (testCount, successCount, failureCount int64, lastTestTime time.Time) {
 	if h == nil {
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"log"
+	"sync"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Availability aggregates the latest result of every site into a single
+// weighted "is the internet up" verdict.
+//
+// Each site contributes its SiteDefinition.Weight (unset or <= 0 counts as 1).
+// Weights are relative, not percentages: availability is the weight of sites
+// whose latest test succeeded divided by the weight of all sites tested so far,
+// so it is always between 0 and 1 however the weights are scaled. Sites that
+// haven't been tested yet don't count. The internet is considered up while
+// availability is at or above the threshold.
+//
+// For example, with an ISP check of weight 10 and five minor sites of weight 1,
+// the ISP check failing alone gives 5/15 = 0.33, which is down at a 0.5 threshold.
+type Availability struct {
+	threshold float64
+	weights   map[string]float64
+
+	mu     sync.RWMutex
+	latest map[string]bool // site name -> latest test succeeded
+	up     bool
+}
+
+// NewAvailability creates an aggregator for the given sites
+func NewAvailability(sites []models.SiteDefinition, threshold float64) *Availability {
+	return &Availability{
+		threshold: threshold,
+		weights:   siteWeights(sites),
+		latest:    make(map[string]bool),
+		up:        true,
+	}
+}
+
+// ReloadSites picks up new weights and forgets sites that are no longer configured,
+// so a removed site's last result stops counting towards the verdict
+func (a *Availability) ReloadSites(sites []models.SiteDefinition) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.weights = siteWeights(sites)
+	for site := range a.latest {
+		if _, ok := a.weights[site]; !ok {
+			delete(a.latest, site)
+		}
+	}
+	a.updateVerdictLocked()
+}
+
+func siteWeights(sites []models.SiteDefinition) map[string]float64 {
+	weights := make(map[string]float64, len(sites))
+	for i := range sites {
+		weights[sites[i].GetName()] = sites[i].Weight
+	}
+	return weights
+}
+
+// Write records a site's latest result and logs when the verdict changes
+func (a *Availability) Write(result *models.TestResult) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.latest[result.Site.Name] = result.Status.Success
+	a.updateVerdictLocked()
+	return nil
+}
+
+// Availability returns the current weighted fraction of sites that are up (1 before any results)
+func (a *Availability) Availability() float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.availabilityLocked()
+}
+
+// IsUp returns whether availability is at or above the threshold
+func (a *Availability) IsUp() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.up
+}
+
+// Name returns the output module name
+func (a *Availability) Name() string {
+	return "availability"
+}
+
+// updateVerdictLocked recomputes the verdict and logs when it changes
+func (a *Availability) updateVerdictLocked() {
+	availability := a.availabilityLocked()
+	up := availability >= a.threshold
+	if up != a.up {
+		a.up = up
+		if up {
+			log.Printf("Internet availability recovered: %.2f (threshold %.2f)", availability, a.threshold)
+		} else {
+			log.Printf("Internet availability below threshold: %.2f (threshold %.2f)", availability, a.threshold)
+		}
+	}
+}
+
+func (a *Availability) availabilityLocked() float64 {
+	var total, upWeight float64
+	for site, success := range a.latest {
+		w := a.weight(site)
+		total += w
+		if success {
+			upWeight += w
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return upWeight / total
+}
+
+func (a *Availability) weight(site string) float64 {
+	if w := a.weights[site]; w > 0 {
+		return w
+	}
+	return 1
+}
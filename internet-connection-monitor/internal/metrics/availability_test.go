@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestAvailabilityIsWeighted(t *testing.T) {
+	sites := []models.SiteDefinition{
+		{Name: "isp", Weight: 10},
+		{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"},
+	}
+	agg := NewAvailability(sites, 0.5)
+
+	write := func(name string, success bool) {
+		agg.Write(&models.TestResult{
+			Site:   models.SiteInfo{Name: name},
+			Status: models.StatusInfo{Success: success},
+		})
+	}
+
+	if agg.Availability() != 1 || !agg.IsUp() {
+		t.Fatalf("expected full availability before any results")
+	}
+
+	for _, s := range sites {
+		write(s.Name, true)
+	}
+	if !agg.IsUp() {
+		t.Errorf("expected up with every site succeeding")
+	}
+
+	// Five minor sites down are outweighed by the ISP check
+	for _, s := range sites[1:] {
+		write(s.Name, false)
+	}
+	if got := agg.Availability(); math.Abs(got-10.0/15.0) > 1e-9 {
+		t.Errorf("expected availability 10/15, got %v", got)
+	}
+	if !agg.IsUp() {
+		t.Errorf("expected up while the heavily weighted site succeeds")
+	}
+
+	// The ISP check alone going down flips the verdict even with minor sites up
+	for _, s := range sites[1:] {
+		write(s.Name, true)
+	}
+	write("isp", false)
+	if got := agg.Availability(); math.Abs(got-5.0/15.0) > 1e-9 {
+		t.Errorf("expected availability 5/15, got %v", got)
+	}
+	if agg.IsUp() {
+		t.Errorf("expected down when the heavily weighted site fails")
+	}
+}
+
+func TestAvailabilityIgnoresUntestedSites(t *testing.T) {
+	agg := NewAvailability([]models.SiteDefinition{{Name: "a", Weight: 3}, {Name: "b"}}, 0.5)
+
+	agg.Write(&models.TestResult{Site: models.SiteInfo{Name: "b"}, Status: models.StatusInfo{Success: true}})
+	if got := agg.Availability(); got != 1 {
+		t.Errorf("expected untested site to be ignored, got %v", got)
+	}
+}
+
+func TestAvailabilityReloadSitesDropsRemovedSites(t *testing.T) {
+	agg := NewAvailability([]models.SiteDefinition{{Name: "a"}, {Name: "b"}}, 0.5)
+
+	agg.Write(&models.TestResult{Site: models.SiteInfo{Name: "a"}, Status: models.StatusInfo{Success: false}})
+	agg.Write(&models.TestResult{Site: models.SiteInfo{Name: "b"}, Status: models.StatusInfo{Success: false}})
+	if agg.IsUp() {
+		t.Fatalf("expected down with every site failing")
+	}
+
+	// Dropping a failing site and reweighting leaves only b's latest result
+	agg.ReloadSites([]models.SiteDefinition{{Name: "b", Weight: 2}, {Name: "c"}})
+	agg.Write(&models.TestResult{Site: models.SiteInfo{Name: "c"}, Status: models.StatusInfo{Success: true}})
+	if got := agg.Availability(); got < 0.33 || got > 0.34 {
+		t.Errorf("expected 1/3 after reload, got %v", got)
+	}
+}
+
+func TestAvailabilityFollowsDispatcher(t *testing.T) {
+	agg := NewAvailability([]models.SiteDefinition{{Name: "a"}, {Name: "b"}}, 0.5)
+	d := NewDispatcher()
+	d.RegisterOutput(agg)
+
+	d.Dispatch(&models.TestResult{Site: models.SiteInfo{Name: "a"}, Status: models.StatusInfo{Success: true}})
+	d.Dispatch(&models.TestResult{Site: models.SiteInfo{Name: "b"}, Status: models.StatusInfo{Success: false}})
+	if got := agg.Availability(); got != 0.5 || !agg.IsUp() {
+		t.Fatalf("expected availability 0.5 and up, got %v (up %v)", got, agg.IsUp())
+	}
+
+	// A reload through the dispatcher reaches the aggregator too
+	d.ReloadSites([]models.SiteDefinition{{Name: "b"}})
+	if got := agg.Availability(); got != 0 || agg.IsUp() {
+		t.Errorf("expected only b's failure to count after reload, got %v (up %v)", got, agg.IsUp())
+	}
+}
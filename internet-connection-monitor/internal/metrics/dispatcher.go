@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"errors"
+	"log"
 	"sync"
 
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
@@ -8,8 +10,9 @@ import (
 
 // Dispatcher distributes test results to all output modules
 type Dispatcher struct {
-	outputs []Output
-	mu      sync.RWMutex
+	outputs  []Output
+	failures *outputFailureTracker // nil until SetOutputFailurePolicy
+	mu       sync.RWMutex
 }
 
 // Output is an interface for result output modules
@@ -21,6 +24,28 @@ type Output interface {
 	Name() string
 }
 
+// Sink is implemented by outputs that deliver results to an external store
+// (Elasticsearch, syslog, SQLite). Only sinks count towards the output failure
+// policy: in-process consumers such as the availability verdict or the
+// Prometheus and SNMP statistics never fail to take a result, so counting
+// them would keep the policy from ever applying.
+type Sink interface {
+	// IsSink reports whether the output's write errors mean a result was lost
+	IsSink() bool
+}
+
+// ErrSuppressed is returned by an output that deliberately didn't deliver a
+// result, such as a repeated failure collapsed by deduplication. It is neither
+// a failure nor a delivery.
+var ErrSuppressed = errors.New("result suppressed")
+
+// SiteReloader is implemented by outputs that keep per-site state derived from
+// the configured site list and need to follow it when the configuration is reloaded
+type SiteReloader interface {
+	// ReloadSites replaces the configured site list
+	ReloadSites(sites []models.SiteDefinition)
+}
+
 // NewDispatcher creates a new result dispatcher
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
@@ -35,27 +60,80 @@ func (d *Dispatcher) RegisterOutput(output Output) {
 	d.outputs = append(d.outputs, output)
 }
 
+// SetOutputFailurePolicy sets what happens once every sink has been failing
+// for cfg.After. Without it the dispatcher only logs output errors.
+func (d *Dispatcher) SetOutputFailurePolicy(cfg OutputFailureConfig) error {
+	tracker, err := newOutputFailureTracker(cfg)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failures = tracker
+	return nil
+}
+
+// ReloadSites passes a new site list to every registered output that implements SiteReloader
+func (d *Dispatcher) ReloadSites(sites []models.SiteDefinition) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for _, output := range d.outputs {
+		if r, ok := output.(SiteReloader); ok {
+			r.ReloadSites(sites)
+		}
+	}
+}
+
 // Dispatch sends a result to all registered outputs
 // Outputs are called in parallel to avoid blocking
 func (d *Dispatcher) Dispatch(result *models.TestResult) {
 	d.mu.RLock()
 	outputs := make([]Output, len(d.outputs))
 	copy(outputs, d.outputs)
+	failures := d.failures
 	d.mu.RUnlock()
 
 	// Fan out to all outputs in parallel
 	var wg sync.WaitGroup
+	var countMu sync.Mutex
+	delivered, failed := 0, 0 // sinks only
 	for _, output := range outputs {
 		wg.Add(1)
 		go func(o Output) {
 			defer wg.Done()
-			if err := o.Write(result); err != nil {
-				// TODO: Log error (but don't fail the dispatch)
-				// We don't want one failing output to block others
+			err := o.Write(result)
+			if errors.Is(err, ErrSuppressed) {
+				return
+			}
+			if err != nil {
+				// Log but don't fail the dispatch: one failing output must not block others
+				log.Printf("Output %s failed: %v", o.Name(), err)
+			}
+			if !isSink(o) {
+				return
 			}
+			countMu.Lock()
+			if err != nil {
+				failed++
+			} else {
+				delivered++
+			}
+			countMu.Unlock()
 		}(output)
 	}
 
 	// Wait for all outputs to complete
 	wg.Wait()
+
+	// A result every sink suppressed says nothing about whether they work
+	if failures != nil && delivered+failed > 0 {
+		failures.record(result, delivered == 0)
+	}
+}
+
+// isSink reports whether an output counts towards the output failure policy
+func isSink(o Output) bool {
+	s, ok := o.(Sink)
+	return ok && s.IsSink()
 }
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Output failure policies: what the dispatcher does once every registered
+// Sink has been failing for OutputFailureConfig.After
+const (
+	// OutputFailureContinue keeps dispatching and only logs (the default)
+	OutputFailureContinue = "continue"
+
+	// OutputFailureFallbackFile also appends each result, as one JSON line, to a
+	// local file until an output recovers, so nothing collected is lost
+	OutputFailureFallbackFile = "fallback_file"
+
+	// OutputFailureUnhealthy reports the process unhealthy so a liveness
+	// probe can restart it
+	OutputFailureUnhealthy = "unhealthy"
+)
+
+// OutputFailureConfig configures the dispatcher's output failure policy
+type OutputFailureConfig struct {
+	Policy       string
+	After        time.Duration // how long every sink must fail before the policy applies
+	FallbackFile string        // for OutputFailureFallbackFile
+
+	// SetHealthy is called with false when OutputFailureUnhealthy applies and
+	// with true once a sink recovers (e.g. HealthServer.SetHealthy)
+	SetHealthy func(healthy bool)
+}
+
+// outputFailureTracker follows whether all sinks are failing and applies the policy
+type outputFailureTracker struct {
+	cfg OutputFailureConfig
+
+	mu             sync.Mutex
+	failingSince   time.Time // zero while any output is succeeding
+	engaged        bool      // the policy currently applies
+	fallbackErrors int       // consecutive failed fallback writes, to log only the first
+
+	now func() time.Time
+}
+
+func newOutputFailureTracker(cfg OutputFailureConfig) (*outputFailureTracker, error) {
+	switch cfg.Policy {
+	case "", OutputFailureContinue:
+		cfg.Policy = OutputFailureContinue
+	case OutputFailureFallbackFile:
+		if cfg.FallbackFile == "" {
+			return nil, fmt.Errorf("output failure policy %q requires a fallback file", cfg.Policy)
+		}
+	case OutputFailureUnhealthy:
+	default:
+		return nil, fmt.Errorf("unknown output failure policy %q (want %s, %s or %s)",
+			cfg.Policy, OutputFailureContinue, OutputFailureFallbackFile, OutputFailureUnhealthy)
+	}
+	return &outputFailureTracker{cfg: cfg, now: time.Now}, nil
+}
+
+// record notes whether a result reached no sink at all
+func (t *outputFailureTracker) record(result *models.TestResult, allFailed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !allFailed {
+		if t.engaged {
+			log.Printf("Sinks recovered after failing since %s", t.failingSince.Format(time.RFC3339))
+			if t.cfg.Policy == OutputFailureUnhealthy && t.cfg.SetHealthy != nil {
+				t.cfg.SetHealthy(true)
+			}
+		}
+		t.failingSince = time.Time{}
+		t.engaged = false
+		return
+	}
+
+	now := t.now()
+	if t.failingSince.IsZero() {
+		t.failingSince = now
+	}
+	if !t.engaged && now.Sub(t.failingSince) >= t.cfg.After {
+		t.engaged = true
+		log.Printf("All sinks have been failing for %v; output failure policy: %s", now.Sub(t.failingSince).Round(time.Second), t.cfg.Policy)
+		if t.cfg.Policy == OutputFailureUnhealthy && t.cfg.SetHealthy != nil {
+			t.cfg.SetHealthy(false)
+		}
+	}
+	if t.engaged && t.cfg.Policy == OutputFailureFallbackFile {
+		t.writeFallback(result)
+	}
+}
+
+// writeFallback appends the result to the fallback file as a JSON line
+func (t *outputFailureTracker) writeFallback(result *models.TestResult) {
+	err := appendJSONLine(t.cfg.FallbackFile, result)
+	if err != nil {
+		if t.fallbackErrors == 0 {
+			log.Printf("Failed to write result to fallback file %s: %v", t.cfg.FallbackFile, err)
+		}
+		t.fallbackErrors++
+		return
+	}
+	t.fallbackErrors = 0
+}
+
+func appendJSONLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// failingOutput fails every write while its failing flag is set
+type failingOutput struct {
+	name    string
+	failing atomic.Bool
+}
+
+func (o *failingOutput) Write(*models.TestResult) error {
+	if o.failing.Load() {
+		return errors.New(o.name + " is down")
+	}
+	return nil
+}
+
+func (o *failingOutput) Name() string { return o.name }
+
+func (o *failingOutput) IsSink() bool { return true }
+
+// newFailingDispatcher returns a dispatcher with two outputs and a controllable clock
+func newFailingDispatcher(t *testing.T, cfg OutputFailureConfig) (*Dispatcher, *failingOutput, *failingOutput, *time.Time) {
+	t.Helper()
+	d := NewDispatcher()
+	a, b := &failingOutput{name: "a"}, &failingOutput{name: "b"}
+	d.RegisterOutput(a)
+	d.RegisterOutput(b)
+	if err := d.SetOutputFailurePolicy(cfg); err != nil {
+		t.Fatalf("SetOutputFailurePolicy: %v", err)
+	}
+	now := time.Now()
+	d.failures.now = func() time.Time { return now }
+	return d, a, b, &now
+}
+
+func TestOutputFailureUnhealthy(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	d, a, b, now := newFailingDispatcher(t, OutputFailureConfig{
+		Policy:     OutputFailureUnhealthy,
+		After:      time.Minute,
+		SetHealthy: healthy.Store,
+	})
+	result := &models.TestResult{Site: models.SiteInfo{Name: "example"}}
+
+	// One output still working is not an outage
+	a.failing.Store(true)
+	d.Dispatch(result)
+	*now = now.Add(2 * time.Minute)
+	d.Dispatch(result)
+	if !healthy.Load() {
+		t.Fatal("expected healthy while one output works")
+	}
+
+	b.failing.Store(true)
+	d.Dispatch(result)
+	if !healthy.Load() {
+		t.Fatal("expected healthy before the failure duration passes")
+	}
+	*now = now.Add(time.Minute)
+	d.Dispatch(result)
+	if healthy.Load() {
+		t.Fatal("expected unhealthy once all outputs failed for the duration")
+	}
+
+	b.failing.Store(false)
+	d.Dispatch(result)
+	if !healthy.Load() {
+		t.Error("expected healthy again after an output recovered")
+	}
+}
+
+func TestOutputFailureIgnoresInProcessOutputs(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	d := NewDispatcher()
+	sink := &failingOutput{name: "elasticsearch"}
+	d.RegisterOutput(sink)
+	d.RegisterOutput(NewAvailability(nil, 0.5)) // never fails
+	d.RegisterOutput(suppressingOutput{})       // never delivers, never fails
+	if err := d.SetOutputFailurePolicy(OutputFailureConfig{
+		Policy:     OutputFailureUnhealthy,
+		After:      time.Minute,
+		SetHealthy: healthy.Store,
+	}); err != nil {
+		t.Fatalf("SetOutputFailurePolicy: %v", err)
+	}
+	now := time.Now()
+	d.failures.now = func() time.Time { return now }
+	result := &models.TestResult{Site: models.SiteInfo{Name: "example"}}
+
+	sink.failing.Store(true)
+	d.Dispatch(result)
+	now = now.Add(time.Minute)
+	d.Dispatch(result)
+	if healthy.Load() {
+		t.Fatal("expected unhealthy once the only sink that can fail failed for the duration")
+	}
+
+	sink.failing.Store(false)
+	d.Dispatch(result)
+	if !healthy.Load() {
+		t.Error("expected healthy again after the sink recovered")
+	}
+}
+
+// suppressingOutput is a sink that suppresses every result, like a deduplicated one
+type suppressingOutput struct{}
+
+func (suppressingOutput) Write(*models.TestResult) error { return ErrSuppressed }
+func (suppressingOutput) Name() string                   { return "suppressing" }
+func (suppressingOutput) IsSink() bool                   { return true }
+
+func TestOutputFailureFallbackFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fallback.jsonl")
+	d, a, b, now := newFailingDispatcher(t, OutputFailureConfig{
+		Policy:       OutputFailureFallbackFile,
+		After:        time.Minute,
+		FallbackFile: path,
+	})
+	write := func(name string) {
+		d.Dispatch(&models.TestResult{Site: models.SiteInfo{Name: name}})
+	}
+
+	a.failing.Store(true)
+	b.failing.Store(true)
+	write("before-threshold")
+	*now = now.Add(time.Minute)
+	write("lost-1")
+	write("lost-2")
+	a.failing.Store(false)
+	write("delivered")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("expected a fallback file: %v", err)
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r models.TestResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("fallback line is not a result: %v", err)
+		}
+		names = append(names, r.Site.Name)
+	}
+	if len(names) != 2 || names[0] != "lost-1" || names[1] != "lost-2" {
+		t.Errorf("expected only results written while all outputs failed past the threshold, got %v", names)
+	}
+}
+
+func TestOutputFailurePolicyValidation(t *testing.T) {
+	d := NewDispatcher()
+	if err := d.SetOutputFailurePolicy(OutputFailureConfig{Policy: "panic"}); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+	if err := d.SetOutputFailurePolicy(OutputFailureConfig{Policy: OutputFailureFallbackFile}); err == nil {
+		t.Error("expected an error for fallback_file without a file")
+	}
+	if err := d.SetOutputFailurePolicy(OutputFailureConfig{}); err != nil {
+		t.Errorf("expected the default policy to be accepted, got %v", err)
+	}
+}
@@ -19,6 +19,117 @@ type TestResult struct {
 	// Timings collected during the test
 	Timings TimingMetrics `json:"timings"`
 
+	// ColdTimings and WarmTimings are only set for sites with MeasureWarm enabled.
+	// ColdTimings mirrors Timings; WarmTimings comes from a second navigation that
+	// reuses the cold test's connections, which is what returning users experience.
+	ColdTimings *TimingMetrics `json:"cold_timings,omitempty"`
+	WarmTimings *TimingMetrics `json:"warm_timings,omitempty"`
+
+	// CoreWebVitals holds the page's user-centric rendering metrics; nil in
+	// ttfb mode or when the browser reported none of them
+	CoreWebVitals *CoreWebVitals `json:"core_web_vitals,omitempty"`
+
+	// FailedSubresourceCount is the number of non-document requests that failed
+	FailedSubresourceCount int `json:"failed_subresource_count,omitempty"`
+
+	// FailedSubresources lists the first few failed subresource URLs
+	FailedSubresources []string `json:"failed_subresources,omitempty"`
+
+	// JSErrorCount is the number of uncaught JavaScript exceptions and
+	// console.error calls during the test
+	JSErrorCount int `json:"js_error_count,omitempty"`
+
+	// JSErrors lists the first few JavaScript error messages, each truncated
+	JSErrors []string `json:"js_errors,omitempty"`
+
+	// ConsoleErrorCount is the number of errors the browser itself logged to
+	// the console during the test: failed resource loads, content blocked by
+	// CSP or mixed content rules, and the like. The page's own errors are
+	// JSErrors.
+	ConsoleErrorCount int `json:"console_error_count,omitempty"`
+
+	// ConsoleErrors lists the first few console error messages, each truncated
+	ConsoleErrors []string `json:"console_errors,omitempty"`
+
+	// BlockedRequestCount is the number of requests blocked by the site's IgnoreResourceDomains
+	BlockedRequestCount int `json:"blocked_request_count,omitempty"`
+
+	// TotalRequests is the number of requests the page sent over the network,
+	// counting each redirect hop; blocked requests and data: URLs are not
+	// included. TotalBytes is what the finished ones transferred, headers and
+	// compressed bodies included. An unusually heavy or light page (say,
+	// because resources were blocked upstream) stands out in either.
+	TotalRequests int   `json:"total_requests,omitempty"`
+	TotalBytes    int64 `json:"total_bytes,omitempty"`
+
+	// SecurityHeaderGrade grades the document's security headers (HSTS, CSP,
+	// X-Content-Type-Options, ...) from A (best) to F; empty unless grading is
+	// enabled or when no document was received
+	SecurityHeaderGrade string `json:"security_header_grade,omitempty"`
+
+	// MissingSecurityHeaders and WeakSecurityHeaders list the graded headers
+	// the document lacked, or set too weakly (e.g. a short HSTS max-age)
+	MissingSecurityHeaders []string `json:"missing_security_headers,omitempty"`
+	WeakSecurityHeaders    []string `json:"weak_security_headers,omitempty"`
+
+	// ResponseHeaders holds the document's response headers, keyed by
+	// lowercase name (repeated headers are joined by newlines); nil unless
+	// response header capture is enabled
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+
+	// ServerTimings holds the server-side durations in milliseconds the
+	// document's Server-Timing header reported, keyed by metric name (e.g.
+	// "db", "app"); omitted when the header is absent
+	ServerTimings map[string]float64 `json:"server_timings,omitempty"`
+
+	// Endpoints has one entry per endpoint tested, for sites with several URLs.
+	// The rest of the result describes one of them: the first that succeeded,
+	// or the first that failed when the site as a whole is down.
+	Endpoints []EndpointResult `json:"endpoints,omitempty"`
+
+	// CaptivePortalDetected is set when the captive portal check found the
+	// network intercepted at the time of the test, so a successful load may
+	// have been the portal's page rather than the site, or when the test's own
+	// page turned out to be a portal's (see ERR_CAPTIVE_PORTAL)
+	CaptivePortalDetected bool `json:"captive_portal_detected,omitempty"`
+
+	// CircuitBreaker is the site's circuit breaker state after this result;
+	// nil unless the circuit breaker is enabled
+	CircuitBreaker *CircuitBreakerInfo `json:"circuit_breaker,omitempty"`
+
+	// Truncated is set when the browser's hard deadline cut the test short.
+	// Timings and other fields hold what was collected up to that point.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// FinalURL is the document URL after following redirects
+	FinalURL string `json:"final_url,omitempty"`
+
+	// RedirectChain is the redirects the document followed to reach FinalURL,
+	// in order (empty without redirects). A hop to an unexpected host, such
+	// as a login page, is the mark of captive portal interception.
+	RedirectChain []RedirectHop `json:"redirect_chain,omitempty"`
+
+	// RemoteIP and RemotePort are the peer Chrome connected to for the final
+	// document, showing CDN POP and anycast changes (empty if it never responded)
+	RemoteIP   string `json:"remote_ip,omitempty"`
+	RemotePort int    `json:"remote_port,omitempty"`
+
+	// Certificate describes the certificate the final document was served
+	// with; nil for plain HTTP or when no response arrived
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+
+	// TLS is the protocol version and cipher suite negotiated for the final
+	// document; nil for plain HTTP. A version or cipher older than usual
+	// points to a middlebox forcing a legacy fallback.
+	TLS *TLSInfo `json:"tls,omitempty"`
+
+	// HARPath is the HAR file written for this test, if any
+	HARPath string `json:"har_path,omitempty"`
+
+	// SuppressedDuplicates is the number of identical results dropped by
+	// deduplication since the previous result was emitted
+	SuppressedDuplicates int `json:"suppressed_duplicates,omitempty"`
+
 	// Error information (if test failed)
 	Error *ErrorInfo `json:"error,omitempty"`
 
@@ -26,6 +137,39 @@ type TestResult struct {
 	Metadata TestMetadata `json:"metadata,omitempty"`
 }
 
+// CertificateInfo describes a server's TLS certificate as Chrome saw it
+type CertificateInfo struct {
+	Subject         string    `json:"subject"`
+	Issuer          string    `json:"issuer"`
+	SubjectAltNames []string  `json:"subject_alt_names,omitempty"`
+	ValidFrom       time.Time `json:"valid_from"`
+	ValidTo         time.Time `json:"valid_to"`
+
+	// DaysUntilExpiry is the number of whole days left before ValidTo at the
+	// time of the test (negative once expired)
+	DaysUntilExpiry int `json:"days_until_expiry"`
+
+	// ExpiresSoon is set when DaysUntilExpiry is below the configured
+	// warning threshold
+	ExpiresSoon bool `json:"expires_soon,omitempty"`
+}
+
+// TLSInfo describes a negotiated TLS (or QUIC) connection, in Chrome's names
+type TLSInfo struct {
+	// Version is the protocol, e.g. "TLS 1.3", "TLS 1.2" or "QUIC"
+	Version string `json:"version"`
+
+	// Cipher is the bulk cipher, e.g. "AES_128_GCM"; MAC is only set for
+	// non-AEAD ciphers, which authenticate separately
+	Cipher string `json:"cipher"`
+	MAC    string `json:"mac,omitempty"`
+
+	// KeyExchange is the TLS 1.2 key exchange (e.g. "ECDHE_RSA"), empty under
+	// TLS 1.3; KeyExchangeGroup is the (EC)DH group, e.g. "X25519"
+	KeyExchange      string `json:"key_exchange,omitempty"`
+	KeyExchangeGroup string `json:"key_exchange_group,omitempty"`
+}
+
 // SiteInfo contains information about the tested site
 type SiteInfo struct {
 	URL      string `json:"url"`
@@ -38,6 +182,16 @@ type StatusInfo struct {
 	Success    bool   `json:"success"`
 	HTTPStatus int    `json:"http_status,omitempty"`
 	Message    string `json:"message,omitempty"`
+	// Protocol is the negotiated application protocol of the document (e.g. "h2", "h3")
+	Protocol string `json:"protocol,omitempty"`
+	// PageTitle is the loaded page's document.title (empty in ttfb mode)
+	PageTitle string `json:"page_title,omitempty"`
+	// Degraded is set on successful tests where the page loaded but is likely
+	// broken for users (e.g. too many failed subresources)
+	Degraded bool `json:"degraded,omitempty"`
+	// Skipped is set on synthetic failures for sites that were not tested
+	// because the network was found to be down earlier in the cycle
+	Skipped bool `json:"skipped,omitempty"`
 }
 
 // TimingMetrics contains all timing measurements in milliseconds
@@ -51,6 +205,11 @@ type TimingMetrics struct {
 	// TLSHandshakeMs is the time for TLS negotiation (nil if not available)
 	TLSHandshakeMs *int64 `json:"tls_handshake_ms,omitempty"`
 
+	// TimeToFinalURLMs is the time spent on redirects: from the first request
+	// until the last redirect was followed (0 without redirects, nil if not available).
+	// Subtract it from DOMContentLoadedMs or FullPageLoadMs to isolate the final page's delivery.
+	TimeToFinalURLMs *int64 `json:"time_to_final_url_ms,omitempty"`
+
 	// TimeToFirstByteMs is the time until first byte received (nil if not available)
 	TimeToFirstByteMs *int64 `json:"time_to_first_byte_ms,omitempty"`
 
@@ -67,6 +226,22 @@ type TimingMetrics struct {
 	TotalDurationMs int64 `json:"total_duration_ms"`
 }
 
+// CoreWebVitals are measured in the page by PerformanceObserver, up to the
+// moment the test finished loading it
+type CoreWebVitals struct {
+	// LargestContentfulPaintMs is when the largest image or text block was
+	// rendered, relative to navigation start (nil if not available)
+	LargestContentfulPaintMs *int64 `json:"lcp_ms,omitempty"`
+
+	// FirstContentfulPaintMs is when any text or image was first rendered,
+	// relative to navigation start (nil if not available)
+	FirstContentfulPaintMs *int64 `json:"fcp_ms,omitempty"`
+
+	// CumulativeLayoutShift is the largest burst of unexpected layout shifts,
+	// a unitless score where 0 is a page that didn't move (nil if not available)
+	CumulativeLayoutShift *float64 `json:"cls,omitempty"`
+}
+
 // ErrorInfo contains error details when a test fails
 type ErrorInfo struct {
 	// ErrorType is Chrome's error code (e.g., "ERR_NAME_NOT_RESOLVED", "ERR_ABORTED", "timeout")
@@ -77,7 +252,8 @@ type ErrorInfo struct {
 	ErrorMessage string `json:"error_message"`
 
 	// FailurePhase indicates which network layer failed (inferred from timing)
-	// Values: "dns", "tcp", "tls", "http", "unknown"
+	// Values: "dns", "tcp", "tls", "quic", "http", "auth" (our OAuth2 token
+	// could not be obtained), "unknown"
 	// Empty for successful requests
 	FailurePhase string `json:"failure_phase,omitempty"`
 
@@ -85,6 +261,35 @@ type ErrorInfo struct {
 	StackTrace string `json:"stack_trace,omitempty"`
 }
 
+// EndpointResult is the outcome of testing one endpoint of a multi-endpoint site
+type EndpointResult struct {
+	URL             string `json:"url"`
+	Success         bool   `json:"success"`
+	HTTPStatus      int    `json:"http_status,omitempty"`
+	TotalDurationMs int64  `json:"total_duration_ms"`
+	ErrorType       string `json:"error_type,omitempty"`
+}
+
+// RedirectHop is one redirect followed by the document: the URL that was
+// requested and the redirect status it answered with
+type RedirectHop struct {
+	URL        string `json:"url"`
+	HTTPStatus int    `json:"http_status"`
+}
+
+// CircuitBreakerInfo describes how often a site is being tested
+type CircuitBreakerInfo struct {
+	// State is "closed" (tested every round) or "open" (backed off after
+	// repeated failures; only tested once BackoffMs has passed)
+	State string `json:"state"`
+
+	// ConsecutiveFailures is the site's current run of failed tests
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// BackoffMs is how long the site is skipped before its next test (0 when closed)
+	BackoffMs int64 `json:"backoff_ms,omitempty"`
+}
+
 // TestMetadata contains information about the test environment
 type TestMetadata struct {
 	// Hostname of the monitor instance
@@ -95,4 +300,47 @@ type TestMetadata struct {
 
 	// Browser user agent
 	UserAgent string `json:"user_agent,omitempty"`
+
+	// MTLS is true when a client certificate was configured for the test
+	MTLS bool `json:"mtls,omitempty"`
+
+	// DoH is true when DNS was resolved over HTTPS instead of the system resolver
+	DoH bool `json:"doh,omitempty"`
+
+	// TestedURL is the URL actually loaded, when it differs from the site URL
+	// (cache busting, or the endpoint a multi-endpoint result describes)
+	TestedURL string `json:"tested_url,omitempty"`
+
+	// Mode is the site's test mode when it is not a full page load (e.g. "ttfb")
+	Mode string `json:"mode,omitempty"`
+
+	// Locale is the locale the browser emulated, from the site's AcceptLanguage
+	Locale string `json:"locale,omitempty"`
+
+	// ClockSkewMs is how far the monitor's clock was ahead of the site's Date
+	// header (negative when behind); nil unless clock skew checking is enabled
+	ClockSkewMs *int64 `json:"clock_skew_ms,omitempty"`
+
+	// ClockSkewWarning is set when ClockSkewMs exceeds the configured threshold,
+	// meaning Timestamp is likely wrong
+	ClockSkewWarning bool `json:"clock_skew_warning,omitempty"`
+
+	// EgressPublicIP is the monitor's public IP as last resolved (may lag a change
+	// by up to the refresh interval); empty unless egress IP lookup is enabled
+	EgressPublicIP string `json:"egress_public_ip,omitempty"`
+
+	// EgressPublicIPChanged is set on the first result after the public IP changed
+	EgressPublicIPChanged bool `json:"egress_public_ip_changed,omitempty"`
+
+	// Interface and SourceIP are the network interface and local address the
+	// test's connections used; empty unless the site sets Interface or SourceIP
+	Interface string `json:"interface,omitempty"`
+	SourceIP  string `json:"source_ip,omitempty"`
+
+	// ScreenshotPath is the screenshot taken when the test failed, if any
+	ScreenshotPath string `json:"screenshot_path,omitempty"`
+
+	// IPVersion is the address family ("4" or "6") the site's host was
+	// pinned to; empty unless the site sets IPVersion
+	IPVersion string `json:"ip_version,omitempty"`
 }
@@ -7,12 +7,27 @@ type SiteDefinition struct {
 	// URL is the full URL to test (e.g., "https://www.google.com")
 	URL string `yaml:"url" json:"url"`
 
+	// URLs are redundant endpoints of the same service (e.g. primary and
+	// secondary), tested as one site according to URLPolicy. When set they
+	// are what gets loaded; URL (or, if empty, the first endpoint) remains
+	// the site's identity in results and statistics.
+	URLs []string `yaml:"urls" json:"urls,omitempty"`
+
+	// URLPolicy decides when a site with URLs is up: "any" (default) tests
+	// every endpoint and needs one to succeed, "all" needs every endpoint to
+	// succeed, "first" tries endpoints in order and stops at the first success
+	URLPolicy string `yaml:"url_policy" json:"url_policy,omitempty"`
+
 	// Name is a short, human-readable identifier (e.g., "google")
 	Name string `yaml:"name" json:"name"`
 
 	// Category groups sites by type (e.g., "search", "social", "infrastructure")
 	Category string `yaml:"category" json:"category"`
 
+	// Weight is the site's relative importance in the cross-site availability
+	// verdict (unset or <= 0 counts as 1); see metrics.Availability
+	Weight float64 `yaml:"weight" json:"weight,omitempty"`
+
 	// TimeoutSeconds is the maximum time to wait for this site to load
 	TimeoutSeconds int `yaml:"timeout_seconds" json:"timeout_seconds"`
 
@@ -22,8 +37,109 @@ type SiteDefinition struct {
 	// ExpectedElements are DOM selectors that should be present for the test to succeed
 	ExpectedElements []string `yaml:"expected_elements" json:"expected_elements,omitempty"`
 
+	// ExpectedTitleContains fails a loaded page whose title doesn't contain
+	// this text (case-sensitive) with ERR_CONTENT_MISMATCH, catching error
+	// and splash pages served with a 200. Empty accepts any title.
+	ExpectedTitleContains string `yaml:"expected_title_contains" json:"expected_title_contains,omitempty"`
+
+	// HealthCheckJS is a JavaScript expression evaluated in the loaded page
+	// (promises are awaited); a falsy result or an exception fails the test
+	// with HEALTHCHECK_FAILED. Empty skips the check.
+	HealthCheckJS string `yaml:"health_check_js" json:"health_check_js,omitempty"`
+
 	// CustomHeaders to send with the request
 	CustomHeaders map[string]string `yaml:"custom_headers" json:"custom_headers,omitempty"`
+
+	// AcceptLanguage is sent as the Accept-Language header (e.g. "de-DE,de;q=0.9"),
+	// and its first language becomes the browser's locale (navigator.language,
+	// Intl formatting). Empty leaves the browser default.
+	AcceptLanguage string `yaml:"accept_language" json:"accept_language,omitempty"`
+
+	// MeasureWarm runs a second navigation in the same browser after the cold one,
+	// reusing its DNS/TCP/TLS state. This doubles the cost of testing the site.
+	MeasureWarm bool `yaml:"measure_warm" json:"measure_warm,omitempty"`
+
+	// ForceHTTP3 forces QUIC/HTTP3 for the site's origin and fails the test
+	// if the document is not served over HTTP/3
+	ForceHTTP3 bool `yaml:"force_http3" json:"force_http3,omitempty"`
+
+	// ClientCert is the client certificate to present for mutual TLS (nil = none)
+	ClientCert *ClientCertificate `yaml:"client_cert" json:"client_cert,omitempty"`
+
+	// OAuth2 obtains a bearer token for the site with the client credentials
+	// grant and sends it as the Authorization header to the site's origin
+	// (nil = none)
+	OAuth2 *OAuth2Credentials `yaml:"oauth2" json:"oauth2,omitempty"`
+
+	// MaxFailedSubresources marks a loaded page as degraded when more subresource
+	// requests than this fail (0 = never degrade)
+	MaxFailedSubresources int `yaml:"max_failed_subresources" json:"max_failed_subresources,omitempty"`
+
+	// DegradeOnJSErrors marks a loaded page as degraded when it throws an
+	// uncaught exception or logs a console error
+	DegradeOnJSErrors bool `yaml:"degrade_on_js_errors" json:"degrade_on_js_errors,omitempty"`
+
+	// CacheBust makes every test fetch a unique URL to defeat caching layers:
+	// "query" appends a random query parameter, "subdomain" prepends a random
+	// label to the host (requires a wildcard DNS record). Empty disables it.
+	CacheBust string `yaml:"cache_bust" json:"cache_bust,omitempty"`
+
+	// ExpectedStatusRanges are the document HTTP statuses that count as success,
+	// as single codes or inclusive spans (e.g. ["200-299", "401"]). Redirects are
+	// followed, so this applies to the final response. Empty means 2xx.
+	ExpectedStatusRanges []string `yaml:"expected_status_ranges" json:"expected_status_ranges,omitempty"`
+
+	// Mode selects how much of the page is loaded: "full" (default) loads the
+	// page and its subresources; "ttfb" stops as soon as the main document's
+	// response arrives and records only DNS, TCP, TLS, and TTFB timings;
+	// "realistic" is a full load that may reuse connections from earlier tests
+	// when the browser pool is enabled
+	Mode string `yaml:"mode" json:"mode,omitempty"`
+
+	// IgnoreResourceDomains are domains (including their subdomains) whose
+	// requests are blocked, so third-party analytics and ad beacons don't
+	// delay or pollute the page's load timings
+	IgnoreResourceDomains []string `yaml:"ignore_resource_domains" json:"ignore_resource_domains,omitempty"`
+
+	// Interface and SourceIP pin the site's connections to one uplink of a
+	// multi-homed host: Interface binds to a network interface by name
+	// (e.g. "wan2", Linux only, needs CAP_NET_RAW) and SourceIP to a local
+	// address. Either or both may be set. Chrome can't do this itself, so the
+	// test runs through a local proxy that makes the bound connections and
+	// resolves DNS over them; the DNS and TCP phase timings then describe
+	// the proxied connection rather than Chrome's own lookup.
+	Interface string `yaml:"interface" json:"interface,omitempty"`
+	SourceIP  string `yaml:"source_ip" json:"source_ip,omitempty"`
+
+	// ProxyURL loads the site through its own proxy instead of the browser's
+	// proxy_url. It may carry credentials, so it is never serialized.
+	ProxyURL string `yaml:"proxy_url" json:"-"`
+
+	// BasicAuthUser and BasicAuthPass answer the site's HTTP authentication
+	// challenge (basic or digest). They are only offered to the site's own
+	// origin and are never serialized.
+	BasicAuthUser string `yaml:"basic_auth_user" json:"-"`
+	BasicAuthPass string `yaml:"basic_auth_pass" json:"-"`
+
+	// IPVersion pins the site's host to one address family: "4" or "6".
+	// Empty uses whichever Chrome picks.
+	IPVersion string `yaml:"ip_version" json:"ip_version,omitempty"`
+}
+
+// ClientCertificate identifies a PEM-encoded client certificate and key on disk.
+// The certificate must also be imported into Chrome's NSS database; see the browser package.
+type ClientCertificate struct {
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"-"`
+}
+
+// OAuth2Credentials identify an OAuth2 client for the client credentials grant.
+// The secret is never serialized into results.
+type OAuth2Credentials struct {
+	TokenURL     string   `yaml:"token_url" json:"token_url"`
+	ClientID     string   `yaml:"client_id" json:"client_id"`
+	ClientSecret string   `yaml:"client_secret" json:"-"`
+	Scopes       []string `yaml:"scopes" json:"scopes,omitempty"`
 }
 
 // GetTimeout returns the timeout duration for this site
@@ -34,6 +150,15 @@ func (s *SiteDefinition) GetTimeout() time.Duration {
 	return time.Duration(s.TimeoutSeconds) * time.Second
 }
 
+// GetIdentity returns the URL a site is reported and tracked under: URL, or
+// its first endpoint when only URLs is set
+func (s *SiteDefinition) GetIdentity() string {
+	if s.URL != "" || len(s.URLs) == 0 {
+		return s.URL
+	}
+	return s.URLs[0]
+}
+
 // GetName returns the site name, deriving it from URL if not set
 func (s *SiteDefinition) GetName() string {
 	if s.Name != "" {
@@ -0,0 +1,25 @@
+package outputs
+
+import "sync"
+
+// backendHealth remembers whether an asynchronous output's last delivery to
+// its backend failed. Write only queues results there, so without it an
+// unreachable backend would never show up as a failed write.
+type backendHealth struct {
+	mu  sync.Mutex
+	err error
+}
+
+// record notes the outcome of the latest delivery, nil for success
+func (h *backendHealth) record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err = err
+}
+
+// lastErr returns the error of the latest delivery, nil if it succeeded
+func (h *backendHealth) lastErr() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
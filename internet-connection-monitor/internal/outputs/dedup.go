@@ -0,0 +1,116 @@
+package outputs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/metrics"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Dedup wraps an output and collapses runs of identical consecutive failures.
+//
+// For each site, the first failure of a run is passed through. Further failures
+// with the same error type and phase are suppressed, except for a "still down"
+// heartbeat every HeartbeatInterval. The recovery (next success) is always passed
+// through. Every emitted result carries the number of results suppressed since
+// the previous emitted one in SuppressedDuplicates, so totals can be rebuilt.
+//
+// Only wrap outputs that record individual results (logs, Elasticsearch); outputs
+// that compute statistics (SNMP, Prometheus) need every result.
+type Dedup struct {
+	inner             metrics.Output
+	heartbeatInterval time.Duration
+
+	mu    sync.Mutex
+	sites map[string]*dedupState
+}
+
+// dedupState tracks the current failure run for one site
+type dedupState struct {
+	key         string
+	lastEmitted time.Time
+	suppressed  int
+}
+
+// NewDedup wraps inner with failure deduplication.
+// A heartbeatInterval of 0 suppresses all repeats until recovery.
+func NewDedup(inner metrics.Output, heartbeatInterval time.Duration) *Dedup {
+	return &Dedup{
+		inner:             inner,
+		heartbeatInterval: heartbeatInterval,
+		sites:             make(map[string]*dedupState),
+	}
+}
+
+// Write forwards the result unless it repeats the site's ongoing failure, in
+// which case it returns metrics.ErrSuppressed
+func (d *Dedup) Write(result *models.TestResult) error {
+	siteName := result.Site.Name
+	if siteName == "" {
+		siteName = result.Site.URL
+	}
+
+	d.mu.Lock()
+	state := d.sites[siteName]
+
+	if result.Status.Success {
+		delete(d.sites, siteName)
+		d.mu.Unlock()
+		if state == nil {
+			return d.inner.Write(result)
+		}
+		// Recovery
+		return d.inner.Write(withSuppressed(result, state.suppressed))
+	}
+
+	key := failureKey(result)
+	if state == nil || state.key != key {
+		// First failure of a new run; carry over anything suppressed from a previous run
+		d.sites[siteName] = &dedupState{key: key, lastEmitted: result.Timestamp}
+		d.mu.Unlock()
+		if state == nil || state.suppressed == 0 {
+			return d.inner.Write(result)
+		}
+		return d.inner.Write(withSuppressed(result, state.suppressed))
+	}
+
+	if d.heartbeatInterval > 0 && result.Timestamp.Sub(state.lastEmitted) >= d.heartbeatInterval {
+		suppressed := state.suppressed
+		state.suppressed = 0
+		state.lastEmitted = result.Timestamp
+		d.mu.Unlock()
+		return d.inner.Write(withSuppressed(result, suppressed))
+	}
+
+	state.suppressed++
+	d.mu.Unlock()
+	return metrics.ErrSuppressed
+}
+
+// IsSink reports whether the wrapped output is a metrics.Sink
+func (d *Dedup) IsSink() bool {
+	s, ok := d.inner.(metrics.Sink)
+	return ok && s.IsSink()
+}
+
+// Name returns the wrapped output's name
+func (d *Dedup) Name() string {
+	return "dedup(" + d.inner.Name() + ")"
+}
+
+// failureKey identifies "the same failure" for deduplication
+func failureKey(result *models.TestResult) string {
+	if result.Error == nil {
+		return ""
+	}
+	return result.Error.ErrorType + "|" + result.Error.FailurePhase
+}
+
+// withSuppressed returns a copy of result annotated with a suppressed count.
+// The dispatcher shares one result across outputs, so it must not be mutated.
+func withSuppressed(result *models.TestResult, suppressed int) *models.TestResult {
+	annotated := *result
+	annotated.SuppressedDuplicates = suppressed
+	return &annotated
+}
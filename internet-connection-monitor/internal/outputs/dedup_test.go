@@ -0,0 +1,116 @@
+package outputs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/metrics"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// recordingOutput collects every result written to it
+type recordingOutput struct {
+	results []*models.TestResult
+}
+
+func (r *recordingOutput) Write(result *models.TestResult) error {
+	r.results = append(r.results, result)
+	return nil
+}
+
+func (r *recordingOutput) Name() string {
+	return "recording"
+}
+
+func TestDedupCollapsesIdenticalFailures(t *testing.T) {
+	inner := &recordingOutput{}
+	dedup := NewDedup(inner, time.Minute)
+
+	start := time.Now()
+	failure := func(offset time.Duration, errorType string) *models.TestResult {
+		return &models.TestResult{
+			Timestamp: start.Add(offset),
+			Site:      models.SiteInfo{Name: "example"},
+			Error:     &models.ErrorInfo{ErrorType: errorType, FailurePhase: "dns"},
+		}
+	}
+
+	// 0s first failure, 10-50s suppressed, 60s heartbeat, 70s suppressed,
+	// 80s different error, 90s recovery
+	writes := []*models.TestResult{
+		failure(0, "ERR_NAME_NOT_RESOLVED"),
+		failure(10*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(20*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(30*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(40*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(50*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(60*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(70*time.Second, "ERR_NAME_NOT_RESOLVED"),
+		failure(80*time.Second, "ERR_CONNECTION_REFUSED"),
+		{
+			Timestamp: start.Add(90 * time.Second),
+			Site:      models.SiteInfo{Name: "example"},
+			Status:    models.StatusInfo{Success: true},
+		},
+	}
+	for _, r := range writes {
+		if err := dedup.Write(r); err != nil && !errors.Is(err, metrics.ErrSuppressed) {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	expected := []struct {
+		offset     time.Duration
+		suppressed int
+	}{
+		{0, 0},                // first failure
+		{60 * time.Second, 5}, // heartbeat covering 10-50s
+		{80 * time.Second, 1}, // new failure type, carrying 70s
+		{90 * time.Second, 0}, // recovery
+	}
+	if len(inner.results) != len(expected) {
+		t.Fatalf("expected %d emitted results, got %d", len(expected), len(inner.results))
+	}
+
+	for i, want := range expected {
+		got := inner.results[i]
+		if !got.Timestamp.Equal(start.Add(want.offset)) {
+			t.Errorf("result %d: expected timestamp +%v, got +%v", i, want.offset, got.Timestamp.Sub(start))
+		}
+		if got.SuppressedDuplicates != want.suppressed {
+			t.Errorf("result %d: expected %d suppressed, got %d", i, want.suppressed, got.SuppressedDuplicates)
+		}
+	}
+
+	// Suppressed count must be annotated on a copy, not the shared result
+	if writes[6].SuppressedDuplicates != 0 {
+		t.Errorf("dedup must not mutate the dispatched result")
+	}
+}
+
+func TestDedupRecoveryCarriesSuppressedCount(t *testing.T) {
+	inner := &recordingOutput{}
+	dedup := NewDedup(inner, 0)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		dedup.Write(&models.TestResult{
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Site:      models.SiteInfo{Name: "example"},
+			Error:     &models.ErrorInfo{ErrorType: "timeout", FailurePhase: "http"},
+		})
+	}
+	dedup.Write(&models.TestResult{
+		Timestamp: start.Add(5 * time.Hour),
+		Site:      models.SiteInfo{Name: "example"},
+		Status:    models.StatusInfo{Success: true},
+	})
+
+	if len(inner.results) != 2 {
+		t.Fatalf("expected first failure and recovery only, got %d results", len(inner.results))
+	}
+	if got := inner.results[1].SuppressedDuplicates; got != 3 {
+		t.Errorf("expected recovery to report 3 suppressed failures, got %d", got)
+	}
+}
@@ -28,6 +28,7 @@ type ElasticsearchOutput struct {
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
 	resultChannel chan *models.TestResult
+	health        *backendHealth
 }
 
 // NewElasticsearchOutput creates a new Elasticsearch output
@@ -80,6 +81,7 @@ func NewElasticsearchOutput(cfg *config.ElasticsearchConfig) (*ElasticsearchOutp
 	log.Printf("Connected to Elasticsearch at %s", cfg.Endpoint)
 
 	// Create bulk indexer
+	health := &backendHealth{}
 	bulkIndexer, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
 		Client:        client,
 		NumWorkers:    2,
@@ -87,6 +89,7 @@ func NewElasticsearchOutput(cfg *config.ElasticsearchConfig) (*ElasticsearchOutp
 		FlushInterval: cfg.FlushInterval,
 		OnError: func(ctx context.Context, err error) {
 			log.Printf("Elasticsearch bulk indexer error: %v", err)
+			health.record(err)
 		},
 	})
 	if err != nil {
@@ -102,6 +105,7 @@ func NewElasticsearchOutput(cfg *config.ElasticsearchConfig) (*ElasticsearchOutp
 		ctx:           ctx,
 		cancel:        cancel,
 		resultChannel: make(chan *models.TestResult, 100),
+		health:        health,
 	}
 
 	// Start background worker to process results
@@ -147,12 +151,17 @@ func (e *ElasticsearchOutput) indexResult(result *models.TestResult) error {
 			Index:      indexName,
 			DocumentID: result.TestID,
 			Body:       bytes.NewReader(data),
+			OnSuccess: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem) {
+				e.health.record(nil)
+			},
 			OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
 				if err != nil {
 					log.Printf("Elasticsearch indexing error: %v", err)
 				} else {
 					log.Printf("Elasticsearch indexing failed: %s: %s", res.Error.Type, res.Error.Reason)
+					err = fmt.Errorf("%s: %s", res.Error.Type, res.Error.Reason)
 				}
+				e.health.record(err)
 			},
 		},
 	)
@@ -192,16 +201,18 @@ func (e *ElasticsearchOutput) Write(result *models.TestResult) error {
 		return nil
 	}
 
-	// Send to channel for async processing
+	// Send to channel for async processing. The result is queued even while
+	// Elasticsearch is failing, so its recovery is noticed.
 	select {
 	case e.resultChannel <- result:
+		if err := e.health.lastErr(); err != nil {
+			return fmt.Errorf("Elasticsearch is failing: %w", err)
+		}
 		return nil
 	case <-e.ctx.Done():
 		return fmt.Errorf("Elasticsearch output is shutting down")
 	default:
-		// Channel is full, log and drop
-		log.Printf("Warning: Elasticsearch result channel is full, dropping result")
-		return nil
+		return fmt.Errorf("Elasticsearch result channel is full, dropping result")
 	}
 }
 
@@ -210,6 +221,11 @@ func (e *ElasticsearchOutput) Name() string {
 	return "elasticsearch"
 }
 
+// IsSink marks Elasticsearch as a metrics.Sink
+func (e *ElasticsearchOutput) IsSink() bool {
+	return true
+}
+
 // Close flushes pending documents and closes the connection
 func (e *ElasticsearchOutput) Close() error {
 	if e == nil {
@@ -0,0 +1,262 @@
+package outputs
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// MetricType says how a metric's value behaves over time
+type MetricType string
+
+const (
+	// MetricCounter only ever increases (until the monitor restarts)
+	MetricCounter MetricType = "counter"
+	// MetricGauge can go up and down
+	MetricGauge MetricType = "gauge"
+)
+
+// MetricDefinition describes a metric once, for every output that exports it
+type MetricDefinition struct {
+	Name string
+	Type MetricType
+	Unit string // e.g. "ms", "seconds", "percent"; empty for plain counts and flags
+	Help string
+}
+
+// MetricPoint is one value of a metric for one set of labels
+type MetricPoint struct {
+	Name      string
+	Labels    map[string]string
+	Type      MetricType
+	Value     float64
+	Timestamp time.Time
+}
+
+// MetricsSnapshot is every metric the monitor exports at a point in time, as
+// a flat list of points sorted by name and then site. It is derived once from
+// the per-site statistics and the most recent results, so an output only has
+// to encode it rather than re-derive metrics from siteStats.
+type MetricsSnapshot struct {
+	Timestamp time.Time
+	Points    []MetricPoint
+}
+
+// Metric names. Per-site points carry a "site" label, plus "category" when
+// the site's latest result names one.
+const (
+	MetricMonitoredSites = "internet_monitor_monitored_sites"
+
+	MetricTestsTotal           = "internet_monitor_tests_total"
+	MetricTestsSuccessfulTotal = "internet_monitor_tests_successful_total"
+	MetricTestsFailedTotal     = "internet_monitor_tests_failed_total"
+	MetricLastSuccessTimestamp = "internet_monitor_last_success_timestamp_seconds"
+	MetricLastFailureTimestamp = "internet_monitor_last_failure_timestamp_seconds"
+	MetricLastDuration         = "internet_monitor_last_duration_ms"
+	MetricAvgDuration          = "internet_monitor_avg_duration_ms"
+	MetricMinDuration          = "internet_monitor_min_duration_ms"
+	MetricMaxDuration          = "internet_monitor_max_duration_ms"
+	MetricEWMADuration         = "internet_monitor_ewma_duration_ms"
+	MetricStdDevDuration       = "internet_monitor_stddev_duration_ms"
+	MetricStdDevDNS            = "internet_monitor_stddev_dns_lookup_ms"
+	MetricStdDevTCP            = "internet_monitor_stddev_tcp_connection_ms"
+	MetricStdDevTLS            = "internet_monitor_stddev_tls_handshake_ms"
+	MetricStdDevTTFB           = "internet_monitor_stddev_time_to_first_byte_ms"
+	MetricUptimePercent        = "internet_monitor_uptime_percent"
+	MetricRecentUptimePercent  = "internet_monitor_recent_uptime_percent"
+	MetricCircuitBreakerOpen   = "internet_monitor_circuit_breaker_open"
+
+	MetricUp              = "internet_monitor_up"
+	MetricDNSLookup       = "internet_monitor_dns_lookup_ms"
+	MetricTCPConnection   = "internet_monitor_tcp_connection_ms"
+	MetricTLSHandshake    = "internet_monitor_tls_handshake_ms"
+	MetricTimeToFirstByte = "internet_monitor_time_to_first_byte_ms"
+)
+
+// metricDefinitions lists every metric a snapshot can contain
+var metricDefinitions = []MetricDefinition{
+	{MetricMonitoredSites, MetricGauge, "", "Number of sites with statistics"},
+
+	{MetricTestsTotal, MetricCounter, "", "Total tests performed"},
+	{MetricTestsSuccessfulTotal, MetricCounter, "", "Successful tests"},
+	{MetricTestsFailedTotal, MetricCounter, "", "Failed tests"},
+	{MetricLastSuccessTimestamp, MetricGauge, "seconds", "Unix time of the last success (0 if never)"},
+	{MetricLastFailureTimestamp, MetricGauge, "seconds", "Unix time of the last failure (0 if never)"},
+	{MetricLastDuration, MetricGauge, "ms", "Duration of the last test"},
+	{MetricAvgDuration, MetricGauge, "ms", "Average test duration"},
+	{MetricMinDuration, MetricGauge, "ms", "Minimum test duration"},
+	{MetricMaxDuration, MetricGauge, "ms", "Maximum test duration"},
+	{MetricEWMADuration, MetricGauge, "ms", "Exponentially weighted moving average of the test duration, favouring recent tests"},
+	{MetricStdDevDuration, MetricGauge, "ms", "Standard deviation (jitter) of the test duration"},
+	{MetricStdDevDNS, MetricGauge, "ms", "Standard deviation of the DNS lookup time"},
+	{MetricStdDevTCP, MetricGauge, "ms", "Standard deviation of the TCP connection time"},
+	{MetricStdDevTLS, MetricGauge, "ms", "Standard deviation of the TLS handshake time"},
+	{MetricStdDevTTFB, MetricGauge, "ms", "Standard deviation of the time to first byte"},
+	{MetricUptimePercent, MetricGauge, "percent", "Percentage of all tests that succeeded"},
+	{MetricRecentUptimePercent, MetricGauge, "percent", "Percentage of the site's recent tests that succeeded"},
+	{MetricCircuitBreakerOpen, MetricGauge, "", "1 while the site is backed off after repeated failures, else 0 (only with the circuit breaker enabled)"},
+
+	{MetricUp, MetricGauge, "", "1 if the site's latest test succeeded, else 0"},
+	{MetricDNSLookup, MetricGauge, "ms", "DNS lookup time of the latest test that measured it"},
+	{MetricTCPConnection, MetricGauge, "ms", "TCP connection time of the latest test that measured it"},
+	{MetricTLSHandshake, MetricGauge, "ms", "TLS handshake time of the latest test that measured it"},
+	{MetricTimeToFirstByte, MetricGauge, "ms", "Time to first byte of the latest test that measured it"},
+}
+
+// MetricDefinitions returns the definition of every metric a snapshot can contain
+func MetricDefinitions() []MetricDefinition {
+	defs := make([]MetricDefinition, len(metricDefinitions))
+	copy(defs, metricDefinitions)
+	return defs
+}
+
+// MetricDefinitionFor returns the definition of the named metric
+func MetricDefinitionFor(name string) (MetricDefinition, bool) {
+	for _, def := range metricDefinitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return MetricDefinition{}, false
+}
+
+// metricHelp returns the named metric's help text with its unit, for outputs
+// that register metrics by hand
+func metricHelp(name string) string {
+	def, _ := MetricDefinitionFor(name)
+	if def.Unit == "ms" {
+		return def.Help + " in milliseconds"
+	}
+	return def.Help
+}
+
+// BuildMetricsSnapshot derives the metric points for the given per-site
+// statistics and recent results (oldest first). Statistics points are
+// stamped now; points taken from a result carry the result's timestamp.
+// Results for sites without statistics are ignored.
+func BuildMetricsSnapshot(stats map[string]SiteStatsSnapshot, recent []*models.TestResult, now time.Time) MetricsSnapshot {
+	var b snapshotBuilder
+
+	// The newest result per site, and per timing phase the newest that measured it
+	type phase struct {
+		name string
+		get  func(*models.TimingMetrics) *int64
+	}
+	phases := []phase{
+		{MetricDNSLookup, func(t *models.TimingMetrics) *int64 { return t.DNSLookupMs }},
+		{MetricTCPConnection, func(t *models.TimingMetrics) *int64 { return t.TCPConnectionMs }},
+		{MetricTLSHandshake, func(t *models.TimingMetrics) *int64 { return t.TLSHandshakeMs }},
+		{MetricTimeToFirstByte, func(t *models.TimingMetrics) *int64 { return t.TimeToFirstByteMs }},
+	}
+	latest := make(map[string]*models.TestResult)
+	latestPhase := make(map[string]map[string]*models.TestResult)
+	for _, r := range recent {
+		name := resultSiteName(r)
+		if _, ok := stats[name]; !ok {
+			continue
+		}
+		latest[name] = r
+		for _, p := range phases {
+			if p.get(&r.Timings) != nil {
+				if latestPhase[name] == nil {
+					latestPhase[name] = make(map[string]*models.TestResult)
+				}
+				latestPhase[name][p.name] = r
+			}
+		}
+	}
+
+	b.add(MetricMonitoredSites, nil, float64(len(stats)), now)
+
+	for name, st := range stats {
+		labels := map[string]string{"site": name}
+		if r := latest[name]; r != nil && r.Site.Category != "" {
+			labels["category"] = r.Site.Category
+		}
+
+		b.add(MetricTestsTotal, labels, float64(st.TotalTests), now)
+		b.add(MetricTestsSuccessfulTotal, labels, float64(st.SuccessfulTests), now)
+		b.add(MetricTestsFailedTotal, labels, float64(st.FailedTests), now)
+		b.add(MetricLastSuccessTimestamp, labels, unixSeconds(st.LastSuccessTime), now)
+		b.add(MetricLastFailureTimestamp, labels, unixSeconds(st.LastFailureTime), now)
+		b.add(MetricLastDuration, labels, float64(st.LastDurationMs), now)
+		b.add(MetricAvgDuration, labels, st.AvgDurationMs, now)
+		b.add(MetricMinDuration, labels, float64(st.MinDurationMs), now)
+		b.add(MetricMaxDuration, labels, float64(st.MaxDurationMs), now)
+		b.add(MetricEWMADuration, labels, st.EWMADurationMs, now)
+		b.add(MetricStdDevDuration, labels, st.StdDevDurationMs, now)
+		b.add(MetricStdDevDNS, labels, st.StdDevDNSMs, now)
+		b.add(MetricStdDevTCP, labels, st.StdDevTCPMs, now)
+		b.add(MetricStdDevTLS, labels, st.StdDevTLSMs, now)
+		b.add(MetricStdDevTTFB, labels, st.StdDevTTFBMs, now)
+		b.add(MetricUptimePercent, labels, st.UptimePercent, now)
+		b.add(MetricRecentUptimePercent, labels, st.RecentUptimePercent, now)
+		b.add(MetricCircuitBreakerOpen, labels, boolValue(st.CircuitBreakerOpen), now)
+
+		if r := latest[name]; r != nil {
+			b.add(MetricUp, labels, boolValue(r.Status.Success), r.Timestamp)
+		}
+		for _, p := range phases {
+			if r := latestPhase[name][p.name]; r != nil {
+				b.add(p.name, labels, float64(*p.get(&r.Timings)), r.Timestamp)
+			}
+		}
+	}
+
+	sort.Slice(b.points, func(i, j int) bool {
+		if b.points[i].Name != b.points[j].Name {
+			return b.points[i].Name < b.points[j].Name
+		}
+		return b.points[i].Labels["site"] < b.points[j].Labels["site"]
+	})
+	return MetricsSnapshot{Timestamp: now, Points: b.points}
+}
+
+// snapshotBuilder collects points, taking each metric's type from its definition
+type snapshotBuilder struct {
+	points []MetricPoint
+}
+
+func (b *snapshotBuilder) add(name string, labels map[string]string, value float64, ts time.Time) {
+	def, _ := MetricDefinitionFor(name)
+	b.points = append(b.points, MetricPoint{
+		Name:      name,
+		Labels:    labels,
+		Type:      def.Type,
+		Value:     value,
+		Timestamp: ts,
+	})
+}
+
+// MetricsSnapshot returns the metrics derived from the agent's per-site
+// statistics and cached results
+func (s *SNMPOutput) MetricsSnapshot() MetricsSnapshot {
+	if s == nil {
+		return MetricsSnapshot{}
+	}
+	return BuildMetricsSnapshot(s.GetAllStatsSnapshot(), s.GetCachedResults(), s.now())
+}
+
+// resultSiteName is the name a result's statistics are kept under
+func resultSiteName(result *models.TestResult) string {
+	if result.Site.Name != "" {
+		return result.Site.Name
+	}
+	return result.Site.URL
+}
+
+// unixSeconds returns t as Unix seconds, 0 for the zero time
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+package outputs
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// pointsByKey indexes a snapshot's points by metric name and site label
+func pointsByKey(t *testing.T, snapshot MetricsSnapshot) map[[2]string]MetricPoint {
+	t.Helper()
+	points := make(map[[2]string]MetricPoint)
+	for _, p := range snapshot.Points {
+		key := [2]string{p.Name, p.Labels["site"]}
+		if _, dup := points[key]; dup {
+			t.Errorf("duplicate point %s{site=%q}", p.Name, p.Labels["site"])
+		}
+		points[key] = p
+	}
+	return points
+}
+
+func TestBuildMetricsSnapshot(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	lastSuccess := now.Add(-time.Minute)
+	stats := map[string]SiteStatsSnapshot{
+		"google": {
+			TotalTests: 10, SuccessfulTests: 9, FailedTests: 1,
+			LastSuccessTime: lastSuccess, LastFailureTime: now.Add(-time.Hour),
+			LastDurationMs: 120, AvgDurationMs: 110.5, MinDurationMs: 90, MaxDurationMs: 300,
+			EWMADurationMs: 115, StdDevDurationMs: 12.5, StdDevDNSMs: 1.5,
+			StdDevTCPMs: 2.5, StdDevTLSMs: 3.5, StdDevTTFBMs: 4.5,
+			UptimePercent: 90, RecentUptimePercent: 100,
+		},
+		"down": {TotalTests: 1, FailedTests: 1, CircuitBreakerOpen: true},
+	}
+	dns, oldDNS, ttfb := int64(7), int64(30), int64(80)
+	recent := []*models.TestResult{
+		{Timestamp: now.Add(-2 * time.Minute), Site: models.SiteInfo{Name: "google"}, Timings: models.TimingMetrics{DNSLookupMs: &oldDNS}},
+		{Timestamp: lastSuccess, Site: models.SiteInfo{Name: "google", Category: "search"}, Status: models.StatusInfo{Success: true},
+			Timings: models.TimingMetrics{DNSLookupMs: &dns, TimeToFirstByteMs: &ttfb}},
+		{Timestamp: now, Site: models.SiteInfo{Name: "down"}},
+		{Timestamp: now, Site: models.SiteInfo{Name: "removed"}, Status: models.StatusInfo{Success: true}},
+	}
+
+	snapshot := BuildMetricsSnapshot(stats, recent, now)
+	if !snapshot.Timestamp.Equal(now) {
+		t.Errorf("expected snapshot timestamp %v, got %v", now, snapshot.Timestamp)
+	}
+	points := pointsByKey(t, snapshot)
+
+	// Every point is defined, typed as defined, and in order
+	for _, p := range snapshot.Points {
+		def, ok := MetricDefinitionFor(p.Name)
+		if !ok {
+			t.Errorf("point %s has no definition", p.Name)
+		} else if p.Type != def.Type {
+			t.Errorf("point %s has type %s, defined as %s", p.Name, p.Type, def.Type)
+		}
+		if p.Labels["site"] == "removed" {
+			t.Errorf("unexpected point %s for a site without statistics", p.Name)
+		}
+	}
+	if !sort.SliceIsSorted(snapshot.Points, func(i, j int) bool {
+		a, b := snapshot.Points[i], snapshot.Points[j]
+		return a.Name < b.Name || (a.Name == b.Name && a.Labels["site"] < b.Labels["site"])
+	}) {
+		t.Error("expected points sorted by name and site")
+	}
+
+	// A site with results and every phase measured has every metric
+	want := map[string]float64{
+		MetricTestsTotal:           10,
+		MetricTestsSuccessfulTotal: 9,
+		MetricTestsFailedTotal:     1,
+		MetricLastSuccessTimestamp: float64(lastSuccess.Unix()),
+		MetricLastFailureTimestamp: float64(now.Add(-time.Hour).Unix()),
+		MetricLastDuration:         120,
+		MetricAvgDuration:          110.5,
+		MetricMinDuration:          90,
+		MetricMaxDuration:          300,
+		MetricEWMADuration:         115,
+		MetricStdDevDuration:       12.5,
+		MetricStdDevDNS:            1.5,
+		MetricStdDevTCP:            2.5,
+		MetricStdDevTLS:            3.5,
+		MetricStdDevTTFB:           4.5,
+		MetricUptimePercent:        90,
+		MetricRecentUptimePercent:  100,
+		MetricCircuitBreakerOpen:   0,
+		MetricUp:                   1,
+		MetricDNSLookup:            7,
+		MetricTimeToFirstByte:      80,
+	}
+	for name, value := range want {
+		p, ok := points[[2]string{name, "google"}]
+		if !ok {
+			t.Errorf("missing %s for google", name)
+			continue
+		}
+		if p.Value != value {
+			t.Errorf("%s for google = %v, want %v", name, p.Value, value)
+		}
+		if p.Labels["category"] != "search" {
+			t.Errorf("%s for google has category %q, want the latest result's", name, p.Labels["category"])
+		}
+	}
+	if p := points[[2]string{MetricUp, "google"}]; !p.Timestamp.Equal(lastSuccess) {
+		t.Errorf("expected result-derived points stamped with the result time, got %v", p.Timestamp)
+	}
+	if p := points[[2]string{MetricTestsTotal, "google"}]; !p.Timestamp.Equal(now) {
+		t.Errorf("expected statistics points stamped with the snapshot time, got %v", p.Timestamp)
+	}
+
+	// Phases no result measured are left out rather than reported as 0
+	for _, name := range []string{MetricTCPConnection, MetricTLSHandshake} {
+		if _, ok := points[[2]string{name, "google"}]; ok {
+			t.Errorf("unexpected %s for google without a measurement", name)
+		}
+	}
+
+	if p := points[[2]string{MetricCircuitBreakerOpen, "down"}]; p.Value != 1 {
+		t.Errorf("expected circuit breaker open for down, got %v", p.Value)
+	}
+	if p := points[[2]string{MetricUp, "down"}]; p.Value != 0 {
+		t.Errorf("expected down to be reported down, got %v", p.Value)
+	}
+	if p := points[[2]string{MetricMonitoredSites, ""}]; p.Value != 2 {
+		t.Errorf("expected 2 monitored sites, got %v", p.Value)
+	}
+
+	// Every defined metric appears for a site that measured everything
+	full := BuildMetricsSnapshot(map[string]SiteStatsSnapshot{"full": {}}, []*models.TestResult{{
+		Site: models.SiteInfo{Name: "full"},
+		Timings: models.TimingMetrics{
+			DNSLookupMs: &dns, TCPConnectionMs: &dns, TLSHandshakeMs: &dns, TimeToFirstByteMs: &ttfb,
+		},
+	}}, now)
+	names := make(map[string]bool)
+	for _, p := range full.Points {
+		names[p.Name] = true
+	}
+	for _, def := range MetricDefinitions() {
+		if !names[def.Name] {
+			t.Errorf("defined metric %s missing from the snapshot", def.Name)
+		}
+	}
+}
+
+func TestSNMPMetricsSnapshot(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	for _, success := range []bool{true, false, true} {
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "example", URL: "https://example.com"},
+			Status:    models.StatusInfo{Success: success},
+			Timings:   models.TimingMetrics{TotalDurationMs: 100},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	points := pointsByKey(t, snmpOutput.MetricsSnapshot())
+	for name, want := range map[string]float64{
+		MetricTestsTotal:           3,
+		MetricTestsSuccessfulTotal: 2,
+		MetricTestsFailedTotal:     1,
+		MetricAvgDuration:          100,
+		MetricUp:                   1,
+	} {
+		if got := points[[2]string{name, "example"}].Value; got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -28,6 +28,7 @@ type PrometheusOutput struct {
 	tcpConnectionMs       *prometheus.GaugeVec
 	tlsHandshakeMs        *prometheus.GaugeVec
 	timeToFirstByteMs     *prometheus.GaugeVec
+	circuitBreakerOpen    *prometheus.GaugeVec
 }
 
 // NewPrometheusOutput creates a new Prometheus exporter
@@ -83,32 +84,40 @@ func NewPrometheusOutput(cfg *config.PrometheusConfig) (*PrometheusOutput, error
 	// Detailed timing metrics
 	p.dnsLookupMs = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "internet_monitor_dns_lookup_ms",
-			Help: "DNS lookup duration in milliseconds",
+			Name: MetricDNSLookup,
+			Help: metricHelp(MetricDNSLookup),
 		},
 		[]string{"site"},
 	)
 
 	p.tcpConnectionMs = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "internet_monitor_tcp_connection_ms",
-			Help: "TCP connection duration in milliseconds",
+			Name: MetricTCPConnection,
+			Help: metricHelp(MetricTCPConnection),
 		},
 		[]string{"site"},
 	)
 
 	p.tlsHandshakeMs = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "internet_monitor_tls_handshake_ms",
-			Help: "TLS handshake duration in milliseconds",
+			Name: MetricTLSHandshake,
+			Help: metricHelp(MetricTLSHandshake),
 		},
 		[]string{"site"},
 	)
 
 	p.timeToFirstByteMs = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "internet_monitor_time_to_first_byte_ms",
-			Help: "Time to first byte in milliseconds",
+			Name: MetricTimeToFirstByte,
+			Help: metricHelp(MetricTimeToFirstByte),
+		},
+		[]string{"site"},
+	)
+
+	p.circuitBreakerOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: MetricCircuitBreakerOpen,
+			Help: metricHelp(MetricCircuitBreakerOpen),
 		},
 		[]string{"site"},
 	)
@@ -122,6 +131,7 @@ func NewPrometheusOutput(cfg *config.PrometheusConfig) (*PrometheusOutput, error
 	prometheus.MustRegister(p.tcpConnectionMs)
 	prometheus.MustRegister(p.tlsHandshakeMs)
 	prometheus.MustRegister(p.timeToFirstByteMs)
+	prometheus.MustRegister(p.circuitBreakerOpen)
 
 	// Create HTTP server
 	mux := http.NewServeMux()
@@ -140,6 +150,7 @@ func NewPrometheusOutput(cfg *config.PrometheusConfig) (*PrometheusOutput, error
 		registry.MustRegister(p.tcpConnectionMs)
 		registry.MustRegister(p.tlsHandshakeMs)
 		registry.MustRegister(p.timeToFirstByteMs)
+		registry.MustRegister(p.circuitBreakerOpen)
 		mux.Handle(cfg.Path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 	}
 
@@ -167,10 +178,7 @@ func (p *PrometheusOutput) Write(result *models.TestResult) error {
 		return nil
 	}
 
-	siteName := result.Site.Name
-	if siteName == "" {
-		siteName = result.Site.URL
-	}
+	siteName := resultSiteName(result)
 
 	// Increment test counter
 	status := "failure"
@@ -179,10 +187,12 @@ func (p *PrometheusOutput) Write(result *models.TestResult) error {
 	}
 	p.testTotal.WithLabelValues(siteName, status).Inc()
 
-	// Update duration metrics
-	durationMs := float64(result.Timings.TotalDurationMs)
-	p.testDurationMs.WithLabelValues(siteName).Set(durationMs)
-	p.testDurationHistogram.WithLabelValues(siteName).Observe(durationMs)
+	// Update duration metrics; a skipped site wasn't tested and has no duration
+	if !result.Status.Skipped {
+		durationMs := float64(result.Timings.TotalDurationMs)
+		p.testDurationMs.WithLabelValues(siteName).Set(durationMs)
+		p.testDurationHistogram.WithLabelValues(siteName).Observe(durationMs)
+	}
 
 	// Update last success timestamp if successful
 	if result.Status.Success {
@@ -203,6 +213,14 @@ func (p *PrometheusOutput) Write(result *models.TestResult) error {
 		p.timeToFirstByteMs.WithLabelValues(siteName).Set(float64(*result.Timings.TimeToFirstByteMs))
 	}
 
+	if result.CircuitBreaker != nil {
+		open := 0.0
+		if result.CircuitBreaker.State == "open" {
+			open = 1
+		}
+		p.circuitBreakerOpen.WithLabelValues(siteName).Set(open)
+	}
+
 	return nil
 }
 
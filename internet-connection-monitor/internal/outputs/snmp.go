@@ -1,16 +1,21 @@
 package outputs
 
 import (
+	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gosnmp/gosnmp"
 
@@ -22,29 +27,97 @@ import (
 // Note: This is a simplified implementation that caches results in memory
 // For production use, consider using a proper SNMP agent framework
 type SNMPOutput struct {
-	config  *config.SNMPConfig
-	cache   []*models.TestResult
-	mu      sync.RWMutex
+	config *config.SNMPConfig
+	mu     sync.RWMutex
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	cacheMu sync.Mutex
+	cache   resultRing
 	maxSize int
-	done    chan struct{}
-	wg      sync.WaitGroup
 
-	// Statistics
-	stats map[string]*siteStats
+	// testsLastMinute counts results from every site; guarded by cacheMu
+	testsLastMinute rateCounter
+
+	// Per-site statistics and history. Writers hold mu for reading plus the
+	// site's own lock, so writes to different sites don't contend; adding or
+	// removing sites takes mu for writing.
+	sites       map[string]*siteState
+	historySize int
+	ewmaAlpha   float64 // Weight of the newest sample in EWMADurationMs
+
+	// percentileSamples bounds each site's durationWindow
+	percentileSamples int
 
-	// SNMP agent lifecycle
-	listener   *net.UDPConn
+	// maxResponseSize bounds GetBulk responses in bytes (see snmp_bulk.go)
+	maxResponseSize int
+
+	// successBucket is the bucket width of each site's successWindow
+	successBucket time.Duration
+
+	// SNMP agent lifecycle. There are two listeners with dual_stack (see
+	// snmp_listen.go), both on actualPort.
+	listeners  []snmpListener
 	actualPort int
 	startTime  time.Time
 
-	// Site indexing for stable OIDs
-	siteIndex     map[string]int
-	nextSiteIndex int
+	// Site indexing for stable OIDs. Configured sites have their index
+	// reserved in configuration order (configuredIndex) and take it on their
+	// first result; other sites are numbered after them in first-seen order.
+	siteIndex       map[string]int
+	configuredIndex map[string]int
+	nextSiteIndex   int
+
+	// lastAlertAck is when an NMS last acknowledged an alert via SET (zero if never)
+	lastAlertAck time.Time
+
+	// lastReload is when a reloaded site list was last applied (zero if never)
+	lastReload time.Time
+
+	// version is the monitor software version served at <base>.10.0
+	version string
+
+	// system is served as the standard system group (see snmp_system.go)
+	system systemGroup
+
+	// views restricts what some communities can see (see snmp_view.go)
+	views map[string]*snmpView
+
+	// acl drops requests from sources outside allowed_cidrs (see snmp_acl.go)
+	acl *sourceACL
+
+	// limiter drops requests from sources over rate_limit (see snmp_ratelimit.go)
+	limiter *rateLimiter
+
+	// usm serves SNMPv3 in place of communities when configured (see snmp_v3.go)
+	usm *usmAgent
+
+	// traps notifies trap targets of site transitions (see snmp_trap.go)
+	traps *trapSender
+
+	// snapshots is the OID snapshot shared by requests (see snmp_snapshot.go)
+	snapshots snapshotCache
+
+	// categories numbers categoryTable rows (see snmp_category.go)
+	categories categoryIndexes
+
+	// now is the clock used for uptime and site expiry. It is set before the
+	// agent starts and never changed (tests pass theirs to newSNMPOutput).
+	now func() time.Time
 
 	startupCh chan error
 	closeOnce sync.Once
 }
 
+// siteState is one site's statistics and raw result history, guarded by its own lock.
+// The history is bounded independently of the global cache so a chatty site
+// can't evict another site's only results.
+type siteState struct {
+	mu      sync.Mutex
+	stats   siteStats
+	history []*models.TestResult
+}
+
 type siteStats struct {
 	TotalTests      int64
 	SuccessfulTests int64
@@ -55,35 +128,278 @@ type siteStats struct {
 	AvgDurationMs   float64
 	MaxDurationMs   int64
 	MinDurationMs   int64
+
+	// EWMADurationMs is an exponentially weighted moving average of the test
+	// duration, which follows recent changes where AvgDurationMs can't
+	EWMADurationMs float64
+
+	// UptimePercent is SuccessfulTests/TotalTests as a percentage;
+	// RecentUptimePercent is the same over the site's retained history
+	// (the last site_history_size tests). Both are 0 before the first test.
+	UptimePercent       float64
+	RecentUptimePercent float64
+
+	// WindowUptimePercent is the same over the tests whose timestamps fall in
+	// the last success_window, as of the newest result
+	WindowUptimePercent float64
+
+	// LastErrorType and LastErrorMessage are from the latest failure (empty
+	// until the site fails), cut to fit a DisplayString
+	LastErrorType    string
+	LastErrorMessage string
+
+	// CircuitBreakerOpen is set while the test loop backs off the site after
+	// repeated failures (from the latest result's circuit breaker state)
+	CircuitBreakerOpen bool
+
+	// StdDev*Ms are the standard deviations (jitter) of the test duration and
+	// of each network phase over every test of the site, 0 until a series has
+	// two samples. High jitter with a good average is an unstable link.
+	StdDevDurationMs float64
+	StdDevDNSMs      float64
+	StdDevTCPMs      float64
+	StdDevTLSMs      float64
+	StdDevTTFBMs     float64
+
+	// P*DurationMs are percentiles of the test duration over the site's last
+	// percentile_samples tests, which show the tail latency an average hides
+	P50DurationMs int64
+	P95DurationMs int64
+	P99DurationMs int64
+
+	// LastSeen is when the agent last received a result for the site (agent clock)
+	LastSeen time.Time
+
+	// Category is the category of the site's latest result, grouping it in
+	// categoryTable
+	Category string
+
+	// testsLastMinute counts the site's results by arrival time (agent clock)
+	testsLastMinute rateCounter
+
+	// jitter accumulates the StdDev*Ms fields
+	jitter siteJitter
+
+	// durations holds the samples behind the P*DurationMs fields
+	durations durationWindow
+
+	// window holds the counts behind WindowUptimePercent
+	window successWindow
+
+	// timedTests counts the tests behind the duration statistics: every test
+	// but those the loop skipped without testing, which have no timings
+	timedTests int64
 }
 
-// NewSNMPOutput creates a new SNMP agent
-func NewSNMPOutput(cfg *config.SNMPConfig) (*SNMPOutput, error) {
+// SiteStatsSnapshot is a point-in-time copy of one site's statistics,
+// for outputs and integrations outside this package
+type SiteStatsSnapshot struct {
+	TotalTests          int64
+	SuccessfulTests     int64
+	FailedTests         int64
+	LastSuccessTime     time.Time // Zero if the site has never succeeded
+	LastFailureTime     time.Time // Zero if the site has never failed
+	LastDurationMs      int64
+	AvgDurationMs       float64
+	MaxDurationMs       int64
+	MinDurationMs       int64
+	EWMADurationMs      float64
+	UptimePercent       float64
+	RecentUptimePercent float64
+	WindowUptimePercent float64
+	LastErrorType       string // Empty if the site has never failed
+	LastErrorMessage    string
+	CircuitBreakerOpen  bool
+	StdDevDurationMs    float64
+	StdDevDNSMs         float64
+	StdDevTCPMs         float64
+	StdDevTLSMs         float64
+	StdDevTTFBMs        float64
+	P50DurationMs       int64
+	P95DurationMs       int64
+	P99DurationMs       int64
+	LastSeen            time.Time
+}
+
+func (st *siteStats) snapshot() SiteStatsSnapshot {
+	return SiteStatsSnapshot{
+		TotalTests:          st.TotalTests,
+		SuccessfulTests:     st.SuccessfulTests,
+		FailedTests:         st.FailedTests,
+		LastSuccessTime:     st.LastSuccessTime,
+		LastFailureTime:     st.LastFailureTime,
+		LastDurationMs:      st.LastDurationMs,
+		AvgDurationMs:       st.AvgDurationMs,
+		MaxDurationMs:       st.MaxDurationMs,
+		MinDurationMs:       st.MinDurationMs,
+		EWMADurationMs:      st.EWMADurationMs,
+		UptimePercent:       st.UptimePercent,
+		RecentUptimePercent: st.RecentUptimePercent,
+		WindowUptimePercent: st.WindowUptimePercent,
+		LastErrorType:       st.LastErrorType,
+		LastErrorMessage:    st.LastErrorMessage,
+		CircuitBreakerOpen:  st.CircuitBreakerOpen,
+		StdDevDurationMs:    st.StdDevDurationMs,
+		StdDevDNSMs:         st.StdDevDNSMs,
+		StdDevTCPMs:         st.StdDevTCPMs,
+		StdDevTLSMs:         st.StdDevTLSMs,
+		StdDevTTFBMs:        st.StdDevTTFBMs,
+		P50DurationMs:       st.P50DurationMs,
+		P95DurationMs:       st.P95DurationMs,
+		P99DurationMs:       st.P99DurationMs,
+		LastSeen:            st.LastSeen,
+	}
+}
+
+// defaultSiteHistorySize is used when SNMPConfig.SiteHistorySize is not set
+const defaultSiteHistorySize = 10
+
+// defaultEWMAAlpha is used when SNMPConfig.EWMAAlpha is not set. At 0.3 a
+// sample's weight halves about every two tests.
+const defaultEWMAAlpha = 0.3
+
+// NewSNMPOutput creates a new SNMP agent reporting version as the monitor's
+// software version. Sites are numbered in the order of the configured site
+// list, so a site keeps its table index across restarts whichever site
+// reports first.
+func NewSNMPOutput(cfg *config.SNMPConfig, version string, sites []models.SiteDefinition) (*SNMPOutput, error) {
+	return newSNMPOutput(cfg, version, sites, time.Now)
+}
+
+// newSNMPOutput is NewSNMPOutput reading the time from now. The agent is
+// already serving requests when it returns, so this is the only safe place to
+// replace the clock.
+func newSNMPOutput(cfg *config.SNMPConfig, version string, sites []models.SiteDefinition, now func() time.Time) (*SNMPOutput, error) {
 	if !cfg.Enabled {
 		return nil, nil
 	}
 
+	// Skipping the community check is only safe when nothing off-host can reach us
+	if cfg.AllowAnyCommunity && !isLoopbackAddress(cfg.ListenAddress) {
+		return nil, fmt.Errorf("SNMP allow_any_community requires a loopback listen address, got %q", cfg.ListenAddress)
+	}
+
+	historySize := cfg.SiteHistorySize
+	if historySize <= 0 {
+		historySize = defaultSiteHistorySize
+	}
+
+	ewmaAlpha := cfg.EWMAAlpha
+	if ewmaAlpha == 0 {
+		ewmaAlpha = defaultEWMAAlpha
+	}
+	if ewmaAlpha < 0 || ewmaAlpha > 1 {
+		return nil, fmt.Errorf("SNMP ewma_alpha must be between 0 and 1, got %v", ewmaAlpha)
+	}
+
+	percentileSamples := cfg.PercentileSamples
+	if percentileSamples <= 0 {
+		percentileSamples = defaultPercentileSamples
+	}
+
+	maxResponseSize := cfg.MaxResponseSize
+	if maxResponseSize == 0 {
+		maxResponseSize = defaultSNMPMaxResponseSize
+	}
+	if maxResponseSize < minSNMPMaxResponseSize {
+		return nil, fmt.Errorf("SNMP max_response_size must be at least %d, got %d", minSNMPMaxResponseSize, maxResponseSize)
+	}
+
+	if _, err := normalizeOID(cfg.EnterpriseOID); err != nil {
+		return nil, fmt.Errorf("SNMP enterprise_oid: %w", err)
+	}
+	views, err := newSNMPViews(cfg.Views)
+	if err != nil {
+		return nil, err
+	}
+
+	successWindow := cfg.SuccessWindow
+	if successWindow == 0 {
+		successWindow = defaultSNMPSuccessWindow
+	}
+	if successWindow < time.Minute {
+		return nil, fmt.Errorf("SNMP success_window must be at least %v, got %v", time.Minute, successWindow)
+	}
+
 	s := &SNMPOutput{
-		config:    cfg,
-		cache:     make([]*models.TestResult, 0, 100),
-		maxSize:   100,
-		done:      make(chan struct{}),
-		stats:     make(map[string]*siteStats),
-		siteIndex: make(map[string]int),
-		startTime: time.Now(),
-		startupCh: make(chan error, 1),
+		config:            cfg,
+		maxSize:           100,
+		done:              make(chan struct{}),
+		sites:             make(map[string]*siteState),
+		historySize:       historySize,
+		ewmaAlpha:         ewmaAlpha,
+		percentileSamples: percentileSamples,
+		maxResponseSize:   maxResponseSize,
+		successBucket:     successWindow / successWindowBuckets,
+		siteIndex:         make(map[string]int),
+		configuredIndex:   make(map[string]int),
+		version:           version,
+		system:            newSystemGroup(cfg.SysDescr, cfg.SysContact, cfg.SysName, cfg.SysLocation, enterpriseBaseOID(cfg.EnterpriseOID), version),
+		views:             views,
+		now:               now,
+		startupCh:         make(chan error, 1),
+	}
+	s.startTime = s.now()
+	s.reserveSiteIndexesLocked(sites)
+
+	acl, err := newSourceACL(cfg.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	s.acl = acl
+
+	limiter, err := newRateLimiter(cfg.RateLimit, cfg.RateLimitBurst)
+	if err != nil {
+		return nil, err
 	}
+	s.limiter = limiter
+
+	usm, err := newUSMAgent(cfg, s.startTime)
+	if err != nil {
+		return nil, err
+	}
+	s.usm = usm
+
+	traps, err := newTrapSender(cfg, usm)
+	if err != nil {
+		return nil, err
+	}
+	s.traps = traps
 
 	// Start SNMP agent server
 	s.wg.Add(1)
 	go s.runSNMPAgent()
 
 	if err := s.waitForStartup(); err != nil {
+		s.traps.close()
 		return nil, err
 	}
 
-	log.Printf("SNMP agent listening on %s:%d (community: %s)", cfg.ListenAddress, s.Port(), cfg.Community)
-	log.Printf("Note: This is a basic SNMP implementation for monitoring. For full MIB support, use SNMPv3 or a dedicated agent.")
+	if s.acl != nil {
+		log.Printf("SNMP agent answering only %s", strings.Join(cfg.AllowedCIDRs, ", "))
+	}
+	if s.traps != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.traps.run(s.baseOID(), s.done)
+		}()
+		log.Printf("Sending SNMP notifications to %s", strings.Join(cfg.TrapTargets, ", "))
+	}
+
+	s.mu.RLock()
+	listening := listenDescription(s.listeners)
+	s.mu.RUnlock()
+	if s.usm != nil {
+		log.Printf("SNMP agent listening on %s (SNMPv3 user %s, %s; v1/v2c disabled)", listening, cfg.Username, securityLevelName(s.usm.level))
+	} else if cfg.AllowAnyCommunity {
+		log.Printf("SNMP agent listening on %s (any community accepted, loopback only)", listening)
+	} else {
+		log.Printf("SNMP agent listening on %s (community: %s)", listening, cfg.Community)
+	}
+	if s.usm == nil {
+		log.Printf("Note: This is a basic SNMP implementation for monitoring. For full MIB support, use SNMPv3 or a dedicated agent.")
+	}
 
 	return s, nil
 }
@@ -93,32 +409,40 @@ func NewSNMPOutput(cfg *config.SNMPConfig) (*SNMPOutput, error) {
 func (s *SNMPOutput) runSNMPAgent() {
 	defer s.wg.Done()
 
-	addr := fmt.Sprintf("%s:%d", s.config.ListenAddress, s.config.Port)
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	listeners, err := listenSNMP(s.config)
 	if err != nil {
-		s.signalStartupError(fmt.Errorf("resolve UDP address: %w", err))
-		return
-	}
-
-	listener, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		s.signalStartupError(fmt.Errorf("listen UDP: %w", err))
+		s.signalStartupError(err)
 		return
 	}
 
 	s.mu.Lock()
-	s.listener = listener
-	if udpAddr.Port == 0 {
-		if la, ok := listener.LocalAddr().(*net.UDPAddr); ok {
-			s.actualPort = la.Port
-		}
-	} else {
-		s.actualPort = udpAddr.Port
-	}
+	s.listeners = listeners
+	s.actualPort = listeners[0].port()
 	s.mu.Unlock()
 
 	s.signalStartupReady()
 
+	for _, listener := range listeners[1:] {
+		s.wg.Add(1)
+		go func(listener snmpListener) {
+			defer s.wg.Done()
+			s.serve(listener)
+		}(listener)
+	}
+	s.serve(listeners[0])
+}
+
+// serve answers requests arriving on listener until the agent is closed
+func (s *SNMPOutput) serve(listener snmpListener) {
+	if listener.tcp != nil {
+		s.serveTCP(listener.tcp)
+		return
+	}
+	s.serveUDP(listener.udp)
+}
+
+// serveUDP answers datagrams arriving on listener until the agent is closed
+func (s *SNMPOutput) serveUDP(listener *net.UDPConn) {
 	buffer := make([]byte, 65535)
 
 	for {
@@ -151,46 +475,98 @@ func (s *SNMPOutput) runSNMPAgent() {
 
 		packet := make([]byte, n)
 		copy(packet, buffer[:n])
-		s.handleRequest(remoteAddr, packet)
+		if response := s.handleRequest(remoteAddr, packet); response != nil {
+			s.send(listener, remoteAddr, response)
+		}
 	}
 }
 
-// Write caches the test result for SNMP queries and updates statistics
+// Write caches the test result for SNMP queries and updates statistics. It is
+// a one-element WriteBatch.
 func (s *SNMPOutput) Write(result *models.TestResult) error {
-	if s == nil {
+	return s.WriteBatch([]*models.TestResult{result})
+}
+
+// WriteBatch caches and applies many results at once, e.g. when replaying
+// history or merging results from other vantage points. The agent-wide lock
+// is taken for writing once for the whole batch, so an SNMP snapshot never
+// reflects half of it. A single result needs no such guarantee and takes the
+// shared path, so concurrent single writes to different sites don't contend.
+func (s *SNMPOutput) WriteBatch(results []*models.TestResult) error {
+	if s == nil || len(results) == 0 {
+		return nil
+	}
+	if len(results) == 1 {
+		s.writeOne(results[0])
 		return nil
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	now := s.now()
 
-	// Add to circular buffer cache
-	if len(s.cache) >= s.maxSize {
-		// Remove oldest entry
-		s.cache = s.cache[1:]
+	s.cacheMu.Lock()
+	for _, result := range results {
+		s.cache.add(result, s.maxSize)
+		s.testsLastMinute.add(now)
 	}
-	s.cache = append(s.cache, result)
+	s.cacheMu.Unlock()
 
-	// Update statistics
-	siteName := result.Site.Name
-	if siteName == "" {
-		siteName = result.Site.URL
+	for _, result := range results {
+		siteName := resultSiteName(result)
+		site, ok := s.sites[siteName]
+		if !ok {
+			site = s.addSiteLocked(siteName)
+		}
+		site.mu.Lock()
+		s.applyResult(site, result, now)
+		site.mu.Unlock()
 	}
+	s.invalidateSnapshot()
+	return nil
+}
 
-	if _, exists := s.stats[siteName]; !exists {
-		s.stats[siteName] = &siteStats{
-			MinDurationMs: result.Timings.TotalDurationMs,
-			MaxDurationMs: result.Timings.TotalDurationMs,
-		}
-		if _, ok := s.siteIndex[siteName]; !ok {
-			s.nextSiteIndex++
-			s.siteIndex[siteName] = s.nextSiteIndex
+// writeOne caches and applies a single result holding mu only for reading
+func (s *SNMPOutput) writeOne(result *models.TestResult) {
+	// Add to circular buffer cache
+	s.cacheMu.Lock()
+	s.cache.add(result, s.maxSize)
+	s.cacheMu.Unlock()
+
+	// Update statistics
+	siteName := resultSiteName(result)
+
+	site := s.acquireSite(siteName)
+	defer s.mu.RUnlock()
+	now := s.now()
+
+	s.cacheMu.Lock()
+	s.testsLastMinute.add(now)
+	s.cacheMu.Unlock()
+
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	s.applyResult(site, result, now)
+	s.invalidateSnapshot()
+}
+
+// applyResult folds a result into the site's history and statistics. The
+// caller holds mu (in either mode) and site.mu.
+func (s *SNMPOutput) applyResult(site *siteState, result *models.TestResult, now time.Time) {
+	if n := len(site.history); n > 0 {
+		var errorType string
+		if result.Error != nil {
+			errorType = result.Error.ErrorType
 		}
+		s.notifyTransition(resultSiteName(result), site.history[n-1].Status.Success, result.Status.Success, errorType)
 	}
+	site.appendHistory(result, s.historySize)
 
-	st := s.stats[siteName]
+	st := &site.stats
+	st.LastSeen = now
+	st.Category = result.Site.Category
+	st.testsLastMinute.add(now)
 	st.TotalTests++
-	st.LastDurationMs = result.Timings.TotalDurationMs
 
 	if result.Status.Success {
 		st.SuccessfulTests++
@@ -198,41 +574,315 @@ func (s *SNMPOutput) Write(result *models.TestResult) error {
 	} else {
 		st.FailedTests++
 		st.LastFailureTime = result.Timestamp
+		st.LastErrorType, st.LastErrorMessage = "", ""
+		if result.Error != nil {
+			st.LastErrorType = displayString(result.Error.ErrorType)
+			st.LastErrorMessage = displayString(result.Error.ErrorMessage)
+		}
 	}
 
+	// A skipped site wasn't tested: its zero duration would drag the latency
+	// statistics down during every outage, so it only counts as a failure
+	if !result.Status.Skipped {
+		s.applyTimings(st, result.Timings)
+	}
+
+	st.UptimePercent = uptimePercent(st.SuccessfulTests, st.TotalTests)
+	st.RecentUptimePercent = site.recentUptimePercent()
+	st.window.add(result.Timestamp, result.Status.Success, s.successBucket)
+	st.WindowUptimePercent = st.window.percent()
+	st.CircuitBreakerOpen = result.CircuitBreaker != nil && result.CircuitBreaker.State == "open"
+
+}
+
+// applyTimings adds a tested result's timings to the duration, jitter and
+// percentile statistics
+func (s *SNMPOutput) applyTimings(st *siteStats, timings models.TimingMetrics) {
+	st.timedTests++
+	st.LastDurationMs = timings.TotalDurationMs
+
 	// Update min/max
-	if result.Timings.TotalDurationMs < st.MinDurationMs {
-		st.MinDurationMs = result.Timings.TotalDurationMs
+	if st.timedTests == 1 || timings.TotalDurationMs < st.MinDurationMs {
+		st.MinDurationMs = timings.TotalDurationMs
 	}
-	if result.Timings.TotalDurationMs > st.MaxDurationMs {
-		st.MaxDurationMs = result.Timings.TotalDurationMs
+	if st.timedTests == 1 || timings.TotalDurationMs > st.MaxDurationMs {
+		st.MaxDurationMs = timings.TotalDurationMs
 	}
 
 	// Calculate running average
-	st.AvgDurationMs = (st.AvgDurationMs*float64(st.TotalTests-1) + float64(result.Timings.TotalDurationMs)) / float64(st.TotalTests)
+	st.AvgDurationMs = (st.AvgDurationMs*float64(st.timedTests-1) + float64(timings.TotalDurationMs)) / float64(st.timedTests)
 
-	return nil
+	// The first sample seeds the EWMA; afterwards each sample moves it by alpha
+	if st.timedTests == 1 {
+		st.EWMADurationMs = float64(timings.TotalDurationMs)
+	} else {
+		st.EWMADurationMs += s.ewmaAlpha * (float64(timings.TotalDurationMs) - st.EWMADurationMs)
+	}
+
+	st.jitter.add(timings)
+	st.StdDevDurationMs = st.jitter.total.stdDev()
+	st.StdDevDNSMs = st.jitter.dns.stdDev()
+	st.StdDevTCPMs = st.jitter.tcp.stdDev()
+	st.StdDevTLSMs = st.jitter.tls.stdDev()
+	st.StdDevTTFBMs = st.jitter.ttfb.stdDev()
+
+	st.durations.add(timings.TotalDurationMs, s.percentileSamples)
+	p := st.durations.percentiles(50, 95, 99)
+	st.P50DurationMs, st.P95DurationMs, st.P99DurationMs = p[0], p[1], p[2]
+}
+
+// acquireSite returns the site's state, creating it (and its stable index) if
+// needed. It returns with s.mu held for reading; the caller must release it.
+func (s *SNMPOutput) acquireSite(siteName string) *siteState {
+	for {
+		s.mu.RLock()
+		if site, ok := s.sites[siteName]; ok {
+			return site
+		}
+		s.mu.RUnlock()
+
+		s.mu.Lock()
+		if _, ok := s.sites[siteName]; !ok {
+			s.addSiteLocked(siteName)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// addSiteLocked creates the site's state, reusing its index if it had one
+// before or had one reserved by the configuration. At MaxTrackedSites the
+// least recently updated site is evicted first. The caller holds mu for
+// writing.
+func (s *SNMPOutput) addSiteLocked(siteName string) *siteState {
+	if limit := s.config.MaxTrackedSites; limit > 0 && len(s.sites) >= limit {
+		s.evictOldestSiteLocked()
+	}
+
+	// LastSeen starts now rather than at the first result, so neither
+	// eviction nor SiteTTL drops the site before its result is applied
+	site := &siteState{stats: siteStats{LastSeen: s.now()}}
+	s.sites[siteName] = site
+	if _, ok := s.siteIndex[siteName]; !ok {
+		if idx, ok := s.configuredIndex[siteName]; ok {
+			s.siteIndex[siteName] = idx
+		} else {
+			s.nextSiteIndex++
+			s.siteIndex[siteName] = s.nextSiteIndex
+		}
+	}
+	return site
+}
+
+// evictOldestSiteLocked drops the site with the oldest LastSeen. As with
+// SiteTTL its index is released but never handed to another site. The caller
+// holds mu for writing.
+func (s *SNMPOutput) evictOldestSiteLocked() {
+	oldest := ""
+	var oldestSeen time.Time
+	for siteName, site := range s.sites {
+		if oldest == "" || site.stats.LastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = siteName, site.stats.LastSeen
+		}
+	}
+	if oldest == "" {
+		return
+	}
+	delete(s.sites, oldest)
+	delete(s.siteIndex, oldest)
+	s.invalidateSnapshot()
+}
+
+// reserveSiteIndexesLocked reserves the next unused indexes for configured
+// sites that have none yet, in configuration order. The caller holds mu for
+// writing (or has not yet shared s).
+func (s *SNMPOutput) reserveSiteIndexesLocked(sites []models.SiteDefinition) {
+	for i := range sites {
+		name := sites[i].GetName()
+		if _, ok := s.configuredIndex[name]; ok {
+			continue
+		}
+		if idx, ok := s.siteIndex[name]; ok {
+			s.configuredIndex[name] = idx
+			continue
+		}
+		s.nextSiteIndex++
+		s.configuredIndex[name] = s.nextSiteIndex
+	}
+}
+
+func (s *SNMPOutput) cacheLen() int {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	return s.cache.len()
+}
+
+// CachedResultTimes returns the timestamps of the oldest and newest cached
+// results, i.e. the span of time the cache covers; both are zero while the
+// cache is empty. Results are cached in arrival order, so this reads the two
+// ends of the buffer.
+func (s *SNMPOutput) CachedResultTimes() (oldest, newest time.Time) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	n := s.cache.len()
+	if n == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return s.cache.at(0).Timestamp, s.cache.at(n - 1).Timestamp
+}
+
+// globalTestsLastMinute returns the number of results from all sites in the minute ending at now
+func (s *SNMPOutput) globalTestsLastMinute(now time.Time) uint32 {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	return s.testsLastMinute.count(now)
 }
 
 // GetCachedResults returns the cached results (for external SNMP polling)
 func (s *SNMPOutput) GetCachedResults() []*models.TestResult {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	// Return a copy to avoid race conditions
+	return s.cache.results()
+}
+
+// appendHistory adds a result to the site's history, dropping its oldest result when full.
+// Caller must hold site.mu.
+func (site *siteState) appendHistory(result *models.TestResult, size int) {
+	h := site.history
+	if len(h) < size {
+		site.history = append(h, result)
+		return
+	}
+	// Shift in place so the evicted result isn't retained by the backing array
+	copy(h, h[1:])
+	h[len(h)-1] = result
+}
+
+// recentUptimePercent is the percentage of successful results in the site's
+// history. Caller must hold site.mu.
+func (site *siteState) recentUptimePercent() float64 {
+	var successful int64
+	for _, r := range site.history {
+		if r.Status.Success {
+			successful++
+		}
+	}
+	return uptimePercent(successful, int64(len(site.history)))
+}
+
+// uptimePercent returns successful/total as a percentage, or 0 when there were no tests
+func uptimePercent(successful, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(successful) / float64(total) * 100
+}
+
+// percentGauge reports a percentage as whole percent, rounded down so that
+// anything short of perfect never shows as 100
+func percentGauge(oid string, percent float64) gosnmp.SnmpPDU {
+	return gaugePDU(oid, uint32(math.Floor(percent)))
+}
+
+// unixOrZero returns t as a Unix time for a Gauge32, 0 for the zero time
+func unixOrZero(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(t.Unix())
+}
+
+// statsCopy returns a copy of the site's statistics
+func (site *siteState) statsCopy() siteStats {
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	return site.stats
+}
+
+// GetSiteHistory returns up to n of the most recent results for a site, oldest first
+func (s *SNMPOutput) GetSiteHistory(siteName string, n int) []*models.TestResult {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Return a copy to avoid race conditions
-	results := make([]*models.TestResult, len(s.cache))
-	copy(results, s.cache)
+	site, ok := s.sites[siteName]
+	if !ok {
+		return []*models.TestResult{}
+	}
+
+	site.mu.Lock()
+	defer site.mu.Unlock()
+
+	h := site.history
+	if n > len(h) || n < 0 {
+		n = len(h)
+	}
+
+	results := make([]*models.TestResult, n)
+	copy(results, h[len(h)-n:])
 	return results
 }
 
+// pruneStaleSites drops sites that haven't reported within SiteTTL.
+// The pruned site's index is released but never handed to another site, so
+// remaining OIDs stay stable and a poller can't mistake a new site for an old one.
+func (s *SNMPOutput) pruneStaleSites() {
+	ttl := s.config.SiteTTL
+	if ttl <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Holding mu exclusively means no writer is inside a site's lock
+	cutoff := s.now().Add(-ttl)
+	for siteName, site := range s.sites {
+		if site.stats.LastSeen.Before(cutoff) {
+			delete(s.sites, siteName)
+			delete(s.siteIndex, siteName)
+			s.invalidateSnapshot()
+		}
+	}
+}
+
+// ReloadSites applies a reloaded site list. Statistics and table rows are keyed
+// by site name, so sites that are still configured keep their counters and
+// index, and new sites have the next unused indexes reserved in configuration
+// order. Removed sites are kept (subject to SiteTTL) unless PruneRemovedSites
+// is set. Either way an index is never reassigned to a different site.
+func (s *SNMPOutput) ReloadSites(sites []models.SiteDefinition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.invalidateSnapshot()
+
+	s.lastReload = s.now()
+	s.reserveSiteIndexesLocked(sites)
+	if !s.config.PruneRemovedSites {
+		return
+	}
+
+	configured := make(map[string]bool, len(sites))
+	for i := range sites {
+		configured[sites[i].GetName()] = true
+	}
+
+	for siteName := range s.siteIndex {
+		if !configured[siteName] {
+			delete(s.sites, siteName)
+			delete(s.siteIndex, siteName)
+		}
+	}
+}
+
 // GetSiteStats returns statistics for a specific site
 func (s *SNMPOutput) GetSiteStats(siteName string) *siteStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if st, exists := s.stats[siteName]; exists {
+	if site, exists := s.sites[siteName]; exists {
 		// Return a copy
-		statsCopy := *st
+		statsCopy := site.statsCopy()
 		return &statsCopy
 	}
 	return nil
@@ -245,40 +895,74 @@ func (s *SNMPOutput) GetAllStats() map[string]*siteStats {
 
 	// Return a copy
 	statsCopy := make(map[string]*siteStats)
-	for site, st := range s.stats {
-		stats := *st
-		statsCopy[site] = &stats
+	for name, site := range s.sites {
+		stats := site.statsCopy()
+		statsCopy[name] = &stats
 	}
 	return statsCopy
 }
 
+// GetAllStatsSnapshot returns a copy of every site's statistics keyed by site name
+func (s *SNMPOutput) GetAllStatsSnapshot() map[string]SiteStatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshots := make(map[string]SiteStatsSnapshot, len(s.sites))
+	for name, site := range s.sites {
+		stats := site.statsCopy()
+		snapshots[name] = stats.snapshot()
+	}
+	return snapshots
+}
+
 // GetSNMPData returns SNMP-compatible data structure
 // This can be queried by external SNMP monitoring systems
 func (s *SNMPOutput) GetSNMPData() map[string]interface{} {
+	s.pruneStaleSites()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	data := make(map[string]interface{})
 
 	// Overall metrics
-	data["cache_size"] = len(s.cache)
+	data["cache_size"] = s.cacheLen()
 	data["cache_max_size"] = s.maxSize
 	data["monitored_sites"] = len(s.siteIndex)
-	data["uptime_seconds"] = int(time.Since(s.startTime).Seconds())
+	now := s.now()
+	data["uptime_seconds"] = int(now.Sub(s.startTime).Seconds())
+	data["tests_last_minute"] = s.globalTestsLastMinute(now)
+	oldest, newest := s.CachedResultTimes()
+	data["oldest_result_time"] = unixOrZero(oldest)
+	data["newest_result_time"] = unixOrZero(newest)
 
 	// Per-site metrics
 	sites := make(map[string]interface{})
-	for siteName, st := range s.stats {
+	for siteName, site := range s.sites {
+		st := site.statsCopy()
 		sites[siteName] = map[string]interface{}{
-			"total_tests":       st.TotalTests,
-			"successful_tests":  st.SuccessfulTests,
-			"failed_tests":      st.FailedTests,
-			"last_success_time": st.LastSuccessTime.Unix(),
-			"last_failure_time": st.LastFailureTime.Unix(),
-			"last_duration_ms":  st.LastDurationMs,
-			"avg_duration_ms":   st.AvgDurationMs,
-			"max_duration_ms":   st.MaxDurationMs,
-			"min_duration_ms":   st.MinDurationMs,
+			"total_tests":           st.TotalTests,
+			"successful_tests":      st.SuccessfulTests,
+			"failed_tests":          st.FailedTests,
+			"last_success_time":     st.LastSuccessTime.Unix(),
+			"last_failure_time":     st.LastFailureTime.Unix(),
+			"last_duration_ms":      st.LastDurationMs,
+			"avg_duration_ms":       st.AvgDurationMs,
+			"max_duration_ms":       st.MaxDurationMs,
+			"min_duration_ms":       st.MinDurationMs,
+			"ewma_duration_ms":      st.EWMADurationMs,
+			"uptime_percent":        st.UptimePercent,
+			"recent_uptime_percent": st.RecentUptimePercent,
+			"window_uptime_percent": st.WindowUptimePercent,
+			"last_error_type":       st.LastErrorType,
+			"last_error_message":    st.LastErrorMessage,
+			"tests_last_minute":     st.testsLastMinute.count(now),
+			"circuit_breaker_open":  st.CircuitBreakerOpen,
+			"stddev_duration_ms":    st.StdDevDurationMs,
+			"stddev_dns_ms":         st.StdDevDNSMs,
+			"stddev_tcp_ms":         st.StdDevTCPMs,
+			"stddev_tls_ms":         st.StdDevTLSMs,
+			"stddev_ttfb_ms":        st.StdDevTTFBMs,
 		}
 	}
 	data["sites"] = sites
@@ -286,19 +970,6 @@ func (s *SNMPOutput) GetSNMPData() map[string]interface{} {
 	return data
 }
 
-// SendTrap sends an SNMP trap for critical events (optional feature)
-func (s *SNMPOutput) SendTrap(trapType string, message string) error {
-	if s == nil || s.config == nil {
-		return nil
-	}
-
-	// This would be implemented if we want to send SNMP traps for alerts
-	// For now, it's a placeholder for future functionality
-	log.Printf("SNMP trap (not implemented): %s - %s", trapType, message)
-
-	return nil
-}
-
 // ExportMIBData exports the current state in a MIB-compatible format
 // This is useful for documentation and external SNMP managers
 func (s *SNMPOutput) ExportMIBData() string {
@@ -349,20 +1020,28 @@ func (s *SNMPOutput) Close() error {
 	s.closeOnce.Do(func() {
 		close(s.done)
 		s.mu.Lock()
-		if s.listener != nil {
-			_ = s.listener.Close()
+		for _, listener := range s.listeners {
+			_ = listener.Close()
 		}
 		s.mu.Unlock()
 	})
 
 	// Wait for goroutine to finish
 	s.wg.Wait()
+	s.traps.close()
+
+	if s.config.DrainPath != "" {
+		if err := s.drainCache(s.config.DrainPath, drainTimeout); err != nil {
+			log.Printf("Warning: failed to drain SNMP cache to %s: %v", s.config.DrainPath, err)
+		}
+	}
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	log.Printf("SNMP agent stopped. Final statistics:")
-	for site, stats := range s.stats {
+	for site, state := range s.sites {
+		stats := state.statsCopy()
 		log.Printf("  %s: %d tests (%d success, %d failed), avg: %.2f ms",
 			site, stats.TotalTests, stats.SuccessfulTests, stats.FailedTests, stats.AvgDurationMs)
 	}
@@ -370,6 +1049,57 @@ func (s *SNMPOutput) Close() error {
 	return nil
 }
 
+// drainTimeout bounds how long Close waits for the cache to be written
+const drainTimeout = 5 * time.Second
+
+// drainCache writes every cached result to path as JSON lines, oldest first.
+// The file is written to a temporary name and renamed, so a failed drain never
+// leaves a truncated file in place. Gives up after timeout so a hung
+// filesystem can't block shutdown.
+func (s *SNMPOutput) drainCache(path string, timeout time.Duration) error {
+	results := s.GetCachedResults()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- writeJSONLines(path, results)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			log.Printf("Drained %d cached results to %s", len(results), path)
+		}
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+}
+
+func writeJSONLines(path string, results []*models.TestResult) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // Helper function to create SNMP PDU (for future enhancement)
 func (s *SNMPOutput) createSNMPPDU(oid string, value interface{}) gosnmp.SnmpPDU {
 	var pduType gosnmp.Asn1BER
@@ -390,7 +1120,7 @@ func (s *SNMPOutput) createSNMPPDU(oid string, value interface{}) gosnmp.SnmpPDU
 	}
 }
 
-// Port returns the UDP port the SNMP agent is bound to.
+// Port returns the UDP (or TCP) port the SNMP agent is bound to.
 // When configured with port 0, this returns the dynamically assigned port.
 func (s *SNMPOutput) Port() int {
 	s.mu.RLock()
@@ -427,28 +1157,46 @@ func (s *SNMPOutput) signalStartupError(err error) {
 	}
 }
 
-func (s *SNMPOutput) handleRequest(remote *net.UDPAddr, packet []byte) {
+// handleRequest decodes a message from remote and returns the response to
+// send, or nil if the request is dropped. It is shared by every transport.
+func (s *SNMPOutput) handleRequest(remote net.Addr, packet []byte) *gosnmp.SnmpPacket {
+	if !s.acl.allows(remoteIP(remote)) {
+		s.acl.logRejected(remote, s.now())
+		return nil
+	}
+	if now := s.now(); !s.limiter.allow(remoteIP(remote), now) {
+		s.limiter.logLimited(remote, now)
+		return nil
+	}
+
+	if version, ok := messageVersion(packet); ok && version == gosnmp.Version3 {
+		return s.handleV3Request(remote, packet)
+	}
+
 	snmpPacket, err := gosnmp.Default.SnmpDecodePacket(packet)
 	if err != nil {
 		log.Printf("SNMP decode error from %s: %v", remote, err)
-		return
+		return nil
 	}
 
 	if snmpPacket == nil {
-		return
+		return nil
 	}
 
 	if snmpPacket.Version != gosnmp.Version2c && snmpPacket.Version != gosnmp.Version1 {
 		log.Printf("SNMP unsupported version %v from %s", snmpPacket.Version, remote)
-		return
+		return nil
 	}
 
-	if snmpPacket.Community != s.config.Community {
-		log.Printf("SNMP unauthorized community from %s", remote)
-		return
+	if s.usm != nil {
+		log.Printf("SNMP %v request from %s refused: only SNMPv3 is enabled", snmpPacket.Version, remote)
+		return nil
 	}
 
-	sortedOIDs, valueMap := s.buildOIDSnapshot()
+	if !s.authorized(snmpPacket) {
+		log.Printf("SNMP unauthorized community from %s", remote)
+		return nil
+	}
 
 	response := &gosnmp.SnmpPacket{
 		Version:        snmpPacket.Version,
@@ -459,43 +1207,130 @@ func (s *SNMPOutput) handleRequest(remote *net.UDPAddr, packet []byte) {
 		NonRepeaters:   snmpPacket.NonRepeaters,
 		MaxRepetitions: snmpPacket.MaxRepetitions,
 	}
+	if snmpPacket.PDUType == gosnmp.SetRequest && !s.canWrite(snmpPacket.Community) {
+		// Read communities are answered, like the read-only v3 user
+		response.Variables, response.Error, response.ErrorIndex = snmpPacket.Variables, gosnmp.NoAccess, 1
+	} else {
+		s.answer(remote, snmpPacket, response, s.viewFor(snmpPacket.Community))
+	}
+	if snmpPacket.Version == gosnmp.Version1 {
+		toV1Response(snmpPacket, response)
+	}
+	return response
+}
 
-	switch snmpPacket.PDUType {
+// handleV3Request answers an SNMPv3 message for the configured user, or with
+// a report during discovery and time resynchronization
+func (s *SNMPOutput) handleV3Request(remote net.Addr, packet []byte) *gosnmp.SnmpPacket {
+	if s.usm == nil {
+		log.Printf("SNMP unsupported version %v from %s", gosnmp.Version3, remote)
+		return nil
+	}
+
+	now := s.now()
+	request, err := s.usm.decode(packet, now)
+	if errors.Is(err, errUnknownEngineID) || errors.Is(err, errNotInTimeWindow) {
+		report, err := s.usm.report(request, err, now)
+		if err != nil {
+			log.Printf("SNMPv3 report to %s: %v", remote, err)
+			return nil
+		}
+		return report
+	}
+	if err != nil {
+		log.Printf("SNMPv3 request from %s rejected: %v", remote, err)
+		return nil
+	}
+
+	response, err := s.usm.reply(request, gosnmp.GetResponse, s.usm.level, now)
+	if err != nil {
+		log.Printf("SNMPv3 response to %s: %v", remote, err)
+		return nil
+	}
+	if request.PDUType == gosnmp.SetRequest {
+		// The v3 user is read-only
+		response.Variables, response.Error, response.ErrorIndex = request.Variables, gosnmp.NoAccess, 1
+	} else {
+		s.answer(remote, request, response, s.viewFor(s.usm.username))
+	}
+	return response
+}
+
+// answer fills in response's variables (and error status) for request,
+// showing only what view allows
+func (s *SNMPOutput) answer(remote net.Addr, request, response *gosnmp.SnmpPacket, view *snmpView) {
+	sortedOIDs, valueMap := view.filter(s.cachedOIDSnapshot())
+	if request.Version == gosnmp.Version1 {
+		sortedOIDs, valueMap = withoutCounter64(sortedOIDs, valueMap)
+	}
+
+	switch request.PDUType {
 	case gosnmp.GetRequest:
-		response.Variables = s.handleGet(snmpPacket.Variables, valueMap)
+		response.Variables = s.handleGet(request.Variables, valueMap)
 	case gosnmp.GetNextRequest:
-		response.Variables = s.handleGetNext(snmpPacket.Variables, valueMap, sortedOIDs)
+		response.Variables, response.Error, response.ErrorIndex = s.handleGetNext(request.Variables, valueMap, sortedOIDs)
 	case gosnmp.GetBulkRequest:
-		response.Variables = s.handleGetBulk(snmpPacket, valueMap, sortedOIDs)
+		response.Variables, response.Error, response.ErrorIndex = s.handleGetBulk(request, valueMap, sortedOIDs)
+		if response.Error == gosnmp.NoError {
+			// An error response just echoes the request's varbinds
+			s.fitResponse(response)
+		}
+	case gosnmp.SetRequest:
+		response.Variables, response.Error, response.ErrorIndex = s.handleSet(request.Variables, view)
 	default:
-		log.Printf("SNMP unsupported PDU type %v from %s", snmpPacket.PDUType, remote)
+		// No varbind can be processed; genErr points at the first (RFC 3416)
+		log.Printf("SNMP unsupported PDU type %v from %s", request.PDUType, remote)
 		response.Error = gosnmp.GenErr
-		response.Variables = snmpPacket.Variables
+		response.Variables = request.Variables
+		if len(request.Variables) > 0 {
+			response.ErrorIndex = 1
+		}
 	}
+}
 
+// send marshals (and for v3, signs and encrypts) a message to remote, from
+// the listener the request arrived on
+func (s *SNMPOutput) send(conn *net.UDPConn, remote *net.UDPAddr, response *gosnmp.SnmpPacket) {
 	respBytes, err := response.MarshalMsg()
 	if err != nil {
 		log.Printf("SNMP marshal error to %s: %v", remote, err)
 		return
 	}
 
-	s.mu.RLock()
-	listener := s.listener
-	s.mu.RUnlock()
-
-	if listener == nil {
-		return
+	if _, err := conn.WriteToUDP(respBytes, remote); err != nil {
+		log.Printf("SNMP write error to %s: %v", remote, err)
 	}
+}
 
-	if _, err := listener.WriteToUDP(respBytes, remote); err != nil {
-		log.Printf("SNMP write error to %s: %v", remote, err)
+// authorized checks the request's community: the community, the write
+// community, or any community with a view. Requests from other communities
+// are dropped unanswered; SETs additionally need canWrite.
+func (s *SNMPOutput) authorized(packet *gosnmp.SnmpPacket) bool {
+	if s.config.AllowAnyCommunity || packet.Community == s.config.Community {
+		return true
 	}
+	if _, ok := s.views[packet.Community]; ok {
+		return true
+	}
+	return s.canWrite(packet.Community)
+}
+
+// canWrite reports whether community may SET, which needs the write
+// community (SETs are refused when none is configured)
+func (s *SNMPOutput) canWrite(community string) bool {
+	return s.config.WriteCommunity != "" && community == s.config.WriteCommunity
 }
 
+// handleGet answers a GetRequest. A malformed OID names no object, so it is
+// answered noSuchObject like any other missing one.
 func (s *SNMPOutput) handleGet(vars []gosnmp.SnmpPDU, valueMap map[string]gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
 	results := make([]gosnmp.SnmpPDU, 0, len(vars))
 	for _, vb := range vars {
-		oid := normalizeOID(vb.Name)
+		oid, err := normalizeOID(vb.Name)
+		if err != nil {
+			results = append(results, gosnmp.SnmpPDU{Name: vb.Name, Type: gosnmp.NoSuchObject})
+			continue
+		}
 		if val, ok := valueMap[oid]; ok {
 			results = append(results, val)
 			continue
@@ -505,22 +1340,35 @@ func (s *SNMPOutput) handleGet(vars []gosnmp.SnmpPDU, valueMap map[string]gosnmp
 	return results
 }
 
-func (s *SNMPOutput) handleGetNext(vars []gosnmp.SnmpPDU, valueMap map[string]gosnmp.SnmpPDU, sortedOIDs []string) []gosnmp.SnmpPDU {
+// handleGetNext answers a GetNextRequest. A malformed OID has no place in
+// the OID order to continue from, so the request fails with genErr at its
+// (1-based) index.
+func (s *SNMPOutput) handleGetNext(vars []gosnmp.SnmpPDU, valueMap map[string]gosnmp.SnmpPDU, sortedOIDs []string) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
 	results := make([]gosnmp.SnmpPDU, 0, len(vars))
-	for _, vb := range vars {
-		oid := normalizeOID(vb.Name)
-		nextOID, ok := nextOID(sortedOIDs, oid)
+	for i, vb := range vars {
+		oid, err := normalizeOID(vb.Name)
+		if err != nil {
+			return vars, gosnmp.GenErr, uint8(i + 1)
+		}
+		_, val, ok := nextValue(sortedOIDs, valueMap, oid)
 		if !ok {
 			results = append(results, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView})
 			continue
 		}
-		results = append(results, valueMap[nextOID])
+		results = append(results, val)
 	}
-	return results
+	return results, gosnmp.NoError, 0
 }
 
-func (s *SNMPOutput) handleGetBulk(packet *gosnmp.SnmpPacket, valueMap map[string]gosnmp.SnmpPDU, sortedOIDs []string) []gosnmp.SnmpPDU {
+// handleGetBulk answers a GetBulkRequest, failing on a malformed OID as
+// handleGetNext does
+func (s *SNMPOutput) handleGetBulk(packet *gosnmp.SnmpPacket, valueMap map[string]gosnmp.SnmpPDU, sortedOIDs []string) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
 	vars := packet.Variables
+	for i, vb := range vars {
+		if _, err := normalizeOID(vb.Name); err != nil {
+			return vars, gosnmp.GenErr, uint8(i + 1)
+		}
+	}
 	nonRepeaters := int(packet.NonRepeaters)
 	if nonRepeaters > len(vars) {
 		nonRepeaters = len(vars)
@@ -530,56 +1378,79 @@ func (s *SNMPOutput) handleGetBulk(packet *gosnmp.SnmpPacket, valueMap map[strin
 		maxRepetitions = 1
 	}
 
-	results := make([]gosnmp.SnmpPDU, 0, len(vars)*maxRepetitions)
+	results := make([]gosnmp.SnmpPDU, 0, len(vars))
 
 	for i := 0; i < nonRepeaters; i++ {
-		oid := normalizeOID(vars[i].Name)
-		next, ok := nextOID(sortedOIDs, oid)
+		oid, _ := normalizeOID(vars[i].Name)
+		_, val, ok := nextValue(sortedOIDs, valueMap, oid)
 		if !ok {
 			results = append(results, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView})
 			continue
 		}
-		results = append(results, valueMap[next])
+		results = append(results, val)
 	}
 
-	for i := nonRepeaters; i < len(vars); i++ {
-		oid := normalizeOID(vars[i].Name)
-		current := oid
-		for r := 0; r < maxRepetitions; r++ {
-			next, ok := nextOID(sortedOIDs, current)
+	// Repeaters stop once the response can't fit in maxResponseSize whatever
+	// the encoding; fitResponse then trims it exactly
+	size := 0
+	for i := nonRepeaters; i < len(vars) && size <= s.maxResponseSize; i++ {
+		current, _ := normalizeOID(vars[i].Name)
+		for r := 0; r < maxRepetitions && size <= s.maxResponseSize; r++ {
+			// Advance by the snapshot's own OID, which always moves forward,
+			// rather than by the value's name
+			next, val, ok := nextValue(sortedOIDs, valueMap, current)
 			if !ok {
 				results = append(results, gosnmp.SnmpPDU{Name: current, Type: gosnmp.EndOfMibView})
 				break
 			}
-			val := valueMap[next]
 			results = append(results, val)
-			current = val.Name
+			size += minVarbindSize(next)
+			current = next
 		}
 	}
 
-	return results
+	return results, gosnmp.NoError, 0
 }
 
 func (s *SNMPOutput) buildOIDSnapshot() ([]string, map[string]gosnmp.SnmpPDU) {
+	s.pruneStaleSites()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	base := normalizeOID(s.config.EnterpriseOID)
-	if base == "." {
-		base = ".1.3.6.1.4.1.99999"
-	}
+	base := s.baseOID()
 
 	values := make(map[string]gosnmp.SnmpPDU)
 
-	cacheSize := uint32(len(s.cache))
+	now := s.now()
+	s.system.addTo(values, now.Sub(s.startTime))
+
+	cacheSize := uint32(s.cacheLen())
 	maxSize := uint32(s.maxSize)
 	siteCount := uint32(len(s.siteIndex))
-	uptime := uint32(time.Since(s.startTime).Seconds())
+	uptime := uint32(now.Sub(s.startTime).Seconds())
 
 	values[fmt.Sprintf("%s.1.0", base)] = gaugePDU(fmt.Sprintf("%s.1.0", base), cacheSize)
 	values[fmt.Sprintf("%s.2.0", base)] = gaugePDU(fmt.Sprintf("%s.2.0", base), maxSize)
 	values[fmt.Sprintf("%s.3.0", base)] = gaugePDU(fmt.Sprintf("%s.3.0", base), siteCount)
 	values[fmt.Sprintf("%s.4.0", base)] = timeTicksPDU(fmt.Sprintf("%s.4.0", base), uptime)
+	values[fmt.Sprintf("%s.6.0", base)] = integerPDU(fmt.Sprintf("%s.6.0", base), 0)
+	var lastAck uint32
+	if !s.lastAlertAck.IsZero() {
+		lastAck = uint32(s.lastAlertAck.Unix())
+	}
+	values[fmt.Sprintf("%s.7.0", base)] = gaugePDU(fmt.Sprintf("%s.7.0", base), lastAck)
+	values[fmt.Sprintf("%s.8.0", base)] = gaugePDU(fmt.Sprintf("%s.8.0", base), s.globalTestsLastMinute(now))
+	lastReload := s.lastReload
+	if lastReload.IsZero() {
+		lastReload = s.startTime
+	}
+	values[fmt.Sprintf("%s.9.0", base)] = gaugePDU(fmt.Sprintf("%s.9.0", base), uint32(lastReload.Unix()))
+	values[fmt.Sprintf("%s.10.0", base)] = octetStringPDU(fmt.Sprintf("%s.10.0", base), s.version)
+	oldest, newest := s.CachedResultTimes()
+	values[fmt.Sprintf("%s.11.0", base)] = gaugePDU(fmt.Sprintf("%s.11.0", base), unixOrZero(oldest))
+	values[fmt.Sprintf("%s.12.0", base)] = gaugePDU(fmt.Sprintf("%s.12.0", base), unixOrZero(newest))
+	values[fmt.Sprintf("%s.13.0", base)] = octetStringPDU(fmt.Sprintf("%s.13.0", base), s.traps.lastTrapMessage())
 
 	type siteEntry struct {
 		name  string
@@ -587,13 +1458,13 @@ func (s *SNMPOutput) buildOIDSnapshot() ([]string, map[string]gosnmp.SnmpPDU) {
 		stats *siteStats
 	}
 
-	entries := make([]siteEntry, 0, len(s.stats))
-	for name, st := range s.stats {
+	entries := make([]siteEntry, 0, len(s.sites))
+	for name, site := range s.sites {
 		idx, ok := s.siteIndex[name]
 		if !ok {
 			continue
 		}
-		statsCopy := *st
+		statsCopy := site.statsCopy()
 		entries = append(entries, siteEntry{name: name, index: idx, stats: &statsCopy})
 	}
 
@@ -627,8 +1498,45 @@ func (s *SNMPOutput) buildOIDSnapshot() ([]string, map[string]gosnmp.SnmpPDU) {
 		values[fmt.Sprintf("%s.8", prefix)] = gaugePDU(fmt.Sprintf("%s.8", prefix), uint32(math.Round(entry.stats.AvgDurationMs)))
 		values[fmt.Sprintf("%s.9", prefix)] = gaugePDU(fmt.Sprintf("%s.9", prefix), uint32(entry.stats.MaxDurationMs))
 		values[fmt.Sprintf("%s.10", prefix)] = gaugePDU(fmt.Sprintf("%s.10", prefix), uint32(entry.stats.MinDurationMs))
+		values[fmt.Sprintf("%s.11", prefix)] = gaugePDU(fmt.Sprintf("%s.11", prefix), uint32(math.Round(entry.stats.EWMADurationMs)))
+		values[fmt.Sprintf("%s.12", prefix)] = percentGauge(fmt.Sprintf("%s.12", prefix), entry.stats.UptimePercent)
+		values[fmt.Sprintf("%s.13", prefix)] = percentGauge(fmt.Sprintf("%s.13", prefix), entry.stats.RecentUptimePercent)
+		values[fmt.Sprintf("%s.14", prefix)] = gaugePDU(fmt.Sprintf("%s.14", prefix), entry.stats.testsLastMinute.count(now))
+		breakerState := circuitBreakerClosed
+		if entry.stats.CircuitBreakerOpen {
+			breakerState = circuitBreakerOpen
+		}
+		values[fmt.Sprintf("%s.15", prefix)] = integerPDU(fmt.Sprintf("%s.15", prefix), breakerState)
+		values[fmt.Sprintf("%s.16", prefix)] = gaugePDU(fmt.Sprintf("%s.16", prefix), uint32(math.Round(entry.stats.StdDevDurationMs)))
+		values[fmt.Sprintf("%s.17", prefix)] = gaugePDU(fmt.Sprintf("%s.17", prefix), uint32(math.Round(entry.stats.StdDevDNSMs)))
+		values[fmt.Sprintf("%s.18", prefix)] = gaugePDU(fmt.Sprintf("%s.18", prefix), uint32(math.Round(entry.stats.StdDevTCPMs)))
+		values[fmt.Sprintf("%s.19", prefix)] = gaugePDU(fmt.Sprintf("%s.19", prefix), uint32(math.Round(entry.stats.StdDevTLSMs)))
+		values[fmt.Sprintf("%s.20", prefix)] = gaugePDU(fmt.Sprintf("%s.20", prefix), uint32(math.Round(entry.stats.StdDevTTFBMs)))
+		values[fmt.Sprintf("%s.21", prefix)] = counter64PDU(fmt.Sprintf("%s.21", prefix), uint64(entry.stats.TotalTests))
+		values[fmt.Sprintf("%s.22", prefix)] = counter64PDU(fmt.Sprintf("%s.22", prefix), uint64(entry.stats.SuccessfulTests))
+		values[fmt.Sprintf("%s.23", prefix)] = counter64PDU(fmt.Sprintf("%s.23", prefix), uint64(entry.stats.FailedTests))
+		values[fmt.Sprintf("%s.24", prefix)] = integerPDU(fmt.Sprintf("%s.24", prefix), 0)
+		values[fmt.Sprintf("%s.25", prefix)] = gaugePDU(fmt.Sprintf("%s.25", prefix), uint32(entry.stats.P50DurationMs))
+		values[fmt.Sprintf("%s.26", prefix)] = gaugePDU(fmt.Sprintf("%s.26", prefix), uint32(entry.stats.P95DurationMs))
+		values[fmt.Sprintf("%s.27", prefix)] = gaugePDU(fmt.Sprintf("%s.27", prefix), uint32(entry.stats.P99DurationMs))
+		values[fmt.Sprintf("%s.28", prefix)] = percentGauge(fmt.Sprintf("%s.28", prefix), entry.stats.WindowUptimePercent)
+		values[fmt.Sprintf("%s.29", prefix)] = octetStringPDU(fmt.Sprintf("%s.29", prefix), entry.stats.LastErrorType)
+		values[fmt.Sprintf("%s.30", prefix)] = octetStringPDU(fmt.Sprintf("%s.30", prefix), entry.stats.LastErrorMessage)
+
+		// The same row in the conformant siteTable (<base>.14.1.<column>.<siteIndex>)
+		for _, col := range mibSiteColumns {
+			pdu := values[fmt.Sprintf("%s.%d", prefix, col.ID)]
+			pdu.Name = fmt.Sprintf("%s.%d.1.%d.%d", base, siteEntryTableID, col.ID, entry.index)
+			values[pdu.Name] = pdu
+		}
 	}
 
+	allStats := make([]*siteStats, 0, len(entries))
+	for _, entry := range entries {
+		allStats = append(allStats, entry.stats)
+	}
+	s.addCategoryRows(values, base, allStats)
+
 	oids := make([]string, 0, len(values))
 	for oid := range values {
 		oids = append(oids, oid)
@@ -641,14 +1549,37 @@ func (s *SNMPOutput) buildOIDSnapshot() ([]string, map[string]gosnmp.SnmpPDU) {
 	return oids, values
 }
 
+// baseOID returns the normalized enterprise OID the agent's tree is rooted at
+func (s *SNMPOutput) baseOID() string {
+	return enterpriseBaseOID(s.config.EnterpriseOID)
+}
+
+// enterpriseBaseOID normalizes a configured enterprise OID, falling back to
+// the default if it is unset or malformed (which NewSNMPOutput rejects)
+func enterpriseBaseOID(enterpriseOID string) string {
+	base, err := normalizeOID(enterpriseOID)
+	if err != nil || base == "." {
+		base = ".1.3.6.1.4.1.99999"
+	}
+	return base
+}
+
 func gaugePDU(oid string, value uint32) gosnmp.SnmpPDU {
 	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Gauge32, Value: value}
 }
 
+func integerPDU(oid string, value int) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Integer, Value: value}
+}
+
 func counterPDU(oid string, value uint32) gosnmp.SnmpPDU {
 	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Counter32, Value: value}
 }
 
+func counter64PDU(oid string, value uint64) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Counter64, Value: value}
+}
+
 func timeTicksPDU(oid string, value uint32) gosnmp.SnmpPDU {
 	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.TimeTicks, Value: value * 100}
 }
@@ -657,10 +1588,51 @@ func octetStringPDU(oid string, value string) gosnmp.SnmpPDU {
 	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: []byte(value)}
 }
 
-func normalizeOID(oid string) string {
+// maxDisplayStringLen is the longest value a DisplayString may hold
+const maxDisplayStringLen = 255
+
+// displayString cuts value to maxDisplayStringLen bytes, at a rune boundary
+func displayString(value string) string {
+	if len(value) <= maxDisplayStringLen {
+		return value
+	}
+	cut := maxDisplayStringLen
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// isLoopbackAddress reports whether a listen address only accepts local connections
+func isLoopbackAddress(addr string) bool {
+	if strings.EqualFold(addr, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(listenHost(addr))
+	return ip != nil && ip.IsLoopback()
+}
+
+// withoutCounter64 drops Counter64 objects from a snapshot: SNMPv1 has no
+// Counter64, so v1 managers see the tree as if they did not exist (RFC 3584)
+func withoutCounter64(oids []string, values map[string]gosnmp.SnmpPDU) ([]string, map[string]gosnmp.SnmpPDU) {
+	kept := make([]string, 0, len(oids))
+	keptValues := make(map[string]gosnmp.SnmpPDU, len(values))
+	for _, oid := range oids {
+		if values[oid].Type == gosnmp.Counter64 {
+			continue
+		}
+		kept = append(kept, oid)
+		keptValues[oid] = values[oid]
+	}
+	return kept, keptValues
+}
+
+// normalizeOID returns oid with a leading dot and no trailing dot, or an
+// error if a component isn't a sub-identifier (a number from 0 to 2^32-1)
+func normalizeOID(oid string) (string, error) {
 	trimmed := strings.TrimSpace(oid)
 	if trimmed == "" {
-		return "."
+		return ".", nil
 	}
 	if !strings.HasPrefix(trimmed, ".") {
 		trimmed = "." + trimmed
@@ -668,18 +1640,47 @@ func normalizeOID(oid string) string {
 	for strings.HasSuffix(trimmed, ".") && len(trimmed) > 1 {
 		trimmed = trimmed[:len(trimmed)-1]
 	}
-	return trimmed
+	if trimmed == "." {
+		return trimmed, nil
+	}
+	for _, part := range strings.Split(trimmed[1:], ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return "", fmt.Errorf("invalid OID %q: component %q is not a sub-identifier", oid, part)
+		}
+	}
+	return trimmed, nil
 }
 
-func nextOID(sorted []string, current string) (string, bool) {
-	for _, oid := range sorted {
-		if compareOIDs(oid, current) > 0 {
-			return oid, true
+// nextValue returns the first OID after current in the snapshot that has a
+// value, and that value. OIDs without a value, or whose value is not named
+// after them, are skipped, so a walk always moves forward even over an
+// inconsistent snapshot.
+func nextValue(sorted []string, values map[string]gosnmp.SnmpPDU, current string) (string, gosnmp.SnmpPDU, bool) {
+	for {
+		next, ok := nextOID(sorted, current)
+		if !ok {
+			return "", gosnmp.SnmpPDU{}, false
+		}
+		if val, ok := values[next]; ok && val.Name == next {
+			return next, val, true
 		}
+		current = next
+	}
+}
+
+func nextOID(sorted []string, current string) (string, bool) {
+	i := sort.Search(len(sorted), func(i int) bool {
+		return compareOIDs(sorted[i], current) > 0
+	})
+	if i == len(sorted) {
+		return "", false
 	}
-	return "", false
+	return sorted[i], true
 }
 
+// compareOIDs orders two normalized OIDs, returning -1, 0 or 1. OIDs from
+// requests and configuration are validated by normalizeOID first, so every
+// component is a number.
 func compareOIDs(a, b string) int {
 	if a == b {
 		return 0
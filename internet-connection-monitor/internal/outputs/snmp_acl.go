@@ -0,0 +1,88 @@
+package outputs
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source address allowlist
+//
+// With allowed_cidrs configured, requests from any other address are dropped
+// before they are decoded, so hosts outside the list can't probe communities
+// or v3 users. Drops are logged at debug level, at most once per
+// aclLogInterval, with the number of drops since the previous log line.
+
+const aclLogInterval = time.Minute
+
+// sourceACL is the parsed allowlist; a nil *sourceACL allows every source
+type sourceACL struct {
+	nets []*net.IPNet
+
+	mu         sync.Mutex
+	lastLog    time.Time
+	suppressed int
+}
+
+// newSourceACL parses CIDRs (a bare address allows just that host); nil when
+// the list is empty
+func newSourceACL(cidrs []string) (*sourceACL, error) {
+	if len(cidrs) == 0 {
+		return nil, nil
+	}
+
+	acl := &sourceACL{}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid SNMP allowed_cidrs entry %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			acl.nets = append(acl.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNMP allowed_cidrs entry %q: %w", cidr, err)
+		}
+		acl.nets = append(acl.nets, ipNet)
+	}
+	return acl, nil
+}
+
+// allows reports whether requests from ip are answered
+func (a *sourceACL) allows(ip net.IP) bool {
+	if a == nil {
+		return true
+	}
+	for _, ipNet := range a.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// logRejected notes a dropped request, logging it unless another was logged
+// within aclLogInterval
+func (a *sourceACL) logRejected(remote net.Addr, now time.Time) {
+	a.mu.Lock()
+	if !a.lastLog.IsZero() && now.Sub(a.lastLog) < aclLogInterval {
+		a.suppressed++
+		a.mu.Unlock()
+		return
+	}
+	suppressed := a.suppressed
+	a.lastLog, a.suppressed = now, 0
+	a.mu.Unlock()
+
+	slog.Debug("SNMP request dropped: source not in allowed_cidrs", "remote", remote.String(), "suppressed", suppressed)
+}
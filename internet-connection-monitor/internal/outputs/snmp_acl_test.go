@@ -0,0 +1,84 @@
+package outputs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+func TestSNMPAllowedCIDRs(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		AllowedCIDRs:  []string{"127.0.0.1/32", "10.0.0.0/8"},
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	get := func(source string) error {
+		client := &gosnmp.GoSNMP{
+			Target:    "127.0.0.1",
+			Port:      uint16(snmpOutput.Port()),
+			Community: "public",
+			Version:   gosnmp.Version2c,
+			Timeout:   300 * time.Millisecond,
+			Retries:   0,
+			LocalAddr: source + ":0",
+		}
+		if err := client.Connect(); err != nil {
+			t.Fatalf("failed to connect SNMP client from %s: %v", source, err)
+		}
+		defer client.Conn.Close()
+		_, err := client.Get([]string{cfg.EnterpriseOID + ".1.0"})
+		return err
+	}
+
+	if err := get("127.0.0.1"); err != nil {
+		t.Fatalf("expected an answer for an allowed source: %v", err)
+	}
+	// Every 127/8 address is loopback on Linux, but only 127.0.0.1 is allowed
+	if err := get("127.0.0.2"); err == nil {
+		t.Fatal("expected no answer for a source outside allowed_cidrs")
+	}
+}
+
+func TestSourceACL(t *testing.T) {
+	acl, err := newSourceACL([]string{"10.0.0.0/8", "192.168.1.5", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("newSourceACL failed: %v", err)
+	}
+	for _, tc := range []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+	} {
+		if got := acl.allows(net.ParseIP(tc.ip)); got != tc.want {
+			t.Errorf("allows(%s) = %v, want %v", tc.ip, got, tc.want)
+		}
+	}
+
+	if acl, err := newSourceACL(nil); err != nil || !acl.allows(net.ParseIP("203.0.113.1")) {
+		t.Errorf("expected an empty list to allow every source, got %v", err)
+	}
+	for _, bad := range []string{"10.0.0.0/33", "not-an-address"} {
+		if _, err := newSourceACL([]string{bad}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package outputs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// GetBulk response size
+//
+// A GetBulk with a large max-repetitions can ask for far more than fits in
+// one datagram. As RFC 3416 allows, the response is cut down to the leading
+// varbinds whose message fits in max_response_size bytes; the manager
+// continues its walk from the last OID it got. The default stays under a
+// typical Ethernet MTU, so responses aren't fragmented.
+
+const (
+	defaultSNMPMaxResponseSize = 1400
+
+	// minSNMPMaxResponseSize is the message size every SNMP entity must
+	// accept (RFC 3417)
+	minSNMPMaxResponseSize = 484
+)
+
+// minVarbindSize is the fewest bytes a varbind for oid can encode to: a tag
+// and length each for the varbind, its OID and its (possibly empty) value,
+// plus one byte per OID arc after the first two, which share a byte. oid is
+// normalized.
+func minVarbindSize(oid string) int {
+	arcs := strings.Count(oid, ".")
+	return 2 + 2 + 2 + (arcs - 1)
+}
+
+// fitResponse drops trailing varbinds until response marshals to at most
+// maxResponseSize bytes. At least one varbind is kept so a walk always makes
+// progress.
+func (s *SNMPOutput) fitResponse(response *gosnmp.SnmpPacket) {
+	vars := response.Variables
+	if s.responseSize(response, len(vars)) <= s.maxResponseSize {
+		return
+	}
+
+	// The smallest count that no longer fits; everything before it does
+	tooBig := sort.Search(len(vars), func(n int) bool {
+		return s.responseSize(response, n) > s.maxResponseSize
+	})
+	response.Variables = vars[:max(tooBig-1, 1)]
+}
+
+// responseSize is the marshaled size of response with only its first n
+// varbinds, or the limit plus one if it can't be marshaled (send logs why)
+func (s *SNMPOutput) responseSize(response *gosnmp.SnmpPacket, n int) int {
+	trimmed := *response
+	trimmed.Variables = response.Variables[:n]
+	msg, err := trimmed.MarshalMsg()
+	if err != nil {
+		return s.maxResponseSize + 1
+	}
+	return len(msg)
+}
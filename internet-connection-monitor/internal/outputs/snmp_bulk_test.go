@@ -0,0 +1,176 @@
+package outputs
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSNMPGetBulkFitsMaxResponseSize(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	for i := 0; i < 100; i++ {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: fmt.Sprintf("site-%d.example", i)},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: int64(i)},
+		})
+	}
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP(cfg.ListenAddress), Port: snmpOutput.Port()})
+	if err != nil {
+		t.Fatalf("failed to dial SNMP agent: %v", err)
+	}
+	defer conn.Close()
+
+	// getBulk sends a GetBulk for oid and returns the raw response size and its varbinds
+	getBulk := func(requestID uint32, oid string) (int, []gosnmp.SnmpPDU) {
+		t.Helper()
+		request := &gosnmp.SnmpPacket{
+			Version:        gosnmp.Version2c,
+			Community:      cfg.Community,
+			PDUType:        gosnmp.GetBulkRequest,
+			RequestID:      requestID,
+			MaxRepetitions: 1000,
+			Variables:      []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Null}},
+		}
+		msg, err := request.MarshalMsg()
+		if err != nil {
+			t.Fatalf("failed to marshal GetBulk: %v", err)
+		}
+		if _, err := conn.Write(msg); err != nil {
+			t.Fatalf("failed to send GetBulk: %v", err)
+		}
+		buf := make([]byte, 65535)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("no GetBulk response: %v", err)
+		}
+		response, err := gosnmp.Default.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			t.Fatalf("failed to decode GetBulk response: %v", err)
+		}
+		return n, response.Variables
+	}
+
+	// Walk the whole tree with max-repetitions 1000; every response must fit
+	// and the walk must still reach every object
+	walked := 0
+	oid := ".1.3.6.1.2.1.1"
+walk:
+	for requestID := uint32(1); ; requestID++ {
+		size, vars := getBulk(requestID, oid)
+		if size > defaultSNMPMaxResponseSize {
+			t.Fatalf("response of %d bytes exceeds the %d byte limit", size, defaultSNMPMaxResponseSize)
+		}
+		if len(vars) == 0 {
+			t.Fatal("expected at least one varbind per response")
+		}
+		if len(vars) == 1000 {
+			t.Fatal("expected the response to be cut short")
+		}
+		for _, v := range vars {
+			if v.Type == gosnmp.EndOfMibView {
+				break walk
+			}
+			walked++
+		}
+		oid = vars[len(vars)-1].Name
+	}
+
+	if want := len(snmpOutput.Snapshot().OIDs); walked != want {
+		t.Errorf("walk returned %d objects, want %d", walked, want)
+	}
+}
+
+func TestSNMPRejectsSmallMaxResponseSize(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:         true,
+		Port:            0,
+		Community:       "public",
+		ListenAddress:   "127.0.0.1",
+		MaxResponseSize: 100,
+	}
+	if snmpOutput, err := NewSNMPOutput(cfg, "test", nil); err == nil {
+		snmpOutput.Close()
+		t.Fatal("expected an error for a max_response_size below 484")
+	}
+}
+
+func TestSNMPGetBulkSkipsInconsistentSnapshot(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	// .2 is listed without a value and .3 has a zero-value PDU, as if the
+	// snapshot had been torn by a race
+	sortedOIDs := []string{".1.3.6.1.4.1.55555.1", ".1.3.6.1.4.1.55555.2", ".1.3.6.1.4.1.55555.3", ".1.3.6.1.4.1.55555.4"}
+	valueMap := map[string]gosnmp.SnmpPDU{
+		".1.3.6.1.4.1.55555.1": gaugePDU(".1.3.6.1.4.1.55555.1", 1),
+		".1.3.6.1.4.1.55555.3": {},
+		".1.3.6.1.4.1.55555.4": gaugePDU(".1.3.6.1.4.1.55555.4", 4),
+	}
+	request := &gosnmp.SnmpPacket{
+		MaxRepetitions: 10,
+		Variables:      []gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.55555"}},
+	}
+
+	done := make(chan []gosnmp.SnmpPDU, 1)
+	go func() {
+		results, _, _ := snmpOutput.handleGetBulk(request, valueMap, sortedOIDs)
+		done <- results
+	}()
+	var results []gosnmp.SnmpPDU
+	select {
+	case results = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("GetBulk did not terminate over an inconsistent snapshot")
+	}
+
+	want := []string{".1.3.6.1.4.1.55555.1", ".1.3.6.1.4.1.55555.4", ".1.3.6.1.4.1.55555.4"}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d varbinds, got %v", len(want), results)
+	}
+	for i, name := range want {
+		if results[i].Name != name {
+			t.Errorf("varbind %d: expected %s, got %s", i+1, name, results[i].Name)
+		}
+	}
+	if results[2].Type != gosnmp.EndOfMibView {
+		t.Errorf("expected the walk to end with endOfMibView, got %v", results[2].Type)
+	}
+
+	// GetNext skips the same OIDs
+	next, _, _ := snmpOutput.handleGetNext([]gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.55555.1"}}, valueMap, sortedOIDs)
+	if next[0].Name != ".1.3.6.1.4.1.55555.4" {
+		t.Errorf("expected GetNext to skip to .4, got %s", next[0].Name)
+	}
+}
@@ -0,0 +1,89 @@
+package outputs
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Category aggregates
+//
+// categoryTable (<base>.15) sums the statistics of every site sharing a
+// category, taken from the category of each site's latest result. Sites
+// without a category are left out. Like site indexes, a category's index is
+// assigned the first time it is served and never reused, so rows keep their
+// OIDs as sites come and go.
+
+// categoryIndexes assigns stable category indexes
+type categoryIndexes struct {
+	mu    sync.Mutex
+	index map[string]int
+	next  int
+}
+
+// indexOf returns category's index, assigning the next one if it has none
+func (c *categoryIndexes) indexOf(category string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if idx, ok := c.index[category]; ok {
+		return idx
+	}
+	if c.index == nil {
+		c.index = make(map[string]int)
+	}
+	c.next++
+	c.index[category] = c.next
+	return c.next
+}
+
+// categoryTotals is one category's sums
+type categoryTotals struct {
+	name            string
+	sites           uint32
+	totalTests      int64
+	successfulTests int64
+	failedTests     int64
+	timedTests      int64 // the tests behind durationSumMs
+	durationSumMs   float64
+}
+
+// addCategoryRows adds a categoryTable row for each category among sites,
+// which are in site index order so new categories are numbered in that order
+func (s *SNMPOutput) addCategoryRows(values map[string]gosnmp.SnmpPDU, base string, sites []*siteStats) {
+	totals := make(map[int]*categoryTotals)
+	for _, st := range sites {
+		if st.Category == "" {
+			continue
+		}
+		idx := s.categories.indexOf(st.Category)
+		t, ok := totals[idx]
+		if !ok {
+			t = &categoryTotals{name: st.Category}
+			totals[idx] = t
+		}
+		t.sites++
+		t.totalTests += st.TotalTests
+		t.successfulTests += st.SuccessfulTests
+		t.failedTests += st.FailedTests
+		t.timedTests += st.timedTests
+		t.durationSumMs += st.AvgDurationMs * float64(st.timedTests)
+	}
+
+	for idx, t := range totals {
+		var avgDurationMs float64
+		if t.timedTests > 0 {
+			avgDurationMs = t.durationSumMs / float64(t.timedTests)
+		}
+		oid := func(column int) string {
+			return fmt.Sprintf("%s.%d.1.%d.%d", base, categoryTableID, column, idx)
+		}
+		values[oid(1)] = octetStringPDU(oid(1), t.name)
+		values[oid(2)] = counterPDU(oid(2), uint32(t.totalTests))
+		values[oid(3)] = counterPDU(oid(3), uint32(t.successfulTests))
+		values[oid(4)] = counterPDU(oid(4), uint32(t.failedTests))
+		values[oid(5)] = gaugePDU(oid(5), uint32(math.Round(avgDurationMs)))
+		values[oid(6)] = gaugePDU(oid(6), t.sites)
+	}
+}
@@ -0,0 +1,54 @@
+package outputs
+
+import (
+	"math"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// runningStdDev tracks the standard deviation of a series with Welford's
+// algorithm: constant memory however many samples, and numerically stable
+// where the textbook sum-of-squares formula is not.
+// It is not safe for concurrent use; callers guard it with their own lock.
+type runningStdDev struct {
+	n    int64
+	mean float64
+	m2   float64 // sum of squared differences from the current mean
+}
+
+// add records one sample
+func (r *runningStdDev) add(x float64) {
+	r.n++
+	delta := x - r.mean
+	r.mean += delta / float64(r.n)
+	r.m2 += delta * (x - r.mean)
+}
+
+// stdDev returns the sample standard deviation, 0 until there are two samples
+func (r *runningStdDev) stdDev() float64 {
+	if r.n < 2 {
+		return 0
+	}
+	return math.Sqrt(r.m2 / float64(r.n-1))
+}
+
+// siteJitter accumulates the spread of a site's total duration and of each
+// network phase. Phases a result did not measure are skipped, so each phase
+// has its own sample count.
+type siteJitter struct {
+	total, dns, tcp, tls, ttfb runningStdDev
+}
+
+// add records a result's timings
+func (j *siteJitter) add(t models.TimingMetrics) {
+	j.total.add(float64(t.TotalDurationMs))
+	addPhase := func(r *runningStdDev, ms *int64) {
+		if ms != nil {
+			r.add(float64(*ms))
+		}
+	}
+	addPhase(&j.dns, t.DNSLookupMs)
+	addPhase(&j.tcp, t.TCPConnectionMs)
+	addPhase(&j.tls, t.TLSHandshakeMs)
+	addPhase(&j.ttfb, t.TimeToFirstByteMs)
+}
@@ -0,0 +1,145 @@
+package outputs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+// Listening sockets
+//
+// listen_address may be an IPv4 or IPv6 address (brackets optional) or a host
+// name, and transport picks the protocol and stack: udp (either stack, the
+// default), udp4 or udp6, or tcp, tcp4 or tcp6 for SNMP over TCP (RFC 3430,
+// see snmp_tcp.go). With dual_stack and a wildcard address the agent binds
+// separate IPv4 and IPv6 sockets on the same port, which serves both stacks
+// even where the host makes IPv6 sockets IPv6-only (net.ipv6.bindv6only).
+
+const defaultSNMPTransport = "udp"
+
+// snmpListener is one bound socket: a UDP socket, or a TCP listener when the
+// transport is tcp
+type snmpListener struct {
+	udp *net.UDPConn
+	tcp *net.TCPListener
+}
+
+func (l snmpListener) addr() net.Addr {
+	if l.tcp != nil {
+		return l.tcp.Addr()
+	}
+	return l.udp.LocalAddr()
+}
+
+// port is the bound port
+func (l snmpListener) port() int {
+	if l.tcp != nil {
+		return l.tcp.Addr().(*net.TCPAddr).Port
+	}
+	return l.udp.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (l snmpListener) Close() error {
+	if l.tcp != nil {
+		return l.tcp.Close()
+	}
+	return l.udp.Close()
+}
+
+// listenSNMP binds the agent's sockets. With port 0 every socket shares the
+// port chosen for the first.
+func listenSNMP(cfg *config.SNMPConfig) ([]snmpListener, error) {
+	network := cfg.Transport
+	if network == "" {
+		network = defaultSNMPTransport
+	}
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("invalid SNMP transport %q (want udp, udp4, udp6, tcp, tcp4 or tcp6)", cfg.Transport)
+	}
+	host := listenHost(cfg.ListenAddress)
+
+	if !cfg.DualStack {
+		l, err := listen(network, host, cfg.Port)
+		if err != nil {
+			return nil, err
+		}
+		return []snmpListener{l}, nil
+	}
+
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("SNMP dual_stack needs transport udp or tcp, got %q", network)
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return nil, fmt.Errorf("SNMP dual_stack needs an unspecified listen_address (0.0.0.0 or ::), got %q", cfg.ListenAddress)
+	}
+	v6, err := listen(network+"6", "::", cfg.Port)
+	if err != nil {
+		return nil, err
+	}
+	v4, err := listen(network+"4", "0.0.0.0", v6.port())
+	if err != nil {
+		v6.Close()
+		return nil, err
+	}
+	return []snmpListener{v6, v4}, nil
+}
+
+func listen(network, host string, port int) (snmpListener, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	if strings.HasPrefix(network, "tcp") {
+		tcpAddr, err := net.ResolveTCPAddr(network, addr)
+		if err != nil {
+			return snmpListener{}, fmt.Errorf("resolve TCP address: %w", err)
+		}
+		l, err := net.ListenTCP(network, tcpAddr)
+		if err != nil {
+			return snmpListener{}, fmt.Errorf("listen TCP: %w", err)
+		}
+		return snmpListener{tcp: l}, nil
+	}
+
+	udpAddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return snmpListener{}, fmt.Errorf("resolve UDP address: %w", err)
+	}
+	conn, err := net.ListenUDP(network, udpAddr)
+	if err != nil {
+		return snmpListener{}, fmt.Errorf("listen UDP: %w", err)
+	}
+	return snmpListener{udp: conn}, nil
+}
+
+// listenHost strips the brackets an IPv6 listen address may be written with
+func listenHost(addr string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// listenDescription is the bound addresses for logging, e.g.
+// "[::]:161, 0.0.0.0:161", with "(tcp)" appended for the tcp transport
+func listenDescription(listeners []snmpListener) string {
+	addrs := make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		addrs = append(addrs, l.addr().String())
+	}
+	desc := strings.Join(addrs, ", ")
+	if len(listeners) > 0 && listeners[0].tcp != nil {
+		desc += " (tcp)"
+	}
+	return desc
+}
+
+// remoteIP is the address a request came from
+func remoteIP(remote net.Addr) net.IP {
+	switch a := remote.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+	return nil
+}
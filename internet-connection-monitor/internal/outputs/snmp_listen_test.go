@@ -0,0 +1,129 @@
+package outputs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+// skipWithoutIPv6 skips tests needing the IPv6 loopback, which some
+// containers lack
+func skipWithoutIPv6(t *testing.T) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	conn.Close()
+}
+
+// getCacheSize queries cacheSize.0 from target, failing the test on error
+func getCacheSize(t *testing.T, target string, port int) {
+	t.Helper()
+	client := &gosnmp.GoSNMP{
+		Target:    target,
+		Port:      uint16(port),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client to %s: %v", target, err)
+	}
+	defer client.Conn.Close()
+	packet, err := client.Get([]string{".1.3.6.1.4.1.55555.1.0"})
+	if err != nil {
+		t.Fatalf("snmp get over %s failed: %v", target, err)
+	}
+	if len(packet.Variables) != 1 || packet.Variables[0].Type != gosnmp.Gauge32 {
+		t.Fatalf("unexpected response over %s: %v", target, packet.Variables)
+	}
+}
+
+func TestSNMPListensOnIPv6(t *testing.T) {
+	skipWithoutIPv6(t)
+
+	for _, tc := range []struct {
+		name, address, transport string
+	}{
+		{"literal", "::1", ""},
+		{"bracketed", "[::1]", ""},
+		{"udp6", "::1", "udp6"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.SNMPConfig{
+				Enabled:       true,
+				Port:          0,
+				Community:     "public",
+				ListenAddress: tc.address,
+				Transport:     tc.transport,
+				EnterpriseOID: ".1.3.6.1.4.1.55555",
+			}
+			snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+			if err != nil {
+				t.Fatalf("failed to create SNMP output: %v", err)
+			}
+			defer snmpOutput.Close()
+
+			if snmpOutput.Port() == 0 {
+				t.Fatal("expected the bound port to be reported")
+			}
+			getCacheSize(t, "::1", snmpOutput.Port())
+		})
+	}
+}
+
+func TestSNMPDualStack(t *testing.T) {
+	skipWithoutIPv6(t)
+
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "::",
+		DualStack:     true,
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	if len(snmpOutput.listeners) != 2 {
+		t.Fatalf("expected an IPv4 and an IPv6 listener, got %d", len(snmpOutput.listeners))
+	}
+	getCacheSize(t, "127.0.0.1", snmpOutput.Port())
+	getCacheSize(t, "::1", snmpOutput.Port())
+}
+
+func TestSNMPRejectsInvalidListenConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name, address, transport string
+		dualStack                bool
+	}{
+		{"unknown transport", "127.0.0.1", "sctp", false},
+		{"dual stack on a specific address", "127.0.0.1", "", true},
+		{"dual stack with udp6", "::", "udp6", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.SNMPConfig{
+				Enabled:       true,
+				Port:          0,
+				Community:     "public",
+				ListenAddress: tc.address,
+				Transport:     tc.transport,
+				DualStack:     tc.dualStack,
+			}
+			if snmpOutput, err := NewSNMPOutput(cfg, "test", nil); err == nil {
+				snmpOutput.Close()
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package outputs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// mibObject describes one scalar or site table column in the enterprise subtree
+type mibObject struct {
+	ID          int
+	Name        string
+	Type        gosnmp.Asn1BER
+	Description string
+}
+
+// siteTableID is the sub-OID of the per-site table under the enterprise base.
+// Its rows are laid out index first (<base>.5.<siteIndex>.<column>), which
+// SMIv2 cannot describe as a table.
+const siteTableID = 5
+
+// siteEntryTableID is the sub-OID of siteTable, which serves the same rows in
+// the conventional SMIv2 layout: <base>.14.1.<column>.<siteIndex>
+const siteEntryTableID = 14
+
+// categoryTableID is the sub-OID of categoryTable, which aggregates the
+// sites of each category: <base>.15.1.<column>.<categoryIndex>
+const categoryTableID = 15
+
+// lastTrapMessageID is the scalar notifications carry their message in
+const lastTrapMessageID = 13
+
+// mibScalars lists the scalar objects exposed as <base>.<id>.0
+var mibScalars = []mibObject{
+	{1, "cacheSize", gosnmp.Gauge32, "Number of results currently cached"},
+	{2, "maxCacheSize", gosnmp.Gauge32, "Maximum number of cached results"},
+	{3, "monitoredSites", gosnmp.Gauge32, "Number of sites with statistics"},
+	{4, "agentUptime", gosnmp.TimeTicks, "Time since the SNMP agent started"},
+	{6, "resetStats", gosnmp.Integer, "Write 1 (with the write community) to reset all site statistics"},
+	{7, "lastAlertAck", gosnmp.Gauge32, "Unix time of the last alert acknowledgement (0 if never); write any integer to acknowledge"},
+	{8, "testsLastMinute", gosnmp.Gauge32, "Results received from all sites in the last minute"},
+	{9, "lastConfigReload", gosnmp.Gauge32, "Unix time the site list was last reloaded (agent start time before any reload)"},
+	{10, "agentVersion", gosnmp.OctetString, "Monitor software version"},
+	{11, "oldestResultTime", gosnmp.Gauge32, "Unix time of the oldest cached result (0 while the cache is empty)"},
+	{12, "newestResultTime", gosnmp.Gauge32, "Unix time of the newest cached result (0 while the cache is empty); stops advancing when tests stall"},
+	{lastTrapMessageID, "lastTrapMessage", gosnmp.OctetString, "Message of the most recent notification sent to the trap targets (empty if none)"},
+}
+
+// mibSiteColumns lists the per-site columns exposed as <base>.5.<siteIndex>.<id>
+// and <base>.14.1.<id>.<siteIndex>
+var mibSiteColumns = []mibObject{
+	{1, "siteName", gosnmp.OctetString, "Site name"},
+	{2, "totalTests", gosnmp.Counter32, "Total tests performed"},
+	{3, "successfulTests", gosnmp.Counter32, "Successful tests"},
+	{4, "failedTests", gosnmp.Counter32, "Failed tests"},
+	{5, "lastSuccessTime", gosnmp.Gauge32, "Unix time of the last success (0 if never)"},
+	{6, "lastFailureTime", gosnmp.Gauge32, "Unix time of the last failure (0 if never)"},
+	{7, "lastDurationMs", gosnmp.Gauge32, "Duration of the last test in milliseconds"},
+	{8, "avgDurationMs", gosnmp.Gauge32, "Average test duration in milliseconds"},
+	{9, "maxDurationMs", gosnmp.Gauge32, "Maximum test duration in milliseconds"},
+	{10, "minDurationMs", gosnmp.Gauge32, "Minimum test duration in milliseconds"},
+	{11, "ewmaDurationMs", gosnmp.Gauge32, "Exponentially weighted moving average of the test duration in milliseconds, favouring recent tests"},
+	{12, "uptimePercent", gosnmp.Gauge32, "Percentage of all tests that succeeded, rounded down (0 before the first test)"},
+	{13, "recentUptimePercent", gosnmp.Gauge32, "Percentage of the site's last site_history_size tests that succeeded, rounded down"},
+	{14, "testsLastMinute", gosnmp.Gauge32, "Results received for the site in the last minute"},
+	{15, "circuitBreakerState", gosnmp.Integer, "closed(1): tested every round; open(2): backed off after repeated failures"},
+	{16, "stddevDurationMs", gosnmp.Gauge32, "Standard deviation (jitter) of the test duration in milliseconds"},
+	{17, "stddevDnsMs", gosnmp.Gauge32, "Standard deviation of the DNS lookup time in milliseconds"},
+	{18, "stddevTcpMs", gosnmp.Gauge32, "Standard deviation of the TCP connection time in milliseconds"},
+	{19, "stddevTlsMs", gosnmp.Gauge32, "Standard deviation of the TLS handshake time in milliseconds"},
+	{20, "stddevTtfbMs", gosnmp.Gauge32, "Standard deviation of the time to first byte in milliseconds"},
+	{21, "totalTests64", gosnmp.Counter64, "Total tests performed (64-bit; totalTests wraps at 2^32)"},
+	{22, "successfulTests64", gosnmp.Counter64, "Successful tests (64-bit)"},
+	{23, "failedTests64", gosnmp.Counter64, "Failed tests (64-bit)"},
+	{siteResetColumnID, "siteReset", gosnmp.Integer, "Write 1 (with the write community) to reset this site's statistics; reads as 0"},
+	{25, "p50DurationMs", gosnmp.Gauge32, "Median test duration in milliseconds over the site's last percentile_samples tests"},
+	{26, "p95DurationMs", gosnmp.Gauge32, "95th percentile test duration in milliseconds over the site's last percentile_samples tests"},
+	{27, "p99DurationMs", gosnmp.Gauge32, "99th percentile test duration in milliseconds over the site's last percentile_samples tests"},
+	{28, "windowUptimePercent", gosnmp.Gauge32, "Percentage of the site's tests in the last success_window that succeeded, rounded down"},
+	{29, "lastErrorType", gosnmp.OctetString, "Error type of the site's latest failure, e.g. ERR_NAME_NOT_RESOLVED (empty if it has never failed)"},
+	{30, "lastErrorMessage", gosnmp.OctetString, "Error message of the site's latest failure, cut to 255 bytes (empty if it has never failed)"},
+}
+
+// mibCategoryColumns lists the per-category columns exposed as
+// <base>.15.1.<id>.<categoryIndex>. Names carry the category prefix as SMI
+// descriptors share one namespace.
+var mibCategoryColumns = []mibObject{
+	{1, "categoryName", gosnmp.OctetString, "Category name, from the category of the sites' results"},
+	{2, "categoryTotalTests", gosnmp.Counter32, "Total tests performed by the category's sites"},
+	{3, "categorySuccessfulTests", gosnmp.Counter32, "Successful tests of the category's sites"},
+	{4, "categoryFailedTests", gosnmp.Counter32, "Failed tests of the category's sites"},
+	{5, "categoryAvgDurationMs", gosnmp.Gauge32, "Average test duration in milliseconds over every test of the category's sites"},
+	{6, "categorySites", gosnmp.Gauge32, "Number of sites in the category"},
+}
+
+// circuitBreakerState values (site column 15)
+const (
+	circuitBreakerClosed = 1
+	circuitBreakerOpen   = 2
+)
+
+// MIBSnapshot is a point-in-time view of every OID the agent serves
+type MIBSnapshot struct {
+	// Base is the normalized enterprise OID the tree is rooted at
+	Base string
+	// OIDs are all served OIDs in walk order
+	OIDs []string
+	// Values maps each OID to the PDU returned for it
+	Values map[string]gosnmp.SnmpPDU
+}
+
+// Snapshot returns the OID tree the agent would currently serve.
+// It is intended for tests and tooling; use VerifyMIBTree to check its structure.
+func (s *SNMPOutput) Snapshot() MIBSnapshot {
+	oids, values := s.buildOIDSnapshot()
+	return MIBSnapshot{
+		Base:   s.baseOID(),
+		OIDs:   oids,
+		Values: values,
+	}
+}
+
+// VerifyMIBTree checks that a snapshot forms a well-ordered, correctly typed tree:
+// OIDs are strictly ascending, every OID has a matching value, every scalar is
+// present, and every site row carries every column with the expected type, in
+// both the legacy site table and siteTable, as does every categoryTable row.
+// All problems found are returned together.
+func VerifyMIBTree(snapshot MIBSnapshot) error {
+	var errs []error
+
+	if len(snapshot.OIDs) != len(snapshot.Values) {
+		errs = append(errs, fmt.Errorf("snapshot has %d OIDs but %d values", len(snapshot.OIDs), len(snapshot.Values)))
+	}
+
+	for i, oid := range snapshot.OIDs {
+		if i > 0 && compareOIDs(snapshot.OIDs[i-1], oid) >= 0 {
+			errs = append(errs, fmt.Errorf("OID %s is not after %s", oid, snapshot.OIDs[i-1]))
+		}
+		pdu, ok := snapshot.Values[oid]
+		if !ok {
+			errs = append(errs, fmt.Errorf("OID %s has no value", oid))
+			continue
+		}
+		if pdu.Name != oid {
+			errs = append(errs, fmt.Errorf("OID %s has value named %q", oid, pdu.Name))
+		}
+	}
+
+	base := snapshot.Base + "."
+	seenScalars := make(map[int]bool)
+	siteColumns := make(map[int]map[int]bool)  // legacy table, by site index
+	entryColumns := make(map[int]map[int]bool) // siteTable, by site index
+	categoryRows := make(map[int]map[int]bool) // categoryTable, by category index
+
+	for _, oid := range snapshot.OIDs {
+		if !strings.HasPrefix(oid, base) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(oid, base), ".")
+		ids := make([]int, 0, len(parts))
+		for _, p := range parts {
+			id, err := strconv.Atoi(p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("OID %s has non-numeric component %q", oid, p))
+				ids = nil
+				break
+			}
+			ids = append(ids, id)
+		}
+		if ids == nil {
+			continue
+		}
+
+		pdu := snapshot.Values[oid]
+		switch {
+		case len(ids) == 2 && ids[1] == 0:
+			obj, ok := findMIBObject(mibScalars, ids[0])
+			if !ok {
+				errs = append(errs, fmt.Errorf("OID %s is not a known scalar", oid))
+				continue
+			}
+			seenScalars[ids[0]] = true
+			if pdu.Type != obj.Type {
+				errs = append(errs, fmt.Errorf("scalar %s (%s) has type %v, want %v", obj.Name, oid, pdu.Type, obj.Type))
+			}
+		case len(ids) == 3 && ids[0] == siteTableID:
+			obj, ok := findMIBObject(mibSiteColumns, ids[2])
+			if !ok {
+				errs = append(errs, fmt.Errorf("OID %s is not a known site column", oid))
+				continue
+			}
+			if siteColumns[ids[1]] == nil {
+				siteColumns[ids[1]] = make(map[int]bool)
+			}
+			siteColumns[ids[1]][ids[2]] = true
+			if pdu.Type != obj.Type {
+				errs = append(errs, fmt.Errorf("site column %s (%s) has type %v, want %v", obj.Name, oid, pdu.Type, obj.Type))
+			}
+		case len(ids) == 4 && ids[0] == siteEntryTableID && ids[1] == 1:
+			obj, ok := findMIBObject(mibSiteColumns, ids[2])
+			if !ok {
+				errs = append(errs, fmt.Errorf("OID %s is not a known site column", oid))
+				continue
+			}
+			if entryColumns[ids[3]] == nil {
+				entryColumns[ids[3]] = make(map[int]bool)
+			}
+			entryColumns[ids[3]][ids[2]] = true
+			if pdu.Type != obj.Type {
+				errs = append(errs, fmt.Errorf("site column %s (%s) has type %v, want %v", obj.Name, oid, pdu.Type, obj.Type))
+			}
+		case len(ids) == 4 && ids[0] == categoryTableID && ids[1] == 1:
+			obj, ok := findMIBObject(mibCategoryColumns, ids[2])
+			if !ok {
+				errs = append(errs, fmt.Errorf("OID %s is not a known category column", oid))
+				continue
+			}
+			if categoryRows[ids[3]] == nil {
+				categoryRows[ids[3]] = make(map[int]bool)
+			}
+			categoryRows[ids[3]][ids[2]] = true
+			if pdu.Type != obj.Type {
+				errs = append(errs, fmt.Errorf("category column %s (%s) has type %v, want %v", obj.Name, oid, pdu.Type, obj.Type))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("OID %s does not match the MIB layout", oid))
+		}
+	}
+
+	for _, obj := range mibScalars {
+		if !seenScalars[obj.ID] {
+			errs = append(errs, fmt.Errorf("scalar %s (%s.%d.0) is missing", obj.Name, snapshot.Base, obj.ID))
+		}
+	}
+
+	errs = append(errs, missingColumns("site", mibSiteColumns, siteColumns)...)
+	errs = append(errs, missingColumns("siteTable row", mibSiteColumns, entryColumns)...)
+	errs = append(errs, missingColumns("categoryTable row", mibCategoryColumns, categoryRows)...)
+	for idx := range siteColumns {
+		if entryColumns[idx] == nil {
+			errs = append(errs, fmt.Errorf("site %d has no siteTable row", idx))
+		}
+	}
+	for idx := range entryColumns {
+		if siteColumns[idx] == nil {
+			errs = append(errs, fmt.Errorf("siteTable row %d has no site", idx))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// missingColumns reports every one of columns absent from rows
+func missingColumns(what string, columns []mibObject, rows map[int]map[int]bool) []error {
+	indexes := make([]int, 0, len(rows))
+	for idx := range rows {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	var errs []error
+	for _, idx := range indexes {
+		for _, obj := range columns {
+			if !rows[idx][obj.ID] {
+				errs = append(errs, fmt.Errorf("%s %d is missing column %s", what, idx, obj.Name))
+			}
+		}
+	}
+	return errs
+}
+
+func findMIBObject(objects []mibObject, id int) (mibObject, bool) {
+	for _, obj := range objects {
+		if obj.ID == id {
+			return obj, true
+		}
+	}
+	return mibObject{}, false
+}
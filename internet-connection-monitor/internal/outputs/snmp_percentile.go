@@ -0,0 +1,48 @@
+package outputs
+
+import (
+	"math"
+	"sort"
+)
+
+// defaultPercentileSamples is used when SNMPConfig.PercentileSamples is not set
+const defaultPercentileSamples = 100
+
+// durationWindow keeps a site's most recent test durations for percentiles.
+// Memory is bounded by the sample count however long the site runs; once
+// full, each new sample replaces the oldest.
+// It is not safe for concurrent use; callers guard it with their own lock.
+type durationWindow struct {
+	samples []int64
+	next    int // index of the oldest sample once full
+}
+
+// add records one sample, keeping at most size
+func (w *durationWindow) add(ms int64, size int) {
+	if len(w.samples) < size {
+		w.samples = append(w.samples, ms)
+		return
+	}
+	w.samples[w.next] = ms
+	w.next = (w.next + 1) % len(w.samples)
+}
+
+// percentiles returns the nearest-rank percentile of the window for each of
+// ps (0 < p <= 100), all 0 while the window is empty
+func (w *durationWindow) percentiles(ps ...float64) []int64 {
+	out := make([]int64, len(ps))
+	if len(w.samples) == 0 {
+		return out
+	}
+
+	sorted := append([]int64(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i, p := range ps {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		out[i] = sorted[rank-1]
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package outputs
+
+import "time"
+
+// rateWindowSeconds is the span of a rateCounter, in one-second buckets
+const rateWindowSeconds = 60
+
+// rateCounter counts events over the last minute using one bucket per second,
+// so recording and reading cost the same however many events there were.
+// It is not safe for concurrent use; callers guard it with their own lock.
+type rateCounter struct {
+	counts [rateWindowSeconds]uint32
+	// seconds holds the Unix second each bucket was last used for; a bucket
+	// whose second has left the window is stale and counts as empty
+	seconds [rateWindowSeconds]int64
+}
+
+// add records one event at now
+func (r *rateCounter) add(now time.Time) {
+	sec := now.Unix()
+	i := bucketIndex(sec)
+	if r.seconds[i] != sec {
+		r.seconds[i] = sec
+		r.counts[i] = 0
+	}
+	r.counts[i]++
+}
+
+// count returns the number of events in the minute ending at now
+func (r *rateCounter) count(now time.Time) uint32 {
+	sec := now.Unix()
+	var total uint32
+	for i, bucketSec := range r.seconds {
+		if age := sec - bucketSec; age >= 0 && age < rateWindowSeconds {
+			total += r.counts[i]
+		}
+	}
+	return total
+}
+
+func bucketIndex(sec int64) int {
+	i := int(sec % rateWindowSeconds)
+	if i < 0 {
+		i += rateWindowSeconds
+	}
+	return i
+}
@@ -0,0 +1,142 @@
+package outputs
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// Per-source rate limit
+//
+// A GetBulk response is much larger than its request, so an agent that
+// answers spoofed requests can be used for UDP amplification. With rate_limit
+// set, each source address gets a token bucket of rate_limit requests per
+// second and rate_limit_burst tokens; a request without a token is dropped
+// unanswered before it is decoded, so the spoofed victim receives nothing.
+// At most maxRateLimitSources buckets are kept. When a new source needs one,
+// buckets that have refilled (indistinguishable from new ones) are swept, and
+// failing that the least recently used bucket is evicted. Drops are logged at
+// debug level like the allowlist's, at most once per aclLogInterval.
+
+// maxRateLimitSources bounds the rate limiter's memory
+const maxRateLimitSources = 4096
+
+// rateLimiter is the per-source token buckets; a nil *rateLimiter allows
+// every request
+type rateLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64 // Bucket capacity
+
+	mu         sync.Mutex
+	buckets    map[string]*tokenBucket
+	lastLog    time.Time
+	suppressed int
+}
+
+// tokenBucket is one source's bucket, as of last
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for rate requests per second per source,
+// nil when rate is 0. A burst of 0 defaults to one second's worth of requests.
+func newRateLimiter(rate float64, burst int) (*rateLimiter, error) {
+	if rate == 0 {
+		return nil, nil
+	}
+	if rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return nil, fmt.Errorf("SNMP rate_limit must be positive, got %v", rate)
+	}
+	if burst < 0 {
+		return nil, fmt.Errorf("SNMP rate_limit_burst must not be negative, got %d", burst)
+	}
+	if burst == 0 {
+		burst = max(int(math.Ceil(rate)), 1)
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}, nil
+}
+
+// allow takes a token from ip's bucket, reporting whether there was one
+func (l *rateLimiter) allow(ip net.IP, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	key := ip.String()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitSources {
+			l.evictLocked(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.rate, l.burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens earned since last, up to burst
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+}
+
+// evictLocked makes room for a bucket: it removes every bucket that has
+// refilled, or if none has, the least recently used one
+func (l *rateLimiter) evictLocked(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, b := range l.buckets {
+		b.refill(now, l.rate, l.burst)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+			continue
+		}
+		if oldestKey == "" || b.last.Before(oldest) {
+			oldestKey, oldest = key, b.last
+		}
+	}
+	if len(l.buckets) >= maxRateLimitSources {
+		delete(l.buckets, oldestKey)
+	}
+}
+
+// sources returns the number of buckets held
+func (l *rateLimiter) sources() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// logLimited notes a dropped request, logging it unless another was logged
+// within aclLogInterval
+func (l *rateLimiter) logLimited(remote net.Addr, now time.Time) {
+	l.mu.Lock()
+	if !l.lastLog.IsZero() && now.Sub(l.lastLog) < aclLogInterval {
+		l.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	suppressed := l.suppressed
+	l.lastLog, l.suppressed = now, 0
+	l.mu.Unlock()
+
+	slog.Debug("SNMP request dropped: source over rate_limit", "remote", remote.String(), "suppressed", suppressed)
+}
@@ -0,0 +1,156 @@
+package outputs
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+func TestSNMPRateLimitDropsFlood(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		Community:      "public",
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  ".1.3.6.1.4.1.55555",
+		RateLimit:      1,
+		RateLimitBurst: 5,
+	}
+	// Freeze the clock so no tokens are earned during the flood
+	clock := newTestClock(time.Now())
+	snmpOutput, err := newSNMPOutput(cfg, "test", nil, clock.now)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP(cfg.ListenAddress), Port: snmpOutput.Port()})
+	if err != nil {
+		t.Fatalf("failed to dial SNMP agent: %v", err)
+	}
+	defer conn.Close()
+
+	// flood sends n GetRequests at once and counts the responses
+	requestID := uint32(0)
+	flood := func(n int) int {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			requestID++
+			request := &gosnmp.SnmpPacket{
+				Version:   gosnmp.Version2c,
+				Community: cfg.Community,
+				PDUType:   gosnmp.GetRequest,
+				RequestID: requestID,
+				Variables: []gosnmp.SnmpPDU{{Name: cfg.EnterpriseOID + ".1.0", Type: gosnmp.Null}},
+			}
+			msg, err := request.MarshalMsg()
+			if err != nil {
+				t.Fatalf("failed to marshal GetRequest: %v", err)
+			}
+			if _, err := conn.Write(msg); err != nil {
+				t.Fatalf("failed to send GetRequest: %v", err)
+			}
+		}
+		answered := 0
+		buf := make([]byte, 65535)
+		for {
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			if _, err := conn.Read(buf); err != nil {
+				return answered
+			}
+			answered++
+		}
+	}
+
+	if got := flood(20); got != 5 {
+		t.Fatalf("expected the burst of 5 of 20 requests answered, got %d", got)
+	}
+	if got := flood(3); got != 0 {
+		t.Fatalf("expected no answers with the bucket empty, got %d", got)
+	}
+
+	// Two seconds earn two tokens
+	snmpOutput.mu.Lock()
+	clock.advance(2 * time.Second)
+	snmpOutput.mu.Unlock()
+	if got := flood(5); got != 2 {
+		t.Fatalf("expected 2 answers after 2s at 1/s, got %d", got)
+	}
+
+	// Other sources have their own buckets. Every 127/8 address is loopback
+	// on Linux.
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   0,
+		LocalAddr: "127.0.0.2:0",
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+	if _, err := client.Get([]string{cfg.EnterpriseOID + ".1.0"}); err != nil {
+		t.Fatalf("expected an answer for another source: %v", err)
+	}
+}
+
+func TestRateLimiterIsBounded(t *testing.T) {
+	limiter, err := newRateLimiter(1, 1)
+	if err != nil {
+		t.Fatalf("newRateLimiter failed: %v", err)
+	}
+	now := time.Now()
+
+	// A spoofed flood from more sources than fit, each within its rate
+	for i := 0; i < 2*maxRateLimitSources; i++ {
+		ip := net.ParseIP(fmt.Sprintf("10.%d.%d.1", i/256, i%256))
+		if !limiter.allow(ip, now) {
+			t.Fatalf("expected the first request from %s to be allowed", ip)
+		}
+		if n := limiter.sources(); n > maxRateLimitSources {
+			t.Fatalf("expected at most %d buckets, got %d", maxRateLimitSources, n)
+		}
+	}
+
+	// Refilled buckets are swept rather than the busy one evicted
+	busy := net.ParseIP("192.0.2.1")
+	limiter.allow(busy, now)
+	later := now.Add(10 * time.Second)
+	limiter.allow(busy, later)
+	if !limiter.allow(net.ParseIP("192.0.2.2"), later) {
+		t.Fatal("expected a new source to be allowed")
+	}
+	if n := limiter.sources(); n != 2 {
+		t.Fatalf("expected the refilled buckets to be swept, leaving 2, got %d", n)
+	}
+	if limiter.allow(busy, later) {
+		t.Error("expected the busy source to still be limited")
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	if limiter, err := newRateLimiter(0, 0); limiter != nil || err != nil {
+		t.Errorf("expected no limiter for rate 0, got %v, %v", limiter, err)
+	}
+	if _, err := newRateLimiter(-1, 0); err == nil {
+		t.Error("expected an error for a negative rate")
+	}
+	if _, err := newRateLimiter(1, -1); err == nil {
+		t.Error("expected an error for a negative burst")
+	}
+	limiter, err := newRateLimiter(2.5, 0)
+	if err != nil {
+		t.Fatalf("newRateLimiter failed: %v", err)
+	}
+	if limiter.burst != 3 {
+		t.Errorf("expected the burst to default to 3, got %v", limiter.burst)
+	}
+}
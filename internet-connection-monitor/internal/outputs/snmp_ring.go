@@ -0,0 +1,47 @@
+package outputs
+
+import "github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+
+// resultRing holds the most recent results in a fixed-size buffer. A new
+// result overwrites the slot of the one it evicts, so evicted results are
+// unreachable (and collectible) straight away.
+// It is not safe for concurrent use; callers guard it with their own lock.
+type resultRing struct {
+	buf   []*models.TestResult
+	start int // index of the oldest result
+	n     int
+}
+
+// add appends result, evicting the oldest once size results are held. The
+// buffer is allocated on first use, at size.
+func (r *resultRing) add(result *models.TestResult, size int) {
+	if r.buf == nil {
+		r.buf = make([]*models.TestResult, size)
+	}
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = result
+		r.n++
+		return
+	}
+	r.buf[r.start] = result
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// len returns the number of results held
+func (r *resultRing) len() int {
+	return r.n
+}
+
+// at returns the i-th oldest result, 0 <= i < len()
+func (r *resultRing) at(i int) *models.TestResult {
+	return r.buf[(r.start+i)%len(r.buf)]
+}
+
+// results returns a copy of the held results, oldest first
+func (r *resultRing) results() []*models.TestResult {
+	out := make([]*models.TestResult, r.n)
+	for i := range out {
+		out[i] = r.at(i)
+	}
+	return out
+}
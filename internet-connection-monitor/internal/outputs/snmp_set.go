@@ -0,0 +1,202 @@
+package outputs
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Writable control scalars and site columns. Everything else in the tree is
+// read-only.
+const (
+	resetStatsScalarID = 6 // SET 1 to clear all site statistics; reads as 0
+	alertAckScalarID   = 7 // SET any integer to acknowledge; reads as the Unix time of the last ack
+
+	siteResetColumnID = 24 // SET 1 to clear the site's statistics; reads as 0
+)
+
+// writableScalars maps each writable scalar ID to its SET handler.
+// Handlers are called with s.mu held exclusively and must not block.
+var writableScalars = map[int]func(s *SNMPOutput, value int) gosnmp.SNMPError{
+	resetStatsScalarID: (*SNMPOutput).setResetStats,
+	alertAckScalarID:   (*SNMPOutput).setAlertAck,
+}
+
+// writableSiteColumns maps each writable site column ID to its SET handler,
+// which is called like a writableScalars handler with the row's site name.
+// A column is writable in both the legacy site table and siteTable.
+var writableSiteColumns = map[int]func(s *SNMPOutput, siteName string, value int) gosnmp.SNMPError{
+	siteResetColumnID: (*SNMPOutput).setSiteReset,
+}
+
+// setHandler applies one value; a nil receiver only validates it
+type setHandler func(s *SNMPOutput, value int) gosnmp.SNMPError
+
+// handleSet applies a SetRequest. Like a real agent, the request is all or
+// nothing: every binding is validated before any is applied, and the first
+// invalid one is reported by error status and (1-based) index. OIDs outside
+// the community's view are refused with noAccess.
+func (s *SNMPOutput) handleSet(vars []gosnmp.SnmpPDU, view *snmpView) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
+	base := s.baseOID()
+
+	type setOp struct {
+		apply setHandler
+		value int
+	}
+	ops := make([]setOp, 0, len(vars))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, vb := range vars {
+		oid, err := normalizeOID(vb.Name)
+		if err != nil {
+			// Nothing can ever exist at a malformed OID
+			return vars, gosnmp.NoCreation, uint8(i + 1)
+		}
+		if !view.contains(oid) {
+			return vars, gosnmp.NoAccess, uint8(i + 1)
+		}
+
+		apply, obj, errStatus := s.setTarget(base, oid)
+		if errStatus != gosnmp.NoError {
+			return vars, errStatus, uint8(i + 1)
+		}
+
+		// INTEGER is always accepted; a Gauge32 object (lastAlertAck) also
+		// takes its own SYNTAX, which MIB-driven managers send
+		var value int
+		switch v := vb.Value.(type) {
+		case int:
+			value = v
+		case uint:
+			value = int(v)
+		}
+		if vb.Type != gosnmp.Integer && (vb.Type != gosnmp.Gauge32 || obj.Type != gosnmp.Gauge32) {
+			return vars, gosnmp.WrongType, uint8(i + 1)
+		}
+		ops = append(ops, setOp{apply: apply, value: value})
+	}
+
+	// Validate values before changing anything
+	for i, op := range ops {
+		if errStatus := op.apply(nil, op.value); errStatus != gosnmp.NoError {
+			return vars, errStatus, uint8(i + 1)
+		}
+	}
+	for _, op := range ops {
+		op.apply(s, op.value)
+	}
+	s.invalidateSnapshot()
+
+	return vars, gosnmp.NoError, 0
+}
+
+// setTarget resolves a writable scalar or site column instance to its handler
+// and MIB object. Other OIDs are notWritable, and rows that don't exist are
+// noCreation. The caller holds mu.
+func (s *SNMPOutput) setTarget(base, oid string) (setHandler, mibObject, gosnmp.SNMPError) {
+	if !strings.HasPrefix(oid, base+".") {
+		return nil, mibObject{}, gosnmp.NotWritable
+	}
+	ids, ok := oidComponents(strings.TrimPrefix(oid, base+"."))
+	if !ok {
+		return nil, mibObject{}, gosnmp.NotWritable
+	}
+
+	if len(ids) == 2 && ids[1] == 0 {
+		handler, ok := writableScalars[ids[0]]
+		if !ok {
+			return nil, mibObject{}, gosnmp.NotWritable
+		}
+		obj, _ := findMIBObject(mibScalars, ids[0])
+		return handler, obj, gosnmp.NoError
+	}
+
+	var column, index int
+	switch {
+	case len(ids) == 3 && ids[0] == siteTableID:
+		index, column = ids[1], ids[2]
+	case len(ids) == 4 && ids[0] == siteEntryTableID && ids[1] == 1:
+		column, index = ids[2], ids[3]
+	default:
+		return nil, mibObject{}, gosnmp.NotWritable
+	}
+	handler, ok := writableSiteColumns[column]
+	if !ok {
+		return nil, mibObject{}, gosnmp.NotWritable
+	}
+	for siteName, idx := range s.siteIndex {
+		if _, hasRow := s.sites[siteName]; idx == index && hasRow {
+			obj, _ := findMIBObject(mibSiteColumns, column)
+			return func(s *SNMPOutput, value int) gosnmp.SNMPError {
+				return handler(s, siteName, value)
+			}, obj, gosnmp.NoError
+		}
+	}
+	return nil, mibObject{}, gosnmp.NoCreation
+}
+
+// oidComponents parses a dotted OID suffix into its sub-identifiers
+func oidComponents(suffix string) ([]int, bool) {
+	parts := strings.Split(suffix, ".")
+	ids := make([]int, 0, len(parts))
+	for _, p := range parts {
+		id, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// setResetStats clears every site's statistics and history. Site indexes are
+// kept so sites reappear at the same OIDs once they report again.
+// A nil receiver only validates the value.
+func (s *SNMPOutput) setResetStats(value int) gosnmp.SNMPError {
+	if value != 1 {
+		return gosnmp.WrongValue
+	}
+	if s == nil {
+		return gosnmp.NoError
+	}
+
+	for siteName := range s.sites {
+		delete(s.sites, siteName)
+	}
+	log.Printf("SNMP: site statistics reset by SET request")
+	return gosnmp.NoError
+}
+
+// setAlertAck records an alert acknowledgement. A nil receiver only validates the value.
+func (s *SNMPOutput) setAlertAck(value int) gosnmp.SNMPError {
+	if s == nil {
+		return gosnmp.NoError
+	}
+
+	s.lastAlertAck = s.now()
+	log.Printf("SNMP: alert acknowledged by SET request (value %d)", value)
+	return gosnmp.NoError
+}
+
+// setSiteReset clears one site's statistics and history. Unlike resetStats
+// the row stays, reading zeros until the site reports again.
+// A nil receiver only validates the value.
+func (s *SNMPOutput) setSiteReset(siteName string, value int) gosnmp.SNMPError {
+	if value != 1 {
+		return gosnmp.WrongValue
+	}
+	if s == nil {
+		return gosnmp.NoError
+	}
+
+	// Keep LastSeen so site_ttl still counts from the site's last result, and
+	// the category so the site stays in its categoryTable row
+	old := s.sites[siteName].stats
+	s.sites[siteName] = &siteState{stats: siteStats{LastSeen: old.LastSeen, Category: old.Category}}
+	log.Printf("SNMP: statistics for %s reset by SET request", siteName)
+	return gosnmp.NoError
+}
@@ -0,0 +1,297 @@
+package outputs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// SMIv2 MIB module generated from the MIB tables in snmp_mib.go and
+// snmp_trap.go, so managers can load names, types and descriptions for the
+// OIDs the agent actually serves.
+//
+// The module describes the scalars, siteTable (the conventional copy of the
+// site rows at <base>.14), categoryTable and the notifications. The index-first rows at
+// <base>.5 cannot be written as an SMIv2 table, so the module only names
+// that subtree.
+
+const (
+	smiModuleName   = "INTERNET-CONNECTION-MONITOR-MIB"
+	smiModuleObject = "internetConnectionMonitorMIB"
+	smiLastUpdated  = "202610160000Z"
+
+	// smiIndexColumnID numbers the not-accessible index column of siteEntry
+	// and categoryEntry, which is never served, well clear of their columns
+	smiIndexColumnID = 100
+
+	// smiConformanceID is the sub-OID of the (unserved) conformance groups
+	smiConformanceID = 99
+)
+
+// smiEnumerations gives the SYNTAX of Integer objects with named values
+var smiEnumerations = map[string]string{
+	"circuitBreakerState": fmt.Sprintf("INTEGER { closed(%d), open(%d) }", circuitBreakerClosed, circuitBreakerOpen),
+}
+
+// ExportSMIv2 returns an SMIv2 MIB module describing the agent's tree
+func (s *SNMPOutput) ExportSMIv2() string {
+	return ExportSMIv2Module(s.baseOID())
+}
+
+// ExportSMIv2Module returns an SMIv2 MIB module (INTERNET-CONNECTION-MONITOR-MIB)
+// for the agent's scalars, siteTable, categoryTable and notifications, rooted
+// at enterpriseOID
+func ExportSMIv2Module(enterpriseOID string) string {
+	base := enterpriseBaseOID(enterpriseOID)
+
+	var b strings.Builder
+	w := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format, args...)
+	}
+
+	moduleOID, usesEnterprises := smiModuleOID(base)
+	w("%s DEFINITIONS ::= BEGIN\n\n", smiModuleName)
+	w("IMPORTS\n")
+	w("    %s\n        FROM SNMPv2-SMI\n", strings.Join(smiImports(usesEnterprises), ", "))
+	w("    DisplayString\n        FROM SNMPv2-TC\n")
+	w("    MODULE-COMPLIANCE, OBJECT-GROUP, NOTIFICATION-GROUP\n        FROM SNMPv2-CONF;\n\n")
+
+	w("%s MODULE-IDENTITY\n", smiModuleObject)
+	w("    LAST-UPDATED \"%s\"\n", smiLastUpdated)
+	w("    ORGANIZATION \"Internet Connection Monitor\"\n")
+	w("    CONTACT-INFO \"https://github.com/nickborgers/monorepo\"\n")
+	w("    DESCRIPTION  \"Statistics from the Internet Connection Monitor's synthetic browser tests, per site and overall.\"\n")
+	w("    REVISION     \"%s\"\n", smiLastUpdated)
+	w("    DESCRIPTION  \"Generated from the agent's MIB tables.\"\n")
+	w("    ::= %s\n\n", moduleOID)
+
+	w("icmNotifications OBJECT IDENTIFIER ::= { %s %d }\n", smiModuleObject, notificationsID)
+	w("-- Site rows laid out index first (<base>.%d.<siteIndex>.<column>); siteTable\n", siteTableID)
+	w("-- serves the same values in the conventional layout\n")
+	w("icmSiteRows OBJECT IDENTIFIER ::= { %s %d }\n", smiModuleObject, siteTableID)
+	w("icmConformance OBJECT IDENTIFIER ::= { %s %d }\n\n", smiModuleObject, smiConformanceID)
+
+	scalars := sortedMIBObjects(mibScalars)
+	for _, obj := range scalars {
+		access := "read-only"
+		if _, ok := writableScalars[obj.ID]; ok {
+			access = "read-write"
+		}
+		writeSMIObjectType(&b, obj.Name, smiSyntax(obj), access, obj.Description, smiModuleObject, obj.ID)
+	}
+
+	columns := sortedMIBObjects(mibSiteColumns)
+	writeSMITable(&b, smiTable{
+		name:        "site",
+		id:          siteEntryTableID,
+		description: "Statistics for each monitored site. Indexes follow the order of the configured site list and are never reused.",
+		columns:     columns,
+		writable: func(id int) bool {
+			_, ok := writableSiteColumns[id]
+			return ok
+		},
+	})
+
+	categoryColumns := sortedMIBObjects(mibCategoryColumns)
+	writeSMITable(&b, smiTable{
+		name:        "category",
+		id:          categoryTableID,
+		description: "Statistics summed over the sites of each category. Indexes are assigned as categories first appear and are never reused.",
+		columns:     categoryColumns,
+	})
+
+	siteName, _ := findMIBObject(mibSiteColumns, 1)
+	lastTrapMessage, _ := findMIBObject(mibScalars, lastTrapMessageID)
+	notifications := sortedMIBObjects(mibNotifications)
+	for _, n := range notifications {
+		objects := []string{lastTrapMessage.Name}
+		if n.Name != "monitorAlert" {
+			objects = []string{smiColumnName(siteName.Name), lastTrapMessage.Name}
+		}
+		w("%s NOTIFICATION-TYPE\n", n.Name)
+		w("    OBJECTS     { %s }\n", strings.Join(objects, ", "))
+		w("    STATUS      current\n")
+		w("    DESCRIPTION %s\n", smiQuote(n.Description))
+		w("    ::= { icmNotifications %d }\n\n", n.ID)
+	}
+
+	scalarNames := make([]string, 0, len(scalars))
+	for _, obj := range scalars {
+		scalarNames = append(scalarNames, obj.Name)
+	}
+	columnNames := make([]string, 0, len(columns))
+	for _, obj := range columns {
+		columnNames = append(columnNames, smiColumnName(obj.Name))
+	}
+	categoryNames := make([]string, 0, len(categoryColumns))
+	for _, obj := range categoryColumns {
+		categoryNames = append(categoryNames, obj.Name)
+	}
+	notificationNames := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		notificationNames = append(notificationNames, n.Name)
+	}
+
+	w("icmGroups OBJECT IDENTIFIER ::= { icmConformance 1 }\n")
+	w("icmCompliances OBJECT IDENTIFIER ::= { icmConformance 2 }\n\n")
+	writeSMIGroup(&b, "icmScalarGroup", "OBJECT-GROUP", "OBJECTS", scalarNames, "Agent-wide statistics and controls", 1)
+	writeSMIGroup(&b, "icmSiteGroup", "OBJECT-GROUP", "OBJECTS", columnNames, "Per-site statistics", 2)
+	writeSMIGroup(&b, "icmNotificationGroup", "NOTIFICATION-GROUP", "NOTIFICATIONS", notificationNames, "Site state changes and alerts", 3)
+	writeSMIGroup(&b, "icmCategoryGroup", "OBJECT-GROUP", "OBJECTS", categoryNames, "Per-category statistics", 4)
+
+	w("icmCompliance MODULE-COMPLIANCE\n")
+	w("    STATUS      current\n")
+	w("    DESCRIPTION \"The Internet Connection Monitor SNMP agent\"\n")
+	w("    MODULE      -- this module\n")
+	w("    MANDATORY-GROUPS { icmScalarGroup, icmSiteGroup, icmNotificationGroup, icmCategoryGroup }\n")
+	w("    ::= { icmCompliances 1 }\n\n")
+
+	w("END\n")
+	return b.String()
+}
+
+// smiTable describes a conventional table at <module>.<id>: <name>Table,
+// <name>Entry indexed by the not-accessible <name>Index, and its columns
+type smiTable struct {
+	name        string
+	id          int
+	description string
+	columns     []mibObject
+	writable    func(id int) bool // nil if every column is read-only
+}
+
+func writeSMITable(b *strings.Builder, t smiTable) {
+	table, entry, index := t.name+"Table", t.name+"Entry", t.name+"Index"
+	entryType := strings.ToUpper(t.name[:1]) + t.name[1:] + "Entry"
+
+	fmt.Fprintf(b, "%s OBJECT-TYPE\n", table)
+	fmt.Fprintf(b, "    SYNTAX      SEQUENCE OF %s\n", entryType)
+	fmt.Fprintf(b, "    MAX-ACCESS  not-accessible\n")
+	fmt.Fprintf(b, "    STATUS      current\n")
+	fmt.Fprintf(b, "    DESCRIPTION %s\n", smiQuote(t.description))
+	fmt.Fprintf(b, "    ::= { %s %d }\n\n", smiModuleObject, t.id)
+
+	fmt.Fprintf(b, "%s OBJECT-TYPE\n", entry)
+	fmt.Fprintf(b, "    SYNTAX      %s\n", entryType)
+	fmt.Fprintf(b, "    MAX-ACCESS  not-accessible\n")
+	fmt.Fprintf(b, "    STATUS      current\n")
+	fmt.Fprintf(b, "    DESCRIPTION \"Statistics for one %s\"\n", t.name)
+	fmt.Fprintf(b, "    INDEX       { %s }\n", index)
+	fmt.Fprintf(b, "    ::= { %s 1 }\n\n", table)
+
+	fmt.Fprintf(b, "%s ::= SEQUENCE {\n", entryType)
+	for _, obj := range t.columns {
+		fmt.Fprintf(b, "    %s %s,\n", smiColumnName(obj.Name), smiSequenceSyntax(obj))
+	}
+	fmt.Fprintf(b, "    %s Integer32\n}\n\n", index)
+
+	for _, obj := range t.columns {
+		access := "read-only"
+		if t.writable != nil && t.writable(obj.ID) {
+			access = "read-write"
+		}
+		writeSMIObjectType(b, smiColumnName(obj.Name), smiSyntax(obj), access, obj.Description, entry, obj.ID)
+	}
+	writeSMIObjectType(b, index, "Integer32 (1..2147483647)", "not-accessible", "Index of the "+t.name, entry, smiIndexColumnID)
+}
+
+func writeSMIObjectType(b *strings.Builder, name, syntax, access, description, parent string, id int) {
+	fmt.Fprintf(b, "%s OBJECT-TYPE\n", name)
+	fmt.Fprintf(b, "    SYNTAX      %s\n", syntax)
+	fmt.Fprintf(b, "    MAX-ACCESS  %s\n", access)
+	fmt.Fprintf(b, "    STATUS      current\n")
+	fmt.Fprintf(b, "    DESCRIPTION %s\n", smiQuote(description))
+	fmt.Fprintf(b, "    ::= { %s %d }\n\n", parent, id)
+}
+
+func writeSMIGroup(b *strings.Builder, name, macro, clause string, members []string, description string, id int) {
+	fmt.Fprintf(b, "%s %s\n", name, macro)
+	fmt.Fprintf(b, "    %s {\n        %s\n    }\n", clause, strings.Join(members, ",\n        "))
+	fmt.Fprintf(b, "    STATUS      current\n")
+	fmt.Fprintf(b, "    DESCRIPTION %s\n", smiQuote(description))
+	fmt.Fprintf(b, "    ::= { icmGroups %d }\n\n", id)
+}
+
+// smiModuleOID returns the OID value of the module identity, relative to
+// enterprises when the base is under it
+func smiModuleOID(base string) (string, bool) {
+	const enterprisesOID = ".1.3.6.1.4.1."
+	if strings.HasPrefix(base, enterprisesOID) {
+		return "{ enterprises " + strings.ReplaceAll(strings.TrimPrefix(base, enterprisesOID), ".", " ") + " }", true
+	}
+	return "{ iso " + strings.ReplaceAll(strings.TrimPrefix(base, ".1."), ".", " ") + " }", false
+}
+
+// smiImports lists the SNMPv2-SMI names the module uses
+func smiImports(usesEnterprises bool) []string {
+	imports := []string{"MODULE-IDENTITY", "OBJECT-TYPE", "NOTIFICATION-TYPE", "Integer32"}
+	if usesEnterprises {
+		imports = append(imports, "enterprises")
+	}
+	seen := make(map[string]bool)
+	for _, objects := range [][]mibObject{mibScalars, mibSiteColumns, mibCategoryColumns} {
+		for _, obj := range objects {
+			switch syntax := smiSequenceSyntax(obj); syntax {
+			case "Counter32", "Counter64", "Gauge32", "TimeTicks":
+				if !seen[syntax] {
+					seen[syntax] = true
+					imports = append(imports, syntax)
+				}
+			}
+		}
+	}
+	return imports
+}
+
+// smiSyntax is the SYNTAX clause of an object
+func smiSyntax(obj mibObject) string {
+	if enum, ok := smiEnumerations[obj.Name]; ok {
+		return enum
+	}
+	return smiSequenceSyntax(obj)
+}
+
+// smiSequenceSyntax is the syntax of an object without named values, as
+// written in a SEQUENCE
+func smiSequenceSyntax(obj mibObject) string {
+	switch obj.Type {
+	case gosnmp.Counter32:
+		return "Counter32"
+	case gosnmp.Counter64:
+		return "Counter64"
+	case gosnmp.Gauge32:
+		return "Gauge32"
+	case gosnmp.TimeTicks:
+		return "TimeTicks"
+	case gosnmp.OctetString:
+		return "DisplayString"
+	}
+	if _, ok := smiEnumerations[obj.Name]; ok {
+		return "INTEGER"
+	}
+	return "Integer32"
+}
+
+// smiColumnName is a table column's descriptor. Descriptors share one
+// namespace, so site columns are prefixed with "site" to keep them apart from
+// scalars of the same name (testsLastMinute); category columns are already
+// prefixed.
+func smiColumnName(name string) string {
+	if strings.HasPrefix(name, "site") || strings.HasPrefix(name, "category") {
+		return name
+	}
+	return "site" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// smiQuote quotes a DESCRIPTION; SMI strings cannot contain double quotes
+func smiQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+func sortedMIBObjects(objects []mibObject) []mibObject {
+	sorted := append([]mibObject(nil), objects...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
@@ -0,0 +1,203 @@
+package outputs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+var (
+	smiDefinitionRE = regexp.MustCompile(`(?m)^(\w+) (MODULE-IDENTITY|OBJECT-TYPE|NOTIFICATION-TYPE|OBJECT IDENTIFIER|OBJECT-GROUP|NOTIFICATION-GROUP|MODULE-COMPLIANCE)\b`)
+	smiClauseRE     = regexp.MustCompile(`(?m)^    (SYNTAX|MAX-ACCESS|INDEX|OBJECTS) +(.+)$`)
+	smiAssignmentRE = regexp.MustCompile(`::= \{ ([\w ]+) \}`)
+)
+
+// smiDefinition is one macro invocation of a generated module
+type smiDefinition struct {
+	macro   string
+	clauses map[string]string
+	oid     string
+}
+
+// parseSMIModule resolves every definition in module to its OID
+func parseSMIModule(t *testing.T, module string) map[string]*smiDefinition {
+	t.Helper()
+
+	defs := make(map[string]*smiDefinition)
+	matches := smiDefinitionRE.FindAllStringSubmatchIndex(module, -1)
+	parents := make(map[string][]string)
+	for i, m := range matches {
+		name, macro := module[m[2]:m[3]], module[m[4]:m[5]]
+		end := len(module)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		body := module[m[0]:end]
+
+		if _, dup := defs[name]; dup {
+			t.Fatalf("descriptor %s is defined twice", name)
+		}
+		def := &smiDefinition{macro: macro, clauses: make(map[string]string)}
+		for _, clause := range smiClauseRE.FindAllStringSubmatch(body, -1) {
+			def.clauses[clause[1]] = clause[2]
+		}
+		assignment := smiAssignmentRE.FindStringSubmatch(body)
+		if assignment == nil {
+			t.Fatalf("%s has no OID assignment", name)
+		}
+		parents[name] = strings.Fields(assignment[1])
+		defs[name] = def
+	}
+
+	var resolve func(name string) string
+	resolve = func(name string) string {
+		if name == "enterprises" {
+			return ".1.3.6.1.4.1"
+		}
+		def, ok := defs[name]
+		if !ok {
+			t.Fatalf("OID assignment refers to undefined %s", name)
+		}
+		if def.oid == "" {
+			def.oid = resolve(parents[name][0]) + "." + strings.Join(parents[name][1:], ".")
+		}
+		return def.oid
+	}
+	for name := range defs {
+		resolve(name)
+	}
+	return defs
+}
+
+func TestExportSMIv2DescribesServedOIDs(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", []models.SiteDefinition{{Name: "a.example", URL: "https://a.example"}, {Name: "b.example", URL: "https://b.example"}})
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+	for _, site := range []string{"a.example", "b.example"} {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: site, URL: "https://" + site, Category: "search"},
+			Status:    models.StatusInfo{Success: true},
+		})
+	}
+
+	module := snmpOutput.ExportSMIv2()
+	if !strings.HasPrefix(module, "INTERNET-CONNECTION-MONITOR-MIB DEFINITIONS ::= BEGIN\n") || !strings.HasSuffix(module, "\nEND\n") {
+		t.Fatalf("expected a complete module, got:\n%s", module)
+	}
+	if strings.Count(module, "{") != strings.Count(module, "}") {
+		t.Fatalf("unbalanced braces in module")
+	}
+	defs := parseSMIModule(t, module)
+
+	if got := defs["internetConnectionMonitorMIB"].oid; got != cfg.EnterpriseOID {
+		t.Fatalf("expected the module identity at %s, got %s", cfg.EnterpriseOID, got)
+	}
+
+	// Scalars carry their type and access
+	for _, obj := range mibScalars {
+		def, ok := defs[obj.Name]
+		if !ok || def.macro != "OBJECT-TYPE" {
+			t.Errorf("scalar %s has no OBJECT-TYPE", obj.Name)
+			continue
+		}
+		if def.clauses["SYNTAX"] != smiSyntax(obj) {
+			t.Errorf("scalar %s has SYNTAX %q", obj.Name, def.clauses["SYNTAX"])
+		}
+		wantAccess := "read-only"
+		if obj.ID == resetStatsScalarID || obj.ID == alertAckScalarID {
+			wantAccess = "read-write"
+		}
+		if def.clauses["MAX-ACCESS"] != wantAccess {
+			t.Errorf("scalar %s has MAX-ACCESS %q, want %s", obj.Name, def.clauses["MAX-ACCESS"], wantAccess)
+		}
+	}
+	if got := defs["siteCircuitBreakerState"].clauses["SYNTAX"]; got != "INTEGER { closed(1), open(2) }" {
+		t.Errorf("expected circuitBreakerState to be enumerated, got %q", got)
+	}
+	if got := defs["siteTotalTests64"].clauses["SYNTAX"]; got != "Counter64" {
+		t.Errorf("expected totalTests64 to be Counter64, got %q", got)
+	}
+	if got := defs["siteReset"].clauses["MAX-ACCESS"]; got != "read-write" {
+		t.Errorf("expected siteReset to be read-write, got %q", got)
+	}
+
+	// The table is indexed by a not-accessible column listed in the SEQUENCE
+	entry := defs["siteEntry"]
+	if entry == nil || entry.clauses["INDEX"] != "{ siteIndex }" {
+		t.Fatalf("expected siteEntry with INDEX { siteIndex }, got %+v", entry)
+	}
+	if got := defs["siteIndex"].clauses["MAX-ACCESS"]; got != "not-accessible" {
+		t.Errorf("expected siteIndex to be not-accessible, got %q", got)
+	}
+	if !strings.Contains(module, "    siteIndex Integer32\n}") {
+		t.Errorf("expected siteIndex to close the SiteEntry SEQUENCE")
+	}
+	if entry := defs["categoryEntry"]; entry == nil || entry.clauses["INDEX"] != "{ categoryIndex }" {
+		t.Errorf("expected categoryEntry with INDEX { categoryIndex }, got %+v", entry)
+	}
+	for _, obj := range mibSiteColumns {
+		if !strings.Contains(module, fmt.Sprintf("\n    %s %s,\n", smiColumnName(obj.Name), smiSequenceSyntax(obj))) {
+			t.Errorf("column %s is missing from the SiteEntry SEQUENCE", obj.Name)
+		}
+	}
+
+	// Every served OID outside the legacy site rows is an instance of an
+	// accessible object in the module
+	objects := make(map[string]string)
+	for name, def := range defs {
+		if def.macro == "OBJECT-TYPE" && def.clauses["MAX-ACCESS"] != "not-accessible" {
+			objects[def.oid] = name
+		}
+	}
+	snapshot := snmpOutput.Snapshot()
+	legacyRows := fmt.Sprintf("%s.%d.", snapshot.Base, siteTableID)
+	served := 0
+	for _, oid := range snapshot.OIDs {
+		if !strings.HasPrefix(oid, snapshot.Base+".") || strings.HasPrefix(oid, legacyRows) {
+			continue
+		}
+		served++
+		instance := oid[strings.LastIndex(oid, "."):]
+		if _, ok := objects[strings.TrimSuffix(oid, instance)]; !ok {
+			t.Errorf("served OID %s is not an instance of an object in the module", oid)
+		}
+	}
+	// The scalars, two siteTable rows and one categoryTable row
+	if want := len(mibScalars) + 2*len(mibSiteColumns) + len(mibCategoryColumns); served != want {
+		t.Errorf("expected %d served instances, got %d", want, served)
+	}
+
+	// Notifications name their varbinds
+	for _, n := range mibNotifications {
+		def, ok := defs[n.Name]
+		if !ok || def.macro != "NOTIFICATION-TYPE" {
+			t.Errorf("notification %s has no NOTIFICATION-TYPE", n.Name)
+			continue
+		}
+		if want := fmt.Sprintf("%s.%d.%d", snapshot.Base, notificationsID, n.ID); def.oid != want {
+			t.Errorf("notification %s is at %s, want %s", n.Name, def.oid, want)
+		}
+		want := "{ siteName, lastTrapMessage }"
+		if n.Name == "monitorAlert" {
+			want = "{ lastTrapMessage }"
+		}
+		if def.clauses["OBJECTS"] != want {
+			t.Errorf("notification %s has OBJECTS %q, want %s", n.Name, def.clauses["OBJECTS"], want)
+		}
+	}
+}
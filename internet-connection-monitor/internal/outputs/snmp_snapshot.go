@@ -0,0 +1,65 @@
+package outputs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Snapshot cache
+//
+// Building the OID snapshot walks every site and sorts every OID, which is too
+// much to repeat for each packet of a GetNext/GetBulk walk. Requests share the
+// last snapshot until something it was built from changes: writes, SETs,
+// reloads and traps bump a generation counter, and a snapshot built under an
+// older generation is rebuilt. Uptime, testsLastMinute and SiteTTL expiry
+// change with the clock alone, so a snapshot is also rebuilt once it is
+// snapshotMaxAge old.
+
+const snapshotMaxAge = time.Second
+
+// oidSnapshot is one built snapshot; it is shared by concurrent requests and
+// must not be modified
+type oidSnapshot struct {
+	oids       []string
+	values     map[string]gosnmp.SnmpPDU
+	generation uint64
+	built      time.Time
+}
+
+// snapshotCache holds the last snapshot answer() served
+type snapshotCache struct {
+	generation atomic.Uint64
+
+	mu   sync.Mutex
+	last *oidSnapshot
+}
+
+// invalidateSnapshot makes the next request rebuild the snapshot
+func (s *SNMPOutput) invalidateSnapshot() {
+	s.snapshots.generation.Add(1)
+}
+
+// cachedOIDSnapshot returns the last snapshot while it is current, building a
+// new one otherwise. Concurrent callers wait for a single build.
+func (s *SNMPOutput) cachedOIDSnapshot() ([]string, map[string]gosnmp.SnmpPDU) {
+	c := &s.snapshots
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	generation := c.generation.Load()
+	now := s.now()
+	if last := c.last; last != nil && last.generation == generation {
+		if age := now.Sub(last.built); age >= 0 && age < snapshotMaxAge {
+			return last.oids, last.values
+		}
+	}
+
+	// Read the generation before building so a write that races the build
+	// leaves the result stale rather than hiding the write
+	oids, values := s.buildOIDSnapshot()
+	c.last = &oidSnapshot{oids: oids, values: values, generation: generation, built: now}
+	return oids, values
+}
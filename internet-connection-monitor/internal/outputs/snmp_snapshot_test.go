@@ -0,0 +1,152 @@
+package outputs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSNMPSnapshotReusedUntilWrite(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	clock := newTestClock(time.Unix(1_700_000_000, 0))
+	snmpOutput, err := newSNMPOutput(cfg, "test", nil, clock.now)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func() {
+		if err := snmpOutput.Write(&models.TestResult{
+			Timestamp: clock.now(),
+			Site:      models.SiteInfo{Name: "a.example"},
+			Status:    models.StatusInfo{Success: true},
+		}); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	base := cfg.EnterpriseOID
+	// get returns the site's total tests and the snapshot that answered
+	get := func() (uint32, *oidSnapshot) {
+		t.Helper()
+		packet, err := client.Get([]string{base + ".5.1.2"})
+		if err != nil {
+			t.Fatalf("snmp get failed: %v", err)
+		}
+		snmpOutput.snapshots.mu.Lock()
+		defer snmpOutput.snapshots.mu.Unlock()
+		return pduValueAsUint32(t, packet.Variables[0]), snmpOutput.snapshots.last
+	}
+
+	write()
+	total, first := get()
+	if total != 1 {
+		t.Fatalf("expected 1 test, got %d", total)
+	}
+	for i := 0; i < 3; i++ {
+		if _, snapshot := get(); snapshot != first {
+			t.Fatalf("get %d rebuilt the snapshot without a write in between", i+2)
+		}
+	}
+
+	write()
+	total, second := get()
+	if second == first {
+		t.Fatal("expected a write to invalidate the snapshot")
+	}
+	if total != 2 {
+		t.Errorf("expected 2 tests after the write, got %d", total)
+	}
+
+	// Clock-driven objects such as uptime are refreshed once the snapshot ages out
+	clock.advance(snapshotMaxAge)
+	if _, third := get(); third == second {
+		t.Error("expected a snapshot older than snapshotMaxAge to be rebuilt")
+	}
+}
+
+// BenchmarkSNMPGetNextWalk measures answering the GetNexts of a walk over
+// many sites, with the cached snapshot and with a rebuild for every request
+// as if each were preceded by a write.
+func BenchmarkSNMPGetNextWalk(b *testing.B) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		b.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	for i := 0; i < 200; i++ {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: fmt.Sprintf("site-%d", i)},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: int64(i)},
+		})
+	}
+
+	// getNext returns the next OID after oid, or "" at the end of the MIB
+	getNext := func(oid string) string {
+		request := &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version2c,
+			PDUType:   gosnmp.GetNextRequest,
+			Variables: []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Null}},
+		}
+		response := &gosnmp.SnmpPacket{}
+		snmpOutput.answer(nil, request, response, nil)
+		if response.Variables[0].Type == gosnmp.EndOfMibView {
+			return ""
+		}
+		return response.Variables[0].Name
+	}
+
+	for _, bc := range []struct {
+		name       string
+		invalidate bool
+	}{
+		{"cached", false},
+		{"rebuilt", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			oid := cfg.EnterpriseOID + ".5"
+			for i := 0; i < b.N; i++ {
+				if bc.invalidate {
+					snmpOutput.invalidateSnapshot()
+				}
+				if oid = getNext(oid); oid == "" {
+					oid = cfg.EnterpriseOID + ".5"
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package outputs
+
+import "time"
+
+const (
+	// successWindowBuckets is the number of buckets a successWindow divides
+	// its span into
+	successWindowBuckets = 60
+
+	// defaultSNMPSuccessWindow is used when SNMPConfig.SuccessWindow is not set
+	defaultSNMPSuccessWindow = 15 * time.Minute
+)
+
+// successWindow counts successful and failed tests over a sliding window of
+// result timestamps, in successWindowBuckets buckets of width window/buckets.
+// The window ends at the newest timestamp recorded, so it advances as results
+// arrive; a result older than the window is ignored.
+// It is not safe for concurrent use; callers guard it with their own lock.
+type successWindow struct {
+	successes [successWindowBuckets]uint32
+	failures  [successWindowBuckets]uint32
+	// buckets holds the bucket number (timestamp / width) each slot was last
+	// used for; a slot whose bucket has left the window counts as empty
+	buckets [successWindowBuckets]int64
+	newest  int64
+}
+
+// add records one test at ts
+func (w *successWindow) add(ts time.Time, success bool, width time.Duration) {
+	b := ts.UnixNano() / int64(width)
+	if b > w.newest {
+		w.newest = b
+	}
+	if w.newest-b >= successWindowBuckets {
+		return
+	}
+
+	i := int(b % successWindowBuckets)
+	if i < 0 {
+		i += successWindowBuckets
+	}
+	if w.buckets[i] != b {
+		w.buckets[i] = b
+		w.successes[i] = 0
+		w.failures[i] = 0
+	}
+	if success {
+		w.successes[i]++
+	} else {
+		w.failures[i]++
+	}
+}
+
+// percent returns the percentage of tests in the window that succeeded, 0
+// before the first test
+func (w *successWindow) percent() float64 {
+	var successes, total int64
+	for i, b := range w.buckets {
+		if age := w.newest - b; age >= 0 && age < successWindowBuckets {
+			successes += int64(w.successes[i])
+			total += int64(w.successes[i]) + int64(w.failures[i])
+		}
+	}
+	return uptimePercent(successes, total)
+}
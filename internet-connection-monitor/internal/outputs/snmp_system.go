@@ -0,0 +1,57 @@
+package outputs
+
+import (
+	"os"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// systemGroupOID is the standard MIB-II system group (SNMPv2-MIB::system).
+// Discovery tools read it first to identify a device, so without it the agent
+// shows up as dead or unknown even though the enterprise tree is healthy.
+const systemGroupOID = ".1.3.6.1.2.1.1"
+
+// systemGroup holds the identity values served under systemGroupOID
+type systemGroup struct {
+	descr    string
+	objectID string
+	contact  string
+	name     string
+	location string
+}
+
+// newSystemGroup resolves the system group from configuration, defaulting
+// sysDescr to the software version and sysName to the hostname
+func newSystemGroup(descr, contact, name, location, baseOID, version string) systemGroup {
+	if descr == "" {
+		descr = "Internet Connection Monitor " + version
+	}
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	return systemGroup{
+		descr:    descr,
+		objectID: baseOID,
+		contact:  contact,
+		name:     name,
+		location: location,
+	}
+}
+
+// sysUpTimePDU returns sysUpTime.0, which is in hundredths of a second
+func sysUpTimePDU(uptime time.Duration) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: systemGroupOID + ".3.0", Type: gosnmp.TimeTicks, Value: uint32(uptime / (10 * time.Millisecond))}
+}
+
+// addTo adds sysDescr.0 through sysLocation.0 to an OID snapshot
+func (g systemGroup) addTo(values map[string]gosnmp.SnmpPDU, uptime time.Duration) {
+	add := func(pdu gosnmp.SnmpPDU) { values[pdu.Name] = pdu }
+
+	add(octetStringPDU(systemGroupOID+".1.0", g.descr))
+	add(gosnmp.SnmpPDU{Name: systemGroupOID + ".2.0", Type: gosnmp.ObjectIdentifier, Value: g.objectID})
+	add(sysUpTimePDU(uptime))
+	add(octetStringPDU(systemGroupOID+".4.0", g.contact))
+	add(octetStringPDU(systemGroupOID+".5.0", g.name))
+	add(octetStringPDU(systemGroupOID+".6.0", g.location))
+}
@@ -0,0 +1,149 @@
+package outputs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// SNMP over TCP (RFC 3430)
+//
+// With transport tcp the agent accepts connections and answers each message
+// on the connection it arrived on, in order. Messages need no extra framing:
+// each is a BER SEQUENCE whose header gives its length. Requests are handled
+// exactly as over UDP (handleRequest), including the allowlist, which is also
+// checked when a connection is accepted.
+
+const (
+	// tcpIdleTimeout closes connections that send nothing for this long
+	tcpIdleTimeout = 2 * time.Minute
+
+	// maxSNMPMessageSize bounds a message read from a connection, the
+	// largest a UDP datagram could carry
+	maxSNMPMessageSize = 65535
+)
+
+// serveTCP accepts connections on listener until the agent is closed
+func (s *SNMPOutput) serveTCP(listener *net.TCPListener) {
+	for {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("SNMP agent accept error: %v", err)
+				continue
+			}
+		}
+
+		if !s.acl.allows(remoteIP(conn.RemoteAddr())) {
+			s.acl.logRejected(conn.RemoteAddr(), s.now())
+			conn.Close()
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn answers the messages on one connection until the peer closes
+// it, it idles out, or the agent is closed
+func (s *SNMPOutput) serveTCPConn(conn *net.TCPConn) {
+	defer s.wg.Done()
+
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-s.done:
+		case <-closed:
+		}
+		conn.Close()
+	}()
+
+	remote := conn.RemoteAddr()
+	reader := bufio.NewReader(conn)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout)); err != nil {
+			return
+		}
+		packet, err := readSNMPMessage(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+					log.Printf("SNMP read error from %s: %v", remote, err)
+				}
+			}
+			return
+		}
+
+		response := s.handleRequest(remote, packet)
+		if response == nil {
+			continue
+		}
+		respBytes, err := response.MarshalMsg()
+		if err != nil {
+			log.Printf("SNMP marshal error to %s: %v", remote, err)
+			continue
+		}
+		if _, err := conn.Write(respBytes); err != nil {
+			log.Printf("SNMP write error to %s: %v", remote, err)
+			return
+		}
+	}
+}
+
+// readSNMPMessage reads one BER-encoded message (a SEQUENCE) from r
+func readSNMPMessage(r *bufio.Reader) ([]byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag != 0x30 {
+		return nil, fmt.Errorf("message does not start with a SEQUENCE (tag 0x%02x)", tag)
+	}
+
+	header := []byte{tag}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	header = append(header, first)
+
+	length := int(first)
+	if first&0x80 != 0 {
+		// Long form: the low bits count the length bytes that follow
+		n := int(first &^ 0x80)
+		if n == 0 || n > 3 {
+			return nil, fmt.Errorf("unsupported message length encoding 0x%02x", first)
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			header = append(header, b)
+			length = length<<8 | int(b)
+		}
+	}
+	if len(header)+length > maxSNMPMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds %d", len(header)+length, maxSNMPMessageSize)
+	}
+
+	msg := make([]byte, len(header)+length)
+	copy(msg, header)
+	if _, err := io.ReadFull(r, msg[len(header):]); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,81 @@
+package outputs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSNMPOverTCP(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		Transport:     "tcp",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	snmpOutput.Write(&models.TestResult{
+		Timestamp: time.Now(),
+		Site:      models.SiteInfo{Name: "example.com"},
+		Status:    models.StatusInfo{Success: true},
+	})
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Transport: "tcp",
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client over TCP: %v", err)
+	}
+	defer client.Conn.Close()
+
+	base := cfg.EnterpriseOID
+	packet, err := client.Get([]string{base + ".1.0", base + ".5.1.1"})
+	if err != nil {
+		t.Fatalf("snmp get over TCP failed: %v", err)
+	}
+	if got := pduValueAsUint32(t, packet.Variables[0]); got != 1 {
+		t.Errorf("expected cacheSize 1, got %d", got)
+	}
+	if got, _ := packet.Variables[1].Value.([]byte); string(got) != "example.com" {
+		t.Errorf("expected siteName example.com, got %q", got)
+	}
+
+	want := 0
+	for _, oid := range snmpOutput.Snapshot().OIDs {
+		if strings.HasPrefix(oid, base+".") {
+			want++
+		}
+	}
+
+	// A walk sends many messages over the same connection
+	for name, walk := range map[string]func(string) ([]gosnmp.SnmpPDU, error){
+		"GetNext": client.WalkAll,
+		"GetBulk": client.BulkWalkAll,
+	} {
+		pdus, err := walk(base)
+		if err != nil {
+			t.Fatalf("%s walk over TCP failed: %v", name, err)
+		}
+		if len(pdus) != want {
+			t.Errorf("%s walk over TCP returned %d objects, want %d", name, len(pdus), want)
+		}
+	}
+}
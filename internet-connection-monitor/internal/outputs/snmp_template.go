@@ -0,0 +1,212 @@
+package outputs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Monitoring-system templates generated from the MIB tables in snmp_mib.go,
+// so external pollers stay in sync with the OIDs the agent actually serves.
+//
+// Site rows are laid out as <base>.5.<siteIndex>.<column>, i.e. the index comes
+// before the column. Neither Zabbix nor Telegraf assume that layout by default,
+// so both templates walk the whole site table and strip the column suffix.
+
+const (
+	zabbixExportVersion = "6.0"
+	zabbixTemplateGroup = "Templates/Network devices"
+	zabbixTemplateName  = "Internet Connection Monitor SNMP"
+	zabbixKeyPrefix     = "icm"
+)
+
+type zabbixExport struct {
+	XMLName   xml.Name         `xml:"zabbix_export"`
+	Version   string           `xml:"version"`
+	Groups    []zabbixGroup    `xml:"groups>group"`
+	Templates []zabbixTemplate `xml:"templates>template"`
+}
+
+type zabbixGroup struct {
+	UUID string `xml:"uuid,omitempty"`
+	Name string `xml:"name"`
+}
+
+type zabbixTemplate struct {
+	UUID           string                `xml:"uuid"`
+	Template       string                `xml:"template"`
+	Name           string                `xml:"name"`
+	Description    string                `xml:"description"`
+	Groups         []zabbixGroup         `xml:"groups>group"`
+	Items          []zabbixItem          `xml:"items>item"`
+	DiscoveryRules []zabbixDiscoveryRule `xml:"discovery_rules>discovery_rule"`
+}
+
+type zabbixItem struct {
+	UUID        string `xml:"uuid"`
+	Name        string `xml:"name"`
+	Type        string `xml:"type"`
+	SNMPOID     string `xml:"snmp_oid"`
+	Key         string `xml:"key"`
+	Delay       string `xml:"delay"`
+	ValueType   string `xml:"value_type"`
+	Description string `xml:"description"`
+}
+
+type zabbixDiscoveryRule struct {
+	UUID           string                `xml:"uuid"`
+	Name           string                `xml:"name"`
+	Type           string                `xml:"type"`
+	SNMPOID        string                `xml:"snmp_oid"`
+	Key            string                `xml:"key"`
+	Delay          string                `xml:"delay"`
+	ItemPrototypes []zabbixItem          `xml:"item_prototypes>item_prototype"`
+	Preprocessing  []zabbixPreprocessing `xml:"preprocessing>step"`
+}
+
+type zabbixPreprocessing struct {
+	Type       string   `xml:"type"`
+	Parameters []string `xml:"parameters>parameter"`
+}
+
+// zabbixSiteDiscoveryJS turns the raw walk of the site table into one LLD row
+// per site, keeping only the siteName column and stripping it from the index
+const zabbixSiteDiscoveryJS = `var rows = JSON.parse(value);
+if (rows.data) {
+    rows = rows.data;
+}
+var suffix = '.%d';
+var sites = [];
+for (var i = 0; i < rows.length; i++) {
+    var index = rows[i]['{#SNMPINDEX}'];
+    if (index.length > suffix.length && index.slice(-suffix.length) === suffix) {
+        sites.push({'{#SNMPINDEX}': index.slice(0, -suffix.length), '{#SITENAME}': rows[i]['{#SITENAME}']});
+    }
+}
+return JSON.stringify(sites);`
+
+// ExportZabbixTemplate returns a Zabbix SNMP template (XML export format) with
+// an item for every scalar and a site discovery rule with an item prototype
+// for every site column, rooted at enterpriseOID
+func ExportZabbixTemplate(enterpriseOID string) (string, error) {
+	base := enterpriseBaseOID(enterpriseOID)
+	siteTable := fmt.Sprintf("%s.%d", base, siteTableID)
+
+	tmpl := zabbixTemplate{
+		UUID:        zabbixUUID("template"),
+		Template:    zabbixTemplateName,
+		Name:        zabbixTemplateName,
+		Description: fmt.Sprintf("Generated from the internet-connection-monitor MIB rooted at %s", base),
+		Groups:      []zabbixGroup{{Name: zabbixTemplateGroup}},
+	}
+
+	for _, obj := range mibScalars {
+		key := fmt.Sprintf("%s.%s", zabbixKeyPrefix, obj.Name)
+		tmpl.Items = append(tmpl.Items, zabbixItem{
+			UUID:        zabbixUUID(key),
+			Name:        obj.Description,
+			Type:        "SNMP_AGENT",
+			SNMPOID:     fmt.Sprintf("%s.%d.0", base, obj.ID),
+			Key:         key,
+			Delay:       "1m",
+			ValueType:   zabbixValueType(obj.Type),
+			Description: obj.Name,
+		})
+	}
+
+	discoveryKey := fmt.Sprintf("%s.site.discovery", zabbixKeyPrefix)
+	rule := zabbixDiscoveryRule{
+		UUID:    zabbixUUID(discoveryKey),
+		Name:    "Site discovery",
+		Type:    "SNMP_AGENT",
+		SNMPOID: fmt.Sprintf("discovery[{#SITENAME},%s]", siteTable),
+		Key:     discoveryKey,
+		Delay:   "1h",
+		Preprocessing: []zabbixPreprocessing{{
+			Type:       "JAVASCRIPT",
+			Parameters: []string{fmt.Sprintf(zabbixSiteDiscoveryJS, mibSiteColumns[0].ID)},
+		}},
+	}
+
+	for _, obj := range mibSiteColumns {
+		key := fmt.Sprintf("%s.site.%s[{#SNMPINDEX}]", zabbixKeyPrefix, obj.Name)
+		rule.ItemPrototypes = append(rule.ItemPrototypes, zabbixItem{
+			UUID:        zabbixUUID(key),
+			Name:        fmt.Sprintf("{#SITENAME}: %s", obj.Description),
+			Type:        "SNMP_AGENT",
+			SNMPOID:     fmt.Sprintf("%s.{#SNMPINDEX}.%d", siteTable, obj.ID),
+			Key:         key,
+			Delay:       "1m",
+			ValueType:   zabbixValueType(obj.Type),
+			Description: obj.Name,
+		})
+	}
+	tmpl.DiscoveryRules = []zabbixDiscoveryRule{rule}
+
+	export := zabbixExport{
+		Version:   zabbixExportVersion,
+		Groups:    []zabbixGroup{{UUID: zabbixUUID("group"), Name: zabbixTemplateGroup}},
+		Templates: []zabbixTemplate{tmpl},
+	}
+
+	data, err := xml.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal Zabbix template: %w", err)
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+// ExportTelegrafConfig returns a Telegraf inputs.snmp configuration polling
+// every scalar and site column rooted at enterpriseOID from agent
+// (e.g. "udp://127.0.0.1:161")
+func ExportTelegrafConfig(enterpriseOID, agent, community string) string {
+	base := enterpriseBaseOID(enterpriseOID)
+	siteTable := fmt.Sprintf("%s.%d", base, siteTableID)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated from the internet-connection-monitor MIB rooted at %s\n", base)
+	b.WriteString("[[inputs.snmp]]\n")
+	fmt.Fprintf(&b, "  agents = [%q]\n", agent)
+	b.WriteString("  version = 2\n")
+	fmt.Fprintf(&b, "  community = %q\n", community)
+	b.WriteString("  name = \"internet_monitor\"\n")
+
+	for _, obj := range mibScalars {
+		b.WriteString("\n  # " + obj.Description + "\n")
+		b.WriteString("  [[inputs.snmp.field]]\n")
+		fmt.Fprintf(&b, "    name = %q\n", obj.Name)
+		fmt.Fprintf(&b, "    oid = \"%s.%d.0\"\n", base, obj.ID)
+	}
+
+	b.WriteString("\n  [[inputs.snmp.table]]\n")
+	b.WriteString("    name = \"internet_monitor_sites\"\n")
+	for i, obj := range mibSiteColumns {
+		b.WriteString("\n    # " + obj.Description + "\n")
+		b.WriteString("    [[inputs.snmp.table.field]]\n")
+		fmt.Fprintf(&b, "      name = %q\n", obj.Name)
+		fmt.Fprintf(&b, "      oid = %q\n", siteTable)
+		fmt.Fprintf(&b, "      oid_index_suffix = \".%d\"\n", obj.ID)
+		if i == 0 {
+			b.WriteString("      is_tag = true\n")
+		}
+	}
+
+	return b.String()
+}
+
+func zabbixValueType(t gosnmp.Asn1BER) string {
+	if t == gosnmp.OctetString {
+		return "CHAR"
+	}
+	return "UNSIGNED"
+}
+
+// zabbixUUID derives a stable UUID so re-imports update rather than duplicate
+func zabbixUUID(key string) string {
+	sum := md5.Sum([]byte(zabbixTemplateName + "/" + key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,83 @@
+package outputs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExportZabbixTemplateCoversMIB(t *testing.T) {
+	base := ".1.3.6.1.4.1.55555"
+
+	out, err := ExportZabbixTemplate(base)
+	if err != nil {
+		t.Fatalf("ExportZabbixTemplate failed: %v", err)
+	}
+
+	var export zabbixExport
+	if err := xml.Unmarshal([]byte(out), &export); err != nil {
+		t.Fatalf("template is not valid XML: %v", err)
+	}
+	if len(export.Templates) != 1 {
+		t.Fatalf("expected 1 template, got %d", len(export.Templates))
+	}
+	tmpl := export.Templates[0]
+
+	itemOIDs := make(map[string]string)
+	for _, item := range tmpl.Items {
+		itemOIDs[item.SNMPOID] = item.ValueType
+	}
+	for _, obj := range mibScalars {
+		oid := fmt.Sprintf("%s.%d.0", base, obj.ID)
+		if _, ok := itemOIDs[oid]; !ok {
+			t.Errorf("scalar %s (%s) has no item", obj.Name, oid)
+		}
+	}
+
+	if len(tmpl.DiscoveryRules) != 1 {
+		t.Fatalf("expected 1 discovery rule, got %d", len(tmpl.DiscoveryRules))
+	}
+	rule := tmpl.DiscoveryRules[0]
+	if rule.SNMPOID != "discovery[{#SITENAME},"+base+".5]" {
+		t.Errorf("unexpected discovery OID %q", rule.SNMPOID)
+	}
+
+	prototypes := make(map[string]string)
+	for _, proto := range rule.ItemPrototypes {
+		prototypes[proto.SNMPOID] = proto.ValueType
+	}
+	for _, obj := range mibSiteColumns {
+		oid := fmt.Sprintf("%s.5.{#SNMPINDEX}.%d", base, obj.ID)
+		valueType, ok := prototypes[oid]
+		if !ok {
+			t.Errorf("site column %s (%s) has no item prototype", obj.Name, oid)
+			continue
+		}
+		if valueType != zabbixValueType(obj.Type) {
+			t.Errorf("site column %s has value type %s", obj.Name, valueType)
+		}
+	}
+
+	// UUIDs must be stable across exports so re-imports update in place
+	again, _ := ExportZabbixTemplate(base)
+	if again != out {
+		t.Error("expected identical output for identical input")
+	}
+}
+
+func TestExportTelegrafConfigCoversMIB(t *testing.T) {
+	out := ExportTelegrafConfig("1.3.6.1.4.1.55555.", "udp://127.0.0.1:161", "public")
+
+	for _, obj := range mibScalars {
+		if !strings.Contains(out, fmt.Sprintf("oid = \".1.3.6.1.4.1.55555.%d.0\"", obj.ID)) {
+			t.Errorf("scalar %s missing from Telegraf config", obj.Name)
+		}
+	}
+	for _, obj := range mibSiteColumns {
+		field := fmt.Sprintf("name = %q\n      oid = \".1.3.6.1.4.1.55555.5\"\n      oid_index_suffix = \".%d\"", obj.Name, obj.ID)
+		if !strings.Contains(out, field) {
+			t.Errorf("site column %s missing from Telegraf config", obj.Name)
+		}
+	}
+}
@@ -1,8 +1,18 @@
 package outputs
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"weak"
 
 	"github.com/gosnmp/gosnmp"
 
@@ -10,6 +20,29 @@ import (
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
 )
 
+// testClock is a clock for newSNMPOutput that a test can move forward while
+// the agent goroutine reads it
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newTestClock(t time.Time) *testClock {
+	return &testClock{t: t}
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
 func TestSNMPAgentRespondsToGetAndWalk(t *testing.T) {
 	cfg := &config.SNMPConfig{
 		Enabled:       true,
@@ -19,7 +52,7 @@ func TestSNMPAgentRespondsToGetAndWalk(t *testing.T) {
 		EnterpriseOID: ".1.3.6.1.4.1.55555",
 	}
 
-	snmpOutput, err := NewSNMPOutput(cfg)
+	snmpOutput, err := NewSNMPOutput(cfg, "9.8.7", nil)
 	if err != nil {
 		t.Fatalf("failed to create SNMP output: %v", err)
 	}
@@ -83,6 +116,15 @@ func TestSNMPAgentRespondsToGetAndWalk(t *testing.T) {
 	}
 	t.Log("verified cache size via SNMP get")
 
+	packet, err = client.Get([]string{baseOID + ".10.0"})
+	if err != nil {
+		t.Fatalf("snmp get for version failed: %v", err)
+	}
+	if v, ok := packet.Variables[0].Value.([]byte); !ok || string(v) != "9.8.7" {
+		t.Fatalf("expected version 9.8.7, got %v", packet.Variables[0].Value)
+	}
+	t.Log("verified version via SNMP get")
+
 	// Ensure walking the tree returns the site name and metrics without error.
 	walked := make([]gosnmp.SnmpPDU, 0)
 	if err := client.Walk(baseOID, func(pdu gosnmp.SnmpPDU) error {
@@ -128,8 +170,8 @@ func TestSNMPAgentRespondsToGetAndWalk(t *testing.T) {
 	}
 	t.Log("verified missing OID response")
 
-	// Walk should eventually end with EndOfMibView via GetNext past the last site metric.
-	packet, err = client.GetNext([]string{baseOID + ".5.1.10"})
+	// Walk should eventually end with EndOfMibView via GetNext past the last object.
+	packet, err = client.GetNext([]string{walked[len(walked)-1].Name})
 	if err != nil {
 		t.Fatalf("snmp getnext failed: %v", err)
 	}
@@ -166,3 +208,2300 @@ func pduValueAsUint32(t *testing.T, pdu gosnmp.SnmpPDU) uint32 {
 	}
 	return 0
 }
+
+func TestVerifyMIBTree(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	for _, name := range []string{"alpha", "beta"} {
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: name},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: 100},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	snapshot := snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("expected live snapshot to verify, got: %v", err)
+	}
+
+	// Drop a site column and retype a scalar; both problems should be reported.
+	broken := MIBSnapshot{Base: snapshot.Base, Values: make(map[string]gosnmp.SnmpPDU)}
+	for _, oid := range snapshot.OIDs {
+		if oid == snapshot.Base+".5.2.7" {
+			continue
+		}
+		pdu := snapshot.Values[oid]
+		if oid == snapshot.Base+".1.0" {
+			pdu.Type = gosnmp.Integer
+		}
+		broken.OIDs = append(broken.OIDs, oid)
+		broken.Values[oid] = pdu
+	}
+
+	err = VerifyMIBTree(broken)
+	if err == nil {
+		t.Fatalf("expected broken snapshot to fail verification")
+	}
+	for _, want := range []string{"site 2 is missing column lastDurationMs", "scalar cacheSize"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestSNMPSiteHistoryIsBoundedPerSite(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:         true,
+		Port:            0,
+		Community:       "public",
+		ListenAddress:   "127.0.0.1",
+		EnterpriseOID:   ".1.3.6.1.4.1.55555",
+		SiteHistorySize: 3,
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	now := time.Now()
+	quiet := &models.TestResult{Timestamp: now, Site: models.SiteInfo{Name: "quiet"}}
+	if err := snmpOutput.Write(quiet); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+
+	// Enough results from one site to flush the global cache entirely
+	for i := 0; i < 150; i++ {
+		result := &models.TestResult{
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Site:      models.SiteInfo{Name: "chatty"},
+			Timings:   models.TimingMetrics{TotalDurationMs: int64(i)},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	quietHistory := snmpOutput.GetSiteHistory("quiet", 10)
+	if len(quietHistory) != 1 || quietHistory[0] != quiet {
+		t.Fatalf("expected quiet site to keep its only result, got %d results", len(quietHistory))
+	}
+
+	chattyHistory := snmpOutput.GetSiteHistory("chatty", 10)
+	if len(chattyHistory) != 3 {
+		t.Fatalf("expected chatty history capped at 3, got %d", len(chattyHistory))
+	}
+	for i, want := range []int64{147, 148, 149} {
+		if got := chattyHistory[i].Timings.TotalDurationMs; got != want {
+			t.Errorf("history[%d]: expected duration %d, got %d", i, want, got)
+		}
+	}
+
+	if got := snmpOutput.GetSiteHistory("chatty", 2); len(got) != 2 || got[1].Timings.TotalDurationMs != 149 {
+		t.Errorf("expected the 2 most recent results, got %d", len(got))
+	}
+	if got := snmpOutput.GetSiteHistory("missing", 5); len(got) != 0 {
+		t.Errorf("expected no history for unknown site, got %d", len(got))
+	}
+}
+
+func TestSNMPAllowAnyCommunity(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:           true,
+		Port:              0,
+		Community:         "public",
+		ListenAddress:     "0.0.0.0",
+		EnterpriseOID:     ".1.3.6.1.4.1.55555",
+		AllowAnyCommunity: true,
+	}
+
+	if _, err := NewSNMPOutput(cfg, "test", nil); err == nil {
+		t.Fatalf("expected allow_any_community to be refused on a non-loopback address")
+	}
+
+	cfg.ListenAddress = "127.0.0.1"
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: "anything-goes",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	packet, err := client.Get([]string{".1.3.6.1.4.1.55555.2.0"})
+	if err != nil {
+		t.Fatalf("expected any community to be accepted, got: %v", err)
+	}
+	if got := pduValueAsUint32(t, packet.Variables[0]); got != 100 {
+		t.Fatalf("expected max cache size 100, got %d", got)
+	}
+}
+
+func TestSNMPViewsRestrictCommunities(t *testing.T) {
+	base := ".1.3.6.1.4.1.55555"
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		Community:      "public",
+		WriteCommunity: "private",
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  base,
+		Views: map[string][]string{
+			"nms":     {base + ".1", base + ".3"},
+			"private": {base + ".7"},
+		},
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	result := &models.TestResult{
+		Timestamp: time.Now(),
+		Site:      models.SiteInfo{Name: "intranet.example"},
+		Status:    models.StatusInfo{Success: true},
+	}
+	if err := snmpOutput.Write(result); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+
+	client := func(community string) *gosnmp.GoSNMP {
+		c := &gosnmp.GoSNMP{
+			Target:    cfg.ListenAddress,
+			Port:      uint16(snmpOutput.Port()),
+			Community: community,
+			Version:   gosnmp.Version2c,
+			Timeout:   time.Second,
+			Retries:   1,
+		}
+		if err := c.Connect(); err != nil {
+			t.Fatalf("failed to connect SNMP client: %v", err)
+		}
+		t.Cleanup(func() { c.Conn.Close() })
+		return c
+	}
+
+	// The view community is accepted and sees only its subtrees
+	nms := client("nms")
+	packet, err := nms.Get([]string{base + ".3.0", base + ".2.0", base + ".5.1.1"})
+	if err != nil {
+		t.Fatalf("snmp get failed: %v", err)
+	}
+	if got := pduValueAsUint32(t, packet.Variables[0]); got != 1 {
+		t.Errorf("expected monitoredSites 1 inside the view, got %d", got)
+	}
+	for _, pdu := range packet.Variables[1:] {
+		if pdu.Type != gosnmp.NoSuchObject {
+			t.Errorf("expected NoSuchObject for %s outside the view, got %v", pdu.Name, pdu.Type)
+		}
+	}
+
+	var walked []string
+	if err := nms.BulkWalk(base, func(pdu gosnmp.SnmpPDU) error {
+		walked = append(walked, pdu.Name)
+		return nil
+	}); err != nil {
+		t.Fatalf("snmp walk failed: %v", err)
+	}
+	if want := []string{base + ".1.0", base + ".3.0"}; strings.Join(walked, " ") != strings.Join(want, " ") {
+		t.Errorf("expected walk to return only %v, got %v", want, walked)
+	}
+
+	packet, err = nms.GetNext([]string{base + ".3.0"})
+	if err != nil {
+		t.Fatalf("snmp getnext failed: %v", err)
+	}
+	if packet.Variables[0].Type != gosnmp.EndOfMibView {
+		t.Errorf("expected EndOfMibView past the view, got %v", packet.Variables[0].Type)
+	}
+
+	// The read community is unrestricted
+	packet, err = client("public").Get([]string{base + ".5.1.1"})
+	if err != nil {
+		t.Fatalf("snmp get failed: %v", err)
+	}
+	if packet.Variables[0].Type != gosnmp.OctetString {
+		t.Errorf("expected the site table to be visible to the read community, got %v", packet.Variables[0].Type)
+	}
+
+	// A view also limits SETs by the write community
+	private := client("private")
+	packet, err = private.Set([]gosnmp.SnmpPDU{{Name: base + ".6.0", Type: gosnmp.Integer, Value: 1}})
+	if err != nil {
+		t.Fatalf("snmp set failed: %v", err)
+	}
+	if packet.Error != gosnmp.NoAccess {
+		t.Errorf("expected noAccess for a SET outside the view, got %v", packet.Error)
+	}
+	if snmpOutput.GetSiteStats("intranet.example") == nil {
+		t.Errorf("expected a refused SET to leave statistics untouched")
+	}
+
+	// Unknown communities are still rejected
+	unknown := client("guess")
+	unknown.Timeout = 200 * time.Millisecond
+	unknown.Retries = 0
+	if _, err := unknown.Get([]string{base + ".1.0"}); err == nil {
+		t.Errorf("expected an unknown community to get no response")
+	}
+}
+
+func TestSNMPSystemGroup(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		SysContact:    "noc@example.com",
+		SysName:       "monitor-1",
+		SysLocation:   "Rack 4",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "9.8.7", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: cfg.Community,
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	oids := make([]string, 6)
+	for i := range oids {
+		oids[i] = fmt.Sprintf("%s.%d.0", systemGroupOID, i+1)
+	}
+	packet, err := client.Get(oids)
+	if err != nil {
+		t.Fatalf("snmp get failed: %v", err)
+	}
+	if len(packet.Variables) != len(oids) {
+		t.Fatalf("expected %d variables, got %d", len(oids), len(packet.Variables))
+	}
+
+	wantStrings := map[int]string{
+		0: "Internet Connection Monitor 9.8.7", // sysDescr defaults to the version
+		3: "noc@example.com",
+		4: "monitor-1",
+		5: "Rack 4",
+	}
+	for i, want := range wantStrings {
+		if v, ok := packet.Variables[i].Value.([]byte); !ok || string(v) != want {
+			t.Errorf("%s: expected %q, got %v", oids[i], want, packet.Variables[i].Value)
+		}
+	}
+	if got := packet.Variables[1]; got.Type != gosnmp.ObjectIdentifier || got.Value != cfg.EnterpriseOID {
+		t.Errorf("sysObjectID: expected %s, got %v (%v)", cfg.EnterpriseOID, got.Value, got.Type)
+	}
+	if got := packet.Variables[2]; got.Type != gosnmp.TimeTicks {
+		t.Errorf("sysUpTime: expected TimeTicks, got %v", got.Type)
+	}
+
+	// sysUpTime counts hundredths of a second
+	values := make(map[string]gosnmp.SnmpPDU)
+	snmpOutput.system.addTo(values, 90*time.Second+250*time.Millisecond)
+	if got := pduValueAsUint32(t, values[systemGroupOID+".3.0"]); got != 9025 {
+		t.Errorf("sysUpTime: expected 9025 ticks, got %d", got)
+	}
+}
+
+func TestSNMPWalkSystemGroup(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		SysName:       "monitor-1",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "9.8.7", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	client := &gosnmp.GoSNMP{
+		Target:         cfg.ListenAddress,
+		Port:           uint16(snmpOutput.Port()),
+		Community:      cfg.Community,
+		Version:        gosnmp.Version2c,
+		Timeout:        time.Second,
+		Retries:        1,
+		MaxRepetitions: 4,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	want := []string{"1", "2", "3", "4", "5", "6"}
+	for name, walk := range map[string]func(string) ([]gosnmp.SnmpPDU, error){
+		"GetNext": client.WalkAll,
+		"GetBulk": client.BulkWalkAll,
+	} {
+		pdus, err := walk(systemGroupOID)
+		if err != nil {
+			t.Fatalf("%s walk of the system group failed: %v", name, err)
+		}
+		if len(pdus) != len(want) {
+			t.Fatalf("%s walk: expected %d system objects, got %d: %v", name, len(want), len(pdus), pdus)
+		}
+		for i, pdu := range pdus {
+			if oid := systemGroupOID + "." + want[i] + ".0"; pdu.Name != oid {
+				t.Errorf("%s walk: expected %s at position %d, got %s", name, oid, i, pdu.Name)
+			}
+		}
+		if v, ok := pdus[4].Value.([]byte); !ok || string(v) != "monitor-1" {
+			t.Errorf("%s walk: expected sysName monitor-1, got %v", name, pdus[4].Value)
+		}
+	}
+
+	// The system group comes before the enterprise tree
+	packet, err := client.GetNext([]string{systemGroupOID + ".6.0"})
+	if err != nil {
+		t.Fatalf("snmp getnext failed: %v", err)
+	}
+	if got := packet.Variables[0].Name; got != cfg.EnterpriseOID+".1.0" {
+		t.Fatalf("expected the walk to continue into %s.1.0, got %s", cfg.EnterpriseOID, got)
+	}
+}
+
+func TestSNMPCloseDrainsCacheToJSONL(t *testing.T) {
+	drainPath := filepath.Join(t.TempDir(), "drain.jsonl")
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		DrainPath:     drainPath,
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+
+	now := time.Now()
+	for i, name := range []string{"first", "second", "third"} {
+		result := &models.TestResult{
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			TestID:    name,
+			Site:      models.SiteInfo{Name: "example.com"},
+			Status:    models.StatusInfo{Success: true},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	if err := snmpOutput.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(drainPath)
+	if err != nil {
+		t.Fatalf("drain file not written: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 JSON lines, got %d", len(lines))
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		var result models.TestResult
+		if err := json.Unmarshal([]byte(lines[i]), &result); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", i, err)
+		}
+		if result.TestID != want {
+			t.Errorf("line %d: expected test %s, got %s", i, want, result.TestID)
+		}
+	}
+}
+
+func TestSNMPCloseSurvivesDrainFailure(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		DrainPath:     filepath.Join(t.TempDir(), "missing", "drain.jsonl"),
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	if err := snmpOutput.Write(&models.TestResult{Site: models.SiteInfo{Name: "example.com"}}); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+
+	if err := snmpOutput.Close(); err != nil {
+		t.Errorf("Close should not fail because of a drain error: %v", err)
+	}
+}
+
+func TestSNMPSiteTTLPrunesStaleSites(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		SiteTTL:       10 * time.Minute,
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	clock := time.Now()
+	snmpOutput.mu.Lock()
+	snmpOutput.now = func() time.Time { return clock }
+	snmpOutput.mu.Unlock()
+
+	write := func(name string) {
+		result := &models.TestResult{
+			Timestamp: clock,
+			Site:      models.SiteInfo{Name: name},
+			Status:    models.StatusInfo{Success: true},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	write("removed.example")
+	write("kept.example")
+
+	// Only kept.example keeps reporting
+	clock = clock.Add(6 * time.Minute)
+	write("kept.example")
+	clock = clock.Add(6 * time.Minute)
+
+	snapshot := snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+
+	base := cfg.EnterpriseOID
+	if _, ok := snapshot.Values[base+".5.1.1"]; ok {
+		t.Errorf("expected stale site row %s.5.1 to be pruned", base)
+	}
+	if got, _ := snapshot.Values[base+".5.2.1"].Value.([]byte); string(got) != "kept.example" {
+		t.Errorf("expected kept.example to keep index 2, got %q", got)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[base+".3.0"]); got != 1 {
+		t.Errorf("expected 1 monitored site after pruning, got %d", got)
+	}
+	if snmpOutput.GetSiteStats("removed.example") != nil {
+		t.Errorf("expected stats for the stale site to be dropped")
+	}
+
+	// A pruned site that returns gets a fresh index rather than reusing its old one
+	write("removed.example")
+	snapshot = snmpOutput.Snapshot()
+	if got, _ := snapshot.Values[base+".5.3.1"].Value.([]byte); string(got) != "removed.example" {
+		t.Errorf("expected returning site at index 3, got %q", got)
+	}
+}
+
+func TestSNMPMaxTrackedSitesEvictsLeastRecentlyUpdated(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:         true,
+		Port:            0,
+		Community:       "public",
+		ListenAddress:   "127.0.0.1",
+		EnterpriseOID:   ".1.3.6.1.4.1.55555",
+		MaxTrackedSites: 2,
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	clock := time.Now()
+	snmpOutput.mu.Lock()
+	snmpOutput.now = func() time.Time { return clock }
+	snmpOutput.mu.Unlock()
+
+	write := func(name string) {
+		clock = clock.Add(time.Second)
+		result := &models.TestResult{
+			Timestamp: clock,
+			Site:      models.SiteInfo{Name: name},
+			Status:    models.StatusInfo{Success: true},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	write("a.example")
+	write("b.example")
+	write("a.example")
+	// b.example is now the least recently updated
+	write("c.example")
+
+	snapshot := snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+
+	base := cfg.EnterpriseOID
+	if snmpOutput.GetSiteStats("b.example") != nil {
+		t.Error("expected b.example to be evicted")
+	}
+	if _, ok := snapshot.Values[base+".5.2.1"]; ok {
+		t.Error("expected the evicted row 2 to be gone")
+	}
+	if got, _ := snapshot.Values[base+".5.1.1"].Value.([]byte); string(got) != "a.example" {
+		t.Errorf("expected a.example to keep index 1, got %q", got)
+	}
+	if got, _ := snapshot.Values[base+".5.3.1"].Value.([]byte); string(got) != "c.example" {
+		t.Errorf("expected c.example at index 3, got %q", got)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[base+".3.0"]); got != 2 {
+		t.Errorf("expected 2 monitored sites, got %d", got)
+	}
+
+	// An evicted site that returns gets a fresh index and evicts a.example
+	write("b.example")
+	snapshot = snmpOutput.Snapshot()
+	if got, _ := snapshot.Values[base+".5.4.1"].Value.([]byte); string(got) != "b.example" {
+		t.Errorf("expected returning b.example at index 4, got %q", got)
+	}
+	if snmpOutput.GetSiteStats("a.example") != nil {
+		t.Error("expected a.example to be evicted")
+	}
+}
+
+func TestSNMPCategoryAggregates(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(name, category string, success bool, durationMs int64) {
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: name, Category: category},
+			Status:    models.StatusInfo{Success: success},
+			Timings:   models.TimingMetrics{TotalDurationMs: durationMs},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	write("google.com", "search", true, 100)
+	write("google.com", "search", true, 200)
+	write("bing.com", "search", false, 600)
+	write("github.com", "dev", true, 50)
+	write("uncategorized.example", "", true, 10)
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	pdus, err := client.WalkAll(fmt.Sprintf("%s.%d", cfg.EnterpriseOID, categoryTableID))
+	if err != nil {
+		t.Fatalf("walk of categoryTable failed: %v", err)
+	}
+	if want := 2 * len(mibCategoryColumns); len(pdus) != want {
+		t.Fatalf("expected %d categoryTable values, got %d", want, len(pdus))
+	}
+	values := make(map[string]gosnmp.SnmpPDU, len(pdus))
+	for _, pdu := range pdus {
+		values[pdu.Name] = pdu
+	}
+
+	// Categories are numbered in site index order: search (google.com) first
+	for _, tc := range []struct {
+		index                    int
+		name                     string
+		total, ok, failed, avgMs uint32
+		sites                    uint32
+	}{
+		{1, "search", 3, 2, 1, 300, 2},
+		{2, "dev", 1, 1, 0, 50, 1},
+	} {
+		column := func(id int) gosnmp.SnmpPDU {
+			return values[fmt.Sprintf("%s.%d.1.%d.%d", cfg.EnterpriseOID, categoryTableID, id, tc.index)]
+		}
+		if got, _ := column(1).Value.([]byte); string(got) != tc.name {
+			t.Errorf("category %d: expected name %q, got %q", tc.index, tc.name, got)
+		}
+		for id, want := range map[int]uint32{2: tc.total, 3: tc.ok, 4: tc.failed, 5: tc.avgMs, 6: tc.sites} {
+			if got := pduValueAsUint32(t, column(id)); got != want {
+				t.Errorf("category %s column %d = %d, want %d", tc.name, id, got, want)
+			}
+		}
+	}
+
+	if err := VerifyMIBTree(snmpOutput.Snapshot()); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+}
+
+func TestSNMPReloadSitesKeepsIndexes(t *testing.T) {
+	for _, prune := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prune=%v", prune), func(t *testing.T) {
+			cfg := &config.SNMPConfig{
+				Enabled:           true,
+				Port:              0,
+				Community:         "public",
+				ListenAddress:     "127.0.0.1",
+				EnterpriseOID:     ".1.3.6.1.4.1.55555",
+				PruneRemovedSites: prune,
+			}
+
+			snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+			if err != nil {
+				t.Fatalf("failed to create SNMP output: %v", err)
+			}
+			defer snmpOutput.Close()
+
+			write := func(name string) {
+				result := &models.TestResult{
+					Timestamp: time.Now(),
+					Site:      models.SiteInfo{Name: name},
+					Status:    models.StatusInfo{Success: true},
+				}
+				if err := snmpOutput.Write(result); err != nil {
+					t.Fatalf("failed to write result: %v", err)
+				}
+			}
+
+			write("alpha")
+			write("beta")
+			write("beta")
+
+			snmpOutput.ReloadSites([]models.SiteDefinition{{Name: "beta"}, {Name: "gamma"}})
+			write("gamma")
+
+			snapshot := snmpOutput.Snapshot()
+			if err := VerifyMIBTree(snapshot); err != nil {
+				t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+			}
+
+			base := cfg.EnterpriseOID
+			if got, _ := snapshot.Values[base+".5.2.1"].Value.([]byte); string(got) != "beta" {
+				t.Errorf("expected beta to keep index 2, got %q", got)
+			}
+			if got := pduValueAsUint32(t, snapshot.Values[base+".5.2.2"]); got != 2 {
+				t.Errorf("expected beta to keep its 2 tests, got %d", got)
+			}
+			if got, _ := snapshot.Values[base+".5.3.1"].Value.([]byte); string(got) != "gamma" {
+				t.Errorf("expected new site gamma at index 3, got %q", got)
+			}
+
+			_, alphaKept := snapshot.Values[base+".5.1.1"]
+			if alphaKept == prune {
+				t.Errorf("removed site alpha kept=%v with prune_removed_sites=%v", alphaKept, prune)
+			}
+
+			// A pruned site that is configured again gets a new index
+			write("alpha")
+			want := ".5.1.1"
+			if prune {
+				want = ".5.4.1"
+			}
+			snapshot = snmpOutput.Snapshot()
+			if got, _ := snapshot.Values[base+want].Value.([]byte); string(got) != "alpha" {
+				t.Errorf("expected alpha at %s, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestSNMPSiteIndexesFollowConfigOrder(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	sites := []models.SiteDefinition{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}, {Name: "alpha"}}
+
+	// Whichever order sites report in, e.g. across restarts, configured sites
+	// get their configuration position and others follow in first-seen order
+	for _, order := range [][]string{
+		{"gamma", "extra", "alpha", "beta"},
+		{"extra", "beta", "alpha", "gamma"},
+	} {
+		t.Run(strings.Join(order, ","), func(t *testing.T) {
+			snmpOutput, err := NewSNMPOutput(cfg, "test", sites)
+			if err != nil {
+				t.Fatalf("failed to create SNMP output: %v", err)
+			}
+			defer snmpOutput.Close()
+
+			base := cfg.EnterpriseOID
+			for i, name := range order {
+				snmpOutput.Write(&models.TestResult{
+					Timestamp: time.Now(),
+					Site:      models.SiteInfo{Name: name},
+					Status:    models.StatusInfo{Success: true},
+				})
+				// Reserved indexes don't count as sites until they report
+				snapshot := snmpOutput.Snapshot()
+				if got := pduValueAsUint32(t, snapshot.Values[base+".3.0"]); got != uint32(i+1) {
+					t.Errorf("after %d sites reported, site count is %d", i+1, got)
+				}
+			}
+
+			snapshot := snmpOutput.Snapshot()
+			if err := VerifyMIBTree(snapshot); err != nil {
+				t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+			}
+			for idx, want := range []string{"alpha", "beta", "gamma", "extra"} {
+				oid := fmt.Sprintf("%s.5.%d.1", base, idx+1)
+				if got, _ := snapshot.Values[oid].Value.([]byte); string(got) != want {
+					t.Errorf("expected %s at %s, got %q", want, oid, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSNMPGetAllStatsSnapshot(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	now := time.Now()
+	results := []*models.TestResult{
+		{Timestamp: now, Site: models.SiteInfo{Name: "a"}, Status: models.StatusInfo{Success: true}, Timings: models.TimingMetrics{TotalDurationMs: 100}},
+		{Timestamp: now.Add(time.Second), Site: models.SiteInfo{Name: "a"}, Status: models.StatusInfo{Success: false}, Timings: models.TimingMetrics{TotalDurationMs: 300}},
+		{Timestamp: now, Site: models.SiteInfo{Name: "b"}, Status: models.StatusInfo{Success: true}, Timings: models.TimingMetrics{TotalDurationMs: 50}},
+	}
+	for _, r := range results {
+		if err := snmpOutput.Write(r); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	snapshots := snmpOutput.GetAllStatsSnapshot()
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 sites, got %d", len(snapshots))
+	}
+
+	a := snapshots["a"]
+	if a.TotalTests != 2 || a.SuccessfulTests != 1 || a.FailedTests != 1 {
+		t.Errorf("unexpected counts for a: %+v", a)
+	}
+	if a.MinDurationMs != 100 || a.MaxDurationMs != 300 || a.AvgDurationMs != 200 {
+		t.Errorf("unexpected durations for a: %+v", a)
+	}
+	if !a.LastFailureTime.Equal(now.Add(time.Second)) || !a.LastSuccessTime.Equal(now) {
+		t.Errorf("unexpected last success/failure times for a: %+v", a)
+	}
+
+	// Snapshots are copies; later writes must not change them
+	snmpOutput.Write(results[2])
+	if snapshots["b"].TotalTests != 1 {
+		t.Errorf("expected snapshot to be unaffected by later writes")
+	}
+}
+
+func TestSNMPSkippedResultsKeepLatencyStats(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	now := time.Now()
+	for i, ms := range []int64{100, 300} {
+		snmpOutput.Write(&models.TestResult{Timestamp: now.Add(time.Duration(i) * time.Second), Site: models.SiteInfo{Name: "a"}, Status: models.StatusInfo{Success: true}, Timings: models.TimingMetrics{TotalDurationMs: ms}})
+	}
+	// Skipped by the test loop during an outage: no timings at all
+	snmpOutput.Write(&models.TestResult{
+		Timestamp: now.Add(2 * time.Second),
+		Site:      models.SiteInfo{Name: "a"},
+		Status:    models.StatusInfo{Success: false, Skipped: true},
+		Error:     &models.ErrorInfo{ErrorType: "ERR_NETWORK_DOWN_SKIPPED"},
+	})
+
+	a := snmpOutput.GetAllStatsSnapshot()["a"]
+	if a.TotalTests != 3 || a.FailedTests != 1 {
+		t.Errorf("expected the skipped result to count as a failure, got %+v", a)
+	}
+	if a.MinDurationMs != 100 || a.MaxDurationMs != 300 || a.AvgDurationMs != 200 || a.LastDurationMs != 300 {
+		t.Errorf("expected the skipped result to leave the durations alone, got min %d, max %d, avg %v, last %d",
+			a.MinDurationMs, a.MaxDurationMs, a.AvgDurationMs, a.LastDurationMs)
+	}
+	if a.P50DurationMs == 0 {
+		t.Errorf("expected percentiles over the tested results only, got p50 %d", a.P50DurationMs)
+	}
+}
+
+func TestSNMPEWMATracksLatencyStep(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EWMAAlpha:     0.3,
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(durationMs int64) {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "cdn"},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: durationMs},
+		})
+	}
+
+	// A long healthy history, then latency jumps tenfold
+	for i := 0; i < 50; i++ {
+		write(100)
+	}
+	if got := snmpOutput.GetAllStatsSnapshot()["cdn"].EWMADurationMs; got != 100 {
+		t.Fatalf("expected EWMA 100ms on a flat history, got %v", got)
+	}
+	for i := 0; i < 5; i++ {
+		write(1000)
+	}
+
+	st := snmpOutput.GetAllStatsSnapshot()["cdn"]
+	// 1000 - 900*0.7^5 ≈ 849ms, while the cumulative average has barely moved
+	if st.EWMADurationMs < 800 {
+		t.Errorf("expected EWMA to approach the new latency within 5 tests, got %.0fms", st.EWMADurationMs)
+	}
+	if st.AvgDurationMs > 200 {
+		t.Errorf("expected cumulative average to lag behind, got %.0fms", st.AvgDurationMs)
+	}
+
+	snapshot := snmpOutput.Snapshot()
+	if got := pduValueAsUint32(t, snapshot.Values[snapshot.Base+".5.1.11"]); got != uint32(math.Round(st.EWMADurationMs)) {
+		t.Errorf("expected ewmaDurationMs OID %d, got %d", uint32(math.Round(st.EWMADurationMs)), got)
+	}
+}
+
+func TestSNMPUptimePercent(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:         true,
+		Port:            0,
+		Community:       "public",
+		ListenAddress:   "127.0.0.1",
+		SiteHistorySize: 4,
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(success bool) {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "example.com"},
+			Status:    models.StatusInfo{Success: success},
+		})
+	}
+
+	// One failure in eight tests, outside the last four
+	for _, success := range []bool{true, true, true, false, true, true, true, true} {
+		write(success)
+	}
+
+	st := snmpOutput.GetSiteStats("example.com")
+	if st.UptimePercent != 87.5 {
+		t.Errorf("expected uptime 87.5%%, got %v", st.UptimePercent)
+	}
+	if st.RecentUptimePercent != 100 {
+		t.Errorf("expected recent uptime 100%%, got %v", st.RecentUptimePercent)
+	}
+
+	snapshot := snmpOutput.Snapshot()
+	if got := pduValueAsUint32(t, snapshot.Values[snapshot.Base+".5.1.12"]); got != 87 {
+		t.Errorf("expected uptimePercent OID 87, got %d", got)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[snapshot.Base+".5.1.13"]); got != 100 {
+		t.Errorf("expected recentUptimePercent OID 100, got %d", got)
+	}
+
+	// A new failure shows up in the window immediately
+	write(false)
+	snapshot = snmpOutput.Snapshot()
+	if got := pduValueAsUint32(t, snapshot.Values[snapshot.Base+".5.1.13"]); got != 75 {
+		t.Errorf("expected recentUptimePercent OID 75, got %d", got)
+	}
+
+	if got := uptimePercent(0, 0); got != 0 {
+		t.Errorf("expected 0%% uptime with no tests, got %v", got)
+	}
+}
+
+func TestSNMPTestsLastMinuteDecays(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	clock := time.Unix(1_700_000_000, 0)
+	snmpOutput.mu.Lock()
+	snmpOutput.now = func() time.Time { return clock }
+	snmpOutput.mu.Unlock()
+
+	write := func(name string, n int) {
+		for i := 0; i < n; i++ {
+			result := &models.TestResult{
+				Timestamp: clock,
+				Site:      models.SiteInfo{Name: name},
+				Status:    models.StatusInfo{Success: true},
+			}
+			if err := snmpOutput.Write(result); err != nil {
+				t.Fatalf("failed to write result: %v", err)
+			}
+		}
+	}
+
+	base := cfg.EnterpriseOID
+	rates := func() (global, alpha, beta uint32) {
+		snapshot := snmpOutput.Snapshot()
+		if err := VerifyMIBTree(snapshot); err != nil {
+			t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+		}
+		return pduValueAsUint32(t, snapshot.Values[base+".8.0"]),
+			pduValueAsUint32(t, snapshot.Values[base+".5.1.14"]),
+			pduValueAsUint32(t, snapshot.Values[base+".5.2.14"])
+	}
+
+	write("alpha", 3)
+	write("beta", 1)
+	clock = clock.Add(30 * time.Second)
+	write("alpha", 2)
+
+	if global, alpha, beta := rates(); global != 6 || alpha != 5 || beta != 1 {
+		t.Errorf("after 30s: rates = %d/%d/%d, want 6/5/1", global, alpha, beta)
+	}
+
+	// The first batch ages out; the second is still inside the minute
+	clock = clock.Add(31 * time.Second)
+	if global, alpha, beta := rates(); global != 2 || alpha != 2 || beta != 0 {
+		t.Errorf("after 61s: rates = %d/%d/%d, want 2/2/0", global, alpha, beta)
+	}
+
+	// An idle minute later everything has decayed, though totals remain
+	clock = clock.Add(time.Minute)
+	if global, alpha, beta := rates(); global != 0 || alpha != 0 || beta != 0 {
+		t.Errorf("after 121s: rates = %d/%d/%d, want 0/0/0", global, alpha, beta)
+	}
+	if got := snmpOutput.GetSiteStats("alpha").TotalTests; got != 5 {
+		t.Errorf("expected 5 total tests for alpha, got %d", got)
+	}
+}
+
+func TestSNMPRejectsInvalidEWMAAlpha(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EWMAAlpha:     1.5,
+	}
+	if snmpOutput, err := NewSNMPOutput(cfg, "test", nil); err == nil {
+		snmpOutput.Close()
+		t.Fatal("expected an error for ewma_alpha 1.5")
+	}
+}
+
+func TestSNMPSetRequest(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		Community:      "public",
+		WriteCommunity: "private",
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  ".1.3.6.1.4.1.55555",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	result := &models.TestResult{
+		Timestamp: time.Now(),
+		Site:      models.SiteInfo{Name: "example.com"},
+		Status:    models.StatusInfo{Success: true},
+	}
+	if err := snmpOutput.Write(result); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+
+	newClient := func(community string) *gosnmp.GoSNMP {
+		client := &gosnmp.GoSNMP{
+			Target:    cfg.ListenAddress,
+			Port:      uint16(snmpOutput.Port()),
+			Community: community,
+			Version:   gosnmp.Version2c,
+			Timeout:   500 * time.Millisecond,
+			Retries:   0,
+		}
+		if err := client.Connect(); err != nil {
+			t.Fatalf("failed to connect SNMP client: %v", err)
+		}
+		t.Cleanup(func() { client.Conn.Close() })
+		return client
+	}
+
+	base := cfg.EnterpriseOID
+	resetOID := base + ".6.0"
+	ackOID := base + ".7.0"
+
+	// The read community cannot SET, and an unknown community gets no answer
+	resp, err := newClient("public").Set([]gosnmp.SnmpPDU{{Name: resetOID, Type: gosnmp.Integer, Value: 1}})
+	if err != nil {
+		t.Fatalf("SET with the read community failed: %v", err)
+	}
+	if resp.Error != gosnmp.NoAccess || resp.ErrorIndex != 1 {
+		t.Errorf("expected noAccess for the read community, got %v at %d", resp.Error, resp.ErrorIndex)
+	}
+	if _, err := newClient("guess").Set([]gosnmp.SnmpPDU{{Name: resetOID, Type: gosnmp.Integer, Value: 1}}); err == nil {
+		t.Errorf("expected SET with an unknown community to be ignored")
+	}
+	if snmpOutput.GetSiteStats("example.com") == nil {
+		t.Fatalf("expected a refused SET to apply nothing")
+	}
+
+	writer := newClient("private")
+
+	// Read-only and unknown OIDs are not writable, and nothing is applied
+	resp, err = writer.Set([]gosnmp.SnmpPDU{
+		{Name: ackOID, Type: gosnmp.Integer, Value: 1},
+		{Name: base + ".1.0", Type: gosnmp.Integer, Value: 5},
+	})
+	if err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if resp.Error != gosnmp.NotWritable || resp.ErrorIndex != 2 {
+		t.Errorf("expected notWritable at index 2, got %v at %d", resp.Error, resp.ErrorIndex)
+	}
+	// The agent writes lastAlertAck under mu
+	snmpOutput.mu.RLock()
+	acked := !snmpOutput.lastAlertAck.IsZero()
+	snmpOutput.mu.RUnlock()
+	if acked {
+		t.Errorf("expected a failed SET to apply nothing")
+	}
+
+	// Wrong type and wrong value
+	resp, err = writer.Set([]gosnmp.SnmpPDU{{Name: resetOID, Type: gosnmp.OctetString, Value: "yes"}})
+	if err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if resp.Error != gosnmp.WrongType {
+		t.Errorf("expected wrongType, got %v", resp.Error)
+	}
+	resp, err = writer.Set([]gosnmp.SnmpPDU{{Name: resetOID, Type: gosnmp.Gauge32, Value: uint(1)}})
+	if err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if resp.Error != gosnmp.WrongType {
+		t.Errorf("expected wrongType for a Gauge32 reset, got %v", resp.Error)
+	}
+	resp, err = writer.Set([]gosnmp.SnmpPDU{{Name: resetOID, Type: gosnmp.Integer, Value: 2}})
+	if err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if resp.Error != gosnmp.WrongValue {
+		t.Errorf("expected wrongValue, got %v", resp.Error)
+	}
+
+	// Acknowledge and reset together
+	resp, err = writer.Set([]gosnmp.SnmpPDU{
+		{Name: ackOID, Type: gosnmp.Integer, Value: 1},
+		{Name: resetOID, Type: gosnmp.Integer, Value: 1},
+	})
+	if err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if resp.Error != gosnmp.NoError {
+		t.Fatalf("expected SET to succeed, got %v at %d", resp.Error, resp.ErrorIndex)
+	}
+	if snmpOutput.GetSiteStats("example.com") != nil {
+		t.Errorf("expected site statistics to be reset")
+	}
+
+	// The write community can also read
+	get, err := writer.Get([]string{ackOID})
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	if pduValueAsUint32(t, get.Variables[0]) == 0 {
+		t.Errorf("expected last alert ack time to be set")
+	}
+
+	// lastAlertAck also accepts its MIB SYNTAX, Gauge32
+	resp, err = writer.Set([]gosnmp.SnmpPDU{{Name: ackOID, Type: gosnmp.Gauge32, Value: uint(1)}})
+	if err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if resp.Error != gosnmp.NoError {
+		t.Errorf("expected a Gauge32 acknowledgement to succeed, got %v", resp.Error)
+	}
+
+	// The site returns at its old index after reporting again
+	if err := snmpOutput.Write(result); err != nil {
+		t.Fatalf("failed to write result: %v", err)
+	}
+	if _, ok := snmpOutput.Snapshot().Values[base+".5.1.1"]; !ok {
+		t.Errorf("expected site to keep index 1 after reset")
+	}
+}
+
+func TestSNMPSetResetsOneSite(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		Community:      "public",
+		WriteCommunity: "private",
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", []models.SiteDefinition{{Name: "a.example", URL: "https://a.example"}, {Name: "b.example", URL: "https://b.example"}})
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	for _, site := range []string{"a.example", "b.example", "a.example"} {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: site, URL: "https://" + site},
+			Status:    models.StatusInfo{Success: true},
+		})
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: "private",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	base := cfg.EnterpriseOID
+	set := func(oid string, value int) *gosnmp.SnmpPacket {
+		t.Helper()
+		resp, err := client.Set([]gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Integer, Value: value}})
+		if err != nil {
+			t.Fatalf("SET %s failed: %v", oid, err)
+		}
+		return resp
+	}
+	totals := func() []uint32 {
+		t.Helper()
+		packet, err := client.Get([]string{base + ".14.1.2.1", base + ".5.1.2", base + ".14.1.2.2", base + ".14.1.24.1"})
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		got := make([]uint32, 0, len(packet.Variables))
+		for _, v := range packet.Variables {
+			got = append(got, pduValueAsUint32(t, v))
+		}
+		return got
+	}
+
+	if got := totals(); got[0] != 2 || got[1] != 2 || got[2] != 1 || got[3] != 0 {
+		t.Fatalf("unexpected totals before reset: %v", got)
+	}
+
+	for _, tc := range []struct {
+		oid   string
+		value int
+		want  gosnmp.SNMPError
+	}{
+		{base + ".14.1.24.1", 2, gosnmp.WrongValue},
+		{base + ".14.1.2.1", 1, gosnmp.NotWritable},
+		{base + ".14.1.24.9", 1, gosnmp.NoCreation},
+	} {
+		if resp := set(tc.oid, tc.value); resp.Error != tc.want {
+			t.Errorf("SET %s = %d: expected %v, got %v", tc.oid, tc.value, tc.want, resp.Error)
+		}
+	}
+
+	// Resetting a site zeroes its row in both tables and leaves the others
+	if resp := set(base+".14.1.24.1", 1); resp.Error != gosnmp.NoError {
+		t.Fatalf("expected the site reset to succeed, got %v", resp.Error)
+	}
+	if got := totals(); got[0] != 0 || got[1] != 0 || got[2] != 1 {
+		t.Fatalf("expected only a.example to be reset, got %v", got)
+	}
+
+	// The legacy table's column resets too
+	if resp := set(base+".5.2.24", 1); resp.Error != gosnmp.NoError {
+		t.Fatalf("expected the legacy site reset to succeed, got %v", resp.Error)
+	}
+	if got := totals(); got[2] != 0 {
+		t.Fatalf("expected b.example to be reset, got %v", got)
+	}
+}
+
+func TestSNMPConcurrentWritesKeepStatsConsistent(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	const writers = 8
+	const perWriter = 500
+	sites := []string{"a", "b", "c", "d"}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				snmpOutput.Write(&models.TestResult{
+					Timestamp: time.Now(),
+					Site:      models.SiteInfo{Name: sites[i%len(sites)]},
+					Status:    models.StatusInfo{Success: i%2 == 0},
+					// Durations 1..100 repeat evenly, so the average is 50.5
+					Timings: models.TimingMetrics{TotalDurationMs: int64(i%100 + 1)},
+				})
+			}
+		}()
+	}
+	// Poll concurrently, as an NMS would
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := VerifyMIBTree(snmpOutput.Snapshot()); err != nil {
+				t.Errorf("inconsistent snapshot during writes: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	for _, name := range sites {
+		st := snmpOutput.GetSiteStats(name)
+		if st == nil {
+			t.Fatalf("missing stats for %s", name)
+		}
+		want := int64(writers * perWriter / len(sites))
+		if st.TotalTests != want || st.SuccessfulTests+st.FailedTests != want {
+			t.Errorf("%s: expected %d tests, got %d (%d/%d)", name, want, st.TotalTests, st.SuccessfulTests, st.FailedTests)
+		}
+		if st.MinDurationMs < 1 || st.MaxDurationMs > 100 || st.MinDurationMs > st.MaxDurationMs {
+			t.Errorf("%s: unexpected min/max %d/%d", name, st.MinDurationMs, st.MaxDurationMs)
+		}
+		if len(snmpOutput.GetSiteHistory(name, -1)) != defaultSiteHistorySize {
+			t.Errorf("%s: expected full history", name)
+		}
+	}
+	var total float64
+	var tests int64
+	for _, st := range snmpOutput.GetAllStats() {
+		total += st.AvgDurationMs * float64(st.TotalTests)
+		tests += st.TotalTests
+	}
+	if avg := total / float64(tests); avg < 50.49 || avg > 50.51 {
+		t.Errorf("expected overall average 50.5, got %v", avg)
+	}
+}
+
+// BenchmarkSNMPWriteParallel measures Write throughput with concurrent writers,
+// optionally while an NMS polls the agent. Writers only share the agent-wide
+// lock in read mode, so they no longer queue behind each other's statistics
+// updates or behind snapshot building.
+func BenchmarkSNMPWriteParallel(b *testing.B) {
+	for _, bc := range []struct {
+		sites   int
+		polling bool
+	}{
+		{1, false},
+		{64, false},
+		{64, true},
+	} {
+		b.Run(fmt.Sprintf("sites=%d/polling=%v", bc.sites, bc.polling), func(b *testing.B) {
+			cfg := &config.SNMPConfig{
+				Enabled:       true,
+				Port:          0,
+				Community:     "public",
+				ListenAddress: "127.0.0.1",
+				EnterpriseOID: ".1.3.6.1.4.1.55555",
+			}
+			snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+			if err != nil {
+				b.Fatalf("failed to create SNMP output: %v", err)
+			}
+			defer snmpOutput.Close()
+
+			results := make([]*models.TestResult, bc.sites)
+			for i := range results {
+				results[i] = &models.TestResult{
+					Timestamp: time.Now(),
+					Site:      models.SiteInfo{Name: fmt.Sprintf("site-%d", i)},
+					Status:    models.StatusInfo{Success: true},
+					Timings:   models.TimingMetrics{TotalDurationMs: int64(i)},
+				}
+				snmpOutput.Write(results[i])
+			}
+
+			stop := make(chan struct{})
+			var pollers sync.WaitGroup
+			if bc.polling {
+				pollers.Add(1)
+				go func() {
+					defer pollers.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							snmpOutput.buildOIDSnapshot()
+						}
+					}
+				}()
+			}
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				result := results[int(next.Add(1))%bc.sites]
+				for pb.Next() {
+					snmpOutput.Write(result)
+				}
+			})
+			b.StopTimer()
+
+			close(stop)
+			pollers.Wait()
+		})
+	}
+}
+
+func TestSNMPCircuitBreakerState(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(breaker *models.CircuitBreakerInfo) {
+		result := &models.TestResult{
+			Timestamp:      time.Now(),
+			Site:           models.SiteInfo{Name: "down.example"},
+			CircuitBreaker: breaker,
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+	state := func() int {
+		snapshot := snmpOutput.Snapshot()
+		if err := VerifyMIBTree(snapshot); err != nil {
+			t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+		}
+		v, _ := snapshot.Values[cfg.EnterpriseOID+".5.1.15"].Value.(int)
+		return v
+	}
+
+	write(nil)
+	if got := state(); got != circuitBreakerClosed {
+		t.Errorf("expected closed(1) without a circuit breaker, got %d", got)
+	}
+	write(&models.CircuitBreakerInfo{State: "open", ConsecutiveFailures: 5, BackoffMs: 60000})
+	if got := state(); got != circuitBreakerOpen {
+		t.Errorf("expected open(2), got %d", got)
+	}
+	write(&models.CircuitBreakerInfo{State: "closed"})
+	if got := state(); got != circuitBreakerClosed {
+		t.Errorf("expected closed(1) after recovery, got %d", got)
+	}
+}
+
+func TestSNMPLastConfigReload(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	oid := cfg.EnterpriseOID + ".9.0"
+	if got := pduValueAsUint32(t, snmpOutput.Snapshot().Values[oid]); got != uint32(snmpOutput.startTime.Unix()) {
+		t.Errorf("expected the agent start time before any reload, got %d", got)
+	}
+
+	reloadTime := time.Unix(1_800_000_000, 0)
+	snmpOutput.mu.Lock()
+	snmpOutput.now = func() time.Time { return reloadTime }
+	snmpOutput.mu.Unlock()
+
+	snmpOutput.ReloadSites([]models.SiteDefinition{{Name: "alpha"}})
+	snapshot := snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[oid]); got != uint32(reloadTime.Unix()) {
+		t.Errorf("expected the reload time %d, got %d", reloadTime.Unix(), got)
+	}
+}
+
+func TestRunningStdDev(t *testing.T) {
+	var r runningStdDev
+	if got := r.stdDev(); got != 0 {
+		t.Errorf("expected 0 with no samples, got %v", got)
+	}
+	r.add(5)
+	if got := r.stdDev(); got != 0 {
+		t.Errorf("expected 0 with one sample, got %v", got)
+	}
+
+	// Sum of squared deviations 32 over 8 samples: sample variance 32/7
+	r = runningStdDev{}
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		r.add(x)
+	}
+	if want := math.Sqrt(32.0 / 7); math.Abs(r.stdDev()-want) > 1e-9 {
+		t.Errorf("expected stddev %v, got %v", want, r.stdDev())
+	}
+
+	// A large offset must not cost precision
+	r = runningStdDev{}
+	for _, x := range []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16} {
+		r.add(x)
+	}
+	if want := math.Sqrt(30); math.Abs(r.stdDev()-want) > 1e-6 {
+		t.Errorf("expected stddev %v with a large offset, got %v", want, r.stdDev())
+	}
+}
+
+func TestSNMPJitter(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	// Durations alternate 100ms either side of 200ms (stddev ~100ms) and DNS
+	// 10ms either side of 20ms; TLS is never measured
+	for i := 0; i < 1000; i++ {
+		total, dns := int64(100), int64(10)
+		if i%2 == 1 {
+			total, dns = 300, 30
+		}
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "jittery"},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: total, DNSLookupMs: &dns},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	st := snmpOutput.GetSiteStats("jittery")
+	if st == nil {
+		t.Fatal("expected stats for the site")
+	}
+	if math.Abs(st.StdDevDurationMs-100) > 0.5 {
+		t.Errorf("expected duration stddev ~100ms, got %v", st.StdDevDurationMs)
+	}
+	if math.Abs(st.StdDevDNSMs-10) > 0.05 {
+		t.Errorf("expected DNS stddev ~10ms, got %v", st.StdDevDNSMs)
+	}
+	if st.StdDevTLSMs != 0 {
+		t.Errorf("expected 0 TLS stddev without samples, got %v", st.StdDevTLSMs)
+	}
+
+	snapshot := snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[cfg.EnterpriseOID+".5.1.16"]); got != 100 {
+		t.Errorf("expected stddevDurationMs OID 100, got %d", got)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[cfg.EnterpriseOID+".5.1.17"]); got != 10 {
+		t.Errorf("expected stddevDnsMs OID 10, got %d", got)
+	}
+}
+
+func TestSNMPDurationPercentiles(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:           true,
+		Port:              0,
+		Community:         "public",
+		ListenAddress:     "127.0.0.1",
+		EnterpriseOID:     ".1.3.6.1.4.1.55555",
+		PercentileSamples: 200,
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(ms int64) {
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "tail"},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: ms},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	// A slow tail the median doesn't show: 180 tests of 100-189ms, 14 of
+	// 1000ms and 6 of 5000ms, shuffled deterministically
+	durations := make([]int64, 0, 200)
+	for i := int64(0); i < 180; i++ {
+		durations = append(durations, 100+i/2)
+	}
+	for i := 0; i < 14; i++ {
+		durations = append(durations, 1000)
+	}
+	for i := 0; i < 6; i++ {
+		durations = append(durations, 5000)
+	}
+	for i := range durations {
+		j := (i * 7) % len(durations)
+		durations[i], durations[j] = durations[j], durations[i]
+	}
+	for _, ms := range durations {
+		write(ms)
+	}
+
+	snapshot := snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+	check := func(column int, low, high uint32) {
+		t.Helper()
+		got := pduValueAsUint32(t, snapshot.Values[fmt.Sprintf("%s.14.1.%d.1", cfg.EnterpriseOID, column)])
+		if got < low || got > high {
+			t.Errorf("column %d: expected %d-%d, got %d", column, low, high, got)
+		}
+	}
+	check(25, 140, 150)   // p50
+	check(26, 1000, 1000) // p95
+	check(27, 5000, 5000) // p99
+
+	// Only the last percentile_samples tests count: 200 fast tests push the
+	// outliers out of the window
+	for i := 0; i < 200; i++ {
+		write(50)
+	}
+	snapshot = snmpOutput.Snapshot()
+	check(25, 50, 50)
+	check(27, 50, 50)
+	if st := snmpOutput.GetSiteStats("tail"); st.MaxDurationMs != 5000 || st.P99DurationMs != 50 {
+		t.Errorf("expected the all-time max to keep the outlier but p99 not, got max %d p99 %d", st.MaxDurationMs, st.P99DurationMs)
+	}
+	if n := len(snmpOutput.sites["tail"].stats.durations.samples); n != 200 {
+		t.Errorf("expected the window to hold 200 samples, got %d", n)
+	}
+}
+
+func TestSNMPWindowUptimePercent(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+		SuccessWindow: 10 * time.Minute,
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	// Buckets are 10s wide; start on a bucket boundary
+	start := time.Unix(1_700_000_000, 0)
+	write := func(offset time.Duration, success bool) {
+		result := &models.TestResult{
+			Timestamp: start.Add(offset),
+			Site:      models.SiteInfo{Name: "flaky"},
+			Status:    models.StatusInfo{Success: success},
+			Timings:   models.TimingMetrics{TotalDurationMs: 100},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+	check := func(want uint32) {
+		t.Helper()
+		snapshot := snmpOutput.Snapshot()
+		for _, oid := range []string{
+			fmt.Sprintf("%s.5.1.28", cfg.EnterpriseOID),
+			fmt.Sprintf("%s.14.1.28.1", cfg.EnterpriseOID),
+		} {
+			if got := pduValueAsUint32(t, snapshot.Values[oid]); got != want {
+				t.Errorf("%s: expected %d%%, got %d%%", oid, want, got)
+			}
+		}
+	}
+
+	// One test a minute for 10 minutes, failing at minutes 0-2 and 6
+	for i := 0; i < 10; i++ {
+		write(time.Duration(i)*time.Minute, i > 2 && i != 6)
+	}
+	check(60)
+
+	// A success at 14m30s moves the window to start at 4m40s: minutes 5-9
+	// (one failure) and the new success remain
+	write(14*time.Minute+30*time.Second, true)
+	check(83)
+
+	// A late result from before the window is ignored there, but still counts
+	// toward the all-time uptime
+	write(time.Minute, false)
+	check(83)
+	if st := snmpOutput.GetSiteStats("flaky"); st.TotalTests != 12 || st.FailedTests != 5 {
+		t.Errorf("expected 12 tests with 5 failures, got %d with %d", st.TotalTests, st.FailedTests)
+	}
+
+	// Once every earlier test has left the window only the newest counts
+	write(30*time.Minute, false)
+	check(0)
+}
+
+func TestSNMPLastError(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(errInfo *models.ErrorInfo) {
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "unresolvable.example"},
+			Status:    models.StatusInfo{Success: errInfo == nil},
+			Error:     errInfo,
+			Timings:   models.TimingMetrics{TotalDurationMs: 100},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    cfg.ListenAddress,
+		Port:      uint16(snmpOutput.Port()),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer client.Conn.Close()
+
+	typeOID := fmt.Sprintf("%s.14.1.29.1", cfg.EnterpriseOID)
+	messageOID := fmt.Sprintf("%s.14.1.30.1", cfg.EnterpriseOID)
+	check := func(wantType, wantMessage string) {
+		t.Helper()
+		result, err := client.Get([]string{typeOID, messageOID})
+		if err != nil {
+			t.Fatalf("SNMP get failed: %v", err)
+		}
+		for i, want := range []string{wantType, wantMessage} {
+			pdu := result.Variables[i]
+			if pdu.Type != gosnmp.OctetString {
+				t.Fatalf("%s: expected an OctetString, got %v", pdu.Name, pdu.Type)
+			}
+			if got := string(pdu.Value.([]byte)); got != want {
+				t.Errorf("%s: expected %q, got %q", pdu.Name, want, got)
+			}
+		}
+	}
+
+	write(nil)
+	check("", "")
+
+	write(&models.ErrorInfo{ErrorType: "ERR_NAME_NOT_RESOLVED", ErrorMessage: "net::ERR_NAME_NOT_RESOLVED"})
+	check("ERR_NAME_NOT_RESOLVED", "net::ERR_NAME_NOT_RESOLVED")
+
+	// A success keeps the last error, as it keeps lastFailureTime
+	write(nil)
+	check("ERR_NAME_NOT_RESOLVED", "net::ERR_NAME_NOT_RESOLVED")
+
+	// Long messages are cut to a DisplayString, not mid-rune
+	long := strings.Repeat("é", 200)
+	write(&models.ErrorInfo{ErrorType: "timeout", ErrorMessage: long})
+	check("timeout", long[:254])
+	if st := snmpOutput.GetSiteStats("unresolvable.example"); st.LastErrorType != "timeout" {
+		t.Errorf("expected LastErrorType timeout, got %q", st.LastErrorType)
+	}
+}
+
+func TestSNMPRejectsShortSuccessWindow(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		SuccessWindow: 30 * time.Second,
+	}
+	if _, err := NewSNMPOutput(cfg, "test", nil); err == nil {
+		t.Fatal("expected an error for a success_window under a minute")
+	}
+}
+
+func TestNormalizeOID(t *testing.T) {
+	for _, tc := range []struct {
+		oid  string
+		want string
+		ok   bool
+	}{
+		{"1.3.6.1", ".1.3.6.1", true},
+		{" .1.3.6.1. ", ".1.3.6.1", true},
+		{"", ".", true},
+		{".1.3.4294967295", ".1.3.4294967295", true},
+		{".1.3.foo.6", "", false},
+		{".1.3..6", "", false},
+		{".1.3.-1", "", false},
+		{".1.3.+6", "", false},
+		{".1.3.4294967296", "", false},
+	} {
+		got, err := normalizeOID(tc.oid)
+		if tc.ok && (err != nil || got != tc.want) {
+			t.Errorf("normalizeOID(%q) = %q, %v; want %q", tc.oid, got, err, tc.want)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("normalizeOID(%q) = %q; want an error", tc.oid, got)
+		}
+	}
+}
+
+func TestSNMPMalformedOIDs(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	ask := func(pduType gosnmp.PDUType, oids ...string) *gosnmp.SnmpPacket {
+		request := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, PDUType: pduType, MaxRepetitions: 5}
+		for _, oid := range oids {
+			request.Variables = append(request.Variables, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Null})
+		}
+		response := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, Community: "public", PDUType: gosnmp.GetResponse}
+		snmpOutput.answer(nil, request, response, nil)
+		return response
+	}
+
+	// Get answers the malformed OID noSuchObject and the rest normally,
+	// rather than reading .1.3.6.1.4.1.55555.0.0 (or anything else) for it
+	response := ask(gosnmp.GetRequest, ".1.3.6.1.4.1.55555.foo.0", ".1.3.6.1.4.1.55555.1.0")
+	if response.Error != gosnmp.NoError {
+		t.Fatalf("expected Get to succeed, got %v", response.Error)
+	}
+	if vb := response.Variables[0]; vb.Type != gosnmp.NoSuchObject || vb.Name != ".1.3.6.1.4.1.55555.foo.0" {
+		t.Errorf("expected noSuchObject for the malformed OID, got %v %s", vb.Type, vb.Name)
+	}
+	if vb := response.Variables[1]; vb.Type != gosnmp.Gauge32 {
+		t.Errorf("expected totalSites, got %v", vb.Type)
+	}
+
+	// GetNext and GetBulk have no place to continue from: the request fails
+	// with genErr pointing at the malformed varbind
+	for _, pduType := range []gosnmp.PDUType{gosnmp.GetNextRequest, gosnmp.GetBulkRequest} {
+		response := ask(pduType, ".1.3.6.1.4.1.55555.1.0", ".1.3.foo.6")
+		if response.Error != gosnmp.GenErr || response.ErrorIndex != 2 {
+			t.Errorf("%v: expected genErr at index 2, got %v at %d", pduType, response.Error, response.ErrorIndex)
+		}
+		if len(response.Variables) != 2 || response.Variables[1].Name != ".1.3.foo.6" {
+			t.Errorf("%v: expected the request's varbinds back, got %v", pduType, response.Variables)
+		}
+	}
+
+	// Malformed OIDs in the configuration are rejected up front
+	bad := *cfg
+	bad.EnterpriseOID = ".1.3.6.1.4.1.x"
+	if _, err := NewSNMPOutput(&bad, "test", nil); err == nil {
+		t.Error("expected an error for a malformed enterprise_oid")
+	}
+	bad = *cfg
+	bad.Views = map[string][]string{"public": {".1.3.6.1.4.1.55555.one"}}
+	if _, err := NewSNMPOutput(&bad, "test", nil); err == nil {
+		t.Error("expected an error for a malformed view OID")
+	}
+}
+
+func TestSNMPCachedResultTimes(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+	snmpOutput.maxSize = 3
+
+	oldestOID, newestOID := cfg.EnterpriseOID+".11.0", cfg.EnterpriseOID+".12.0"
+	snapshot := snmpOutput.Snapshot()
+	if got := pduValueAsUint32(t, snapshot.Values[oldestOID]); got != 0 {
+		t.Errorf("expected oldestResultTime 0 with an empty cache, got %d", got)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[newestOID]); got != 0 {
+		t.Errorf("expected newestResultTime 0 with an empty cache, got %d", got)
+	}
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		result := &models.TestResult{
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+			Site:      models.SiteInfo{Name: "example"},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+
+	// The cache holds the last three results: minutes 2 to 4
+	oldest, newest := snmpOutput.CachedResultTimes()
+	if !oldest.Equal(start.Add(2*time.Minute)) || !newest.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("expected the cache to span minutes 2-4, got %v - %v", oldest, newest)
+	}
+
+	snapshot = snmpOutput.Snapshot()
+	if err := VerifyMIBTree(snapshot); err != nil {
+		t.Fatalf("snapshot is not a valid MIB tree: %v", err)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[oldestOID]); got != uint32(oldest.Unix()) {
+		t.Errorf("expected oldestResultTime %d, got %d", oldest.Unix(), got)
+	}
+	if got := pduValueAsUint32(t, snapshot.Values[newestOID]); got != uint32(newest.Unix()) {
+		t.Errorf("expected newestResultTime %d, got %d", newest.Unix(), got)
+	}
+}
+
+func TestSNMPEvictedResultsAreCollectible(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:         true,
+		Port:            0,
+		Community:       "public",
+		ListenAddress:   "127.0.0.1",
+		EnterpriseOID:   ".1.3.6.1.4.1.55555",
+		SiteHistorySize: 1,
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+	snmpOutput.maxSize = 3
+
+	write := func(i int) weak.Pointer[models.TestResult] {
+		result := &models.TestResult{
+			Timestamp: time.Unix(1700000000+int64(i), 0),
+			Site:      models.SiteInfo{Name: "example"},
+			Status:    models.StatusInfo{Success: true},
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+		return weak.Make(result)
+	}
+
+	var written []weak.Pointer[models.TestResult]
+	for i := 0; i < 5; i++ {
+		written = append(written, write(i))
+	}
+	runtime.GC()
+
+	// The first two were evicted from the cache (and the site's history);
+	// the last three are still cached
+	for i, w := range written {
+		if evicted := i < 2; (w.Value() == nil) != evicted {
+			t.Errorf("result %d: collected = %v, want %v", i, w.Value() == nil, evicted)
+		}
+	}
+	if n := len(snmpOutput.GetCachedResults()); n != 3 {
+		t.Errorf("expected 3 cached results, got %d", n)
+	}
+}
+
+func TestSNMPWriteBatchMatchesIndividualWrites(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+
+	individual, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer individual.Close()
+	batched, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer batched.Close()
+
+	// More results than the cache holds, so the batch must trim it too
+	start := time.Now()
+	results := make([]*models.TestResult, 150)
+	for i := range results {
+		results[i] = &models.TestResult{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Site:      models.SiteInfo{Name: fmt.Sprintf("site-%d", i%3)},
+			Status:    models.StatusInfo{Success: i%4 != 0},
+			Timings:   models.TimingMetrics{TotalDurationMs: int64(100 + i%7*10)},
+		}
+		if err := individual.Write(results[i]); err != nil {
+			t.Fatalf("failed to write result: %v", err)
+		}
+	}
+	if err := batched.WriteBatch(results); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+
+	if got := batched.cacheLen(); got != 100 {
+		t.Errorf("expected the cache trimmed to 100 results, got %d", got)
+	}
+	if oldest, newest := batched.CachedResultTimes(); !oldest.Equal(results[50].Timestamp) || !newest.Equal(results[149].Timestamp) {
+		t.Errorf("expected the newest 100 results cached, got %v..%v", oldest, newest)
+	}
+
+	want := individual.GetAllStatsSnapshot()
+	got := batched.GetAllStatsSnapshot()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d sites, got %d", len(want), len(got))
+	}
+	for name, w := range want {
+		g := got[name]
+		if g.TotalTests != w.TotalTests || g.SuccessfulTests != w.SuccessfulTests ||
+			g.MinDurationMs != w.MinDurationMs || g.MaxDurationMs != w.MaxDurationMs ||
+			g.AvgDurationMs != w.AvgDurationMs || g.EWMADurationMs != w.EWMADurationMs ||
+			g.StdDevDurationMs != w.StdDevDurationMs || !g.LastFailureTime.Equal(w.LastFailureTime) {
+			t.Errorf("%s: batched stats %+v differ from individual stats %+v", name, g, w)
+		}
+		if batched.siteIndex[name] != individual.siteIndex[name] {
+			t.Errorf("%s: expected index %d, got %d", name, individual.siteIndex[name], batched.siteIndex[name])
+		}
+	}
+
+	if err := batched.WriteBatch(nil); err != nil {
+		t.Errorf("expected an empty batch to be a no-op, got %v", err)
+	}
+}
+
+func TestSNMPWriteBatchIsAtomic(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	// Every batch holds one result for each site, so any snapshot taken
+	// between batches shows both sites with the same number of tests
+	batch := []*models.TestResult{
+		{Timestamp: time.Now(), Site: models.SiteInfo{Name: "a"}, Status: models.StatusInfo{Success: true}},
+		{Timestamp: time.Now(), Site: models.SiteInfo{Name: "b"}, Status: models.StatusInfo{Success: true}},
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			snapshots := snmpOutput.GetAllStatsSnapshot()
+			if snapshots["a"].TotalTests != snapshots["b"].TotalTests {
+				t.Errorf("snapshot reflects half a batch: a=%d b=%d", snapshots["a"].TotalTests, snapshots["b"].TotalTests)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		snmpOutput.WriteBatch(batch)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// BenchmarkSNMPWriteBatch compares applying results one Write at a time with
+// a single WriteBatch of the same results.
+func BenchmarkSNMPWriteBatch(b *testing.B) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		b.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	results := make([]*models.TestResult, 256)
+	for i := range results {
+		results[i] = &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: fmt.Sprintf("site-%d", i%16)},
+			Status:    models.StatusInfo{Success: true},
+			Timings:   models.TimingMetrics{TotalDurationMs: int64(i)},
+		}
+	}
+
+	b.Run("individual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, r := range results {
+				snmpOutput.Write(r)
+			}
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			snmpOutput.WriteBatch(results)
+		}
+	})
+}
+
+func TestSNMPCounter64SiteTotals(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	snmpOutput.Write(&models.TestResult{
+		Timestamp: time.Now(),
+		Site:      models.SiteInfo{Name: "example.com", URL: "https://example.com"},
+		Status:    models.StatusInfo{Success: true},
+	})
+
+	// A long-running monitor past 2^32 tests
+	const total = int64(1)<<32 + 5
+	site := snmpOutput.sites["example.com"]
+	site.mu.Lock()
+	site.stats.TotalTests = total
+	site.stats.SuccessfulTests = total - 2
+	site.stats.FailedTests = 2
+	site.mu.Unlock()
+
+	base := ".1.3.6.1.4.1.55555.5.1"
+	connect := func(version gosnmp.SnmpVersion) *gosnmp.GoSNMP {
+		c := &gosnmp.GoSNMP{
+			Target:    cfg.ListenAddress,
+			Port:      uint16(snmpOutput.Port()),
+			Community: cfg.Community,
+			Version:   version,
+			Timeout:   time.Second,
+			Retries:   1,
+		}
+		if err := c.Connect(); err != nil {
+			t.Fatalf("failed to connect SNMP client: %v", err)
+		}
+		t.Cleanup(func() { c.Conn.Close() })
+		return c
+	}
+
+	packet, err := connect(gosnmp.Version2c).Get([]string{base + ".2", base + ".21", base + ".22", base + ".23"})
+	if err != nil {
+		t.Fatalf("snmp get failed: %v", err)
+	}
+	if got := pduValueAsUint32(t, packet.Variables[0]); got != 5 {
+		t.Fatalf("expected the Counter32 total to wrap to 5, got %d", got)
+	}
+	want := []uint64{uint64(total), uint64(total - 2), 2}
+	for i, w := range want {
+		v := packet.Variables[i+1]
+		if v.Type != gosnmp.Counter64 {
+			t.Fatalf("%s: expected Counter64, got %v", v.Name, v.Type)
+		}
+		if got := gosnmp.ToBigInt(v.Value).Uint64(); got != w {
+			t.Fatalf("%s: expected %d, got %d", v.Name, w, got)
+		}
+	}
+
+	// SNMPv1 has no Counter64, so v1 walks skip the columns
+	packet, err = connect(gosnmp.Version1).GetNext([]string{base + ".20"})
+	if err != nil {
+		t.Fatalf("v1 getnext failed: %v", err)
+	}
+	if name := packet.Variables[0].Name; name != ".1.3.6.1.4.1.55555.5.1.24" {
+		t.Fatalf("expected v1 to skip the Counter64 columns to .5.1.24, got %s", name)
+	}
+}
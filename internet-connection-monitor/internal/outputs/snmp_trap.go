@@ -0,0 +1,257 @@
+package outputs
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+// Notifications (traps)
+//
+// With trap_targets configured the agent notifies each target when a site
+// goes down (a failure right after a success) and when it comes back up, and
+// whenever SendTrap is called. Notifications are SNMPv2c traps with the trap
+// community; when SNMPv3 is configured they are v3 informs from the v3 user
+// instead, which the receiver acknowledges. Each carries sysUpTime.0,
+// snmpTrapOID.0 (<base>.0.<id> from mibNotifications), the site's siteName
+// instance in siteTable when there is a site, and lastTrapMessage.0, matching
+// the OBJECTS of the NOTIFICATION-TYPEs in the exported MIB.
+//
+// Sending happens on a background worker so a slow or unreachable receiver
+// never blocks Write; when the queue is full notifications are dropped.
+
+const (
+	snmpTrapOIDOID = ".1.3.6.1.6.3.1.1.4.1.0"
+
+	defaultTrapPort   = 162
+	trapQueueSize     = 100
+	trapSendTimeout   = 2 * time.Second
+	trapInformRetries = 1
+)
+
+// notificationsID is the sub-OID notifications are defined under
+// (<base>.0.<id>, the SMIv2 convention)
+const notificationsID = 0
+
+// mibNotifications lists the notifications sent to trap targets
+var mibNotifications = []mibObject{
+	{1, "siteDown", gosnmp.ObjectIdentifier, "A site failed after its previous test succeeded"},
+	{2, "siteUp", gosnmp.ObjectIdentifier, "A site succeeded after its previous test failed"},
+	{3, "monitorAlert", gosnmp.ObjectIdentifier, "An alert raised through SendTrap"},
+}
+
+// notification is a queued trap
+type notification struct {
+	id        int    // mibNotifications ID
+	siteName  string // empty for notifications not about a site
+	siteIndex int
+	message   string
+	uptime    time.Duration
+}
+
+// trapSender delivers notifications to the configured targets
+type trapSender struct {
+	targets []*gosnmp.GoSNMP
+	queue   chan notification
+
+	mu          sync.Mutex
+	lastMessage string
+}
+
+// newTrapSender connects a client per target; nil when no targets are configured
+func newTrapSender(cfg *config.SNMPConfig, usm *usmAgent) (*trapSender, error) {
+	if len(cfg.TrapTargets) == 0 {
+		return nil, nil
+	}
+
+	community := cfg.TrapCommunity
+	if community == "" {
+		community = cfg.Community
+	}
+
+	t := &trapSender{queue: make(chan notification, trapQueueSize)}
+	for _, target := range cfg.TrapTargets {
+		host, port, err := splitTrapTarget(target)
+		if err != nil {
+			t.close()
+			return nil, err
+		}
+		client := &gosnmp.GoSNMP{
+			Target:    host,
+			Port:      port,
+			Version:   gosnmp.Version2c,
+			Community: community,
+			Timeout:   trapSendTimeout,
+			Retries:   trapInformRetries,
+		}
+		if usm != nil {
+			// Informs are addressed to the receiver's engine, which the
+			// client discovers; only the user's credentials are ours
+			client.Version = gosnmp.Version3
+			client.SecurityModel = gosnmp.UserSecurityModel
+			client.MsgFlags = usm.level
+			client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+				UserName:                 usm.params.UserName,
+				AuthenticationProtocol:   usm.params.AuthenticationProtocol,
+				AuthenticationPassphrase: usm.params.AuthenticationPassphrase,
+				PrivacyProtocol:          usm.params.PrivacyProtocol,
+				PrivacyPassphrase:        usm.params.PrivacyPassphrase,
+			}
+		}
+		if err := client.Connect(); err != nil {
+			t.close()
+			return nil, fmt.Errorf("SNMP trap target %s: %w", target, err)
+		}
+		t.targets = append(t.targets, client)
+	}
+	return t, nil
+}
+
+// splitTrapTarget parses "host" or "host:port" (IPv6 as "[addr]:port")
+func splitTrapTarget(target string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		// No port given
+		return target, defaultTrapPort, nil
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", 0, fmt.Errorf("invalid SNMP trap target port in %q", target)
+	}
+	return host, uint16(port), nil
+}
+
+// enqueue queues n for sending without blocking
+func (t *trapSender) enqueue(n notification) {
+	t.mu.Lock()
+	t.lastMessage = n.message
+	t.mu.Unlock()
+
+	select {
+	case t.queue <- n:
+	default:
+		log.Printf("Warning: SNMP trap queue is full, dropping %s notification", notificationName(n.id))
+	}
+}
+
+// lastTrapMessage is the message of the most recent notification
+func (t *trapSender) lastTrapMessage() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastMessage
+}
+
+// run sends queued notifications until done is closed
+func (t *trapSender) run(base string, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case n := <-t.queue:
+			trap := gosnmp.SnmpTrap{
+				Variables: notificationVarbinds(base, n),
+				IsInform:  t.targets[0].Version == gosnmp.Version3,
+			}
+			for _, client := range t.targets {
+				if _, err := client.SendTrap(trap); err != nil {
+					log.Printf("SNMP trap %s to %s:%d failed: %v", notificationName(n.id), client.Target, client.Port, err)
+				}
+			}
+		}
+	}
+}
+
+func (t *trapSender) close() {
+	if t == nil {
+		return
+	}
+	for _, client := range t.targets {
+		if client.Conn != nil {
+			client.Conn.Close()
+		}
+	}
+}
+
+// notificationVarbinds returns the varbinds of a notification's PDU
+func notificationVarbinds(base string, n notification) []gosnmp.SnmpPDU {
+	vars := []gosnmp.SnmpPDU{
+		sysUpTimePDU(n.uptime),
+		{Name: snmpTrapOIDOID, Type: gosnmp.ObjectIdentifier, Value: fmt.Sprintf("%s.%d.%d", base, notificationsID, n.id)},
+	}
+	if n.siteName != "" {
+		vars = append(vars, octetStringPDU(fmt.Sprintf("%s.%d.1.1.%d", base, siteEntryTableID, n.siteIndex), n.siteName))
+	}
+	return append(vars, octetStringPDU(fmt.Sprintf("%s.%d.0", base, lastTrapMessageID), n.message))
+}
+
+func notificationName(id int) string {
+	for _, n := range mibNotifications {
+		if n.ID == id {
+			return n.Name
+		}
+	}
+	return strconv.Itoa(id)
+}
+
+func notificationID(name string) (int, bool) {
+	for _, n := range mibNotifications {
+		if n.Name == name {
+			return n.ID, true
+		}
+	}
+	return 0, false
+}
+
+// SendTrap notifies the trap targets. trapType names a notification from
+// mibNotifications (siteDown, siteUp, monitorAlert); other types are sent as
+// monitorAlert with the type prefixed to the message. It does nothing
+// without trap targets.
+func (s *SNMPOutput) SendTrap(trapType string, message string) error {
+	if s == nil || s.traps == nil {
+		return nil
+	}
+
+	id, ok := notificationID(trapType)
+	if !ok {
+		id, _ = notificationID("monitorAlert")
+		message = trapType + ": " + message
+	}
+	s.traps.enqueue(notification{id: id, message: message, uptime: s.now().Sub(s.startTime)})
+	s.invalidateSnapshot()
+	return nil
+}
+
+// notifyTransition queues siteDown or siteUp when a site's result differs
+// from its previous one. The caller holds mu (in either mode) and site.mu.
+func (s *SNMPOutput) notifyTransition(siteName string, wasUp, isUp bool, errorType string) {
+	if s.traps == nil || wasUp == isUp {
+		return
+	}
+
+	n := notification{
+		siteName:  siteName,
+		siteIndex: s.siteIndex[siteName],
+		uptime:    s.now().Sub(s.startTime),
+	}
+	if isUp {
+		n.id, _ = notificationID("siteUp")
+		n.message = fmt.Sprintf("%s is up", siteName)
+	} else {
+		n.id, _ = notificationID("siteDown")
+		n.message = fmt.Sprintf("%s is down", siteName)
+		if errorType != "" {
+			n.message += ": " + errorType
+		}
+	}
+	s.traps.enqueue(n)
+}
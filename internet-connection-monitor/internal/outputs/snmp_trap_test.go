@@ -0,0 +1,256 @@
+package outputs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// trapReceiver is a UDP listener decoding the SNMPv2c traps sent to it
+type trapReceiver struct {
+	conn *net.UDPConn
+}
+
+func newTrapReceiver(t *testing.T) *trapReceiver {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen for traps: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &trapReceiver{conn: conn}
+}
+
+func (r *trapReceiver) addr() string {
+	return r.conn.LocalAddr().String()
+}
+
+// next returns the next trap, or nil if none arrives within timeout
+func (r *trapReceiver) next(t *testing.T, timeout time.Duration) *gosnmp.SnmpPacket {
+	t.Helper()
+	buf := make([]byte, 65535)
+	r.conn.SetReadDeadline(time.Now().Add(timeout))
+	n, _, err := r.conn.ReadFromUDP(buf)
+	if err != nil {
+		return nil
+	}
+	packet, err := gosnmp.Default.SnmpDecodePacket(buf[:n])
+	if err != nil {
+		t.Fatalf("failed to decode trap: %v", err)
+	}
+	return packet
+}
+
+// trapVarbinds maps a trap's varbinds by OID, with OID values and strings
+// as strings
+func trapVarbinds(packet *gosnmp.SnmpPacket) map[string]interface{} {
+	vars := make(map[string]interface{}, len(packet.Variables))
+	for _, v := range packet.Variables {
+		switch val := v.Value.(type) {
+		case []byte:
+			vars[v.Name] = string(val)
+		default:
+			vars[v.Name] = val
+		}
+	}
+	return vars
+}
+
+func TestSNMPTrapsOnSiteTransitions(t *testing.T) {
+	receiver := newTrapReceiver(t)
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		TrapCommunity: "traps",
+		TrapTargets:   []string{receiver.addr()},
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", []models.SiteDefinition{{Name: "a.example", URL: "https://a.example"}, {Name: "b.example", URL: "https://b.example"}})
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	write := func(site string, success bool) {
+		result := &models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: site, URL: "https://" + site},
+			Status:    models.StatusInfo{Success: success},
+		}
+		if !success {
+			result.Error = &models.ErrorInfo{ErrorType: "ERR_CONNECTION_REFUSED"}
+		}
+		if err := snmpOutput.Write(result); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	// A site's first result is not a transition, nor is a repeat
+	write("b.example", false)
+	write("b.example", true)
+	write("b.example", true)
+
+	trap := receiver.next(t, 2*time.Second)
+	if trap == nil {
+		t.Fatal("expected a siteUp trap for b.example")
+	}
+	if trap.Version != gosnmp.Version2c || trap.PDUType != gosnmp.SNMPv2Trap || trap.Community != "traps" {
+		t.Fatalf("expected a v2c trap with the trap community, got %v %v %q", trap.Version, trap.PDUType, trap.Community)
+	}
+	vars := trapVarbinds(trap)
+	if got := vars[snmpTrapOIDOID]; got != ".1.3.6.1.4.1.55555.0.2" {
+		t.Fatalf("expected snmpTrapOID siteUp (.0.2), got %v", got)
+	}
+	if got := vars[".1.3.6.1.4.1.55555.14.1.1.2"]; got != "b.example" {
+		t.Fatalf("expected the siteName varbind for index 2, got %v (all: %v)", got, vars)
+	}
+	if got := vars[".1.3.6.1.4.1.55555.13.0"]; got != "b.example is up" {
+		t.Fatalf("expected the message varbind, got %v", got)
+	}
+	if _, ok := vars[systemGroupOID+".3.0"]; !ok || trap.Variables[0].Name != systemGroupOID+".3.0" {
+		t.Fatalf("expected sysUpTime.0 as the first varbind, got %v", trap.Variables)
+	}
+
+	write("b.example", false)
+	trap = receiver.next(t, 2*time.Second)
+	if trap == nil {
+		t.Fatal("expected a siteDown trap for b.example")
+	}
+	vars = trapVarbinds(trap)
+	if got := vars[snmpTrapOIDOID]; got != ".1.3.6.1.4.1.55555.0.1" {
+		t.Fatalf("expected snmpTrapOID siteDown (.0.1), got %v", got)
+	}
+	if got := vars[".1.3.6.1.4.1.55555.13.0"]; got != "b.example is down: ERR_CONNECTION_REFUSED" {
+		t.Fatalf("expected the failure in the message, got %v", got)
+	}
+
+	if err := snmpOutput.SendTrap("diskFull", "results volume is full"); err != nil {
+		t.Fatalf("SendTrap failed: %v", err)
+	}
+	trap = receiver.next(t, 2*time.Second)
+	if trap == nil {
+		t.Fatal("expected a monitorAlert trap")
+	}
+	vars = trapVarbinds(trap)
+	if got := vars[snmpTrapOIDOID]; got != ".1.3.6.1.4.1.55555.0.3" {
+		t.Fatalf("expected snmpTrapOID monitorAlert (.0.3), got %v", got)
+	}
+	if got := vars[".1.3.6.1.4.1.55555.13.0"]; got != "diskFull: results volume is full" {
+		t.Fatalf("expected the alert message, got %v", got)
+	}
+	if len(trap.Variables) != 3 {
+		t.Fatalf("expected no siteName varbind on an alert, got %v", trap.Variables)
+	}
+
+	if extra := receiver.next(t, 200*time.Millisecond); extra != nil {
+		t.Fatalf("expected no further traps, got %v", trapVarbinds(extra))
+	}
+
+	// The last message is also readable as a scalar
+	_, values := snmpOutput.buildOIDSnapshot()
+	if got := string(values[".1.3.6.1.4.1.55555.13.0"].Value.([]byte)); got != "diskFull: results volume is full" {
+		t.Fatalf("expected lastTrapMessage.0 to hold the alert, got %q", got)
+	}
+}
+
+func TestSNMPv3InformsOnSiteTransitions(t *testing.T) {
+	// The listener turns an inform into its response after the callback, so
+	// keep a copy
+	received := make(chan gosnmp.SnmpPacket, 4)
+	listener := gosnmp.NewTrapListener()
+	listener.OnNewTrap = func(packet *gosnmp.SnmpPacket, _ *net.UDPAddr) {
+		received <- *packet
+	}
+	listener.Params = &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID:    "\x80\x00\xd9\x03\x05receiver",
+			UserName:                 "monitor",
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "auth-secret",
+			PrivacyProtocol:          gosnmp.AES,
+			PrivacyPassphrase:        "priv-secret",
+		},
+		Logger: gosnmp.NewLogger(nil),
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+	go listener.Listen(addr)
+	defer listener.Close()
+	select {
+	case <-listener.Listening():
+	case <-time.After(2 * time.Second):
+		t.Fatal("trap listener did not start")
+	}
+
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  ".1.3.6.1.4.1.55555",
+		TrapTargets:    []string{addr},
+		SecurityLevel:  "authPriv",
+		Username:       "monitor",
+		AuthPassphrase: "auth-secret",
+		PrivPassphrase: "priv-secret",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	for _, success := range []bool{true, false} {
+		snmpOutput.Write(&models.TestResult{
+			Timestamp: time.Now(),
+			Site:      models.SiteInfo{Name: "example.com", URL: "https://example.com"},
+			Status:    models.StatusInfo{Success: success},
+		})
+	}
+
+	select {
+	case packet := <-received:
+		if packet.Version != gosnmp.Version3 || packet.PDUType != gosnmp.InformRequest {
+			t.Fatalf("expected a v3 inform, got %v %v", packet.Version, packet.PDUType)
+		}
+		if packet.MsgFlags&gosnmp.AuthPriv != gosnmp.AuthPriv {
+			t.Fatalf("expected an authPriv inform, got flags %v", packet.MsgFlags)
+		}
+		vars := trapVarbinds(&packet)
+		if got := vars[snmpTrapOIDOID]; got != ".1.3.6.1.4.1.55555.0.1" {
+			t.Fatalf("expected snmpTrapOID siteDown, got %v", got)
+		}
+		if got := vars[".1.3.6.1.4.1.55555.14.1.1.1"]; got != "example.com" {
+			t.Fatalf("expected the siteName varbind, got %v", vars)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a siteDown inform")
+	}
+}
+
+func TestSNMPRejectsInvalidTrapTarget(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		TrapTargets:   []string{"127.0.0.1:notaport"},
+	}
+	if snmpOutput, err := NewSNMPOutput(cfg, "test", nil); err == nil {
+		snmpOutput.Close()
+		t.Fatal("expected an error for a trap target with an invalid port")
+	}
+}
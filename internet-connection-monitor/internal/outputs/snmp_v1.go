@@ -0,0 +1,46 @@
+package outputs
+
+import "github.com/gosnmp/gosnmp"
+
+// SNMPv1 errors
+//
+// SNMPv2c reports a missing object in-band, as a noSuchObject,
+// noSuchInstance or endOfMibView value for that varbind. SNMPv1 has no such
+// values: the whole request fails with noSuchName and the error index of the
+// first varbind that failed. v1 also lacks most SNMPv2 error statuses, which
+// are mapped as RFC 3584 section 4.4 describes. An errored v1 response
+// carries the request's varbinds unchanged.
+
+// toV1Response rewrites a response built for SNMPv2c into its SNMPv1 form
+func toV1Response(request, response *gosnmp.SnmpPacket) {
+	if response.Error != gosnmp.NoError {
+		response.Error = v1ErrorStatus(response.Error)
+		response.Variables = request.Variables
+		return
+	}
+
+	for i, vb := range response.Variables {
+		switch vb.Type {
+		case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+			response.Error = gosnmp.NoSuchName
+			response.ErrorIndex = uint8(i + 1)
+			response.Variables = request.Variables
+			return
+		}
+	}
+}
+
+// v1ErrorStatus maps an SNMPv2 error status to SNMPv1 (RFC 3584 4.4)
+func v1ErrorStatus(status gosnmp.SNMPError) gosnmp.SNMPError {
+	switch status {
+	case gosnmp.NoError, gosnmp.TooBig, gosnmp.NoSuchName, gosnmp.BadValue, gosnmp.ReadOnly, gosnmp.GenErr:
+		return status
+	case gosnmp.WrongValue, gosnmp.WrongEncoding, gosnmp.WrongType, gosnmp.WrongLength, gosnmp.InconsistentValue:
+		return gosnmp.BadValue
+	case gosnmp.NoAccess, gosnmp.NotWritable, gosnmp.NoCreation, gosnmp.InconsistentName, gosnmp.AuthorizationError:
+		return gosnmp.NoSuchName
+	default:
+		// resourceUnavailable, commitFailed, undoFailed
+		return gosnmp.GenErr
+	}
+}
@@ -0,0 +1,115 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func TestSNMPv1ErrorStatus(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		Community:      "public",
+		WriteCommunity: "private",
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	snmpOutput.Write(&models.TestResult{
+		Timestamp: time.Now(),
+		Site:      models.SiteInfo{Name: "example.com"},
+		Status:    models.StatusInfo{Success: true},
+	})
+
+	connect := func(version gosnmp.SnmpVersion, community string) *gosnmp.GoSNMP {
+		c := &gosnmp.GoSNMP{
+			Target:    cfg.ListenAddress,
+			Port:      uint16(snmpOutput.Port()),
+			Community: community,
+			Version:   version,
+			Timeout:   time.Second,
+			Retries:   1,
+		}
+		if err := c.Connect(); err != nil {
+			t.Fatalf("failed to connect SNMP client: %v", err)
+		}
+		t.Cleanup(func() { c.Conn.Close() })
+		return c
+	}
+	v1 := connect(gosnmp.Version1, "public")
+
+	base := cfg.EnterpriseOID
+	missing := base + ".5.1.99"
+	requested := []string{base + ".1.0", missing, base + ".2.0"}
+
+	// v1 fails the whole Get at the first missing object
+	packet, err := v1.Get(requested)
+	if err != nil {
+		t.Fatalf("v1 get failed: %v", err)
+	}
+	if packet.Error != gosnmp.NoSuchName || packet.ErrorIndex != 2 {
+		t.Errorf("expected noSuchName at index 2, got %v at %d", packet.Error, packet.ErrorIndex)
+	}
+	if len(packet.Variables) != len(requested) {
+		t.Fatalf("expected the request's %d varbinds echoed, got %d", len(requested), len(packet.Variables))
+	}
+	for i, vb := range packet.Variables {
+		if vb.Name != requested[i] || vb.Type != gosnmp.Null {
+			t.Errorf("varbind %d: expected %s = NULL, got %s (%v)", i+1, requested[i], vb.Name, vb.Type)
+		}
+	}
+
+	// v2c reports the same object in-band
+	packet, err = connect(gosnmp.Version2c, "public").Get(requested)
+	if err != nil {
+		t.Fatalf("v2c get failed: %v", err)
+	}
+	if packet.Error != gosnmp.NoError || packet.ErrorIndex != 0 {
+		t.Errorf("expected no error status from v2c, got %v at %d", packet.Error, packet.ErrorIndex)
+	}
+	if packet.Variables[1].Type != gosnmp.NoSuchObject {
+		t.Errorf("expected v2c noSuchObject for %s, got %v", missing, packet.Variables[1].Type)
+	}
+
+	// GetNext past the last object is noSuchName, not endOfMibView
+	snapshot := snmpOutput.Snapshot()
+	last := snapshot.OIDs[len(snapshot.OIDs)-1]
+	packet, err = v1.GetNext([]string{base + ".1.0", last})
+	if err != nil {
+		t.Fatalf("v1 getnext failed: %v", err)
+	}
+	if packet.Error != gosnmp.NoSuchName || packet.ErrorIndex != 2 {
+		t.Errorf("expected noSuchName at index 2 past the end of the MIB, got %v at %d", packet.Error, packet.ErrorIndex)
+	}
+
+	// SNMPv2 SET errors map to their v1 equivalents
+	for _, tc := range []struct {
+		name      string
+		community string
+		pdu       gosnmp.SnmpPDU
+		want      gosnmp.SNMPError
+	}{
+		{"read community", "public", gosnmp.SnmpPDU{Name: base + ".6.0", Type: gosnmp.Integer, Value: 1}, gosnmp.NoSuchName},
+		{"read-only object", "private", gosnmp.SnmpPDU{Name: base + ".1.0", Type: gosnmp.Integer, Value: 1}, gosnmp.NoSuchName},
+		{"wrong type", "private", gosnmp.SnmpPDU{Name: base + ".6.0", Type: gosnmp.OctetString, Value: "1"}, gosnmp.BadValue},
+		{"wrong value", "private", gosnmp.SnmpPDU{Name: base + ".6.0", Type: gosnmp.Integer, Value: 2}, gosnmp.BadValue},
+	} {
+		packet, err := connect(gosnmp.Version1, tc.community).Set([]gosnmp.SnmpPDU{tc.pdu})
+		if err != nil {
+			t.Fatalf("%s: v1 set failed: %v", tc.name, err)
+		}
+		if packet.Error != tc.want || packet.ErrorIndex != 1 {
+			t.Errorf("%s: expected %v at index 1, got %v at %d", tc.name, tc.want, packet.Error, packet.ErrorIndex)
+		}
+	}
+}
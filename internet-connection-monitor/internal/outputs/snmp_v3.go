@@ -0,0 +1,317 @@
+package outputs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+)
+
+// SNMPv3 (User-based Security Model, RFC 3414)
+//
+// When SNMPConfig.SecurityLevel is set the agent serves a single v3 user and
+// no longer accepts community strings. gosnmp does the cryptography: inbound
+// messages are authenticated and decrypted with the user's keys localized to
+// our engine ID, and responses are encrypted and signed by MarshalMsg.
+//
+// Managers learn the engine ID, boots and time through discovery: a
+// noAuthNoPriv request with an empty engine ID is answered with a
+// usmStatsUnknownEngineIDs report carrying them. The engine ID is random per
+// process, so engine boots can stay 1. Authenticated requests outside the
+// 150 second time window get a usmStatsNotInTimeWindows report; anything else
+// that doesn't match the configured user (name, security level, digest) is
+// dropped.
+
+const (
+	usmStatsNotInTimeWindowsOID = ".1.3.6.1.6.3.15.1.1.2.0"
+	usmStatsUnknownEngineIDsOID = ".1.3.6.1.6.3.15.1.1.4.0"
+
+	// usmTimeWindow is how far a request's engine time may be from ours
+	usmTimeWindow = 150
+
+	// minUSMPassphraseLength is the shortest passphrase RFC 3414 allows
+	minUSMPassphraseLength = 8
+)
+
+// errUnknownEngineID marks a v3 request addressed to another engine ID
+// (including discovery, which leaves it empty)
+var errUnknownEngineID = errors.New("unknown engine ID")
+
+// errNotInTimeWindow marks an authenticated v3 request with stale engine time
+var errNotInTimeWindow = errors.New("not in time window")
+
+// usmAgent holds the v3 user's security parameters and our engine identity
+type usmAgent struct {
+	level    gosnmp.SnmpV3MsgFlags
+	username string
+	engineID string
+	boots    uint32
+	start    time.Time
+
+	// params is the user with keys localized to engineID; responses use a
+	// copy, and it allocates their privacy salts
+	params *gosnmp.UsmSecurityParameters
+
+	unknownEngineIDs atomic.Uint32
+	notInTimeWindows atomic.Uint32
+}
+
+// newUSMAgent validates the v3 settings; nil when v3 is not configured
+func newUSMAgent(cfg *config.SNMPConfig, start time.Time) (*usmAgent, error) {
+	if cfg.SecurityLevel == "" {
+		return nil, nil
+	}
+
+	var level gosnmp.SnmpV3MsgFlags
+	switch strings.ToLower(cfg.SecurityLevel) {
+	case "noauthnopriv":
+		level = gosnmp.NoAuthNoPriv
+	case "authnopriv":
+		level = gosnmp.AuthNoPriv
+	case "authpriv":
+		level = gosnmp.AuthPriv
+	default:
+		return nil, fmt.Errorf("SNMP security_level must be noAuthNoPriv, authNoPriv or authPriv, got %q", cfg.SecurityLevel)
+	}
+	if cfg.Username == "" {
+		return nil, fmt.Errorf("SNMPv3 requires a username")
+	}
+
+	params := &gosnmp.UsmSecurityParameters{
+		UserName:               cfg.Username,
+		AuthenticationProtocol: gosnmp.NoAuth,
+		PrivacyProtocol:        gosnmp.NoPriv,
+	}
+
+	if level&gosnmp.AuthNoPriv != 0 {
+		auth, err := parseAuthProtocol(cfg.AuthProtocol)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.AuthPassphrase) < minUSMPassphraseLength {
+			return nil, fmt.Errorf("SNMPv3 auth_passphrase must be at least %d characters", minUSMPassphraseLength)
+		}
+		params.AuthenticationProtocol = auth
+		params.AuthenticationPassphrase = cfg.AuthPassphrase
+	}
+
+	if level&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		priv, err := parsePrivProtocol(cfg.PrivProtocol)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.PrivPassphrase) < minUSMPassphraseLength {
+			return nil, fmt.Errorf("SNMPv3 priv_passphrase must be at least %d characters", minUSMPassphraseLength)
+		}
+		params.PrivacyProtocol = priv
+		params.PrivacyPassphrase = cfg.PrivPassphrase
+	}
+
+	engineID, err := newEngineID(cfg.EnterpriseOID)
+	if err != nil {
+		return nil, err
+	}
+	params.AuthoritativeEngineID = engineID
+	if err := params.InitSecurityKeys(); err != nil {
+		return nil, fmt.Errorf("SNMPv3 key localization: %w", err)
+	}
+
+	return &usmAgent{
+		level:    level,
+		username: cfg.Username,
+		engineID: engineID,
+		boots:    1,
+		start:    start,
+		params:   params,
+	}, nil
+}
+
+func parseAuthProtocol(name string) (gosnmp.SnmpV3AuthProtocol, error) {
+	switch strings.ToUpper(name) {
+	case "", "SHA":
+		return gosnmp.SHA, nil
+	case "MD5":
+		return gosnmp.MD5, nil
+	case "SHA224":
+		return gosnmp.SHA224, nil
+	case "SHA256":
+		return gosnmp.SHA256, nil
+	case "SHA384":
+		return gosnmp.SHA384, nil
+	case "SHA512":
+		return gosnmp.SHA512, nil
+	}
+	return gosnmp.NoAuth, fmt.Errorf("unsupported SNMP auth_protocol %q", name)
+}
+
+func parsePrivProtocol(name string) (gosnmp.SnmpV3PrivProtocol, error) {
+	switch strings.ToUpper(name) {
+	case "", "AES":
+		return gosnmp.AES, nil
+	case "DES":
+		return gosnmp.DES, nil
+	case "AES192":
+		return gosnmp.AES192, nil
+	case "AES256":
+		return gosnmp.AES256, nil
+	}
+	return gosnmp.NoPriv, fmt.Errorf("unsupported SNMP priv_protocol %q", name)
+}
+
+// newEngineID returns a random engine ID in the RFC 3411 format: our
+// enterprise number with the high bit set, format 5 (octets), 8 random bytes
+func newEngineID(enterpriseOID string) (string, error) {
+	base := enterpriseBaseOID(enterpriseOID)
+	enterprise, err := strconv.ParseUint(base[strings.LastIndex(base, ".")+1:], 10, 31)
+	if err != nil {
+		return "", fmt.Errorf("SNMP enterprise OID %q does not end in an enterprise number", enterpriseOID)
+	}
+
+	id := make([]byte, 13)
+	binary.BigEndian.PutUint32(id, uint32(enterprise)|0x80000000)
+	id[4] = 5
+	if _, err := rand.Read(id[5:]); err != nil {
+		return "", fmt.Errorf("generate SNMP engine ID: %w", err)
+	}
+	return string(id), nil
+}
+
+// engineTime is the engine time to report at now
+func (u *usmAgent) engineTime(now time.Time) uint32 {
+	return uint32(now.Sub(u.start) / time.Second)
+}
+
+// decode authenticates and decrypts a v3 message. Besides decoding errors,
+// it returns errUnknownEngineID and errNotInTimeWindow (with the request)
+// for messages that deserve a report rather than silence.
+func (u *usmAgent) decode(message []byte, now time.Time) (*gosnmp.SnmpPacket, error) {
+	x := &gosnmp.GoSNMP{
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: u.params,
+	}
+
+	// Verifying the digest zeroes it in place; leave the caller's bytes alone.
+	// With response parameters, gosnmp authenticates using the message's flags
+	// and our keys, relocalizing them if the message names another engine.
+	request, err := x.UnmarshalTrap(append([]byte(nil), message...), true)
+	if err != nil {
+		return nil, err
+	}
+	if request.SecurityModel != gosnmp.UserSecurityModel {
+		return nil, fmt.Errorf("unsupported security model %d", request.SecurityModel)
+	}
+	sp, ok := request.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return nil, fmt.Errorf("missing USM security parameters")
+	}
+
+	if sp.AuthoritativeEngineID != u.engineID {
+		return request, errUnknownEngineID
+	}
+	if sp.UserName != u.username {
+		return nil, fmt.Errorf("unknown user %q", sp.UserName)
+	}
+	if level := request.MsgFlags & gosnmp.AuthPriv; level != u.level {
+		return nil, fmt.Errorf("security level %s, want %s", securityLevelName(level), securityLevelName(u.level))
+	}
+
+	if u.level&gosnmp.AuthNoPriv != 0 {
+		ours := int64(u.engineTime(now))
+		if sp.AuthoritativeEngineBoots != u.boots ||
+			int64(sp.AuthoritativeEngineTime) < ours-usmTimeWindow ||
+			int64(sp.AuthoritativeEngineTime) > ours+usmTimeWindow {
+			return request, errNotInTimeWindow
+		}
+	}
+	return request, nil
+}
+
+// reply returns the skeleton of a message answering request at the given
+// security level, signed and encrypted with the user's keys as the level needs
+func (u *usmAgent) reply(request *gosnmp.SnmpPacket, pduType gosnmp.PDUType, level gosnmp.SnmpV3MsgFlags, now time.Time) (*gosnmp.SnmpPacket, error) {
+	sp, ok := u.params.Copy().(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return nil, fmt.Errorf("copy USM security parameters")
+	}
+	sp.AuthoritativeEngineBoots = u.boots
+	sp.AuthoritativeEngineTime = u.engineTime(now)
+	if reqSP, ok := request.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok {
+		sp.UserName = reqSP.UserName
+	}
+
+	response := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           level,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: sp,
+		ContextEngineID:    u.engineID,
+		ContextName:        request.ContextName,
+		PDUType:            pduType,
+		RequestID:          request.RequestID,
+		MsgID:              request.MsgID,
+	}
+	if err := u.params.InitPacket(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// report returns the report answering a request that failed with
+// errUnknownEngineID or errNotInTimeWindow
+func (u *usmAgent) report(request *gosnmp.SnmpPacket, reason error, now time.Time) (*gosnmp.SnmpPacket, error) {
+	var response *gosnmp.SnmpPacket
+	var err error
+	switch reason {
+	case errUnknownEngineID:
+		// Discovery is unauthenticated: the manager has no localized keys yet
+		response, err = u.reply(request, gosnmp.Report, gosnmp.NoAuthNoPriv, now)
+		if err == nil {
+			response.Variables = []gosnmp.SnmpPDU{counterPDU(usmStatsUnknownEngineIDsOID, u.unknownEngineIDs.Add(1))}
+		}
+	case errNotInTimeWindow:
+		// Signed so the manager can trust the engine time it resynchronizes to
+		response, err = u.reply(request, gosnmp.Report, gosnmp.AuthNoPriv, now)
+		if err == nil {
+			response.Variables = []gosnmp.SnmpPDU{counterPDU(usmStatsNotInTimeWindowsOID, u.notInTimeWindows.Add(1))}
+		}
+	default:
+		return nil, fmt.Errorf("no report for %v", reason)
+	}
+	return response, err
+}
+
+func securityLevelName(level gosnmp.SnmpV3MsgFlags) string {
+	switch level & gosnmp.AuthPriv {
+	case gosnmp.AuthPriv:
+		return "authPriv"
+	case gosnmp.AuthNoPriv:
+		return "authNoPriv"
+	}
+	return "noAuthNoPriv"
+}
+
+// messageVersion reads the SNMP version from the start of a message without
+// decoding the rest, which for v3 needs the security parameters
+func messageVersion(message []byte) (gosnmp.SnmpVersion, bool) {
+	// SEQUENCE, length (short or long form), INTEGER of length 1
+	if len(message) < 2 || message[0] != 0x30 {
+		return 0, false
+	}
+	i := 2
+	if message[1]&0x80 != 0 {
+		i += int(message[1] & 0x7f)
+	}
+	if len(message) < i+3 || message[i] != 0x02 || message[i+1] != 0x01 {
+		return 0, false
+	}
+	return gosnmp.SnmpVersion(message[i+2]), true
+}
@@ -0,0 +1,331 @@
+package outputs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// v3Client connects a gosnmp Version3 client with the given USM parameters
+func v3Client(t *testing.T, port int, flags gosnmp.SnmpV3MsgFlags, params *gosnmp.UsmSecurityParameters) *gosnmp.GoSNMP {
+	t.Helper()
+	client := &gosnmp.GoSNMP{
+		Target:             "127.0.0.1",
+		Port:               uint16(port),
+		Version:            gosnmp.Version3,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		MsgFlags:           flags,
+		SecurityParameters: params,
+		Timeout:            300 * time.Millisecond,
+		Retries:            0,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	t.Cleanup(func() { client.Conn.Close() })
+	return client
+}
+
+func TestSNMPv3Requests(t *testing.T) {
+	cases := []struct {
+		name  string
+		cfg   config.SNMPConfig
+		flags gosnmp.SnmpV3MsgFlags
+		auth  gosnmp.SnmpV3AuthProtocol
+		priv  gosnmp.SnmpV3PrivProtocol
+	}{
+		{
+			name:  "authPriv SHA/AES",
+			cfg:   config.SNMPConfig{SecurityLevel: "authPriv", AuthProtocol: "SHA", PrivProtocol: "AES"},
+			flags: gosnmp.AuthPriv, auth: gosnmp.SHA, priv: gosnmp.AES,
+		},
+		{
+			name:  "authPriv MD5/DES",
+			cfg:   config.SNMPConfig{SecurityLevel: "authPriv", AuthProtocol: "MD5", PrivProtocol: "DES"},
+			flags: gosnmp.AuthPriv, auth: gosnmp.MD5, priv: gosnmp.DES,
+		},
+		{
+			name:  "authNoPriv default protocol",
+			cfg:   config.SNMPConfig{SecurityLevel: "authNoPriv"},
+			flags: gosnmp.AuthNoPriv, auth: gosnmp.SHA, priv: gosnmp.NoPriv,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.Enabled = true
+			cfg.Community = "public"
+			cfg.ListenAddress = "127.0.0.1"
+			cfg.EnterpriseOID = ".1.3.6.1.4.1.55555"
+			cfg.Username = "monitor"
+			cfg.AuthPassphrase = "auth-secret"
+			cfg.PrivPassphrase = "priv-secret"
+
+			snmpOutput, err := NewSNMPOutput(&cfg, "test", nil)
+			if err != nil {
+				t.Fatalf("failed to create SNMP output: %v", err)
+			}
+			defer snmpOutput.Close()
+
+			snmpOutput.Write(&models.TestResult{
+				Timestamp: time.Now(),
+				Site:      models.SiteInfo{Name: "example.com", URL: "https://example.com"},
+				Status:    models.StatusInfo{Success: true},
+				Timings:   models.TimingMetrics{TotalDurationMs: 150},
+			})
+
+			user := func() *gosnmp.UsmSecurityParameters {
+				params := &gosnmp.UsmSecurityParameters{
+					UserName:                 "monitor",
+					AuthenticationProtocol:   tc.auth,
+					AuthenticationPassphrase: "auth-secret",
+					PrivacyProtocol:          tc.priv,
+				}
+				if tc.priv != gosnmp.NoPriv {
+					params.PrivacyPassphrase = "priv-secret"
+				}
+				return params
+			}
+			base := ".1.3.6.1.4.1.55555"
+
+			client := v3Client(t, snmpOutput.Port(), tc.flags, user())
+			packet, err := client.Get([]string{base + ".1.0"})
+			if err != nil {
+				t.Fatalf("v3 get failed: %v", err)
+			}
+			if packet.PDUType != gosnmp.GetResponse || len(packet.Variables) != 1 {
+				t.Fatalf("expected a GetResponse with 1 variable, got %v with %d", packet.PDUType, len(packet.Variables))
+			}
+			if got := pduValueAsUint32(t, packet.Variables[0]); got != 1 {
+				t.Fatalf("expected cache size 1, got %d", got)
+			}
+
+			packet, err = client.GetNext([]string{base + ".1.0"})
+			if err != nil {
+				t.Fatalf("v3 getnext failed: %v", err)
+			}
+			if packet.Variables[0].Name != base+".2.0" {
+				t.Fatalf("expected getnext to return %s.2.0, got %s", base, packet.Variables[0].Name)
+			}
+
+			packet, err = client.GetBulk([]string{base + ".1"}, 0, 3)
+			if err != nil {
+				t.Fatalf("v3 getbulk failed: %v", err)
+			}
+			if len(packet.Variables) != 3 || packet.Variables[2].Name != base+".3.0" {
+				t.Fatalf("expected getbulk to return .1.0 through .3.0, got %v", packet.Variables)
+			}
+
+			// A SET is refused even with valid credentials
+			packet, err = client.Set([]gosnmp.SnmpPDU{{Name: base + ".6.0", Type: gosnmp.Integer, Value: 1}})
+			if err != nil {
+				t.Fatalf("v3 set failed: %v", err)
+			}
+			if packet.Error != gosnmp.NoAccess {
+				t.Fatalf("expected noAccess for a v3 SET, got %v", packet.Error)
+			}
+			if got := snmpOutput.cacheLen(); got != 1 {
+				t.Fatalf("expected the refused SET to leave the cache alone, got %d results", got)
+			}
+
+			// Mismatched security parameters get no response
+			wrongAuth := user()
+			wrongAuth.AuthenticationPassphrase = "not-the-secret"
+			wrongUser := user()
+			wrongUser.UserName = "intruder"
+			rejected := map[string]*gosnmp.GoSNMP{
+				"wrong auth passphrase": v3Client(t, snmpOutput.Port(), tc.flags, wrongAuth),
+				"wrong username":        v3Client(t, snmpOutput.Port(), tc.flags, wrongUser),
+				"no auth": v3Client(t, snmpOutput.Port(), gosnmp.NoAuthNoPriv,
+					&gosnmp.UsmSecurityParameters{UserName: "monitor"}),
+			}
+			if tc.priv != gosnmp.NoPriv {
+				wrongPriv := user()
+				wrongPriv.PrivacyPassphrase = "not-the-secret"
+				rejected["wrong priv passphrase"] = v3Client(t, snmpOutput.Port(), tc.flags, wrongPriv)
+				rejected["auth without priv"] = v3Client(t, snmpOutput.Port(), gosnmp.AuthNoPriv,
+					&gosnmp.UsmSecurityParameters{UserName: "monitor", AuthenticationProtocol: tc.auth, AuthenticationPassphrase: "auth-secret"})
+			}
+			for name, c := range rejected {
+				if packet, err := c.Get([]string{base + ".1.0"}); err == nil {
+					t.Errorf("%s: expected the request to be rejected, got %v", name, packet.Variables)
+				}
+			}
+
+			// Community strings are refused once v3 is configured
+			v2c := &gosnmp.GoSNMP{
+				Target:    "127.0.0.1",
+				Port:      uint16(snmpOutput.Port()),
+				Community: "public",
+				Version:   gosnmp.Version2c,
+				Timeout:   300 * time.Millisecond,
+			}
+			if err := v2c.Connect(); err != nil {
+				t.Fatalf("failed to connect SNMP client: %v", err)
+			}
+			defer v2c.Conn.Close()
+			if _, err := v2c.Get([]string{base + ".1.0"}); err == nil {
+				t.Fatal("expected v2c to be refused when SNMPv3 is configured")
+			}
+		})
+	}
+}
+
+func TestSNMPv3NotConfigured(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:       true,
+		Port:          0,
+		Community:     "public",
+		ListenAddress: "127.0.0.1",
+		EnterpriseOID: ".1.3.6.1.4.1.55555",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	v3 := v3Client(t, snmpOutput.Port(), gosnmp.AuthPriv, &gosnmp.UsmSecurityParameters{
+		UserName:                 "monitor",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "auth-secret",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "priv-secret",
+	})
+	if _, err := v3.Get([]string{".1.3.6.1.4.1.55555.1.0"}); err == nil {
+		t.Fatal("expected v3 to be ignored when it is not configured")
+	}
+
+	v2c := &gosnmp.GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(snmpOutput.Port()),
+		Community: "public",
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+		Retries:   1,
+	}
+	if err := v2c.Connect(); err != nil {
+		t.Fatalf("failed to connect SNMP client: %v", err)
+	}
+	defer v2c.Conn.Close()
+	if _, err := v2c.Get([]string{".1.3.6.1.4.1.55555.1.0"}); err != nil {
+		t.Fatalf("expected v2c to keep working without v3, got: %v", err)
+	}
+}
+
+func TestSNMPv3TimeWindow(t *testing.T) {
+	cfg := &config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		ListenAddress:  "127.0.0.1",
+		EnterpriseOID:  ".1.3.6.1.4.1.55555",
+		SecurityLevel:  "authNoPriv",
+		Username:       "monitor",
+		AuthPassphrase: "auth-secret",
+	}
+	snmpOutput, err := NewSNMPOutput(cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("failed to create SNMP output: %v", err)
+	}
+	defer snmpOutput.Close()
+
+	client := v3Client(t, snmpOutput.Port(), gosnmp.AuthNoPriv, &gosnmp.UsmSecurityParameters{
+		UserName:                 "monitor",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "auth-secret",
+	})
+	if _, err := client.Get([]string{".1.3.6.1.4.1.55555.1.0"}); err != nil {
+		t.Fatalf("v3 get failed: %v", err)
+	}
+
+	// An hour later the client's idea of the engine time is stale, so the
+	// agent answers with a signed usmStatsNotInTimeWindows report
+	request := &gosnmp.SnmpPacket{
+		Version:       gosnmp.Version3,
+		MsgFlags:      gosnmp.AuthNoPriv | gosnmp.Reportable,
+		SecurityModel: gosnmp.UserSecurityModel,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 "monitor",
+			AuthoritativeEngineID:    snmpOutput.usm.engineID,
+			AuthoritativeEngineBoots: 1,
+			AuthoritativeEngineTime:  0,
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "auth-secret",
+		},
+		ContextEngineID: snmpOutput.usm.engineID,
+		PDUType:         gosnmp.GetRequest,
+		RequestID:       7,
+		MsgID:           7,
+		Variables:       []gosnmp.SnmpPDU{{Name: ".1.3.6.1.4.1.55555.1.0", Type: gosnmp.Null}},
+	}
+	if err := request.SecurityParameters.InitSecurityKeys(); err != nil {
+		t.Fatal(err)
+	}
+	message, err := request.MarshalMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	later := snmpOutput.usm.start.Add(time.Hour)
+	_, err = snmpOutput.usm.decode(message, later)
+	if err != errNotInTimeWindow {
+		t.Fatalf("expected errNotInTimeWindow an hour later, got %v", err)
+	}
+	if _, err := snmpOutput.usm.decode(message, snmpOutput.usm.start.Add(time.Minute)); err != nil {
+		t.Fatalf("expected a request within the window to be accepted, got %v", err)
+	}
+
+	req, _ := snmpOutput.usm.decode(message, later)
+	report, err := snmpOutput.usm.report(req, errNotInTimeWindow, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.PDUType != gosnmp.Report || report.MsgFlags != gosnmp.AuthNoPriv ||
+		report.Variables[0].Name != usmStatsNotInTimeWindowsOID {
+		t.Fatalf("expected a signed usmStatsNotInTimeWindows report, got %v %v %v", report.PDUType, report.MsgFlags, report.Variables)
+	}
+	if got := report.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthoritativeEngineTime; got != 3600 {
+		t.Fatalf("expected the report to carry engine time 3600, got %d", got)
+	}
+}
+
+func TestSNMPv3RejectsInvalidConfig(t *testing.T) {
+	valid := config.SNMPConfig{
+		Enabled:        true,
+		Port:           0,
+		ListenAddress:  "127.0.0.1",
+		SecurityLevel:  "authPriv",
+		Username:       "monitor",
+		AuthPassphrase: "auth-secret",
+		PrivPassphrase: "priv-secret",
+	}
+	cases := map[string]func(cfg *config.SNMPConfig){
+		"unknown security level": func(cfg *config.SNMPConfig) { cfg.SecurityLevel = "paranoid" },
+		"missing username":       func(cfg *config.SNMPConfig) { cfg.Username = "" },
+		"short auth passphrase":  func(cfg *config.SNMPConfig) { cfg.AuthPassphrase = "short" },
+		"short priv passphrase":  func(cfg *config.SNMPConfig) { cfg.PrivPassphrase = "" },
+		"unknown auth protocol":  func(cfg *config.SNMPConfig) { cfg.AuthProtocol = "CRC32" },
+		"unknown priv protocol":  func(cfg *config.SNMPConfig) { cfg.PrivProtocol = "ROT13" },
+	}
+	for name, mutate := range cases {
+		cfg := valid
+		mutate(&cfg)
+		if snmpOutput, err := NewSNMPOutput(&cfg, "test", nil); err == nil {
+			snmpOutput.Close()
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// noAuthNoPriv needs no passphrases
+	cfg := config.SNMPConfig{Enabled: true, ListenAddress: "127.0.0.1", SecurityLevel: "noAuthNoPriv", Username: "monitor"}
+	snmpOutput, err := NewSNMPOutput(&cfg, "test", nil)
+	if err != nil {
+		t.Fatalf("expected noAuthNoPriv without passphrases to be accepted, got %v", err)
+	}
+	snmpOutput.Close()
+}
@@ -0,0 +1,74 @@
+package outputs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Views (a lightweight take on SNMP VACM)
+//
+// SNMPConfig.Views maps a community to the OID subtrees it may see, e.g. a
+// public NMS that gets aggregate scalars but not the site table, whose site
+// names can reveal internal URLs. Communities with a view are accepted for
+// reads in addition to the community and write community. Communities without
+// a view see the whole tree. OIDs outside a view behave as if they did not
+// exist: Get returns NoSuchObject, GetNext/GetBulk skip them (ending in
+// EndOfMibView), and SET returns noAccess.
+
+// snmpView is the set of OID subtrees a community may access; nil allows everything
+type snmpView struct {
+	prefixes []string
+}
+
+// newSNMPViews normalizes the configured views
+func newSNMPViews(views map[string][]string) (map[string]*snmpView, error) {
+	out := make(map[string]*snmpView, len(views))
+	for community, prefixes := range views {
+		view := &snmpView{prefixes: make([]string, 0, len(prefixes))}
+		for _, p := range prefixes {
+			prefix, err := normalizeOID(p)
+			if err != nil {
+				return nil, fmt.Errorf("SNMP view for community %q: %w", community, err)
+			}
+			view.prefixes = append(view.prefixes, prefix)
+		}
+		out[community] = view
+	}
+	return out, nil
+}
+
+// viewFor returns the view restricting community, or nil if it is unrestricted
+func (s *SNMPOutput) viewFor(community string) *snmpView {
+	return s.views[community]
+}
+
+// contains reports whether oid is inside one of the view's subtrees
+func (v *snmpView) contains(oid string) bool {
+	if v == nil {
+		return true
+	}
+	for _, prefix := range v.prefixes {
+		if prefix == "." || oid == prefix || strings.HasPrefix(oid, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the part of an OID snapshot inside the view
+func (v *snmpView) filter(oids []string, values map[string]gosnmp.SnmpPDU) ([]string, map[string]gosnmp.SnmpPDU) {
+	if v == nil {
+		return oids, values
+	}
+	visibleOIDs := make([]string, 0, len(oids))
+	visibleValues := make(map[string]gosnmp.SnmpPDU, len(oids))
+	for _, oid := range oids {
+		if v.contains(oid) {
+			visibleOIDs = append(visibleOIDs, oid)
+			visibleValues[oid] = values[oid]
+		}
+	}
+	return visibleOIDs, visibleValues
+}
@@ -0,0 +1,288 @@
+package outputs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// sqliteSchema creates the results table: one flattened row per result, with
+// timestamps in Unix milliseconds so range queries use the indexes
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	id                    INTEGER PRIMARY KEY,
+	test_id               TEXT NOT NULL,
+	timestamp_ms          INTEGER NOT NULL,
+	site_name             TEXT NOT NULL,
+	site_url              TEXT NOT NULL,
+	site_category         TEXT,
+	success               INTEGER NOT NULL,
+	http_status           INTEGER,
+	total_duration_ms     INTEGER NOT NULL,
+	dns_lookup_ms         INTEGER,
+	tcp_connection_ms     INTEGER,
+	tls_handshake_ms      INTEGER,
+	time_to_first_byte_ms INTEGER,
+	dom_content_loaded_ms INTEGER,
+	full_page_load_ms     INTEGER,
+	error_type            TEXT,
+	failure_phase         TEXT,
+	error_message         TEXT
+);
+CREATE INDEX IF NOT EXISTS results_site_timestamp ON results (site_name, timestamp_ms);
+CREATE INDEX IF NOT EXISTS results_timestamp ON results (timestamp_ms);
+`
+
+const sqliteInsert = `
+INSERT INTO results (
+	test_id, timestamp_ms, site_name, site_url, site_category, success, http_status,
+	total_duration_ms, dns_lookup_ms, tcp_connection_ms, tls_handshake_ms,
+	time_to_first_byte_ms, dom_content_loaded_ms, full_page_load_ms,
+	error_type, failure_phase, error_message
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLiteOutput stores test results in a local SQLite database. Results are
+// queued and inserted in batches, one transaction each; rows older than the
+// retention period are pruned periodically.
+type SQLiteOutput struct {
+	config        *config.SQLiteConfig
+	db            *sql.DB
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	resultChannel chan *models.TestResult
+	health        backendHealth
+
+	now func() time.Time // replaceable in tests
+}
+
+// NewSQLiteOutput opens (creating if needed) the database and starts the
+// background writer. The driver needs cgo; without it this returns an error.
+func NewSQLiteOutput(cfg *config.SQLiteConfig) (*SQLiteOutput, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("SQLite output requires a database path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create SQLite database directory: %w", err)
+	}
+
+	// WAL lets readers query the history while the monitor writes
+	db, err := sql.Open("sqlite3", "file:"+cfg.Path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// One connection serializes our writes, avoiding SQLITE_BUSY between them
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create SQLite schema in %s: %w", cfg.Path, err)
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SQLiteOutput{
+		config:        cfg,
+		db:            db,
+		ctx:           ctx,
+		cancel:        cancel,
+		resultChannel: make(chan *models.TestResult, 2*batchSize+100),
+		now:           time.Now,
+	}
+
+	s.prune()
+
+	s.wg.Add(1)
+	go s.processResults(batchSize)
+
+	log.Printf("Storing results in SQLite database %s", cfg.Path)
+	return s, nil
+}
+
+// processResults is a background worker that batches queued results into
+// transactions and prunes old rows
+func (s *SQLiteOutput) processResults(batchSize int) {
+	defer s.wg.Done()
+
+	flushInterval := s.config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 10 * time.Second
+	}
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+
+	var pruneC <-chan time.Time
+	if s.config.RetentionDays > 0 && s.config.PruneInterval > 0 {
+		prune := time.NewTicker(s.config.PruneInterval)
+		defer prune.Stop()
+		pruneC = prune.C
+	}
+
+	batch := make([]*models.TestResult, 0, batchSize)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := s.insert(batch)
+		if err != nil {
+			log.Printf("Failed to write %d results to SQLite: %v", len(batch), err)
+		}
+		s.health.record(err)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			// Write whatever was queued before shutdown
+			for {
+				select {
+				case result := <-s.resultChannel:
+					batch = append(batch, result)
+					if len(batch) >= batchSize {
+						write()
+					}
+				default:
+					write()
+					return
+				}
+			}
+		case result := <-s.resultChannel:
+			batch = append(batch, result)
+			if len(batch) >= batchSize {
+				write()
+			}
+		case <-flush.C:
+			write()
+		case <-pruneC:
+			s.prune()
+		}
+	}
+}
+
+// insert writes results in a single transaction
+func (s *SQLiteOutput) insert(results []*models.TestResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(sqliteInsert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range results {
+		var errorType, failurePhase, errorMessage sql.NullString
+		if r.Error != nil {
+			errorType = nullString(r.Error.ErrorType)
+			failurePhase = nullString(r.Error.FailurePhase)
+			errorMessage = nullString(r.Error.ErrorMessage)
+		}
+		var httpStatus sql.NullInt64
+		if r.Status.HTTPStatus != 0 {
+			httpStatus = sql.NullInt64{Int64: int64(r.Status.HTTPStatus), Valid: true}
+		}
+
+		_, err := stmt.Exec(
+			r.TestID, r.Timestamp.UnixMilli(), resultSiteName(r), r.Site.URL, nullString(r.Site.Category),
+			r.Status.Success, httpStatus,
+			r.Timings.TotalDurationMs, nullInt64(r.Timings.DNSLookupMs), nullInt64(r.Timings.TCPConnectionMs),
+			nullInt64(r.Timings.TLSHandshakeMs), nullInt64(r.Timings.TimeToFirstByteMs),
+			nullInt64(r.Timings.DOMContentLoadedMs), nullInt64(r.Timings.FullPageLoadMs),
+			errorType, failurePhase, errorMessage,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// prune deletes rows older than the retention period
+func (s *SQLiteOutput) prune() {
+	if s.config.RetentionDays <= 0 {
+		return
+	}
+	cutoff := s.now().AddDate(0, 0, -s.config.RetentionDays)
+	res, err := s.db.Exec("DELETE FROM results WHERE timestamp_ms < ?", cutoff.UnixMilli())
+	if err != nil {
+		log.Printf("Failed to prune SQLite results: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("Pruned %d SQLite results older than %d days", n, s.config.RetentionDays)
+	}
+}
+
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+func nullInt64(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *v, Valid: true}
+}
+
+// Write queues a test result for the next batch
+func (s *SQLiteOutput) Write(result *models.TestResult) error {
+	if s == nil {
+		return nil
+	}
+
+	// Queued even while SQLite is failing, so its recovery is noticed
+	select {
+	case s.resultChannel <- result:
+		if err := s.health.lastErr(); err != nil {
+			return fmt.Errorf("SQLite writes are failing: %w", err)
+		}
+		return nil
+	case <-s.ctx.Done():
+		return fmt.Errorf("SQLite output is shutting down")
+	default:
+		return fmt.Errorf("SQLite result channel is full, dropping result")
+	}
+}
+
+// Name returns the output module name
+func (s *SQLiteOutput) Name() string {
+	return "sqlite"
+}
+
+// IsSink marks SQLite as a metrics.Sink
+func (s *SQLiteOutput) IsSink() bool {
+	return true
+}
+
+// Close writes queued results and closes the database
+func (s *SQLiteOutput) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	return s.db.Close()
+}
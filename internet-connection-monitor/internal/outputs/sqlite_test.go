@@ -0,0 +1,143 @@
+package outputs
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func newTestSQLiteOutput(t *testing.T, cfg config.SQLiteConfig) *SQLiteOutput {
+	t.Helper()
+	cfg.Enabled = true
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(t.TempDir(), "results.db")
+	}
+	out, err := NewSQLiteOutput(&cfg)
+	if err != nil {
+		t.Fatalf("failed to create SQLite output: %v", err)
+	}
+	return out
+}
+
+func TestSQLiteStoresFlattenedResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	// A batch larger than the number of results: Close must write the remainder
+	out := newTestSQLiteOutput(t, config.SQLiteConfig{Path: path, BatchSize: 2, FlushInterval: time.Hour})
+
+	success, failure := syslogTestResults()
+	success.TestID, failure.TestID = "ok-1", "fail-1"
+	dns := int64(12)
+	success.Timings.DNSLookupMs = &dns
+	third := *success
+	third.TestID = "ok-2"
+	for _, r := range []*models.TestResult{success, failure, &third} {
+		if err := out.Write(r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM results").Scan(&count); err != nil || count != 3 {
+		t.Fatalf("expected 3 rows, got %d (%v)", count, err)
+	}
+
+	var (
+		site, phase, errType string
+		succeeded            bool
+		ts, total            int64
+		dnsMs                sql.NullInt64
+	)
+	row := db.QueryRow("SELECT site_name, success, timestamp_ms, total_duration_ms, dns_lookup_ms FROM results WHERE test_id = 'ok-1'")
+	if err := row.Scan(&site, &succeeded, &ts, &total, &dnsMs); err != nil {
+		t.Fatalf("failed to read success row: %v", err)
+	}
+	if site != "example" || !succeeded || ts != success.Timestamp.UnixMilli() || total != 321 || dnsMs.Int64 != 12 {
+		t.Errorf("unexpected success row: %s %v %d %d %v", site, succeeded, ts, total, dnsMs)
+	}
+
+	row = db.QueryRow("SELECT error_type, failure_phase, dns_lookup_ms FROM results WHERE test_id = 'fail-1'")
+	if err := row.Scan(&errType, &phase, &dnsMs); err != nil {
+		t.Fatalf("failed to read failure row: %v", err)
+	}
+	if errType != "ERR_NAME_NOT_RESOLVED" || phase != "dns" || dnsMs.Valid {
+		t.Errorf("unexpected failure row: %s %s %v", errType, phase, dnsMs)
+	}
+}
+
+func TestSQLitePrunesOldResults(t *testing.T) {
+	out := newTestSQLiteOutput(t, config.SQLiteConfig{RetentionDays: 7, BatchSize: 10, FlushInterval: time.Hour})
+	defer out.Close()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	out.now = func() time.Time { return now }
+	var results []*models.TestResult
+	for _, age := range []time.Duration{30 * 24 * time.Hour, 8 * 24 * time.Hour, 6 * 24 * time.Hour, time.Minute} {
+		results = append(results, &models.TestResult{
+			TestID:    age.String(),
+			Timestamp: now.Add(-age),
+			Site:      models.SiteInfo{Name: "example", URL: "https://example.com"},
+		})
+	}
+	if err := out.insert(results); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	out.prune()
+
+	rows, err := out.db.Query("SELECT test_id FROM results ORDER BY timestamp_ms")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	var kept []string
+	for rows.Next() {
+		var id string
+		rows.Scan(&id)
+		kept = append(kept, id)
+	}
+	if len(kept) != 2 || kept[0] != (6*24*time.Hour).String() || kept[1] != time.Minute.String() {
+		t.Errorf("expected only results within 7 days kept, got %v", kept)
+	}
+}
+
+func TestSQLiteReportsFailingWrites(t *testing.T) {
+	out := newTestSQLiteOutput(t, config.SQLiteConfig{BatchSize: 1, FlushInterval: time.Hour})
+	defer out.Close()
+
+	// The background writer fails from now on; Write only queues, so the
+	// failure shows up on the writes that follow
+	if _, err := out.db.Exec("DROP TABLE results"); err != nil {
+		t.Fatalf("drop table: %v", err)
+	}
+	result := &models.TestResult{TestID: "lost", Site: models.SiteInfo{Name: "example"}}
+	deadline := time.Now().Add(5 * time.Second)
+	for out.Write(result) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected Write to report the failing database")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSQLiteDisabled(t *testing.T) {
+	out, err := NewSQLiteOutput(&config.SQLiteConfig{Enabled: false})
+	if out != nil || err != nil {
+		t.Errorf("expected nil output when disabled, got %v, %v", out, err)
+	}
+	if err := out.Write(&models.TestResult{}); err != nil {
+		t.Errorf("expected Write on a nil output to be a no-op, got %v", err)
+	}
+}
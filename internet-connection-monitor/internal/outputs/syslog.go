@@ -0,0 +1,238 @@
+package outputs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+const (
+	// syslogDialTimeout bounds connecting to the syslog server
+	syslogDialTimeout = 5 * time.Second
+
+	// syslogWriteTimeout bounds sending one message so a stalled TCP server
+	// can't block the dispatcher
+	syslogWriteTimeout = 5 * time.Second
+
+	// syslogSDID names the structured data element carrying the result.
+	// 32473 is the enterprise number reserved for documentation (RFC 5612);
+	// the monitor has no registered one.
+	syslogSDID = "result@32473"
+
+	// RFC 5424 severities
+	syslogSeverityErr  = 3
+	syslogSeverityInfo = 6
+)
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogOutput sends each test result to a syslog server as an RFC 5424
+// message with the result in structured data. Failures are logged at err,
+// successes at info.
+type SyslogOutput struct {
+	config   *config.SyslogConfig
+	facility int
+	hostname string
+	pid      string
+
+	mu   sync.Mutex
+	conn net.Conn // nil after a transport error until the next Write reconnects
+}
+
+// NewSyslogOutput creates a syslog output and connects to the server
+func NewSyslogOutput(cfg *config.SyslogConfig) (*SyslogOutput, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Protocol {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("syslog protocol must be udp, tcp or tls, got %q", cfg.Protocol)
+	}
+	facility, ok := syslogFacilities[strings.ToLower(cfg.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	s := &SyslogOutput{
+		config:   cfg,
+		facility: facility,
+		hostname: hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog server %s: %w", cfg.Address, err)
+	}
+	s.conn = conn
+
+	log.Printf("Sending results to syslog at %s (%s, facility %s)", cfg.Address, cfg.Protocol, cfg.Facility)
+	return s, nil
+}
+
+func (s *SyslogOutput) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if s.config.Protocol == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", s.config.Address, &tls.Config{
+			InsecureSkipVerify: s.config.TLSSkipVerify,
+		})
+	}
+	return dialer.Dial(s.config.Protocol, s.config.Address)
+}
+
+// Write sends a test result, reconnecting once if the connection has failed
+func (s *SyslogOutput) Write(result *models.TestResult) error {
+	frame := s.frame(s.format(result))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(); err != nil {
+				s.conn = nil
+				return fmt.Errorf("syslog reconnect failed: %w", err)
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if _, err = s.conn.Write(frame); err == nil {
+			return nil
+		}
+		// Drop the broken connection; the retry (or the next Write) redials
+		s.conn.Close()
+		s.conn = nil
+	}
+	return fmt.Errorf("syslog write failed: %w", err)
+}
+
+// frame prepares a message for the transport: one datagram per message over
+// UDP, octet-counting framing (RFC 6587/5425) over TCP and TLS
+func (s *SyslogOutput) frame(msg string) []byte {
+	if s.config.Protocol == "udp" {
+		return []byte(msg)
+	}
+	return []byte(strconv.Itoa(len(msg)) + " " + msg)
+}
+
+// format renders a result as an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (s *SyslogOutput) format(result *models.TestResult) string {
+	severity := syslogSeverityInfo
+	if !result.Status.Success {
+		severity = syslogSeverityErr
+	}
+
+	hostname := result.Metadata.Hostname
+	if hostname == "" {
+		hostname = s.hostname
+	}
+
+	params := [][2]string{
+		{"site", result.Site.Name},
+		{"url", result.Site.URL},
+		{"success", strconv.FormatBool(result.Status.Success)},
+		{"total_ms", strconv.FormatInt(result.Timings.TotalDurationMs, 10)},
+	}
+	if result.Status.HTTPStatus != 0 {
+		params = append(params, [2]string{"http_status", strconv.Itoa(result.Status.HTTPStatus)})
+	}
+	if result.Error != nil {
+		params = append(params,
+			[2]string{"error_type", result.Error.ErrorType},
+			[2]string{"failure_phase", result.Error.FailurePhase},
+		)
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, p := range params {
+		sd.WriteString(" " + p[0] + `="` + escapeSDParam(p[1]) + `"`)
+	}
+	sd.WriteString("]")
+
+	msg := fmt.Sprintf("%s ok in %dms", result.Site.Name, result.Timings.TotalDurationMs)
+	if !result.Status.Success {
+		msg = fmt.Sprintf("%s failed: %s", result.Site.Name, result.Status.Message)
+		if result.Error != nil {
+			msg += " (" + result.Error.ErrorType + ")"
+		}
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %s test_result %s %s",
+		s.facility*8+severity,
+		result.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(s.config.AppName, 48),
+		s.pid,
+		sd.String(),
+		msg,
+	)
+}
+
+// escapeSDParam escapes the characters RFC 5424 reserves in parameter values
+func escapeSDParam(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+}
+
+// syslogHeaderField makes a header field valid: printable US-ASCII without
+// spaces, at most max characters, "-" when empty
+func syslogHeaderField(v string, max int) string {
+	v = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, v)
+	if len(v) > max {
+		v = v[:max]
+	}
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+// Name returns the output module name
+func (s *SyslogOutput) Name() string {
+	return "syslog"
+}
+
+// IsSink marks syslog as a metrics.Sink: a failed send loses the result
+func (s *SyslogOutput) IsSink() bool {
+	return true
+}
+
+// Close closes the connection to the syslog server
+func (s *SyslogOutput) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,182 @@
+package outputs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func syslogTestResults() (success, failure *models.TestResult) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	success = &models.TestResult{
+		Timestamp: ts,
+		Site:      models.SiteInfo{Name: "example", URL: "https://example.com"},
+		Status:    models.StatusInfo{Success: true, HTTPStatus: 200},
+		Timings:   models.TimingMetrics{TotalDurationMs: 321},
+		Metadata:  models.TestMetadata{Hostname: "monitor-1"},
+	}
+	failure = &models.TestResult{
+		Timestamp: ts,
+		Site:      models.SiteInfo{Name: "intranet", URL: `https://intranet/"q"]`},
+		Status:    models.StatusInfo{Success: false, Message: "Failed to load page"},
+		Timings:   models.TimingMetrics{TotalDurationMs: 5000},
+		Error:     &models.ErrorInfo{ErrorType: "ERR_NAME_NOT_RESOLVED", FailurePhase: "dns"},
+		Metadata:  models.TestMetadata{Hostname: "monitor-1"},
+	}
+	return success, failure
+}
+
+func TestSyslogUDPFormatsRFC5424(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	out, err := NewSyslogOutput(&config.SyslogConfig{
+		Enabled:  true,
+		Address:  server.LocalAddr().String(),
+		Protocol: "udp",
+		Facility: "local0",
+		AppName:  "icm",
+	})
+	if err != nil {
+		t.Fatalf("failed to create syslog output: %v", err)
+	}
+	defer out.Close()
+
+	success, failure := syslogTestResults()
+	pid := strconv.Itoa(os.Getpid())
+	want := []string{
+		// local0 (16) * 8 + info (6)
+		`<134>1 2024-03-01T12:00:00.123456Z monitor-1 icm ` + pid + ` test_result ` +
+			`[result@32473 site="example" url="https://example.com" success="true" total_ms="321" http_status="200"] ` +
+			`example ok in 321ms`,
+		// local0 (16) * 8 + err (3), with reserved characters escaped
+		`<131>1 2024-03-01T12:00:00.123456Z monitor-1 icm ` + pid + ` test_result ` +
+			`[result@32473 site="intranet" url="https://intranet/\"q\"\]" success="false" total_ms="5000" error_type="ERR_NAME_NOT_RESOLVED" failure_phase="dns"] ` +
+			`intranet failed: Failed to load page (ERR_NAME_NOT_RESOLVED)`,
+	}
+
+	buf := make([]byte, 4096)
+	for i, result := range []*models.TestResult{success, failure} {
+		if err := out.Write(result); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		server.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := server.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to read datagram: %v", err)
+		}
+		if got := string(buf[:n]); got != want[i] {
+			t.Errorf("message %d:\n got %s\nwant %s", i, got, want[i])
+		}
+	}
+}
+
+// readFrame reads one octet-counted syslog frame
+func readFrame(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", fmt.Errorf("bad frame length %q", length)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return "", err
+	}
+	return string(msg), nil
+}
+
+func TestSyslogTCPReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	out, err := NewSyslogOutput(&config.SyslogConfig{
+		Enabled:  true,
+		Address:  listener.Addr().String(),
+		Protocol: "tcp",
+		Facility: "daemon",
+		AppName:  "icm",
+	})
+	if err != nil {
+		t.Fatalf("failed to create syslog output: %v", err)
+	}
+	defer out.Close()
+
+	success, _ := syslogTestResults()
+	if err := out.Write(success); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	first := <-conns
+	first.SetReadDeadline(time.Now().Add(time.Second))
+	msg, err := readFrame(bufio.NewReader(first))
+	if err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	if !strings.HasPrefix(msg, "<30>1 ") { // daemon (3) * 8 + info (6)
+		t.Errorf("unexpected message %q", msg)
+	}
+
+	// The server drops the connection; writes must notice and redial
+	first.Close()
+	var second net.Conn
+	for i := 0; i < 50 && second == nil; i++ {
+		out.Write(success)
+		select {
+		case second = <-conns:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if second == nil {
+		t.Fatal("expected the output to reconnect after the server closed the connection")
+	}
+	defer second.Close()
+
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := readFrame(bufio.NewReader(second)); err != nil {
+		t.Fatalf("failed to read frame after reconnecting: %v", err)
+	}
+}
+
+func TestSyslogRejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []config.SyslogConfig{
+		{Enabled: true, Address: "127.0.0.1:514", Protocol: "sctp", Facility: "local0"},
+		{Enabled: true, Address: "127.0.0.1:514", Protocol: "udp", Facility: "local9"},
+	} {
+		if out, err := NewSyslogOutput(&cfg); err == nil {
+			out.Close()
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+
+	if out, err := NewSyslogOutput(&config.SyslogConfig{Enabled: false}); out != nil || err != nil {
+		t.Errorf("expected nil output for a disabled config, got %v, %v", out, err)
+	}
+}
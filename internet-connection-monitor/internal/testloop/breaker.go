@@ -0,0 +1,107 @@
+package testloop
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// Circuit breaker states, as reported in results
+const (
+	breakerClosed = "closed"
+	breakerOpen   = "open"
+)
+
+// circuitBreaker backs off testing of sites that keep failing. Sites are
+// keyed by their identity URL (SiteDefinition.GetIdentity), like the
+// iterator, so a renamed site keeps its state.
+type circuitBreaker struct {
+	cfg config.CircuitBreakerConfig
+
+	mu    sync.Mutex
+	sites map[string]*breakerSite
+
+	now func() time.Time // replaceable in tests
+}
+
+type breakerSite struct {
+	failures int
+	backoff  time.Duration
+	nextTest time.Time // zero while closed
+}
+
+func newCircuitBreaker(cfg config.CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{
+		cfg:   cfg,
+		sites: make(map[string]*breakerSite),
+		now:   time.Now,
+	}
+}
+
+// allow reports whether the site may be tested now
+func (b *circuitBreaker) allow(site models.SiteDefinition) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st, ok := b.sites[site.GetIdentity()]
+	return !ok || !b.now().Before(st.nextTest)
+}
+
+// record updates the site's breaker with a test outcome and returns its new
+// state; wasOpen reports whether the site had been backed off before this test
+func (b *circuitBreaker) record(site models.SiteDefinition, success bool) (info *models.CircuitBreakerInfo, wasOpen bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st, ok := b.sites[site.GetIdentity()]
+	if !ok {
+		st = &breakerSite{}
+		b.sites[site.GetIdentity()] = st
+	}
+	wasOpen = st.backoff > 0
+
+	if success {
+		*st = breakerSite{}
+		return &models.CircuitBreakerInfo{State: breakerClosed}, wasOpen
+	}
+
+	st.failures++
+	if st.failures < b.cfg.FailureThreshold {
+		return &models.CircuitBreakerInfo{State: breakerClosed, ConsecutiveFailures: st.failures}, wasOpen
+	}
+
+	// Open at the initial backoff, then double with each further failure
+	if st.backoff == 0 {
+		st.backoff = b.cfg.InitialBackoff
+	} else {
+		st.backoff *= 2
+	}
+	if b.cfg.MaxBackoff > 0 && st.backoff > b.cfg.MaxBackoff {
+		st.backoff = b.cfg.MaxBackoff
+	}
+	st.nextTest = b.now().Add(st.backoff)
+
+	return &models.CircuitBreakerInfo{
+		State:               breakerOpen,
+		ConsecutiveFailures: st.failures,
+		BackoffMs:           st.backoff.Milliseconds(),
+	}, wasOpen
+}
+
+// forget drops state for sites no longer in the list
+func (b *circuitBreaker) forget(sites []models.SiteDefinition) {
+	keep := make(map[string]bool, len(sites))
+	for i := range sites {
+		keep[sites[i].GetIdentity()] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for url := range b.sites {
+		if !keep[url] {
+			delete(b.sites, url)
+		}
+	}
+}
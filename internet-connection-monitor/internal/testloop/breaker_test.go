@@ -0,0 +1,154 @@
+package testloop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+func newTestBreaker(clock *time.Time) *circuitBreaker {
+	b := newCircuitBreaker(config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 3,
+		InitialBackoff:   time.Minute,
+		MaxBackoff:       5 * time.Minute,
+	})
+	b.now = func() time.Time { return *clock }
+	return b
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	b := newTestBreaker(&clock)
+	site := models.SiteDefinition{URL: "https://down.example"}
+
+	for i := 1; i < 3; i++ {
+		info, _ := b.record(site, false)
+		if info.State != breakerClosed || info.ConsecutiveFailures != i {
+			t.Fatalf("failure %d: got %+v, want closed", i, info)
+		}
+		if !b.allow(site) {
+			t.Fatalf("failure %d: site should still be tested every round", i)
+		}
+	}
+
+	info, wasOpen := b.record(site, false)
+	if info.State != breakerOpen || info.BackoffMs != time.Minute.Milliseconds() || wasOpen {
+		t.Fatalf("expected the breaker to open with a 1m backoff, got %+v (wasOpen %v)", info, wasOpen)
+	}
+	if b.allow(site) {
+		t.Error("expected the site to be skipped during its backoff")
+	}
+	if !b.allow(models.SiteDefinition{URL: "https://up.example"}) {
+		t.Error("other sites must not be affected")
+	}
+
+	clock = clock.Add(time.Minute)
+	if !b.allow(site) {
+		t.Error("expected a recovery probe once the backoff has passed")
+	}
+}
+
+func TestCircuitBreakerKeysURLsOnlySitesByFirstEndpoint(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	b := newTestBreaker(&clock)
+	down := models.SiteDefinition{Name: "api", URLs: []string{"https://api-a.example", "https://api-b.example"}}
+	up := models.SiteDefinition{Name: "cdn", URLs: []string{"https://cdn-a.example", "https://cdn-b.example"}}
+
+	for i := 0; i < 3; i++ {
+		b.record(down, false)
+	}
+	if b.allow(down) {
+		t.Fatal("expected the failing URLs-only site to be backed off")
+	}
+	if !b.allow(up) {
+		t.Error("another URLs-only site must not share the failing site's breaker")
+	}
+
+	// Forgetting keeps the state of sites still configured
+	b.forget([]models.SiteDefinition{down})
+	if b.allow(down) {
+		t.Error("expected the backoff to survive forget while the site is configured")
+	}
+}
+
+func TestCircuitBreakerBackoffDoublesUpToCap(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	b := newTestBreaker(&clock)
+	site := models.SiteDefinition{URL: "https://down.example"}
+
+	var backoffs []time.Duration
+	for i := 0; i < 7; i++ {
+		info, _ := b.record(site, false)
+		if info.State == breakerOpen {
+			backoffs = append(backoffs, time.Duration(info.BackoffMs)*time.Millisecond)
+		}
+	}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	if len(backoffs) != len(want) {
+		t.Fatalf("got backoffs %v, want %v", backoffs, want)
+	}
+	for i := range want {
+		if backoffs[i] != want[i] {
+			t.Errorf("backoff %d = %v, want %v", i, backoffs[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreakerClosesOnRecovery(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	b := newTestBreaker(&clock)
+	site := models.SiteDefinition{URL: "https://flaky.example"}
+
+	for i := 0; i < 3; i++ {
+		b.record(site, false)
+	}
+	info, wasOpen := b.record(site, true)
+	if info.State != breakerClosed || info.ConsecutiveFailures != 0 || !wasOpen {
+		t.Fatalf("expected recovery to close the breaker, got %+v (wasOpen %v)", info, wasOpen)
+	}
+	if !b.allow(site) {
+		t.Error("expected full frequency after recovery")
+	}
+
+	// The next outage starts again from the initial backoff
+	for i := 0; i < 3; i++ {
+		info, _ = b.record(site, false)
+	}
+	if info.BackoffMs != time.Minute.Milliseconds() {
+		t.Errorf("expected the initial backoff after recovery, got %dms", info.BackoffMs)
+	}
+}
+
+func TestNextSiteSkipsBackedOffSites(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	sites := []models.SiteDefinition{
+		{URL: "https://a.example", Name: "a"},
+		{URL: "https://b.example", Name: "b"},
+	}
+	loop := &TestLoop{iterator: NewSiteIterator(sites), breaker: newTestBreaker(&clock)}
+
+	for i := 0; i < 3; i++ {
+		loop.breaker.record(sites[0], false)
+	}
+	for i := 0; i < 3; i++ {
+		if site, ok := loop.nextSite(); !ok || site.Name != "b" {
+			t.Fatalf("round %d: got %q (ok %v), want b", i, site.Name, ok)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		loop.breaker.record(sites[1], false)
+	}
+	if _, ok := loop.nextSite(); ok {
+		t.Error("expected no site while every site is backed off")
+	}
+
+	loop.breaker.forget(sites[1:])
+	if site, ok := loop.nextSite(); !ok || site.Name != "a" {
+		t.Errorf("expected a removed site's state to be forgotten, got %q (ok %v)", site.Name, ok)
+	}
+}
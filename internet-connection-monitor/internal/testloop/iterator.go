@@ -36,6 +36,28 @@ func (i *SiteIterator) Next() models.SiteDefinition {
 	return site
 }
 
+// SetSites replaces the site list. If the site that would have been tested
+// next is still present, iteration continues from it; otherwise it restarts
+// at the first site.
+func (i *SiteIterator) SetSites(sites []models.SiteDefinition) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	next := 0
+	if len(i.sites) > 0 {
+		identity := i.sites[i.current].GetIdentity()
+		for idx := range sites {
+			if sites[idx].GetIdentity() == identity {
+				next = idx
+				break
+			}
+		}
+	}
+
+	i.sites = sites
+	i.current = next
+}
+
 // Count returns the total number of sites
 func (i *SiteIterator) Count() int {
 	i.mu.Lock()
@@ -43,6 +65,18 @@ func (i *SiteIterator) Count() int {
 	return len(i.sites)
 }
 
+// Remaining returns how many sites are left before iteration wraps back to
+// the first site, i.e. until the current cycle through the list ends (0 when
+// the next site is the first)
+func (i *SiteIterator) Remaining() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.current == 0 {
+		return 0
+	}
+	return len(i.sites) - i.current
+}
+
 // Reset resets the iterator to the first site
 func (i *SiteIterator) Reset() {
 	i.mu.Lock()
@@ -377,3 +377,57 @@ func TestSiteIterator_TwoSites(t *testing.T) {
 		}
 	}
 }
+
+// TestSiteIterator_SetSites tests replacing the site list mid-rotation
+func TestSiteIterator_SetSites(t *testing.T) {
+	iter := NewSiteIterator([]models.SiteDefinition{
+		{URL: "https://google.com", Name: "google"},
+		{URL: "https://github.com", Name: "github"},
+		{URL: "https://example.com", Name: "example"},
+	})
+
+	iter.Next() // google; github is next
+
+	// github is still configured, so the rotation continues from it
+	iter.SetSites([]models.SiteDefinition{
+		{URL: "https://example.com", Name: "example"},
+		{URL: "https://github.com", Name: "github"},
+		{URL: "https://wikipedia.org", Name: "wikipedia"},
+	})
+	if iter.Count() != 3 {
+		t.Errorf("Expected count 3 after SetSites, got %d", iter.Count())
+	}
+	for _, want := range []string{"github", "wikipedia", "example"} {
+		if site := iter.Next(); site.Name != want {
+			t.Errorf("Expected '%s', got '%s'", want, site.Name)
+		}
+	}
+
+	// The next site was removed, so the rotation restarts
+	iter.SetSites([]models.SiteDefinition{
+		{URL: "https://wikipedia.org", Name: "wikipedia"},
+	})
+	if site := iter.Next(); site.Name != "wikipedia" {
+		t.Errorf("Expected 'wikipedia' after its neighbour was removed, got '%s'", site.Name)
+	}
+
+	iter.SetSites(nil)
+	if site := iter.Next(); site.URL != "" {
+		t.Errorf("Expected empty site after clearing the list, got '%s'", site.URL)
+	}
+}
+
+func TestSiteIterator_SetSitesURLsOnly(t *testing.T) {
+	api := models.SiteDefinition{Name: "api", URLs: []string{"https://api-a.example", "https://api-b.example"}}
+	cdn := models.SiteDefinition{Name: "cdn", URLs: []string{"https://cdn-a.example"}}
+	dns := models.SiteDefinition{Name: "dns", URLs: []string{"https://dns-a.example"}}
+	iter := NewSiteIterator([]models.SiteDefinition{api, cdn, dns})
+
+	iter.Next() // api; cdn is next
+
+	// Sites without a url are found by their first endpoint, not all by ""
+	iter.SetSites([]models.SiteDefinition{dns, api, cdn})
+	if site := iter.Next(); site.Name != "cdn" {
+		t.Errorf("Expected 'cdn' after reload, got '%s'", site.Name)
+	}
+}
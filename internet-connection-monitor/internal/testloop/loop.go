@@ -11,6 +11,7 @@ import (
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/browser"
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
 	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/metrics"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
 )
 
 const (
@@ -27,20 +28,29 @@ type TestLoop struct {
 	logger                    *slog.Logger
 	stopChan                  chan struct{}
 	consecutiveChromeFailures int
+	breaker                   *circuitBreaker      // nil unless the circuit breaker is enabled
+	networkDown               *networkDownShortcut // nil unless the network-down shortcut is enabled
 }
 
 // NewTestLoop creates a new continuous test loop
 func NewTestLoop(cfg *config.Config, browserCtrl browser.Controller, dispatcher *metrics.Dispatcher) (*TestLoop, error) {
 	iterator := NewSiteIterator(cfg.Sites.List)
 
-	return &TestLoop{
+	t := &TestLoop{
 		config:     cfg,
 		iterator:   iterator,
 		browser:    browserCtrl,
 		dispatcher: dispatcher,
 		logger:     slog.Default(),
 		stopChan:   make(chan struct{}),
-	}, nil
+	}
+	if cfg.CircuitBreaker.Enabled {
+		t.breaker = newCircuitBreaker(cfg.CircuitBreaker)
+	}
+	if cfg.NetworkDown.Enabled {
+		t.networkDown = newNetworkDownShortcut(cfg.NetworkDown.DNSFailureThreshold)
+	}
+	return t, nil
 }
 
 // Run starts the continuous testing loop
@@ -76,7 +86,11 @@ func (t *TestLoop) Run(ctx context.Context) error {
 // runSingleTest executes one test iteration
 func (t *TestLoop) runSingleTest(ctx context.Context) {
 	// Get next site
-	site := t.iterator.Next()
+	site, ok := t.nextSite()
+	if !ok {
+		t.logger.Debug("All sites are backed off by the circuit breaker")
+		return
+	}
 
 	t.logger.Debug("Testing site", "site", site.Name, "url", site.URL)
 
@@ -84,9 +98,13 @@ func (t *TestLoop) runSingleTest(ctx context.Context) {
 	result, err := t.browser.TestSite(ctx, site)
 	if err != nil {
 		// Check if this is a Chrome startup failure (resource exhaustion)
-		if errors.Is(err, browser.ErrChromeStartupFailure) {
+		var startupErr *browser.StartupError
+		if errors.As(err, &startupErr) {
 			t.consecutiveChromeFailures++
 			t.logger.Warn("Chrome failed to start",
+				"site", startupErr.Site,
+				"cause", startupErr.Err,
+				"attempts", startupErr.Attempts,
 				"consecutive_failures", t.consecutiveChromeFailures,
 				"max_allowed", maxConsecutiveChromeFailures,
 			)
@@ -105,6 +123,16 @@ func (t *TestLoop) runSingleTest(ctx context.Context) {
 			return
 		}
 
+		// The renderer crashed, twice: our browser's problem, not the site's
+		if errors.Is(err, browser.ErrRendererCrash) {
+			args := []any{"site", site.Name, "error", err}
+			if counter, ok := t.browser.(interface{ RendererCrashes() uint64 }); ok {
+				args = append(args, "renderer_crashes", counter.RendererCrashes())
+			}
+			t.logger.Warn("Chrome renderer crashed, result discarded", args...)
+			return
+		}
+
 		// Some other error - log but continue
 		t.logger.Error("Failed to test site",
 			"site", site.Name,
@@ -116,8 +144,85 @@ func (t *TestLoop) runSingleTest(ctx context.Context) {
 	// Test succeeded - reset Chrome failure counter
 	t.consecutiveChromeFailures = 0
 
+	if t.breaker != nil {
+		info, wasOpen := t.breaker.record(site, result.Status.Success)
+		result.CircuitBreaker = info
+		switch {
+		case info.State == breakerOpen:
+			t.logger.Warn("Circuit breaker open: backing off failing site",
+				"site", site.Name,
+				"consecutive_failures", info.ConsecutiveFailures,
+				"backoff", time.Duration(info.BackoffMs)*time.Millisecond,
+			)
+		case wasOpen:
+			t.logger.Info("Circuit breaker closed: site recovered", "site", site.Name)
+		}
+	}
+
 	// Dispatch result to all outputs
 	t.dispatcher.Dispatch(result)
+
+	if t.networkDown != nil && t.networkDown.record(result) {
+		t.skipRestOfCycle()
+	}
+}
+
+// skipRestOfCycle records a synthetic failure for every site left in the
+// current cycle without testing it, so the next cycle starts right away
+func (t *TestLoop) skipRestOfCycle() {
+	remaining := t.iterator.Remaining()
+	if remaining == 0 {
+		return
+	}
+	t.logger.Warn("Network appears down: skipping the rest of this cycle",
+		"dns_failures", t.networkDown.dnsFailures,
+		"skipped_sites", remaining,
+	)
+	for i := 0; i < remaining; i++ {
+		site := t.iterator.Next()
+		if t.breaker != nil && !t.breaker.allow(site) {
+			continue
+		}
+		t.dispatcher.Dispatch(skippedResult(site, t.networkDown.dnsFailures))
+	}
+}
+
+// nextSite returns the next site in the rotation that the circuit breaker
+// allows to be tested, skipping backed-off sites. ok is false when every
+// site is backed off.
+func (t *TestLoop) nextSite() (site models.SiteDefinition, ok bool) {
+	if t.breaker == nil {
+		return t.nextInRotation(), true
+	}
+	for i := 0; i < t.iterator.Count(); i++ {
+		site = t.nextInRotation()
+		if t.breaker.allow(site) {
+			return site, true
+		}
+	}
+	return models.SiteDefinition{}, false
+}
+
+// nextInRotation returns the iterator's next site, noting the start of each
+// cycle through the site list
+func (t *TestLoop) nextInRotation() models.SiteDefinition {
+	if t.networkDown != nil && t.iterator.Remaining() == 0 {
+		t.networkDown.startCycle()
+	}
+	return t.iterator.Next()
+}
+
+// Reload applies the site list from a reloaded configuration without
+// interrupting the loop. Outputs that keep per-site state are told about the
+// new list; see metrics.SiteReloader. Other settings still require a restart.
+func (t *TestLoop) Reload(cfg *config.Config) {
+	t.iterator.SetSites(cfg.Sites.List)
+	if t.breaker != nil {
+		t.breaker.forget(cfg.Sites.List)
+	}
+	t.dispatcher.ReloadSites(cfg.Sites.List)
+
+	t.logger.Info("Reloaded site list", "sites", t.iterator.Count())
 }
 
 // Stop gracefully stops the test loop
@@ -0,0 +1,82 @@
+package testloop
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// errNetworkDownSkipped is the error type of results for sites skipped by the
+// network-down shortcut
+const errNetworkDownSkipped = "ERR_NETWORK_DOWN_SKIPPED"
+
+// networkDownShortcut watches the first tests of each cycle through the site
+// list. When the first threshold tests all fail DNS the Internet is almost
+// certainly down, and testing the remaining sites only delays the next check.
+type networkDownShortcut struct {
+	threshold   int
+	dnsFailures int  // consecutive DNS failures at the start of this cycle
+	decided     bool // the cycle has tripped, or a test got past DNS
+}
+
+func newNetworkDownShortcut(threshold int) *networkDownShortcut {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &networkDownShortcut{threshold: threshold}
+}
+
+// startCycle forgets the previous cycle's outcome
+func (n *networkDownShortcut) startCycle() {
+	n.dnsFailures = 0
+	n.decided = false
+}
+
+// record notes a test result from the current cycle and reports whether the
+// rest of the cycle should be skipped. It trips at most once per cycle.
+func (n *networkDownShortcut) record(result *models.TestResult) bool {
+	if n.decided {
+		return false
+	}
+	if result.Status.Success || result.Error == nil || result.Error.FailurePhase != "dns" {
+		n.decided = true
+		return false
+	}
+	n.dnsFailures++
+	if n.dnsFailures < n.threshold {
+		return false
+	}
+	n.decided = true
+	return true
+}
+
+// skippedResult returns the synthetic failure recorded for a site that was
+// not tested because the network is down
+func skippedResult(site models.SiteDefinition, dnsFailures int) *models.TestResult {
+	hostname, _ := os.Hostname()
+	return &models.TestResult{
+		Timestamp: time.Now(),
+		TestID:    uuid.New().String(),
+		Site: models.SiteInfo{
+			URL:      site.GetIdentity(),
+			Name:     site.GetName(),
+			Category: site.Category,
+		},
+		Status: models.StatusInfo{
+			Success: false,
+			Skipped: true,
+			Message: fmt.Sprintf("Skipped: network down (first %d tests this cycle failed DNS)", dnsFailures),
+		},
+		Error: &models.ErrorInfo{
+			ErrorType:    errNetworkDownSkipped,
+			ErrorMessage: "not tested: the network appears to be down",
+		},
+		Metadata: models.TestMetadata{
+			Hostname: hostname,
+		},
+	}
+}
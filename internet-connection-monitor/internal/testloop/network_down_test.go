@@ -0,0 +1,113 @@
+package testloop
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/config"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/metrics"
+	"github.com/nickborgers/monorepo/internet-connection-monitor/internal/models"
+)
+
+// scriptedBrowser fails DNS for every site while down is set
+type scriptedBrowser struct {
+	down   bool
+	tested []string
+}
+
+func (b *scriptedBrowser) TestSite(_ context.Context, site models.SiteDefinition) (*models.TestResult, error) {
+	b.tested = append(b.tested, site.Name)
+	result := &models.TestResult{Site: models.SiteInfo{Name: site.Name}}
+	if b.down {
+		result.Error = &models.ErrorInfo{ErrorType: "ERR_NAME_NOT_RESOLVED", FailurePhase: "dns"}
+	} else {
+		result.Status.Success = true
+	}
+	return result, nil
+}
+
+func (b *scriptedBrowser) Close() error { return nil }
+
+type recordingOutput struct {
+	results []*models.TestResult
+}
+
+func (o *recordingOutput) Write(result *models.TestResult) error {
+	o.results = append(o.results, result)
+	return nil
+}
+
+func (o *recordingOutput) Name() string { return "recording" }
+
+func TestNetworkDownShortcutSkipsRestOfCycle(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.NetworkDown = config.NetworkDownConfig{Enabled: true, DNSFailureThreshold: 2}
+	cfg.Sites.List = nil
+	for i := 0; i < 5; i++ {
+		cfg.Sites.List = append(cfg.Sites.List, models.SiteDefinition{URL: fmt.Sprintf("https://site%d.example", i), Name: fmt.Sprintf("site%d", i)})
+	}
+
+	browserCtrl := &scriptedBrowser{down: true}
+	out := &recordingOutput{}
+	dispatcher := metrics.NewDispatcher()
+	dispatcher.RegisterOutput(out)
+	loop, err := NewTestLoop(cfg, browserCtrl, dispatcher)
+	if err != nil {
+		t.Fatalf("NewTestLoop: %v", err)
+	}
+
+	// Two DNS failures trip the shortcut; the other three sites are skipped
+	loop.runSingleTest(context.Background())
+	loop.runSingleTest(context.Background())
+	if len(browserCtrl.tested) != 2 || len(out.results) != 5 {
+		t.Fatalf("expected 2 tests and 5 results, got %d tests and %d results", len(browserCtrl.tested), len(out.results))
+	}
+	for i, r := range out.results[2:] {
+		want := fmt.Sprintf("site%d", i+2)
+		// No lookup happened for these sites, so no phase failed
+		if !r.Status.Skipped || r.Status.Success || r.Site.Name != want || r.Error == nil || r.Error.ErrorType != errNetworkDownSkipped || r.Error.FailurePhase != "" {
+			t.Errorf("result %d: expected a skipped failure for %s, got %+v", i+2, want, r)
+		}
+	}
+
+	// The next cycle tests every site again, starting from the first
+	browserCtrl.down = false
+	for i := 0; i < 5; i++ {
+		loop.runSingleTest(context.Background())
+	}
+	if got := browserCtrl.tested[2:]; fmt.Sprint(got) != "[site0 site1 site2 site3 site4]" {
+		t.Errorf("expected a full cycle after recovery, tested %v", got)
+	}
+	for _, r := range out.results[5:] {
+		if r.Status.Skipped {
+			t.Errorf("unexpected skipped result for %s", r.Site.Name)
+		}
+	}
+}
+
+func TestNetworkDownShortcutNeedsLeadingDNSFailures(t *testing.T) {
+	dnsFailure := &models.TestResult{Error: &models.ErrorInfo{FailurePhase: "dns"}}
+	tcpFailure := &models.TestResult{Error: &models.ErrorInfo{FailurePhase: "tcp"}}
+
+	n := newNetworkDownShortcut(2)
+	n.startCycle()
+	if n.record(tcpFailure) || n.record(dnsFailure) || n.record(dnsFailure) {
+		t.Error("expected no trip once a test in the cycle got past DNS")
+	}
+
+	n.startCycle()
+	if n.record(dnsFailure) {
+		t.Error("expected no trip below the threshold")
+	}
+	if !n.record(dnsFailure) {
+		t.Error("expected a trip at the threshold")
+	}
+	if n.record(dnsFailure) {
+		t.Error("expected at most one trip per cycle")
+	}
+
+	if newNetworkDownShortcut(0).threshold != 1 {
+		t.Error("expected the threshold clamped to 1")
+	}
+}